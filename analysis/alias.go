@@ -0,0 +1,369 @@
+// Package analysis provides dataflow and memory analyses of LLVM IR
+// functions.
+package analysis
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/llutil"
+	"github.com/wa-lang/llir/metadata"
+	"github.com/wa-lang/llir/types"
+	"github.com/wa-lang/llir/value"
+)
+
+// AliasResult is the result of an alias query.
+type AliasResult uint8
+
+// Alias results.
+const (
+	// The memory locations never overlap.
+	NoAlias AliasResult = iota
+	// The memory locations may overlap.
+	MayAlias
+	// The memory locations start at the same address and have the same size.
+	MustAlias
+)
+
+// String returns the string representation of the alias result.
+func (r AliasResult) String() string {
+	switch r {
+	case NoAlias:
+		return "NoAlias"
+	case MayAlias:
+		return "MayAlias"
+	case MustAlias:
+		return "MustAlias"
+	}
+	return fmt.Sprintf("AliasResult(%d)", uint8(r))
+}
+
+// UnknownSize denotes a memory location of unknown size.
+const UnknownSize = math.MaxUint64
+
+// AliasAnalysis is a basic alias analysis.
+//
+// The following is taken into account:
+//
+//    * distinct identified objects (allocas, global variables, functions,
+//      noalias parameters and noalias call results) never alias;
+//    * allocas never alias parameters, which point to memory allocated before
+//      the function was called;
+//    * memory locations at constant offsets from the same base (through
+//      getelementptr with constant indices) alias only if their byte ranges
+//      overlap;
+//    * accesses with !tbaa type tags of unrelated types never alias.
+type AliasAnalysis struct {
+	// Data layout used to compute getelementptr offsets and access sizes.
+	Layout *llutil.DataLayout
+}
+
+// NewAliasAnalysis returns a new alias analysis based on the given data
+// layout.
+func NewAliasAnalysis(layout *llutil.DataLayout) *AliasAnalysis {
+	return &AliasAnalysis{Layout: layout}
+}
+
+// Alias reports whether the memory locations of size sizeA at address a and of
+// size sizeB at address b may overlap. Sizes are in bytes; UnknownSize denotes
+// an unknown size.
+func (aa *AliasAnalysis) Alias(a, b value.Value, sizeA, sizeB uint64) AliasResult {
+	if a == b {
+		if sizeA == sizeB && sizeA != UnknownSize {
+			return MustAlias
+		}
+		return MayAlias
+	}
+	baseA, offA, okA := aa.decompose(a)
+	baseB, offB, okB := aa.decompose(b)
+	if isNullPtr(baseA) || isNullPtr(baseB) {
+		return NoAlias
+	}
+	if baseA == baseB {
+		if !okA || !okB {
+			return MayAlias
+		}
+		if offA == offB && sizeA == sizeB && sizeA != UnknownSize {
+			return MustAlias
+		}
+		// Check whether the byte ranges overlap.
+		if offA < offB {
+			if sizeA != UnknownSize && offB-offA >= int64(sizeA) {
+				return NoAlias
+			}
+		} else if offB < offA {
+			if sizeB != UnknownSize && offA-offB >= int64(sizeB) {
+				return NoAlias
+			}
+		}
+		return MayAlias
+	}
+	if aa.distinctObjects(baseA, baseB) {
+		return NoAlias
+	}
+	return MayAlias
+}
+
+// AliasAccess reports whether the memory accessed by the given load or store
+// instructions may overlap, taking the access sizes and !tbaa metadata
+// attachments into account. AliasAccess reports MayAlias for other
+// instructions.
+func (aa *AliasAnalysis) AliasAccess(a, b llir.Instruction) AliasResult {
+	ptrA, typA, ok := accessOf(a)
+	if !ok {
+		return MayAlias
+	}
+	ptrB, typB, ok := accessOf(b)
+	if !ok {
+		return MayAlias
+	}
+	if !TBAAMayAlias(tbaaTag(a), tbaaTag(b)) {
+		return NoAlias
+	}
+	return aa.Alias(ptrA, ptrB, aa.storeSize(typA), aa.storeSize(typB))
+}
+
+// storeSize returns the store size in bytes of the given type; or UnknownSize
+// if the type is unsized (e.g. an opaque struct type).
+func (aa *AliasAnalysis) storeSize(typ types.Type) uint64 {
+	if !llutil.IsSized(typ) {
+		return UnknownSize
+	}
+	return aa.Layout.TypeStoreSize(typ)
+}
+
+// decompose returns the base object of the given pointer, and its constant
+// offset in bytes from the base. The boolean result reports whether the offset
+// is known.
+func (aa *AliasAnalysis) decompose(v value.Value) (base value.Value, offset int64, ok bool) {
+	ok = true
+	for {
+		switch p := v.(type) {
+		case *llir.InstBitCast:
+			v = p.From
+		case *constant.ExprBitCast:
+			v = p.From
+		case *llir.InstAddrSpaceCast:
+			v = p.From
+		case *constant.ExprAddrSpaceCast:
+			v = p.From
+		case *llir.InstGetElementPtr:
+			off, known := aa.gepOffset(p.ElemType, p.Indices)
+			offset += off
+			ok = ok && known
+			v = p.Src
+		case *constant.ExprGetElementPtr:
+			indices := make([]value.Value, len(p.Indices))
+			for i, index := range p.Indices {
+				indices[i] = index
+			}
+			off, known := aa.gepOffset(p.ElemType, indices)
+			offset += off
+			ok = ok && known
+			v = p.Src
+		default:
+			return v, offset, ok
+		}
+	}
+}
+
+// gepOffset returns the constant offset in bytes of the getelementptr with the
+// given element type and indices. The boolean result reports whether the
+// offset is constant.
+func (aa *AliasAnalysis) gepOffset(elemType types.Type, indices []value.Value) (int64, bool) {
+	if !llutil.IsSized(elemType) {
+		return 0, false
+	}
+	offset := int64(0)
+	var t types.Type
+	for i, index := range indices {
+		if c, ok := index.(*constant.Index); ok {
+			index = c.Constant
+		}
+		x, ok := index.(*constant.Int)
+		if !ok || !x.X.IsInt64() {
+			return 0, false
+		}
+		idx := x.X.Int64()
+		if i == 0 {
+			t = elemType
+			offset += idx * int64(aa.Layout.TypeAllocSize(t))
+			continue
+		}
+		switch tt := t.(type) {
+		case *types.StructType:
+			if idx < 0 || idx >= int64(len(tt.Fields)) {
+				return 0, false
+			}
+			offset += int64(aa.Layout.StructFieldOffset(tt, int(idx)))
+			t = tt.Fields[idx]
+		case *types.ArrayType:
+			t = tt.ElemType
+			offset += idx * int64(aa.Layout.TypeAllocSize(t))
+		case *types.VectorType:
+			t = tt.ElemType
+			offset += idx * int64(aa.Layout.TypeAllocSize(t))
+		default:
+			return 0, false
+		}
+	}
+	return offset, true
+}
+
+// distinctObjects reports whether the given distinct base objects are known
+// not to overlap.
+func (aa *AliasAnalysis) distinctObjects(a, b value.Value) bool {
+	if isIdentifiedObject(a) && isIdentifiedObject(b) {
+		return true
+	}
+	// Allocas do not alias memory pointed to by parameters.
+	_, allocaA := a.(*llir.InstAlloca)
+	_, allocaB := b.(*llir.InstAlloca)
+	_, paramA := a.(*llir.Param)
+	_, paramB := b.(*llir.Param)
+	if (allocaA && paramB) || (paramA && allocaB) {
+		return true
+	}
+	// Memory accessed through a noalias parameter is not accessed through
+	// other parameters.
+	if paramA && paramB && (isNoAliasParam(a) || isNoAliasParam(b)) {
+		return true
+	}
+	return false
+}
+
+// isIdentifiedObject reports whether the given base object is a distinct
+// object, not aliasing any other identified object.
+func isIdentifiedObject(v value.Value) bool {
+	switch v := v.(type) {
+	case *llir.InstAlloca, *llir.Global, *llir.Func:
+		return true
+	case *llir.Param:
+		return isNoAliasParam(v)
+	case *llir.InstCall:
+		for _, attr := range v.ReturnAttrs {
+			if attr == enum.ReturnAttrNoAlias {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isNoAliasParam reports whether the given value is a noalias parameter.
+func isNoAliasParam(v value.Value) bool {
+	param, ok := v.(*llir.Param)
+	if !ok {
+		return false
+	}
+	for _, attr := range param.Attrs {
+		if attr == enum.ParamAttrNoAlias {
+			return true
+		}
+	}
+	return false
+}
+
+// isNullPtr reports whether the given value is a null pointer in the default
+// address space, which does not point to any object.
+func isNullPtr(v value.Value) bool {
+	c, ok := v.(*constant.Null)
+	return ok && c.Typ.AddrSpace == 0
+}
+
+// accessOf returns the pointer operand and accessed type of the given load or
+// store instruction.
+func accessOf(inst llir.Instruction) (ptr value.Value, typ types.Type, ok bool) {
+	switch inst := inst.(type) {
+	case *llir.InstLoad:
+		return inst.Src, inst.ElemType, true
+	case *llir.InstStore:
+		return inst.Dst, inst.Src.Type(), true
+	}
+	return nil, nil, false
+}
+
+// tbaaTag returns the !tbaa metadata attachment of the given instruction; or
+// nil if not present.
+func tbaaTag(inst llir.Instruction) *metadata.Tuple {
+	v, ok := inst.(interface {
+		MDAttachments() []*metadata.Attachment
+	})
+	if !ok {
+		return nil
+	}
+	for _, md := range v.MDAttachments() {
+		if md.Name == "tbaa" {
+			tag, _ := md.Node.(*metadata.Tuple)
+			return tag
+		}
+	}
+	return nil
+}
+
+// TBAAMayAlias reports whether accesses with the given !tbaa type tags may
+// alias. Accesses may alias if either tag is nil, if the tags belong to
+// different type hierarchies, or if the access type of one tag is an ancestor
+// of (or the same as) the access type of the other.
+//
+// Both struct-path tags (!{base type, access type, offset}) and scalar type
+// nodes (!{!"name", parent}) are supported as tags; for struct-path tags only
+// the access types are compared.
+func TBAAMayAlias(a, b *metadata.Tuple) bool {
+	if a == nil || b == nil {
+		return true
+	}
+	pathA := tbaaPath(tbaaAccessType(a))
+	pathB := tbaaPath(tbaaAccessType(b))
+	if len(pathA) == 0 || len(pathB) == 0 {
+		return true
+	}
+	// Different roots.
+	if pathA[len(pathA)-1] != pathB[len(pathB)-1] {
+		return true
+	}
+	for _, t := range pathA {
+		if t == pathB[0] {
+			return true
+		}
+	}
+	for _, t := range pathB {
+		if t == pathA[0] {
+			return true
+		}
+	}
+	return false
+}
+
+// tbaaAccessType returns the access type node of the given !tbaa type tag.
+func tbaaAccessType(tag *metadata.Tuple) *metadata.Tuple {
+	// Struct-path tag: !{base type, access type, offset}.
+	if len(tag.Fields) >= 3 {
+		if _, ok := tag.Fields[0].(*metadata.Tuple); ok {
+			t, _ := tag.Fields[1].(*metadata.Tuple)
+			return t
+		}
+	}
+	// Scalar type node used as tag.
+	return tag
+}
+
+// tbaaPath returns the path from the given type node to the root of its type
+// hierarchy, inclusive.
+func tbaaPath(t *metadata.Tuple) []*metadata.Tuple {
+	var path []*metadata.Tuple
+	seen := make(map[*metadata.Tuple]bool)
+	for t != nil && !seen[t] {
+		seen[t] = true
+		path = append(path, t)
+		if len(t.Fields) < 2 {
+			// Root node: !{!"name"}.
+			break
+		}
+		t, _ = t.Fields[1].(*metadata.Tuple)
+	}
+	return path
+}
@@ -0,0 +1,119 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/llutil"
+	"github.com/wa-lang/llir/metadata"
+	"github.com/wa-lang/llir/types"
+	"github.com/wa-lang/llir/value"
+)
+
+func TestAlias(t *testing.T) {
+	aa := NewAliasAnalysis(llutil.NewDataLayout("linux", "x86-64"))
+	m := llir.NewModule()
+	st := types.NewStruct(types.I32, types.I64, types.NewArray(4, types.I8))
+	g := m.NewGlobal("g", st)
+	h := m.NewGlobal("h", types.I32)
+	p := llir.NewParam("p", types.I8Ptr)
+	q := llir.NewParam("q", types.I8Ptr)
+	r := llir.NewParam("r", types.I8Ptr)
+	r.Attrs = append(r.Attrs, enum.ParamAttrNoAlias)
+	n := llir.NewParam("n", types.I64)
+	f := m.NewFunc("f", types.Void, p, q, r, n)
+	entry := f.NewBlock("entry")
+	a1 := entry.NewAlloca(types.I32)
+	a2 := entry.NewAlloca(st)
+	i32 := func(x int64) *constant.Int { return constant.NewInt(types.I32, x) }
+	field0 := entry.NewGetElementPtr(st, g, i32(0), i32(0))
+	field1 := entry.NewGetElementPtr(st, g, i32(0), i32(1))
+	field1Cast := entry.NewBitCast(field1, types.I8Ptr)
+	elem2 := entry.NewGetElementPtr(st, g, i32(0), i32(2), i32(2))
+	elemN := entry.NewGetElementPtr(st, g, i32(0), i32(2), n)
+	a2Field1 := constant.NewGetElementPtr(st, g, i32(0), i32(1))
+	entry.NewRet(nil)
+
+	golden := []struct {
+		name         string
+		a, b         value.Value
+		sizeA, sizeB uint64
+		want         AliasResult
+	}{
+		{name: "same pointer", a: p, b: p, sizeA: 4, sizeB: 4, want: MustAlias},
+		{name: "distinct allocas", a: a1, b: a2, sizeA: 4, sizeB: 4, want: NoAlias},
+		{name: "distinct globals", a: g, b: h, sizeA: 4, sizeB: 4, want: NoAlias},
+		{name: "alloca and param", a: a1, b: p, sizeA: 4, sizeB: 4, want: NoAlias},
+		{name: "global and param", a: g, b: p, sizeA: 4, sizeB: 4, want: MayAlias},
+		{name: "params", a: p, b: q, sizeA: 4, sizeB: 4, want: MayAlias},
+		{name: "noalias param", a: p, b: r, sizeA: 4, sizeB: 4, want: NoAlias},
+		{name: "disjoint fields", a: field0, b: field1, sizeA: 4, sizeB: 8, want: NoAlias},
+		{name: "overlapping fields", a: field0, b: field1, sizeA: 12, sizeB: 8, want: MayAlias},
+		{name: "field and cast", a: field1, b: field1Cast, sizeA: 8, sizeB: 8, want: MustAlias},
+		{name: "instruction and constant gep", a: field1, b: a2Field1, sizeA: 8, sizeB: 8, want: MustAlias},
+		{name: "array element", a: elem2, b: field1, sizeA: 1, sizeB: 8, want: NoAlias},
+		{name: "variable index", a: elemN, b: elem2, sizeA: 1, sizeB: 1, want: MayAlias},
+		{name: "variable index and other object", a: elemN, b: a1, sizeA: 1, sizeB: 4, want: NoAlias},
+		{name: "null", a: constant.NewNull(types.I8Ptr), b: p, sizeA: 1, sizeB: 1, want: NoAlias},
+		{name: "unknown size", a: field0, b: field1, sizeA: UnknownSize, sizeB: 8, want: MayAlias},
+	}
+	for _, gold := range golden {
+		got := aa.Alias(gold.a, gold.b, gold.sizeA, gold.sizeB)
+		if got != gold.want {
+			t.Errorf("%s: expected %v, got %v", gold.name, gold.want, got)
+		}
+	}
+}
+
+func TestAliasAccessTBAA(t *testing.T) {
+	aa := NewAliasAnalysis(llutil.NewDataLayout("linux", "x86-64"))
+	root := &metadata.Tuple{MetadataID: -1, Fields: []metadata.Field{&metadata.String{Value: "Simple C/C++ TBAA"}}}
+	char := &metadata.Tuple{MetadataID: -1, Fields: []metadata.Field{&metadata.String{Value: "omnipotent char"}, root, constant.NewInt(types.I64, 0)}}
+	intType := &metadata.Tuple{MetadataID: -1, Fields: []metadata.Field{&metadata.String{Value: "int"}, char, constant.NewInt(types.I64, 0)}}
+	floatType := &metadata.Tuple{MetadataID: -1, Fields: []metadata.Field{&metadata.String{Value: "float"}, char, constant.NewInt(types.I64, 0)}}
+	tag := func(typ *metadata.Tuple) *metadata.Tuple {
+		return &metadata.Tuple{MetadataID: -1, Fields: []metadata.Field{typ, typ, constant.NewInt(types.I64, 0)}}
+	}
+	p := llir.NewParam("p", types.I8Ptr)
+	q := llir.NewParam("q", types.I8Ptr)
+	f := llir.NewFunc("f", types.Void, p, q)
+	entry := f.NewBlock("entry")
+	pi := entry.NewBitCast(p, types.I32Ptr)
+	qf := entry.NewBitCast(q, types.NewPointer(types.Float))
+	loadInt := entry.NewLoad(types.I32, pi)
+	loadInt.Metadata = append(loadInt.Metadata, &metadata.Attachment{Name: "tbaa", Node: tag(intType)})
+	storeFloat := entry.NewStore(constant.NewFloat(types.Float, 1), qf)
+	storeFloat.Metadata = append(storeFloat.Metadata, &metadata.Attachment{Name: "tbaa", Node: tag(floatType)})
+	storeChar := entry.NewStore(constant.NewInt(types.I8, 1), q)
+	storeChar.Metadata = append(storeChar.Metadata, &metadata.Attachment{Name: "tbaa", Node: tag(char)})
+	storeUntagged := entry.NewStore(constant.NewInt(types.I32, 1), pi)
+	entry.NewRet(nil)
+
+	if got := aa.AliasAccess(loadInt, storeFloat); got != NoAlias {
+		t.Errorf("int and float accesses; expected %v, got %v", NoAlias, got)
+	}
+	if got := aa.AliasAccess(loadInt, storeChar); got != MayAlias {
+		t.Errorf("int and char accesses; expected %v, got %v", MayAlias, got)
+	}
+	if got := aa.AliasAccess(loadInt, storeUntagged); got != MustAlias {
+		t.Errorf("int and untagged accesses; expected %v, got %v", MustAlias, got)
+	}
+}
+
+func TestAliasAccessUnsized(t *testing.T) {
+	aa := NewAliasAnalysis(llutil.NewDataLayout("linux", "x86-64"))
+	opaque := &types.StructType{TypeName: "T", Opaque: true}
+	p := llir.NewParam("p", types.NewPointer(opaque))
+	f := llir.NewFunc("f", types.Void, p)
+	entry := f.NewBlock("entry")
+	elem := entry.NewGetElementPtr(opaque, p, constant.NewInt(types.I64, 1))
+	loadOpaque := entry.NewLoad(opaque, p)
+	store := entry.NewStore(constant.NewInt(types.I32, 1), entry.NewBitCast(elem, types.I32Ptr))
+	entry.NewRet(nil)
+
+	if got := aa.AliasAccess(loadOpaque, store); got != MayAlias {
+		t.Errorf("opaque and i32 accesses; expected %v, got %v", MayAlias, got)
+	}
+}
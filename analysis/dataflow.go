@@ -0,0 +1,258 @@
+package analysis
+
+import (
+	"fmt"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/cfg"
+)
+
+// Fact is a dataflow fact; an element of the lattice of a dataflow problem.
+// Facts are treated as immutable values by the solver.
+type Fact interface{}
+
+// Lattice is the lattice of facts of a dataflow problem.
+type Lattice interface {
+	// Bottom returns the least element of the lattice; the identity of Join.
+	// All facts are initialised to Bottom.
+	Bottom() Fact
+	// Top returns the greatest element of the lattice; the most conservative
+	// fact. Facts of basic blocks visited more than MaxVisits times are set to
+	// Top, which guarantees termination for lattices of unbounded height.
+	Top() Fact
+	// Join returns the least upper bound of a and b. Join must not modify a or
+	// b.
+	Join(a, b Fact) Fact
+	// Equal reports whether a and b are equal.
+	Equal(a, b Fact) bool
+}
+
+// Direction is the direction of a dataflow analysis.
+type Direction uint8
+
+// Dataflow directions.
+const (
+	// Facts flow from the entry basic block along control flow edges.
+	Forward Direction = iota
+	// Facts flow from the exit basic blocks against control flow edges.
+	Backward
+)
+
+// String returns the string representation of the dataflow direction.
+func (d Direction) String() string {
+	switch d {
+	case Forward:
+		return "Forward"
+	case Backward:
+		return "Backward"
+	}
+	return fmt.Sprintf("Direction(%d)", uint8(d))
+}
+
+// Problem is a dataflow problem.
+type Problem interface {
+	Lattice
+	// Direction returns the direction of the analysis.
+	Direction() Direction
+	// Boundary returns the fact on entry to the function for forward analyses,
+	// or on exit from the function (i.e. after the terminators of basic blocks
+	// without successors) for backward analyses.
+	Boundary() Fact
+	// Transfer returns the fact after the given instruction or terminator in
+	// the direction of the analysis, given the fact before it; i.e. the fact
+	// after inst in program order for forward analyses, and the fact before
+	// inst for backward analyses. Transfer must not modify fact.
+	Transfer(inst interface{}, fact Fact) Fact
+}
+
+// MaxVisits is the maximum number of times the solver visits a basic block
+// before setting its fact to Top.
+const MaxVisits = 100
+
+// Result is the solution of a dataflow problem.
+type Result struct {
+	// Dataflow problem.
+	Problem Problem
+	// Control flow graph of the function.
+	Graph *cfg.Graph
+
+	// Facts on entry to and exit from each basic block, in program order.
+	in, out map[*llir.Block]Fact
+	// Parent basic block of each instruction and terminator.
+	parent map[interface{}]*llir.Block
+}
+
+// Solve solves the given dataflow problem over the control flow graph of a
+// function, using a worklist algorithm. Basic blocks are visited in reverse
+// post-order for forward problems and in post-order for backward problems.
+//
+// The solver terminates if the transfer functions are monotone and the lattice
+// has finite height; lattices of unbounded height are handled by setting the
+// facts of basic blocks visited more than MaxVisits times to Top.
+func Solve(g *cfg.Graph, p Problem) *Result {
+	r := &Result{
+		Problem: p,
+		Graph:   g,
+		in:      make(map[*llir.Block]Fact),
+		out:     make(map[*llir.Block]Fact),
+		parent:  make(map[interface{}]*llir.Block),
+	}
+	f := g.Func
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			r.parent[inst] = block
+		}
+		if block.Term != nil {
+			r.parent[block.Term] = block
+		}
+		r.in[block] = p.Bottom()
+		r.out[block] = p.Bottom()
+	}
+	// Visiting order of basic blocks, followed by basic blocks unreachable from
+	// entry.
+	order := g.ReversePostOrder()
+	if p.Direction() == Backward {
+		order = g.PostOrder()
+	}
+	order = append(order[:len(order):len(order)], g.Unreachable()...)
+	// Basic blocks to visit; visited in order, each iteration visiting the
+	// basic blocks of the worklist.
+	inWork := make(map[*llir.Block]bool)
+	for _, block := range order {
+		inWork[block] = true
+	}
+	visits := make(map[*llir.Block]int)
+	for pending := len(order); pending > 0; {
+		for _, block := range order {
+			if !inWork[block] {
+				continue
+			}
+			inWork[block] = false
+			pending--
+			visits[block]++
+			if !r.visit(block, visits[block] > MaxVisits) {
+				continue
+			}
+			// Revisit dependent basic blocks.
+			deps := g.Succs(block)
+			if p.Direction() == Backward {
+				deps = g.Preds(block)
+			}
+			for _, dep := range deps {
+				if !inWork[dep] {
+					inWork[dep] = true
+					pending++
+				}
+			}
+		}
+	}
+	return r
+}
+
+// visit recomputes the facts of the given basic block, and reports whether the
+// fact propagated to dependent basic blocks changed. If top is set, the
+// propagated fact is set to Top.
+func (r *Result) visit(block *llir.Block, top bool) bool {
+	p := r.Problem
+	g := r.Graph
+	if p.Direction() == Forward {
+		in := p.Bottom()
+		if block == g.Entry {
+			in = p.Boundary()
+		}
+		for _, pred := range g.Preds(block) {
+			in = p.Join(in, r.out[pred])
+		}
+		r.in[block] = in
+		out := r.transferBlock(block, in, nil)
+		if top {
+			out = p.Top()
+		}
+		if p.Equal(out, r.out[block]) {
+			return false
+		}
+		r.out[block] = out
+		return true
+	}
+	out := p.Bottom()
+	succs := g.Succs(block)
+	if len(succs) == 0 {
+		out = p.Boundary()
+	}
+	for _, succ := range succs {
+		out = p.Join(out, r.in[succ])
+	}
+	r.out[block] = out
+	in := r.transferBlock(block, out, nil)
+	if top {
+		in = p.Top()
+	}
+	if p.Equal(in, r.in[block]) {
+		return false
+	}
+	r.in[block] = in
+	return true
+}
+
+// transferBlock applies the transfer functions of the instructions and
+// terminator of the given basic block in the direction of the analysis, given
+// the fact at the start of the basic block in that direction. If stop is
+// non-nil, the fact before stop in the direction of the analysis is returned.
+func (r *Result) transferBlock(block *llir.Block, fact Fact, stop interface{}) Fact {
+	p := r.Problem
+	var insts []interface{}
+	for _, inst := range block.Insts {
+		insts = append(insts, inst)
+	}
+	if block.Term != nil {
+		insts = append(insts, block.Term)
+	}
+	if p.Direction() == Backward {
+		for i, j := 0, len(insts)-1; i < j; i, j = i+1, j-1 {
+			insts[i], insts[j] = insts[j], insts[i]
+		}
+	}
+	for _, inst := range insts {
+		if inst == stop {
+			return fact
+		}
+		fact = p.Transfer(inst, fact)
+	}
+	return fact
+}
+
+// In returns the fact on entry to the given basic block.
+func (r *Result) In(block *llir.Block) Fact {
+	return r.in[block]
+}
+
+// Out returns the fact on exit from the given basic block.
+func (r *Result) Out(block *llir.Block) Fact {
+	return r.out[block]
+}
+
+// Before returns the fact immediately before the given instruction or
+// terminator in program order; or nil if not part of the function.
+func (r *Result) Before(inst interface{}) Fact {
+	block, ok := r.parent[inst]
+	if !ok {
+		return nil
+	}
+	if r.Problem.Direction() == Forward {
+		return r.transferBlock(block, r.in[block], inst)
+	}
+	return r.Problem.Transfer(inst, r.After(inst))
+}
+
+// After returns the fact immediately after the given instruction or terminator
+// in program order; or nil if not part of the function.
+func (r *Result) After(inst interface{}) Fact {
+	block, ok := r.parent[inst]
+	if !ok {
+		return nil
+	}
+	if r.Problem.Direction() == Forward {
+		return r.Problem.Transfer(inst, r.Before(inst))
+	}
+	return r.transferBlock(block, r.out[block], inst)
+}
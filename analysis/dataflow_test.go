@@ -0,0 +1,208 @@
+package analysis
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/cfg"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/types"
+	"github.com/wa-lang/llir/value"
+)
+
+// set is an immutable set used as dataflow fact.
+type set map[interface{}]bool
+
+// with returns a copy of s with the given elements added and removed.
+func (s set) with(add, remove []interface{}) set {
+	t := make(set)
+	for x := range s {
+		t[x] = true
+	}
+	for _, x := range remove {
+		delete(t, x)
+	}
+	for _, x := range add {
+		t[x] = true
+	}
+	return t
+}
+
+// String returns the sorted identifiers of the elements of the set.
+func (s set) String() string {
+	var ids []string
+	for x := range s {
+		switch x := x.(type) {
+		case value.Value:
+			ids = append(ids, x.Ident())
+		case *llir.InstStore:
+			ids = append(ids, "store "+x.Src.Ident())
+		}
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, ", ")
+}
+
+// setLattice is a lattice of sets ordered by inclusion.
+type setLattice struct{}
+
+func (setLattice) Bottom() Fact { return set{} }
+func (setLattice) Top() Fact    { panic("top of set lattice") }
+func (setLattice) Join(a, b Fact) Fact {
+	var add []interface{}
+	for x := range b.(set) {
+		add = append(add, x)
+	}
+	return a.(set).with(add, nil)
+}
+func (setLattice) Equal(a, b Fact) bool {
+	x, y := a.(set), b.(set)
+	if len(x) != len(y) {
+		return false
+	}
+	for e := range x {
+		if !y[e] {
+			return false
+		}
+	}
+	return true
+}
+
+// reachingStores is a forward dataflow problem computing the store
+// instructions reaching each program point.
+type reachingStores struct {
+	setLattice
+}
+
+func (reachingStores) Direction() Direction { return Forward }
+func (reachingStores) Boundary() Fact       { return set{} }
+func (reachingStores) Transfer(inst interface{}, fact Fact) Fact {
+	store, ok := inst.(*llir.InstStore)
+	if !ok {
+		return fact
+	}
+	// Kill stores to the same address.
+	var kill []interface{}
+	for x := range fact.(set) {
+		if x.(*llir.InstStore).Dst == store.Dst {
+			kill = append(kill, x)
+		}
+	}
+	return fact.(set).with([]interface{}{store}, kill)
+}
+
+// liveValues is a backward dataflow problem computing the live SSA values of
+// functions without phi instructions.
+type liveValues struct {
+	setLattice
+}
+
+func (liveValues) Direction() Direction { return Backward }
+func (liveValues) Boundary() Fact       { return set{} }
+func (liveValues) Transfer(inst interface{}, fact Fact) Fact {
+	var uses []interface{}
+	for _, op := range inst.(interface{ Operands() []*value.Value }).Operands() {
+		switch (*op).(type) {
+		case *llir.Param, llir.Instruction:
+			uses = append(uses, *op)
+		}
+	}
+	var defs []interface{}
+	if v, ok := inst.(value.Value); ok {
+		defs = append(defs, v)
+	}
+	return fact.(set).with(uses, defs)
+}
+
+// counter is a forward dataflow problem of unbounded height, counting the
+// number of loop iterations.
+type counter struct{}
+
+func (counter) Bottom() Fact { return 0 }
+func (counter) Top() Fact    { return math.MaxInt32 }
+func (counter) Join(a, b Fact) Fact {
+	if a.(int) > b.(int) {
+		return a
+	}
+	return b
+}
+func (counter) Equal(a, b Fact) bool { return a == b }
+func (counter) Direction() Direction { return Forward }
+func (counter) Boundary() Fact       { return 0 }
+func (counter) Transfer(inst interface{}, fact Fact) Fact {
+	if _, ok := inst.(*llir.InstAdd); ok && fact.(int) != math.MaxInt32 {
+		return fact.(int) + 1
+	}
+	return fact
+}
+
+func TestSolve(t *testing.T) {
+	i32 := func(x int64) *constant.Int { return constant.NewInt(types.I32, x) }
+	c := llir.NewParam("c", types.I1)
+	f := llir.NewFunc("f", types.Void, c)
+	entry := f.NewBlock("entry")
+	loop := f.NewBlock("loop")
+	exit := f.NewBlock("exit")
+	p := entry.NewAlloca(types.I32)
+	p.SetName("p")
+	entry.NewStore(i32(0), p)
+	entry.NewBr(loop)
+	v := loop.NewLoad(types.I32, p)
+	v.SetName("v")
+	w := loop.NewAdd(v, i32(1))
+	w.SetName("w")
+	store := loop.NewStore(w, p)
+	loop.NewCondBr(c, loop, exit)
+	r := exit.NewLoad(types.I32, p)
+	r.SetName("r")
+	exit.NewRet(nil)
+	g := cfg.New(f)
+
+	// Forward problem.
+	res := Solve(g, reachingStores{})
+	facts := []struct {
+		name string
+		got  Fact
+		want string
+	}{
+		{name: "in entry", got: res.In(entry), want: ""},
+		{name: "out entry", got: res.Out(entry), want: "store 0"},
+		{name: "in loop", got: res.In(loop), want: "store %w, store 0"},
+		{name: "before store", got: res.Before(store), want: "store %w, store 0"},
+		{name: "after store", got: res.After(store), want: "store %w"},
+		{name: "in exit", got: res.In(exit), want: "store %w"},
+	}
+	for _, fact := range facts {
+		if got := fact.got.(set).String(); got != fact.want {
+			t.Errorf("reaching stores %s mismatch; expected %q, got %q", fact.name, fact.want, got)
+		}
+	}
+
+	// Backward problem, compared with the liveness analysis.
+	res = Solve(g, liveValues{})
+	lv := NewLiveness(g)
+	for _, block := range f.Blocks {
+		var want []string
+		for _, v := range lv.LiveIn(block) {
+			want = append(want, v.Ident())
+		}
+		if got := res.In(block).(set).String(); got != strings.Join(want, ", ") {
+			t.Errorf("live-in of %s mismatch; expected %q, got %q", block.Ident(), want, got)
+		}
+	}
+	if got, want := res.Before(w).(set).String(), "%c, %p, %v"; got != want {
+		t.Errorf("live before %%w mismatch; expected %q, got %q", want, got)
+	}
+	if got, want := res.After(w).(set).String(), "%c, %p, %w"; got != want {
+		t.Errorf("live after %%w mismatch; expected %q, got %q", want, got)
+	}
+
+	// Lattice of unbounded height.
+	res = Solve(g, counter{})
+	if got, want := res.Out(loop), math.MaxInt32; got != want {
+		t.Errorf("counter on exit from loop mismatch; expected %v, got %v", want, got)
+	}
+}
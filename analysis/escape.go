@@ -0,0 +1,239 @@
+package analysis
+
+import (
+	"fmt"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/llutil"
+	"github.com/wa-lang/llir/value"
+)
+
+// EscapeKind specifies how the address of an allocation escapes.
+type EscapeKind uint8
+
+// Escape kinds.
+const (
+	// The address does not escape.
+	NoEscape EscapeKind = iota
+	// The address is stored to memory.
+	EscapeStore
+	// The address is passed to a call without nocapture.
+	EscapeCall
+	// The address is returned.
+	EscapeReturn
+	// The address is converted to an integer by ptrtoint.
+	EscapePtrToInt
+	// The address is used by an instruction which is not analysed (e.g.
+	// insertvalue); assumed to escape.
+	EscapeOther
+)
+
+// String returns the string representation of the escape kind.
+func (kind EscapeKind) String() string {
+	switch kind {
+	case NoEscape:
+		return "NoEscape"
+	case EscapeStore:
+		return "EscapeStore"
+	case EscapeCall:
+		return "EscapeCall"
+	case EscapeReturn:
+		return "EscapeReturn"
+	case EscapePtrToInt:
+		return "EscapePtrToInt"
+	case EscapeOther:
+		return "EscapeOther"
+	}
+	return fmt.Sprintf("EscapeKind(%d)", uint8(kind))
+}
+
+// EscapeAnalysis determines whether the addresses of the allocations of a
+// function escape the function. Allocations are alloca instructions and calls
+// to allocation functions (e.g. malloc or the gc_alloc function of a language
+// runtime), returning the address of newly allocated memory.
+//
+// The address of an allocation escapes if the address, or a pointer derived
+// from it through getelementptr, bitcast, addrspacecast, phi or select
+// instructions, is stored to memory, passed to a call without the nocapture
+// parameter attribute, returned or converted to an integer. Loading from and
+// storing to the allocation, as well as comparing its address, do not cause
+// the address to escape.
+type EscapeAnalysis struct {
+	// Function of the escape analysis.
+	Func *llir.Func
+	// Names of allocation functions (without '@' prefix).
+	AllocFuncs []string
+
+	// Users of each value, in function order.
+	users map[value.Value][]interface{}
+}
+
+// NewEscapeAnalysis returns a new escape analysis of the given function. Calls
+// to the named allocation functions are treated as allocations.
+func NewEscapeAnalysis(f *llir.Func, allocFuncs ...string) *EscapeAnalysis {
+	return &EscapeAnalysis{
+		Func:       f,
+		AllocFuncs: allocFuncs,
+		users:      llutil.Users(f),
+	}
+}
+
+// IsAllocation reports whether the given value is an allocation; i.e. an alloca
+// instruction or a call to an allocation function.
+func (ea *EscapeAnalysis) IsAllocation(v value.Value) bool {
+	switch v := v.(type) {
+	case *llir.InstAlloca:
+		return true
+	case *llir.InstCall:
+		f, ok := stripCasts(v.Callee).(*llir.Func)
+		if !ok {
+			return false
+		}
+		for _, name := range ea.AllocFuncs {
+			if f.Name() == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Allocations returns the allocations of the function, in function order.
+func (ea *EscapeAnalysis) Allocations() []value.Value {
+	var allocs []value.Value
+	for _, block := range ea.Func.Blocks {
+		for _, inst := range block.Insts {
+			if v, ok := inst.(value.Value); ok && ea.IsAllocation(v) {
+				allocs = append(allocs, v)
+			}
+		}
+	}
+	return allocs
+}
+
+// NonEscaping returns the allocations of the function whose addresses do not
+// escape, in function order. Such heap allocations may be moved to the stack.
+func (ea *EscapeAnalysis) NonEscaping() []value.Value {
+	var allocs []value.Value
+	for _, alloc := range ea.Allocations() {
+		if !ea.Escapes(alloc) {
+			allocs = append(allocs, alloc)
+		}
+	}
+	return allocs
+}
+
+// Escapes reports whether the address of the given allocation escapes the
+// function.
+func (ea *EscapeAnalysis) Escapes(alloc value.Value) bool {
+	kind, _ := ea.EscapeOf(alloc)
+	return kind != NoEscape
+}
+
+// EscapeOf returns how the address of the given allocation escapes the
+// function, and the first instruction or terminator through which it escapes;
+// or NoEscape and nil if the address does not escape.
+func (ea *EscapeAnalysis) EscapeOf(alloc value.Value) (EscapeKind, interface{}) {
+	visited := map[value.Value]bool{alloc: true}
+	work := []value.Value{alloc}
+	for len(work) > 0 {
+		ptr := work[0]
+		work = work[1:]
+		for _, user := range ea.users[ptr] {
+			kind, derived := ea.escapeThrough(ptr, user)
+			if kind != NoEscape {
+				return kind, user
+			}
+			if derived != nil && !visited[derived] {
+				visited[derived] = true
+				work = append(work, derived)
+			}
+		}
+	}
+	return NoEscape, nil
+}
+
+// escapeThrough returns how the given pointer escapes through its use by user.
+// If user derives a new pointer from ptr, the derived pointer is returned.
+func (ea *EscapeAnalysis) escapeThrough(ptr value.Value, user interface{}) (kind EscapeKind, derived value.Value) {
+	switch user := user.(type) {
+	// Memory accesses.
+	case *llir.InstLoad:
+		return NoEscape, nil
+	case *llir.InstStore:
+		if user.Src == ptr {
+			return EscapeStore, nil
+		}
+		return NoEscape, nil
+	case *llir.InstAtomicRMW:
+		if user.X == ptr {
+			return EscapeStore, nil
+		}
+		return NoEscape, nil
+	case *llir.InstCmpXchg:
+		if user.Cmp == ptr || user.New == ptr {
+			return EscapeStore, nil
+		}
+		return NoEscape, nil
+	// Derived pointers.
+	case *llir.InstGetElementPtr, *llir.InstBitCast, *llir.InstAddrSpaceCast, *llir.InstPhi, *llir.InstSelect:
+		return NoEscape, user.(value.Value)
+	// Other uses.
+	case *llir.InstICmp:
+		return NoEscape, nil
+	case *llir.InstPtrToInt:
+		return EscapePtrToInt, nil
+	case *llir.InstCall:
+		return ea.escapeThroughCall(ptr, user.Callee, user.Args), nil
+	case *llir.TermInvoke:
+		return ea.escapeThroughCall(ptr, user.Invokee, user.Args), nil
+	case *llir.TermCallBr:
+		return ea.escapeThroughCall(ptr, user.Callee, user.Args), nil
+	case *llir.TermRet:
+		return EscapeReturn, nil
+	}
+	return EscapeOther, nil
+}
+
+// escapeThroughCall returns how the given pointer escapes through its use by
+// the call to callee with the given arguments.
+func (ea *EscapeAnalysis) escapeThroughCall(ptr, callee value.Value, args []value.Value) EscapeKind {
+	if callee == ptr {
+		return EscapeOther
+	}
+	f, _ := stripCasts(callee).(*llir.Func)
+	found := false
+	for i, arg := range args {
+		var attrs []llir.ParamAttribute
+		if a, ok := arg.(*llir.Arg); ok {
+			arg = a.Value
+			attrs = a.Attrs
+		}
+		if arg != ptr {
+			continue
+		}
+		found = true
+		if f != nil && i < len(f.Params) {
+			attrs = append(attrs[:len(attrs):len(attrs)], f.Params[i].Attrs...)
+		}
+		if !hasParamAttr(attrs, enum.ParamAttrNoCapture) {
+			return EscapeCall
+		}
+	}
+	if !found {
+		// Used in operand bundle.
+		return EscapeCall
+	}
+	return NoEscape
+}
+
+// hasParamAttr reports whether the given parameter attributes contain attr.
+func hasParamAttr(attrs []llir.ParamAttribute, attr enum.ParamAttr) bool {
+	for _, a := range attrs {
+		if a == attr {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/types"
+	"github.com/wa-lang/llir/value"
+)
+
+func TestEscapeAnalysis(t *testing.T) {
+	i32 := func(x int64) *constant.Int { return constant.NewInt(types.I32, x) }
+	m := llir.NewModule()
+	gcAlloc := m.NewFunc("gc_alloc", types.I8Ptr, llir.NewParam("size", types.I64))
+	nocapture := llir.NewParam("p", types.I8Ptr)
+	nocapture.Attrs = append(nocapture.Attrs, enum.ParamAttrNoCapture)
+	use := m.NewFunc("use", types.Void, nocapture)
+	capture := m.NewFunc("capture", types.Void, llir.NewParam("p", types.I8Ptr))
+	global := m.NewGlobal("global", types.I8Ptr)
+	c := llir.NewParam("c", types.I1)
+	f := m.NewFunc("f", types.I8Ptr, c)
+	entry := f.NewBlock("entry")
+	size := constant.NewInt(types.I64, 16)
+
+	// Local uses only.
+	local := entry.NewAlloca(types.I32)
+	entry.NewStore(i32(0), local)
+	entry.NewLoad(types.I32, local)
+	localCast := entry.NewBitCast(local, types.I8Ptr)
+	entry.NewCall(use, localCast)
+	entry.NewICmp(enum.IPredEQ, localCast, constant.NewNull(types.I8Ptr))
+	// Stored to a global through a derived pointer.
+	stored := entry.NewCall(gcAlloc, size)
+	storedElem := entry.NewGetElementPtr(types.I8, stored, i32(4))
+	storeInst := entry.NewStore(storedElem, global)
+	// Passed to a call without nocapture.
+	passed := entry.NewCall(gcAlloc, size)
+	passCall := entry.NewCall(capture, passed)
+	// Passed to a call with nocapture argument attribute.
+	passedNoCapture := entry.NewCall(gcAlloc, size)
+	entry.NewCall(capture, llir.NewArg(passedNoCapture, enum.ParamAttrNoCapture))
+	// Converted to an integer.
+	converted := entry.NewAlloca(types.I32)
+	ptrToInt := entry.NewPtrToInt(converted, types.I64)
+	// Returned through a select.
+	returned := entry.NewCall(gcAlloc, size)
+	sel := entry.NewSelect(c, returned, constant.NewNull(types.I8Ptr))
+	// Not an allocation.
+	other := entry.NewCall(capture, constant.NewNull(types.I8Ptr))
+	ret := entry.NewRet(sel)
+
+	ea := NewEscapeAnalysis(f, "gc_alloc")
+	golden := []struct {
+		name  string
+		alloc value.Value
+		kind  EscapeKind
+		site  interface{}
+	}{
+		{name: "local", alloc: local, kind: NoEscape},
+		{name: "stored", alloc: stored, kind: EscapeStore, site: storeInst},
+		{name: "passed", alloc: passed, kind: EscapeCall, site: passCall},
+		{name: "passed nocapture", alloc: passedNoCapture, kind: NoEscape},
+		{name: "converted", alloc: converted, kind: EscapePtrToInt, site: ptrToInt},
+		{name: "returned", alloc: returned, kind: EscapeReturn, site: ret},
+	}
+	for _, g := range golden {
+		kind, site := ea.EscapeOf(g.alloc)
+		if kind != g.kind {
+			t.Errorf("%s: escape kind mismatch; expected %v, got %v", g.name, g.kind, kind)
+		}
+		if site != g.site {
+			t.Errorf("%s: escape site mismatch; expected %v, got %v", g.name, g.site, site)
+		}
+	}
+	if ea.IsAllocation(other) {
+		t.Errorf("expected call to @capture not to be an allocation")
+	}
+	if got, want := len(ea.Allocations()), len(golden); got != want {
+		t.Errorf("number of allocations mismatch; expected %d, got %d", want, got)
+	}
+	nonEscaping := ea.NonEscaping()
+	if len(nonEscaping) != 2 || nonEscaping[0] != local || nonEscaping[1] != passedNoCapture {
+		t.Errorf("non-escaping allocations mismatch; expected local and passed nocapture allocations, got %v", nonEscaping)
+	}
+}
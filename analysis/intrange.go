@@ -0,0 +1,435 @@
+package analysis
+
+import (
+	"fmt"
+	"math/big"
+	"math/bits"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/value"
+)
+
+// IntRange is a range of integer values, with inclusive signed and unsigned
+// bounds. Both bounds hold for every value in the range.
+type IntRange struct {
+	// Bit width of the integer values; at most 64.
+	Width uint64
+	// Signed bounds.
+	SMin, SMax int64
+	// Unsigned bounds.
+	UMin, UMax uint64
+}
+
+// FullRange returns the range of all integer values of the given bit width.
+func FullRange(w uint64) IntRange {
+	return IntRange{
+		Width: w,
+		SMin:  signExtend(signBit(w), w),
+		SMax:  int64(mask(w) >> 1),
+		UMin:  0,
+		UMax:  mask(w),
+	}
+}
+
+// String returns the string representation of the integer range.
+func (r IntRange) String() string {
+	if r.IsEmpty() {
+		return "empty"
+	}
+	return fmt.Sprintf("u[%d, %d] s[%d, %d]", r.UMin, r.UMax, r.SMin, r.SMax)
+}
+
+// IsEmpty reports whether the range contains no values; e.g. the range of a
+// value in unreachable code.
+func (r IntRange) IsEmpty() bool {
+	return r.UMin > r.UMax || r.SMin > r.SMax
+}
+
+// IsSingle reports whether the range contains a single value.
+func (r IntRange) IsSingle() bool {
+	return !r.IsEmpty() && r.UMin == r.UMax
+}
+
+// Intersect returns the range of values in both r and o.
+func (r IntRange) Intersect(o IntRange) IntRange {
+	if r.IsEmpty() || o.IsEmpty() {
+		return emptyRange(r.Width)
+	}
+	x := IntRange{
+		Width: r.Width,
+		SMin:  maxInt64(r.SMin, o.SMin),
+		SMax:  minInt64(r.SMax, o.SMax),
+		UMin:  maxUint64(r.UMin, o.UMin),
+		UMax:  minUint64(r.UMax, o.UMax),
+	}
+	if x.IsEmpty() {
+		return emptyRange(r.Width)
+	}
+	// Refine signed bounds using unsigned bounds and vice versa.
+	u := unsignedRange(x.UMin, x.UMax, x.Width)
+	s := signedRange(x.SMin, x.SMax, x.Width)
+	x.SMin, x.SMax = maxInt64(x.SMin, u.SMin), minInt64(x.SMax, u.SMax)
+	x.UMin, x.UMax = maxUint64(x.UMin, s.UMin), minUint64(x.UMax, s.UMax)
+	if x.IsEmpty() {
+		return emptyRange(r.Width)
+	}
+	return x
+}
+
+// Union returns a range containing the values of r and o.
+func (r IntRange) Union(o IntRange) IntRange {
+	if r.IsEmpty() {
+		return o
+	}
+	if o.IsEmpty() {
+		return r
+	}
+	return IntRange{
+		Width: r.Width,
+		SMin:  minInt64(r.SMin, o.SMin),
+		SMax:  maxInt64(r.SMax, o.SMax),
+		UMin:  minUint64(r.UMin, o.UMin),
+		UMax:  maxUint64(r.UMax, o.UMax),
+	}
+}
+
+// KnownBits returns the bits known for all values of the range; the common
+// leading bits of the unsigned bounds.
+func (r IntRange) KnownBits() KnownBits {
+	if r.IsEmpty() {
+		return KnownBits{Width: r.Width}
+	}
+	n := uint64(bits.LeadingZeros64(r.UMin^r.UMax)) - (64 - r.Width)
+	common := mask(r.Width) &^ mask(r.Width-n)
+	return KnownBits{Width: r.Width, Zero: ^r.UMin & common, One: r.UMin & common}
+}
+
+// Range returns the range of the given integer value. The boolean result
+// reports whether v is an integer of at most 64 bits.
+func (va *ValueAnalysis) Range(v value.Value) (IntRange, bool) {
+	if _, ok := intWidth(v); !ok {
+		return IntRange{}, false
+	}
+	return va.rangeOf(v, 0).Intersect(va.knownBits(v, 0).Range()), true
+}
+
+// RangeAt returns the range of the given integer value in the given basic
+// block, taking into account the conditional branches on icmp instructions
+// comparing v to a constant, through which control must pass to reach the
+// basic block. The boolean result reports whether v is an integer of at most
+// 64 bits.
+func (va *ValueAnalysis) RangeAt(v value.Value, block *llir.Block) (IntRange, bool) {
+	r, ok := va.Range(v)
+	if !ok {
+		return IntRange{}, false
+	}
+	g := va.Dom.Graph
+	for b := block; b != nil; b = va.Dom.IDom(b) {
+		// The edge from the single predecessor of b to b is taken on every
+		// path to block.
+		preds := g.Preds(b)
+		if len(preds) != 1 {
+			continue
+		}
+		br, cmp, ok := isBranchOn(preds[0].Term)
+		if !ok {
+			continue
+		}
+		pred, x, y := cmp.Pred, cmp.X, cmp.Y
+		if y == v {
+			x, y = y, x
+			pred = swapPred(pred)
+		}
+		c, ok := y.(*constant.Int)
+		if x != v || !ok {
+			continue
+		}
+		if br.TargetFalse == b {
+			pred = invertPred(pred)
+		}
+		r = refine(r, pred, truncate(c.X, r.Width))
+	}
+	return r, true
+}
+
+// refine returns the range r restricted to the values x for which `x pred c`
+// holds.
+func refine(r IntRange, pred enum.IPred, c uint64) IntRange {
+	if pred != enum.IPredNE {
+		return r.Intersect(cmpRange(pred, c, r.Width))
+	}
+	// Exclude c from the bounds of r.
+	sc := signExtend(c, r.Width)
+	if r.UMin == c && r.UMin < r.UMax {
+		r.UMin++
+	} else if r.UMax == c && r.UMin < r.UMax {
+		r.UMax--
+	}
+	if r.SMin == sc && r.SMin < r.SMax {
+		r.SMin++
+	} else if r.SMax == sc && r.SMin < r.SMax {
+		r.SMax--
+	}
+	return r.Intersect(r)
+}
+
+// isBranchOn reports whether the given conditional branch has distinct
+// targets and branches on an icmp instruction; and if so returns the
+// instruction.
+func isBranchOn(term llir.Terminator) (*llir.TermCondBr, *llir.InstICmp, bool) {
+	br, ok := term.(*llir.TermCondBr)
+	if !ok || br.TargetTrue == br.TargetFalse {
+		return nil, nil, false
+	}
+	cmp, ok := br.Cond.(*llir.InstICmp)
+	if !ok {
+		return nil, nil, false
+	}
+	return br, cmp, true
+}
+
+// cmpRange returns the range of values x for which `x pred c` holds.
+func cmpRange(pred enum.IPred, c, w uint64) IntRange {
+	m := mask(w)
+	minS, maxS := signExtend(signBit(w), w), int64(m>>1)
+	sc := signExtend(c, w)
+	switch pred {
+	case enum.IPredEQ:
+		return unsignedRange(c, c, w)
+	case enum.IPredULT:
+		if c == 0 {
+			return emptyRange(w)
+		}
+		return unsignedRange(0, c-1, w)
+	case enum.IPredULE:
+		return unsignedRange(0, c, w)
+	case enum.IPredUGT:
+		if c == m {
+			return emptyRange(w)
+		}
+		return unsignedRange(c+1, m, w)
+	case enum.IPredUGE:
+		return unsignedRange(c, m, w)
+	case enum.IPredSLT:
+		if sc == minS {
+			return emptyRange(w)
+		}
+		return signedRange(minS, sc-1, w)
+	case enum.IPredSLE:
+		return signedRange(minS, sc, w)
+	case enum.IPredSGT:
+		if sc == maxS {
+			return emptyRange(w)
+		}
+		return signedRange(sc+1, maxS, w)
+	case enum.IPredSGE:
+		return signedRange(sc, maxS, w)
+	}
+	return FullRange(w)
+}
+
+// rangeOf returns the range of the given integer value.
+func (va *ValueAnalysis) rangeOf(v value.Value, depth int) IntRange {
+	w, _ := intWidth(v)
+	if c, ok := v.(*constant.Int); ok {
+		x := truncate(c.X, w)
+		return unsignedRange(x, x, w)
+	}
+	full := FullRange(w)
+	if depth >= maxDepth {
+		return full
+	}
+	depth++
+	switch inst := v.(type) {
+	case *llir.InstZExt:
+		x := va.rangeOf(inst.From, depth)
+		return unsignedRange(x.UMin, x.UMax, w)
+	case *llir.InstSExt:
+		x := va.rangeOf(inst.From, depth)
+		return signedRange(x.SMin, x.SMax, w)
+	case *llir.InstTrunc:
+		x := va.rangeOf(inst.From, depth)
+		if x.UMax <= mask(w) {
+			return unsignedRange(x.UMin, x.UMax, w)
+		}
+	case *llir.InstAnd:
+		x, y := va.rangeOf(inst.X, depth), va.rangeOf(inst.Y, depth)
+		return unsignedRange(0, minUint64(x.UMax, y.UMax), w)
+	case *llir.InstOr:
+		x, y := va.rangeOf(inst.X, depth), va.rangeOf(inst.Y, depth)
+		hi := maxUint64(x.UMax, y.UMax)
+		return unsignedRange(maxUint64(x.UMin, y.UMin), mask(uint64(bits.Len64(hi))), w)
+	case *llir.InstAdd:
+		x, y := va.rangeOf(inst.X, depth), va.rangeOf(inst.Y, depth)
+		return arithRange(x, y, (*big.Int).Add)
+	case *llir.InstSub:
+		x, y := va.rangeOf(inst.X, depth), va.rangeOf(inst.Y, depth)
+		// Subtract the bounds of y in reverse.
+		y.UMin, y.UMax = y.UMax, y.UMin
+		y.SMin, y.SMax = y.SMax, y.SMin
+		return arithRange(x, y, (*big.Int).Sub)
+	case *llir.InstMul:
+		x, y := va.rangeOf(inst.X, depth), va.rangeOf(inst.Y, depth)
+		if x.UMax <= mask(32) && y.UMax <= mask(32) && x.UMax*y.UMax <= mask(w) {
+			return unsignedRange(x.UMin*y.UMin, x.UMax*y.UMax, w)
+		}
+	case *llir.InstShl:
+		if s, ok := shiftAmount(inst.Y, w); ok {
+			x := va.rangeOf(inst.X, depth)
+			if x.UMax <= mask(w)>>s {
+				return unsignedRange(x.UMin<<s, x.UMax<<s, w)
+			}
+		}
+	case *llir.InstLShr:
+		if s, ok := shiftAmount(inst.Y, w); ok {
+			x := va.rangeOf(inst.X, depth)
+			return unsignedRange(x.UMin>>s, x.UMax>>s, w)
+		}
+	case *llir.InstUDiv:
+		if c, ok := inst.Y.(*constant.Int); ok {
+			if d := truncate(c.X, w); d != 0 {
+				x := va.rangeOf(inst.X, depth)
+				return unsignedRange(x.UMin/d, x.UMax/d, w)
+			}
+		}
+	case *llir.InstURem:
+		if c, ok := inst.Y.(*constant.Int); ok {
+			if d := truncate(c.X, w); d != 0 {
+				x := va.rangeOf(inst.X, depth)
+				if x.UMax < d {
+					return x
+				}
+				return unsignedRange(0, d-1, w)
+			}
+		}
+	case *llir.InstSelect:
+		return va.rangeOf(inst.ValueTrue, depth).Union(va.rangeOf(inst.ValueFalse, depth))
+	case *llir.InstPhi:
+		r := emptyRange(w)
+		for _, inc := range inst.Incs {
+			r = r.Union(va.rangeOf(inc.X, depth))
+		}
+		if len(inst.Incs) == 0 {
+			return full
+		}
+		return r
+	case *llir.InstLoad, *llir.InstCall:
+		if r, ok := rangeMetadata(inst, w); ok {
+			return r
+		}
+	}
+	return va.knownBits(v, depth).Range()
+}
+
+// arithRange returns the range of op applied to the values of x and y, where
+// op is monotonically increasing in both operands given the bounds of x and y.
+// Bounds overflowing the bit width are dropped.
+func arithRange(x, y IntRange, op func(z, a, b *big.Int) *big.Int) IntRange {
+	w := x.Width
+	r := FullRange(w)
+	apply := func(a, b *big.Int) *big.Int {
+		return op(new(big.Int), a, b)
+	}
+	ulo := apply(new(big.Int).SetUint64(x.UMin), new(big.Int).SetUint64(y.UMin))
+	uhi := apply(new(big.Int).SetUint64(x.UMax), new(big.Int).SetUint64(y.UMax))
+	if inRange(ulo, w, false) && inRange(uhi, w, false) {
+		r = r.Intersect(unsignedRange(ulo.Uint64(), uhi.Uint64(), w))
+	}
+	slo := apply(big.NewInt(x.SMin), big.NewInt(y.SMin))
+	shi := apply(big.NewInt(x.SMax), big.NewInt(y.SMax))
+	if inRange(slo, w, true) && inRange(shi, w, true) {
+		r = r.Intersect(signedRange(slo.Int64(), shi.Int64(), w))
+	}
+	return r
+}
+
+// unsignedRange returns the range of values with the given unsigned bounds.
+func unsignedRange(lo, hi, w uint64) IntRange {
+	r := FullRange(w)
+	r.UMin, r.UMax = lo, hi
+	if lo > hi {
+		return emptyRange(w)
+	}
+	sign := signBit(w)
+	switch {
+	case hi < sign:
+		r.SMin, r.SMax = int64(lo), int64(hi)
+	case lo >= sign:
+		r.SMin, r.SMax = signExtend(lo, w), signExtend(hi, w)
+	}
+	return r
+}
+
+// signedRange returns the range of values with the given signed bounds.
+func signedRange(lo, hi int64, w uint64) IntRange {
+	r := FullRange(w)
+	r.SMin, r.SMax = lo, hi
+	if lo > hi {
+		return emptyRange(w)
+	}
+	switch {
+	case lo >= 0:
+		r.UMin, r.UMax = uint64(lo), uint64(hi)
+	case hi < 0:
+		r.UMin, r.UMax = uint64(lo)&mask(w), uint64(hi)&mask(w)
+	}
+	return r
+}
+
+// halfOpenRange returns the range of values in [lo, hi), which wraps around if
+// lo >= hi, as used by !range metadata.
+func halfOpenRange(lo, hi, w uint64) IntRange {
+	m := mask(w)
+	if lo == hi {
+		return FullRange(w)
+	}
+	hi = (hi - 1) & m
+	if lo <= hi {
+		return unsignedRange(lo, hi, w)
+	}
+	// Wrapped range, contiguous in the signed domain if it does not wrap
+	// around the signed boundary.
+	if slo, shi := signExtend(lo, w), signExtend(hi, w); slo <= shi {
+		return signedRange(slo, shi, w)
+	}
+	return FullRange(w)
+}
+
+// emptyRange returns the empty range of integer values of the given bit width.
+func emptyRange(w uint64) IntRange {
+	return IntRange{Width: w, SMin: 1, SMax: 0, UMin: 1, UMax: 0}
+}
+
+// minInt64 returns the minimum of x and y.
+func minInt64(x, y int64) int64 {
+	if x < y {
+		return x
+	}
+	return y
+}
+
+// maxInt64 returns the maximum of x and y.
+func maxInt64(x, y int64) int64 {
+	if x > y {
+		return x
+	}
+	return y
+}
+
+// minUint64 returns the minimum of x and y.
+func minUint64(x, y uint64) uint64 {
+	if x < y {
+		return x
+	}
+	return y
+}
+
+// maxUint64 returns the maximum of x and y.
+func maxUint64(x, y uint64) uint64 {
+	if x > y {
+		return x
+	}
+	return y
+}
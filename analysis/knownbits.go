@@ -0,0 +1,328 @@
+package analysis
+
+import (
+	"math/big"
+	"math/bits"
+	"strings"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/cfg"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/metadata"
+	"github.com/wa-lang/llir/types"
+	"github.com/wa-lang/llir/value"
+)
+
+// KnownBits is the set of bits of an integer value known to be zero or one.
+type KnownBits struct {
+	// Bit width of the integer value; at most 64.
+	Width uint64
+	// Bits known to be zero.
+	Zero uint64
+	// Bits known to be one.
+	One uint64
+}
+
+// String returns the string representation of the known bits; one character
+// per bit, most significant bit first, with '?' denoting an unknown bit.
+func (k KnownBits) String() string {
+	buf := &strings.Builder{}
+	for i := int(k.Width) - 1; i >= 0; i-- {
+		bit := uint64(1) << uint(i)
+		switch {
+		case k.Zero&bit != 0:
+			buf.WriteByte('0')
+		case k.One&bit != 0:
+			buf.WriteByte('1')
+		default:
+			buf.WriteByte('?')
+		}
+	}
+	return buf.String()
+}
+
+// IsConstant reports whether all bits are known.
+func (k KnownBits) IsConstant() bool {
+	return k.Zero|k.One == mask(k.Width)
+}
+
+// IsNonNegative reports whether the sign bit is known to be zero.
+func (k KnownBits) IsNonNegative() bool {
+	return k.Zero&signBit(k.Width) != 0
+}
+
+// IsNegative reports whether the sign bit is known to be one.
+func (k KnownBits) IsNegative() bool {
+	return k.One&signBit(k.Width) != 0
+}
+
+// Intersect returns the bits known in both k and o; i.e. the known bits of a
+// value which is either k or o.
+func (k KnownBits) Intersect(o KnownBits) KnownBits {
+	return KnownBits{Width: k.Width, Zero: k.Zero & o.Zero, One: k.One & o.One}
+}
+
+// Range returns the range of integer values with the known bits.
+func (k KnownBits) Range() IntRange {
+	w := k.Width
+	r := IntRange{Width: w, UMin: k.One, UMax: ^k.Zero & mask(w)}
+	sign := signBit(w)
+	switch {
+	case k.IsNonNegative():
+		r.SMin, r.SMax = int64(r.UMin), int64(r.UMax)
+	case k.IsNegative():
+		r.SMin, r.SMax = signExtend(r.UMin, w), signExtend(r.UMax, w)
+	default:
+		r.SMin, r.SMax = signExtend(r.UMin|sign, w), signExtend(r.UMax&^sign, w)
+	}
+	return r
+}
+
+// union returns the bits known in either k or o.
+func (k KnownBits) union(o KnownBits) KnownBits {
+	return KnownBits{Width: k.Width, Zero: k.Zero | o.Zero, One: k.One | o.One}
+}
+
+// ValueAnalysis computes known bits and ranges of integer SSA values.
+//
+// Integer values of at most 64 bits are analysed. Bitwise operations (and, or,
+// xor, shifts by constant amounts), casts (zext, sext, trunc), arithmetic
+// (add, sub, mul, udiv and urem), phi and select instructions, and !range
+// metadata of loads and calls are taken into account. RangeAt additionally
+// takes icmp-guarded conditional branches dominating a basic block into
+// account.
+type ValueAnalysis struct {
+	// Dominator tree of the function.
+	Dom *cfg.DomTree
+}
+
+// NewValueAnalysis returns a new value analysis of the function of the given
+// dominator tree.
+func NewValueAnalysis(dom *cfg.DomTree) *ValueAnalysis {
+	return &ValueAnalysis{Dom: dom}
+}
+
+// maxDepth is the maximum recursion depth of the value analysis.
+const maxDepth = 6
+
+// KnownBits returns the known bits of the given integer value. The boolean
+// result reports whether v is an integer of at most 64 bits.
+func (va *ValueAnalysis) KnownBits(v value.Value) (KnownBits, bool) {
+	if _, ok := intWidth(v); !ok {
+		return KnownBits{}, false
+	}
+	return va.knownBits(v, 0).union(va.rangeOf(v, 0).KnownBits()), true
+}
+
+// IsNonNegative reports whether the given integer value is known to be
+// non-negative; as required for the nneg flag of zext.
+func (va *ValueAnalysis) IsNonNegative(v value.Value) bool {
+	k, ok := va.KnownBits(v)
+	return ok && k.IsNonNegative()
+}
+
+// HaveNoCommonBits reports whether the given integer values are known to have
+// no set bits in common; as required for the disjoint flag of or.
+func (va *ValueAnalysis) HaveNoCommonBits(a, b value.Value) bool {
+	ka, ok := va.KnownBits(a)
+	if !ok {
+		return false
+	}
+	kb, ok := va.KnownBits(b)
+	return ok && ka.Zero|kb.Zero == mask(ka.Width)
+}
+
+// knownBits returns the known bits of the given integer value.
+func (va *ValueAnalysis) knownBits(v value.Value, depth int) KnownBits {
+	w, _ := intWidth(v)
+	unknown := KnownBits{Width: w}
+	if c, ok := v.(*constant.Int); ok {
+		x := truncate(c.X, w)
+		return KnownBits{Width: w, Zero: ^x & mask(w), One: x}
+	}
+	if depth >= maxDepth {
+		return unknown
+	}
+	depth++
+	m := mask(w)
+	switch inst := v.(type) {
+	case *llir.InstAnd:
+		x, y := va.knownBits(inst.X, depth), va.knownBits(inst.Y, depth)
+		return KnownBits{Width: w, Zero: x.Zero | y.Zero, One: x.One & y.One}
+	case *llir.InstOr:
+		x, y := va.knownBits(inst.X, depth), va.knownBits(inst.Y, depth)
+		return KnownBits{Width: w, Zero: x.Zero & y.Zero, One: x.One | y.One}
+	case *llir.InstXor:
+		x, y := va.knownBits(inst.X, depth), va.knownBits(inst.Y, depth)
+		return KnownBits{Width: w, Zero: (x.Zero & y.Zero) | (x.One & y.One), One: (x.Zero & y.One) | (x.One & y.Zero)}
+	case *llir.InstShl:
+		if s, ok := shiftAmount(inst.Y, w); ok {
+			x := va.knownBits(inst.X, depth)
+			return KnownBits{Width: w, Zero: (x.Zero<<s | mask(s)) & m, One: x.One << s & m}
+		}
+	case *llir.InstLShr:
+		if s, ok := shiftAmount(inst.Y, w); ok {
+			x := va.knownBits(inst.X, depth)
+			return KnownBits{Width: w, Zero: x.Zero>>s | (m &^ (m >> s)), One: x.One >> s}
+		}
+	case *llir.InstAShr:
+		if s, ok := shiftAmount(inst.Y, w); ok {
+			x := va.knownBits(inst.X, depth)
+			return KnownBits{Width: w, Zero: uint64(signExtend(x.Zero, w)>>s) & m, One: uint64(signExtend(x.One, w)>>s) & m}
+		}
+	case *llir.InstZExt:
+		x := va.knownBits(inst.From, depth)
+		return KnownBits{Width: w, Zero: x.Zero | (m &^ mask(x.Width)), One: x.One}
+	case *llir.InstSExt:
+		x := va.knownBits(inst.From, depth)
+		return KnownBits{Width: w, Zero: uint64(signExtend(x.Zero, x.Width)) & m, One: uint64(signExtend(x.One, x.Width)) & m}
+	case *llir.InstTrunc:
+		x := va.knownBits(inst.From, depth)
+		return KnownBits{Width: w, Zero: x.Zero & m, One: x.One & m}
+	case *llir.InstAdd:
+		return addKnownBits(va.knownBits(inst.X, depth), va.knownBits(inst.Y, depth), false)
+	case *llir.InstSub:
+		y := va.knownBits(inst.Y, depth)
+		// x - y = x + ^y + 1
+		return addKnownBits(va.knownBits(inst.X, depth), KnownBits{Width: w, Zero: y.One, One: y.Zero}, true)
+	case *llir.InstMul:
+		// The trailing zeros of the operands add up.
+		x, y := va.knownBits(inst.X, depth), va.knownBits(inst.Y, depth)
+		tz := uint64(bits.TrailingZeros64(^x.Zero) + bits.TrailingZeros64(^y.Zero))
+		if tz > w {
+			tz = w
+		}
+		return KnownBits{Width: w, Zero: mask(tz)}
+	case *llir.InstURem:
+		if c, ok := inst.Y.(*constant.Int); ok {
+			d := truncate(c.X, w)
+			if d != 0 && d&(d-1) == 0 {
+				// Remainder of power of two.
+				x := va.knownBits(inst.X, depth)
+				return KnownBits{Width: w, Zero: x.Zero | (m &^ (d - 1)), One: x.One & (d - 1)}
+			}
+		}
+	case *llir.InstSelect:
+		return va.knownBits(inst.ValueTrue, depth).Intersect(va.knownBits(inst.ValueFalse, depth))
+	case *llir.InstPhi:
+		if len(inst.Incs) == 0 {
+			return unknown
+		}
+		k := va.knownBits(inst.Incs[0].X, depth)
+		for _, inc := range inst.Incs[1:] {
+			k = k.Intersect(va.knownBits(inc.X, depth))
+		}
+		return k
+	case *llir.InstLoad, *llir.InstCall:
+		if r, ok := rangeMetadata(inst, w); ok {
+			return r.KnownBits()
+		}
+	}
+	return unknown
+}
+
+// addKnownBits returns the known bits of the sum of x, y and the given carry
+// bit.
+func addKnownBits(x, y KnownBits, carry bool) KnownBits {
+	w := x.Width
+	m := mask(w)
+	c := uint64(0)
+	if carry {
+		c = 1
+	}
+	// Largest and smallest possible sums.
+	possibleSumZero := (^x.Zero + ^y.Zero + c) & m
+	possibleSumOne := (x.One + y.One + c) & m
+	// Known carry bits into each bit position.
+	carryKnownZero := ^(possibleSumZero ^ x.Zero ^ y.Zero) & m
+	carryKnownOne := (possibleSumOne ^ x.One ^ y.One) & m
+	known := (x.Zero | x.One) & (y.Zero | y.One) & (carryKnownZero | carryKnownOne)
+	return KnownBits{Width: w, Zero: ^possibleSumOne & known, One: possibleSumOne & known}
+}
+
+// rangeMetadata returns the range specified by the !range metadata attachment
+// of the given instruction.
+func rangeMetadata(inst interface{}, w uint64) (IntRange, bool) {
+	v, ok := inst.(interface {
+		MDAttachments() []*metadata.Attachment
+	})
+	if !ok {
+		return IntRange{}, false
+	}
+	for _, md := range v.MDAttachments() {
+		if md.Name != "range" {
+			continue
+		}
+		tuple, ok := md.Node.(*metadata.Tuple)
+		if !ok || len(tuple.Fields) == 0 || len(tuple.Fields)%2 != 0 {
+			return IntRange{}, false
+		}
+		r := emptyRange(w)
+		for i := 0; i < len(tuple.Fields); i += 2 {
+			lo, ok := metadataInt(tuple.Fields[i])
+			if !ok {
+				return IntRange{}, false
+			}
+			hi, ok := metadataInt(tuple.Fields[i+1])
+			if !ok {
+				return IntRange{}, false
+			}
+			r = r.Union(halfOpenRange(truncate(lo.X, w), truncate(hi.X, w), w))
+		}
+		return r, true
+	}
+	return IntRange{}, false
+}
+
+// metadataInt returns the integer constant of the given metadata field.
+func metadataInt(field metadata.Field) (*constant.Int, bool) {
+	if v, ok := field.(*metadata.Value); ok {
+		c, ok := v.Value.(*constant.Int)
+		return c, ok
+	}
+	c, ok := field.(*constant.Int)
+	return c, ok
+}
+
+// shiftAmount returns the constant shift amount of the given value, if less
+// than the bit width w.
+func shiftAmount(v value.Value, w uint64) (uint64, bool) {
+	c, ok := v.(*constant.Int)
+	if !ok || !c.X.IsUint64() || c.X.Uint64() >= w {
+		return 0, false
+	}
+	return c.X.Uint64(), true
+}
+
+// intWidth returns the bit width of the given value, if an integer of at most
+// 64 bits.
+func intWidth(v value.Value) (uint64, bool) {
+	t, ok := v.Type().(*types.IntType)
+	if !ok || t.BitSize == 0 || t.BitSize > 64 {
+		return 0, false
+	}
+	return t.BitSize, true
+}
+
+// truncate returns x truncated to w bits.
+func truncate(x *big.Int, w uint64) uint64 {
+	return normalize(x, w, false).Uint64()
+}
+
+// mask returns the mask of the w least significant bits.
+func mask(w uint64) uint64 {
+	if w >= 64 {
+		return ^uint64(0)
+	}
+	return 1<<w - 1
+}
+
+// signBit returns the sign bit of integers of w bits.
+func signBit(w uint64) uint64 {
+	return 1 << (w - 1)
+}
+
+// signExtend returns the w-bit integer x sign-extended to 64 bits.
+func signExtend(x, w uint64) int64 {
+	return int64(x<<(64-w)) >> (64 - w)
+}
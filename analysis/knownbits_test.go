@@ -0,0 +1,120 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/cfg"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/metadata"
+	"github.com/wa-lang/llir/types"
+	"github.com/wa-lang/llir/value"
+)
+
+func TestValueAnalysis(t *testing.T) {
+	i32 := func(x int64) *constant.Int { return constant.NewInt(types.I32, x) }
+	x := llir.NewParam("x", types.I32)
+	b := llir.NewParam("b", types.I8)
+	p := llir.NewParam("p", types.NewPointer(types.I32))
+	f := llir.NewFunc("f", types.Void, x, b, p)
+	entry := f.NewBlock("entry")
+	then := f.NewBlock("then")
+	els := f.NewBlock("else")
+	a := entry.NewAnd(x, i32(255))
+	o := entry.NewOr(a, i32(256))
+	s := entry.NewShl(a, i32(4))
+	z := entry.NewZExt(b, types.I32)
+	sum := entry.NewAdd(a, z)
+	diff := entry.NewSub(o, i32(256))
+	tr := entry.NewTrunc(s, types.I8)
+	sx := entry.NewSExt(b, types.I32)
+	l := entry.NewLoad(types.I32, p)
+	rng := &metadata.Tuple{MetadataID: -1, Fields: []metadata.Field{i32(0), i32(10)}}
+	l.Metadata = append(l.Metadata, &metadata.Attachment{Name: "range", Node: rng})
+	cond := entry.NewICmp(enum.IPredULT, x, i32(100))
+	entry.NewCondBr(cond, then, els)
+	then.NewRet(nil)
+	els.NewRet(nil)
+
+	va := NewValueAnalysis(cfg.NewDomTree(cfg.New(f)))
+	knownBits := []struct {
+		name string
+		v    value.Value
+		want string
+	}{
+		{name: "and", v: a, want: "000000000000000000000000????????"},
+		{name: "or", v: o, want: "000000000000000000000001????????"},
+		{name: "shl", v: s, want: "00000000000000000000????????0000"},
+		{name: "zext", v: z, want: "000000000000000000000000????????"},
+		{name: "add", v: sum, want: "00000000000000000000000?????????"},
+		{name: "sub", v: diff, want: "000000000000000000000000????????"},
+		{name: "trunc", v: tr, want: "????0000"},
+		{name: "sext", v: sx, want: "????????????????????????????????"},
+		{name: "range metadata", v: l, want: "0000000000000000000000000000????"},
+		{name: "constant", v: i32(-2), want: "11111111111111111111111111111110"},
+	}
+	for _, g := range knownBits {
+		k, ok := va.KnownBits(g.v)
+		if !ok {
+			t.Errorf("%s: expected known bits", g.name)
+			continue
+		}
+		if got := k.String(); got != g.want {
+			t.Errorf("%s: known bits mismatch; expected %q, got %q", g.name, g.want, got)
+		}
+	}
+	ranges := []struct {
+		name string
+		v    value.Value
+		want string
+	}{
+		{name: "and", v: a, want: "u[0, 255] s[0, 255]"},
+		{name: "or", v: o, want: "u[256, 511] s[256, 511]"},
+		{name: "shl", v: s, want: "u[0, 4080] s[0, 4080]"},
+		{name: "add", v: sum, want: "u[0, 510] s[0, 510]"},
+		{name: "sext", v: sx, want: "u[0, 4294967295] s[-128, 127]"},
+		{name: "range metadata", v: l, want: "u[0, 9] s[0, 9]"},
+		{name: "param", v: x, want: "u[0, 4294967295] s[-2147483648, 2147483647]"},
+	}
+	for _, g := range ranges {
+		r, ok := va.Range(g.v)
+		if !ok {
+			t.Errorf("%s: expected range", g.name)
+			continue
+		}
+		if got := r.String(); got != g.want {
+			t.Errorf("%s: range mismatch; expected %q, got %q", g.name, g.want, got)
+		}
+	}
+	rangesAt := []struct {
+		block *llir.Block
+		want  string
+	}{
+		{block: entry, want: "u[0, 4294967295] s[-2147483648, 2147483647]"},
+		{block: then, want: "u[0, 99] s[0, 99]"},
+		{block: els, want: "u[100, 4294967295] s[-2147483648, 2147483647]"},
+	}
+	for _, g := range rangesAt {
+		r, _ := va.RangeAt(x, g.block)
+		if got := r.String(); got != g.want {
+			t.Errorf("range of %%x at %s mismatch; expected %q, got %q", g.block.Ident(), g.want, got)
+		}
+	}
+
+	if !va.HaveNoCommonBits(a, i32(256)) {
+		t.Errorf("expected and and 256 to have no common bits")
+	}
+	if va.HaveNoCommonBits(a, i32(128)) {
+		t.Errorf("expected and and 128 to have common bits")
+	}
+	if !va.IsNonNegative(z) {
+		t.Errorf("expected zext to be non-negative")
+	}
+	if va.IsNonNegative(sx) {
+		t.Errorf("expected sext not to be known non-negative")
+	}
+	if _, ok := va.KnownBits(p); ok {
+		t.Errorf("expected no known bits of pointer")
+	}
+}
@@ -0,0 +1,463 @@
+package analysis
+
+import (
+	"math/bits"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/cfg"
+	"github.com/wa-lang/llir/llutil"
+	"github.com/wa-lang/llir/types"
+	"github.com/wa-lang/llir/value"
+)
+
+// Liveness is the liveness of the SSA values (parameters and non-void
+// instructions) of a function.
+//
+// Uses of phi instructions are treated as uses at the end of the corresponding
+// incoming predecessors, rather than in the basic block of the phi instruction.
+// The results of phi instructions are live-in to their basic block, as they
+// are defined on entry to the basic block.
+type Liveness struct {
+	// Control flow graph of the function.
+	Graph *cfg.Graph
+	// SSA values of the function, parameters first followed by instructions in
+	// function order.
+	Values []value.Value
+
+	// Index of each SSA value in Values.
+	index map[value.Value]int
+	// Parent basic block of each instruction and terminator.
+	parent map[interface{}]*llir.Block
+	// Live-in and live-out sets of each basic block.
+	liveIn  map[*llir.Block]bitSet
+	liveOut map[*llir.Block]bitSet
+}
+
+// NewLiveness computes the liveness of the SSA values of the function of the
+// given control flow graph.
+func NewLiveness(g *cfg.Graph) *Liveness {
+	f := g.Func
+	lv := &Liveness{
+		Graph:   g,
+		index:   make(map[value.Value]int),
+		parent:  make(map[interface{}]*llir.Block),
+		liveIn:  make(map[*llir.Block]bitSet),
+		liveOut: make(map[*llir.Block]bitSet),
+	}
+	for _, param := range f.Params {
+		lv.addValue(param)
+	}
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			lv.parent[inst] = block
+			if v, ok := inst.(value.Value); ok && !types.IsVoid(v.Type()) {
+				lv.addValue(v)
+			}
+		}
+		if block.Term != nil {
+			lv.parent[block.Term] = block
+			if v, ok := block.Term.(value.Value); ok && !types.IsVoid(v.Type()) {
+				lv.addValue(v)
+			}
+		}
+	}
+	// Compute upward-exposed uses, definitions, phi definitions and phi uses of
+	// basic blocks.
+	n := len(lv.Values)
+	uses := make(map[*llir.Block]bitSet)
+	defs := make(map[*llir.Block]bitSet)
+	phiDefs := make(map[*llir.Block]bitSet)
+	phiUses := make(map[cfg.Edge]bitSet)
+	for _, block := range f.Blocks {
+		use, def, phiDef := newBitSet(n), newBitSet(n), newBitSet(n)
+		for _, inst := range block.Insts {
+			if phi, ok := inst.(*llir.InstPhi); ok {
+				if i, ok := lv.valueIndex(inst); ok {
+					phiDef.add(i)
+				}
+				for _, inc := range phi.Incs {
+					pred, ok := inc.Pred.(*llir.Block)
+					if !ok {
+						continue
+					}
+					if i, ok := lv.index[inc.X]; ok {
+						e := cfg.Edge{From: pred, To: block}
+						if phiUses[e] == nil {
+							phiUses[e] = newBitSet(n)
+						}
+						phiUses[e].add(i)
+					}
+				}
+			} else {
+				lv.visitUses(inst, func(i int) {
+					if !def.has(i) {
+						use.add(i)
+					}
+				})
+			}
+			if i, ok := lv.valueIndex(inst); ok {
+				def.add(i)
+			}
+		}
+		if block.Term != nil {
+			lv.visitUses(block.Term, func(i int) {
+				if !def.has(i) {
+					use.add(i)
+				}
+			})
+			if i, ok := lv.valueIndex(block.Term); ok {
+				def.add(i)
+			}
+		}
+		uses[block], defs[block], phiDefs[block] = use, def, phiDef
+		lv.liveIn[block], lv.liveOut[block] = newBitSet(n), newBitSet(n)
+	}
+	// Iterate to a fixed point, visiting basic blocks backwards.
+	for changed := true; changed; {
+		changed = false
+		for i := len(f.Blocks) - 1; i >= 0; i-- {
+			block := f.Blocks[i]
+			out := lv.liveOut[block]
+			for _, succ := range g.Succs(block) {
+				// Values live-in to the successor, except for its phi
+				// definitions, and values used by its phi instructions on
+				// the edge from block.
+				for j, w := range lv.liveIn[succ] {
+					out[j] |= w &^ phiDefs[succ][j]
+				}
+				if use, ok := phiUses[cfg.Edge{From: block, To: succ}]; ok {
+					out.union(use)
+				}
+			}
+			in := newBitSet(n)
+			for j := range in {
+				in[j] = uses[block][j] | (out[j] &^ defs[block][j]) | phiDefs[block][j]
+			}
+			if !in.equal(lv.liveIn[block]) {
+				lv.liveIn[block] = in
+				changed = true
+			}
+		}
+	}
+	return lv
+}
+
+// LiveIn returns the SSA values live on entry to the given basic block, in
+// order of Values.
+func (lv *Liveness) LiveIn(block *llir.Block) []value.Value {
+	return lv.values(lv.liveIn[block])
+}
+
+// LiveOut returns the SSA values live on exit from the given basic block, in
+// order of Values.
+func (lv *Liveness) LiveOut(block *llir.Block) []value.Value {
+	return lv.values(lv.liveOut[block])
+}
+
+// IsLiveIn reports whether the given SSA value is live on entry to the given
+// basic block.
+func (lv *Liveness) IsLiveIn(v value.Value, block *llir.Block) bool {
+	i, ok := lv.index[v]
+	return ok && lv.liveIn[block].has(i)
+}
+
+// IsLiveOut reports whether the given SSA value is live on exit from the given
+// basic block.
+func (lv *Liveness) IsLiveOut(v value.Value, block *llir.Block) bool {
+	i, ok := lv.index[v]
+	return ok && lv.liveOut[block].has(i)
+}
+
+// LiveAt reports whether the given SSA value is live immediately before the
+// given instruction or terminator; i.e. whether v is defined before inst and
+// used by inst or a later instruction.
+//
+// Phi instructions execute in parallel on entry to their basic block; the
+// results of phi instructions are not live before any phi instruction of the
+// basic block.
+func (lv *Liveness) LiveAt(v value.Value, inst interface{}) bool {
+	i, ok := lv.index[v]
+	if !ok {
+		return false
+	}
+	block, ok := lv.parent[inst]
+	if !ok {
+		return false
+	}
+	live := lv.liveOut[block].has(i)
+	visit := func(inst interface{}) {
+		if j, ok := lv.valueIndex(inst); ok && j == i {
+			live = false
+		}
+		if _, ok := inst.(*llir.InstPhi); ok {
+			return
+		}
+		lv.visitUses(inst, func(j int) {
+			if j == i {
+				live = true
+			}
+		})
+	}
+	if block.Term != nil {
+		visit(block.Term)
+		if block.Term == inst {
+			return live
+		}
+	}
+	for j := len(block.Insts) - 1; j >= 0; j-- {
+		visit(block.Insts[j])
+		if block.Insts[j] == inst {
+			break
+		}
+	}
+	return live
+}
+
+// MaxPressure returns the maximum number of SSA values simultaneously live at
+// any point of the function; a measure of register pressure.
+func (lv *Liveness) MaxPressure() int {
+	max := 0
+	lv.walkBackward(func(live bitSet, _ []int) {
+		if n := live.count(); n > max {
+			max = n
+		}
+	})
+	return max
+}
+
+// Interference is the interference graph of the SSA values of a function. Two
+// values interfere if one is live at the definition of the other, in which
+// case they may not share a register.
+type Interference struct {
+	// Nodes of the interference graph, in order of Liveness.Values.
+	Nodes []value.Value
+
+	// Index of each node.
+	index map[value.Value]int
+	// Adjacency sets of each node.
+	adj []bitSet
+}
+
+// Interference returns the interference graph of the SSA values of the
+// function.
+func (lv *Liveness) Interference() *Interference {
+	n := len(lv.Values)
+	ig := &Interference{
+		Nodes: lv.Values,
+		index: lv.index,
+		adj:   make([]bitSet, n),
+	}
+	for i := range ig.adj {
+		ig.adj[i] = newBitSet(n)
+	}
+	lv.walkBackward(func(live bitSet, defs []int) {
+		for _, d := range defs {
+			for i := range lv.Values {
+				if i != d && live.has(i) {
+					ig.adj[d].add(i)
+					ig.adj[i].add(d)
+				}
+			}
+		}
+	})
+	return ig
+}
+
+// Interfere reports whether the given SSA values interfere.
+func (ig *Interference) Interfere(a, b value.Value) bool {
+	i, ok := ig.index[a]
+	if !ok {
+		return false
+	}
+	j, ok := ig.index[b]
+	return ok && ig.adj[i].has(j)
+}
+
+// Neighbors returns the SSA values interfering with the given value, in order
+// of Nodes.
+func (ig *Interference) Neighbors(v value.Value) []value.Value {
+	i, ok := ig.index[v]
+	if !ok {
+		return nil
+	}
+	var vs []value.Value
+	for j, w := range ig.Nodes {
+		if ig.adj[i].has(j) {
+			vs = append(vs, w)
+		}
+	}
+	return vs
+}
+
+// Degree returns the number of SSA values interfering with the given value.
+func (ig *Interference) Degree(v value.Value) int {
+	i, ok := ig.index[v]
+	if !ok {
+		return 0
+	}
+	return ig.adj[i].count()
+}
+
+// walkBackward walks the basic blocks of the function backwards from their
+// live-out sets, invoking visit at each definition point with the set of
+// values live after the definition, including the defined values themselves.
+// The phi instructions of a basic block, and the parameters of the function,
+// are each defined at a single point.
+func (lv *Liveness) walkBackward(visit func(live bitSet, defs []int)) {
+	f := lv.Graph.Func
+	n := len(lv.Values)
+	kill := func(live bitSet, defs []int) {
+		for _, d := range defs {
+			live.remove(d)
+		}
+	}
+	for _, block := range f.Blocks {
+		live := newBitSet(n)
+		live.union(lv.liveOut[block])
+		if block.Term != nil {
+			var defs []int
+			if d, ok := lv.valueIndex(block.Term); ok {
+				defs = []int{d}
+				live.add(d)
+			}
+			visit(live, defs)
+			kill(live, defs)
+			lv.visitUses(block.Term, live.add)
+		}
+		var phis []int
+		for j := len(block.Insts) - 1; j >= 0; j-- {
+			inst := block.Insts[j]
+			d, ok := lv.valueIndex(inst)
+			if _, isPhi := inst.(*llir.InstPhi); isPhi {
+				if ok {
+					phis = append(phis, d)
+				}
+				continue
+			}
+			var defs []int
+			if ok {
+				defs = []int{d}
+				live.add(d)
+			}
+			visit(live, defs)
+			kill(live, defs)
+			lv.visitUses(inst, live.add)
+		}
+		if len(phis) > 0 {
+			// Reverse to definition order.
+			for i, j := 0, len(phis)-1; i < j; i, j = i+1, j-1 {
+				phis[i], phis[j] = phis[j], phis[i]
+			}
+			for _, d := range phis {
+				live.add(d)
+			}
+			visit(live, phis)
+			kill(live, phis)
+		}
+		if block == lv.Graph.Entry && len(f.Params) > 0 {
+			params := make([]int, len(f.Params))
+			for i, param := range f.Params {
+				params[i] = lv.index[param]
+				live.add(params[i])
+			}
+			visit(live, params)
+		}
+	}
+}
+
+// addValue adds the given SSA value to the values of the liveness analysis.
+func (lv *Liveness) addValue(v value.Value) {
+	lv.index[v] = len(lv.Values)
+	lv.Values = append(lv.Values, v)
+}
+
+// valueIndex returns the index of the SSA value defined by the given
+// instruction.
+func (lv *Liveness) valueIndex(inst interface{}) (int, bool) {
+	v, ok := inst.(value.Value)
+	if !ok {
+		return 0, false
+	}
+	i, ok := lv.index[v]
+	return i, ok
+}
+
+// visitUses invokes visit with the index of each SSA value used by the given
+// instruction or terminator.
+func (lv *Liveness) visitUses(inst interface{}, visit func(i int)) {
+	user, ok := inst.(interface {
+		Operands() []*value.Value
+	})
+	if !ok {
+		return
+	}
+	for _, op := range llutil.Operands(user) {
+		v := *op
+		if v == nil {
+			continue
+		}
+		if i, ok := lv.index[v]; ok {
+			visit(i)
+		}
+	}
+}
+
+// values returns the SSA values of the given set, in order of Values.
+func (lv *Liveness) values(s bitSet) []value.Value {
+	var vs []value.Value
+	for i, v := range lv.Values {
+		if s.has(i) {
+			vs = append(vs, v)
+		}
+	}
+	return vs
+}
+
+// bitSet is a set of non-negative integers.
+type bitSet []uint64
+
+// newBitSet returns a new empty set of integers in the range [0, n).
+func newBitSet(n int) bitSet {
+	return make(bitSet, (n+63)/64)
+}
+
+// add adds i to the set.
+func (s bitSet) add(i int) {
+	s[i/64] |= 1 << uint(i%64)
+}
+
+// remove removes i from the set.
+func (s bitSet) remove(i int) {
+	s[i/64] &^= 1 << uint(i%64)
+}
+
+// has reports whether i is in the set.
+func (s bitSet) has(i int) bool {
+	return s[i/64]&(1<<uint(i%64)) != 0
+}
+
+// union adds the elements of t to the set.
+func (s bitSet) union(t bitSet) {
+	for i, w := range t {
+		s[i] |= w
+	}
+}
+
+// equal reports whether the sets contain the same elements.
+func (s bitSet) equal(t bitSet) bool {
+	for i, w := range t {
+		if s[i] != w {
+			return false
+		}
+	}
+	return true
+}
+
+// count returns the number of elements of the set.
+func (s bitSet) count() int {
+	n := 0
+	for _, w := range s {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
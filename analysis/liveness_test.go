@@ -0,0 +1,121 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/cfg"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/types"
+	"github.com/wa-lang/llir/value"
+)
+
+func TestLiveness(t *testing.T) {
+	i32 := func(x int64) *constant.Int { return constant.NewInt(types.I32, x) }
+	a := llir.NewParam("a", types.I32)
+	b := llir.NewParam("b", types.I32)
+	f := llir.NewFunc("f", types.I32, a, b)
+	entry := f.NewBlock("entry")
+	then := f.NewBlock("then")
+	els := f.NewBlock("else")
+	join := f.NewBlock("join")
+	x := entry.NewAdd(a, b)
+	x.SetName("x")
+	c := entry.NewICmp(enum.IPredSLT, a, b)
+	c.SetName("c")
+	br := entry.NewCondBr(c, then, els)
+	y := then.NewMul(x, i32(2))
+	y.SetName("y")
+	thenBr := then.NewBr(join)
+	z := els.NewSub(x, i32(1))
+	z.SetName("z")
+	els.NewBr(join)
+	p := join.NewPhi(llir.NewIncoming(y, then), llir.NewIncoming(z, els))
+	p.SetName("p")
+	r := join.NewAdd(p, a)
+	r.SetName("r")
+	// Call with an argument wrapped with parameter attributes.
+	g := llir.NewFunc("g", types.Void, llir.NewParam("", types.I32))
+	use := join.NewCall(g, llir.NewArg(p, enum.ParamAttrNoUndef))
+	join.NewRet(r)
+
+	lv := NewLiveness(cfg.New(f))
+	idents := func(vs []value.Value) string {
+		var ss []string
+		for _, v := range vs {
+			ss = append(ss, v.Ident())
+		}
+		return strings.Join(ss, " ")
+	}
+	sets := []struct {
+		name string
+		got  []value.Value
+		want string
+	}{
+		{name: "live-in entry", got: lv.LiveIn(entry), want: "%a %b"},
+		{name: "live-out entry", got: lv.LiveOut(entry), want: "%a %x"},
+		{name: "live-in then", got: lv.LiveIn(then), want: "%a %x"},
+		{name: "live-out then", got: lv.LiveOut(then), want: "%a %y"},
+		{name: "live-out else", got: lv.LiveOut(els), want: "%a %z"},
+		{name: "live-in join", got: lv.LiveIn(join), want: "%a %p"},
+		{name: "live-out join", got: lv.LiveOut(join), want: ""},
+	}
+	for _, g := range sets {
+		if got := idents(g.got); got != g.want {
+			t.Errorf("%s mismatch; expected %q, got %q", g.name, g.want, got)
+		}
+	}
+	queries := []struct {
+		v    value.Value
+		inst interface{}
+		want bool
+	}{
+		{v: b, inst: c, want: true},
+		{v: b, inst: br, want: false},
+		{v: c, inst: br, want: true},
+		{v: x, inst: x, want: false},
+		{v: x, inst: y, want: true},
+		{v: x, inst: thenBr, want: false},
+		{v: y, inst: thenBr, want: true},
+		{v: p, inst: p, want: false},
+		{v: p, inst: r, want: true},
+		{v: a, inst: r, want: true},
+		{v: p, inst: use, want: true},
+	}
+	for _, g := range queries {
+		if got := lv.LiveAt(g.v, g.inst); got != g.want {
+			t.Errorf("live %s at %v mismatch; expected %v, got %v", g.v.Ident(), g.inst, g.want, got)
+		}
+	}
+	if got, want := lv.MaxPressure(), 3; got != want {
+		t.Errorf("max pressure mismatch; expected %d, got %d", want, got)
+	}
+
+	ig := lv.Interference()
+	pairs := []struct {
+		a, b value.Value
+		want bool
+	}{
+		{a: a, b: b, want: true},
+		{a: a, b: x, want: true},
+		{a: x, b: c, want: true},
+		{a: y, b: z, want: false},
+		{a: y, b: x, want: false},
+		{a: p, b: a, want: true},
+		{a: p, b: r, want: true},
+		{a: b, b: c, want: false},
+	}
+	for _, g := range pairs {
+		if got := ig.Interfere(g.a, g.b); got != g.want {
+			t.Errorf("interference of %s and %s mismatch; expected %v, got %v", g.a.Ident(), g.b.Ident(), g.want, got)
+		}
+		if got := ig.Interfere(g.b, g.a); got != g.want {
+			t.Errorf("interference of %s and %s mismatch; expected %v, got %v", g.b.Ident(), g.a.Ident(), g.want, got)
+		}
+	}
+	if got, want := idents(ig.Neighbors(a)), "%b %x %c %y %z %p"; got != want {
+		t.Errorf("neighbors of %%a mismatch; expected %q, got %q", want, got)
+	}
+}
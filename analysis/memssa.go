@@ -0,0 +1,546 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/cfg"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/value"
+)
+
+// MemoryAccess is a node of Memory SSA form; one of the following.
+//
+//    *analysis.MemoryDef
+//    *analysis.MemoryUse
+//    *analysis.MemoryPhi
+type MemoryAccess interface {
+	fmt.Stringer
+	// Block returns the parent basic block of the memory access.
+	Block() *llir.Block
+	// isMemoryAccess ensures that only Memory SSA nodes can be assigned to the
+	// analysis.MemoryAccess interface.
+	isMemoryAccess()
+}
+
+// MemoryDef is a memory access which may modify memory; i.e. a store, call,
+// atomicrmw, cmpxchg or fence instruction, or an invoke or callbr terminator.
+// The live-on-entry definition of a function is a MemoryDef without
+// instruction, representing the memory state on entry to the function.
+type MemoryDef struct {
+	// Instruction or terminator modifying memory; or nil for the live-on-entry
+	// definition.
+	Inst interface{}
+	// Memory access defining the memory state before the instruction; or nil
+	// for the live-on-entry definition.
+	Defining MemoryAccess
+
+	// ID of the memory definition; 0 for the live-on-entry definition.
+	id int
+	// Parent basic block.
+	block *llir.Block
+}
+
+// String returns the string representation of the memory definition.
+func (d *MemoryDef) String() string {
+	if d.Inst == nil {
+		return "liveOnEntry"
+	}
+	return fmt.Sprintf("%d = MemoryDef(%s)", d.id, accessID(d.Defining))
+}
+
+// Block returns the parent basic block of the memory definition.
+func (d *MemoryDef) Block() *llir.Block {
+	return d.block
+}
+
+// MemoryUse is a memory access which reads but does not modify memory; i.e. a
+// load instruction or a call (or invoke or callbr terminator) to a readonly
+// function.
+type MemoryUse struct {
+	// Instruction or terminator reading memory.
+	Inst interface{}
+	// Memory access defining the memory state read by the instruction.
+	Defining MemoryAccess
+
+	// Parent basic block.
+	block *llir.Block
+}
+
+// String returns the string representation of the memory use.
+func (u *MemoryUse) String() string {
+	return fmt.Sprintf("MemoryUse(%s)", accessID(u.Defining))
+}
+
+// Block returns the parent basic block of the memory use.
+func (u *MemoryUse) Block() *llir.Block {
+	return u.block
+}
+
+// MemoryPhi merges the memory states of the predecessors of a basic block.
+type MemoryPhi struct {
+	// Incoming memory states, in order of predecessors.
+	Incs []*MemoryIncoming
+
+	// ID of the memory phi.
+	id int
+	// Parent basic block.
+	block *llir.Block
+}
+
+// String returns the string representation of the memory phi.
+func (phi *MemoryPhi) String() string {
+	incs := make([]string, len(phi.Incs))
+	for i, inc := range phi.Incs {
+		incs[i] = fmt.Sprintf("{%s,%s}", inc.Pred.Ident(), accessID(inc.Access))
+	}
+	return fmt.Sprintf("%d = MemoryPhi(%s)", phi.id, strings.Join(incs, ","))
+}
+
+// Block returns the parent basic block of the memory phi.
+func (phi *MemoryPhi) Block() *llir.Block {
+	return phi.block
+}
+
+// MemoryIncoming is an incoming memory state of a memory phi.
+type MemoryIncoming struct {
+	// Memory access defining the memory state on exit from the predecessor.
+	Access MemoryAccess
+	// Predecessor basic block of the incoming memory state.
+	Pred *llir.Block
+}
+
+// isMemoryAccess ensures that only Memory SSA nodes can be assigned to the
+// analysis.MemoryAccess interface.
+func (*MemoryDef) isMemoryAccess() {}
+func (*MemoryUse) isMemoryAccess() {}
+func (*MemoryPhi) isMemoryAccess() {}
+
+// MemorySSA is the Memory SSA form of a function. All memory is treated as a
+// single variable in SSA form; each instruction accessing memory is linked to
+// the memory definition or phi defining the memory state it accesses.
+//
+// Calls to readnone functions do not access memory, and calls to readonly
+// functions are memory uses. The memory accesses of invoke and callbr
+// terminators follow the instructions of their basic block, and define the
+// memory state on exit to each successor. Memory accesses of basic blocks
+// unreachable from entry are not part of the Memory SSA form.
+//
+// The Memory SSA form is a snapshot of the function; it is not updated when
+// the function is modified.
+type MemorySSA struct {
+	// Dominator tree of the function.
+	Dom *cfg.DomTree
+	// Alias analysis used by the clobber walker.
+	AA *AliasAnalysis
+	// Live-on-entry definition of the function.
+	LiveOnEntry *MemoryDef
+
+	// Memory access of each instruction and terminator.
+	accesses map[interface{}]MemoryAccess
+	// Memory phi of each basic block.
+	phis map[*llir.Block]*MemoryPhi
+	// Memory accesses of each basic block, in instruction order.
+	blockAccesses map[*llir.Block][]MemoryAccess
+}
+
+// NewMemorySSA returns the Memory SSA form of the function of the given
+// dominator tree. The alias analysis is used by the clobber walker.
+func NewMemorySSA(dom *cfg.DomTree, aa *AliasAnalysis) *MemorySSA {
+	g := dom.Graph
+	mssa := &MemorySSA{
+		Dom:           dom,
+		AA:            aa,
+		LiveOnEntry:   &MemoryDef{block: g.Entry},
+		accesses:      make(map[interface{}]MemoryAccess),
+		phis:          make(map[*llir.Block]*MemoryPhi),
+		blockAccesses: make(map[*llir.Block][]MemoryAccess),
+	}
+	if g.Entry == nil {
+		return mssa
+	}
+	// Create memory accesses, and place memory phis at the iterated dominance
+	// frontier of basic blocks defining memory.
+	var defBlocks []*llir.Block
+	for _, block := range g.Func.Blocks {
+		if !g.Reachable(block) {
+			continue
+		}
+		defines := false
+		add := func(inst interface{}) {
+			switch effectOf(inst) {
+			case memRead:
+				mssa.addAccess(inst, &MemoryUse{Inst: inst, block: block})
+			case memWrite:
+				mssa.addAccess(inst, &MemoryDef{Inst: inst, block: block})
+				defines = true
+			}
+		}
+		for _, inst := range block.Insts {
+			add(inst)
+		}
+		if block.Term != nil {
+			add(block.Term)
+		}
+		if defines {
+			defBlocks = append(defBlocks, block)
+		}
+	}
+	for _, block := range dom.IteratedFrontier(defBlocks) {
+		phi := &MemoryPhi{block: block}
+		for _, pred := range g.Preds(block) {
+			if g.Reachable(pred) {
+				phi.Incs = append(phi.Incs, &MemoryIncoming{Pred: pred})
+			}
+		}
+		mssa.phis[block] = phi
+	}
+	// Assign IDs in function order.
+	id := 1
+	for _, block := range g.Func.Blocks {
+		if phi, ok := mssa.phis[block]; ok {
+			phi.id = id
+			id++
+		}
+		for _, access := range mssa.blockAccesses[block] {
+			if def, ok := access.(*MemoryDef); ok {
+				def.id = id
+				id++
+			}
+		}
+	}
+	mssa.rename(g.Entry, mssa.LiveOnEntry)
+	return mssa
+}
+
+// Access returns the memory access of the given instruction or terminator; or
+// nil if it does not access memory.
+func (mssa *MemorySSA) Access(inst interface{}) MemoryAccess {
+	return mssa.accesses[inst]
+}
+
+// Phi returns the memory phi of the given basic block; or nil if not present.
+func (mssa *MemorySSA) Phi(block *llir.Block) *MemoryPhi {
+	return mssa.phis[block]
+}
+
+// BlockAccesses returns the memory accesses of the given basic block, memory
+// phi first followed by the memory accesses of instructions in order, and of
+// the terminator.
+func (mssa *MemorySSA) BlockAccesses(block *llir.Block) []MemoryAccess {
+	var accesses []MemoryAccess
+	if phi, ok := mssa.phis[block]; ok {
+		accesses = append(accesses, phi)
+	}
+	return append(accesses, mssa.blockAccesses[block]...)
+}
+
+// String returns the string representation of the Memory SSA form; the
+// instructions of the function annotated with their memory accesses.
+func (mssa *MemorySSA) String() string {
+	buf := &strings.Builder{}
+	for _, block := range mssa.Dom.Graph.Func.Blocks {
+		fmt.Fprintf(buf, "%s:\n", block.Ident()[1:])
+		if phi, ok := mssa.phis[block]; ok {
+			fmt.Fprintf(buf, "; %s\n", phi)
+		}
+		for _, inst := range block.Insts {
+			if access, ok := mssa.accesses[inst]; ok {
+				fmt.Fprintf(buf, "; %s\n", access)
+			}
+			fmt.Fprintf(buf, "\t%s\n", inst.LLString())
+		}
+		if block.Term != nil {
+			if access, ok := mssa.accesses[block.Term]; ok {
+				fmt.Fprintf(buf, "; %s\n", access)
+			}
+			fmt.Fprintf(buf, "\t%s\n", block.Term.LLString())
+		}
+	}
+	return buf.String()
+}
+
+// ClobberingAccess returns the nearest memory access dominating the given load
+// or store instruction which may modify the memory location accessed by the
+// instruction; either a MemoryDef, a MemoryPhi merging different clobbering
+// accesses, or LiveOnEntry if the memory location is not modified in the
+// function before the instruction.
+//
+// For other instructions accessing memory, the defining access is returned.
+func (mssa *MemorySSA) ClobberingAccess(inst llir.Instruction) MemoryAccess {
+	var defining MemoryAccess
+	switch access := mssa.accesses[inst].(type) {
+	case *MemoryUse:
+		defining = access.Defining
+	case *MemoryDef:
+		defining = access.Defining
+	default:
+		return nil
+	}
+	ptr, typ, ok := accessOf(inst)
+	if !ok {
+		return defining
+	}
+	loc := &memLoc{ptr: ptr, size: mssa.AA.storeSize(typ), inst: inst}
+	return mssa.clobbering(defining, loc)
+}
+
+// ClobberingAccessFrom returns the nearest memory access, starting at the
+// given memory access and walking upwards, which may modify the memory
+// location of the given size in bytes at address ptr. See ClobberingAccess.
+func (mssa *MemorySSA) ClobberingAccessFrom(start MemoryAccess, ptr value.Value, size uint64) MemoryAccess {
+	if use, ok := start.(*MemoryUse); ok {
+		start = use.Defining
+	}
+	return mssa.clobbering(start, &memLoc{ptr: ptr, size: size})
+}
+
+// memLoc is a memory location.
+type memLoc struct {
+	// Address of the memory location.
+	ptr value.Value
+	// Size in bytes of the memory location; or UnknownSize.
+	size uint64
+	// Load or store instruction accessing the memory location; or nil.
+	inst llir.Instruction
+}
+
+// clobbering returns the nearest memory access clobbering the given memory
+// location, starting at access.
+func (mssa *MemorySSA) clobbering(access MemoryAccess, loc *memLoc) MemoryAccess {
+	w := &walker{
+		mssa:   mssa,
+		loc:    loc,
+		onPath: make(map[*MemoryPhi]int),
+		done:   make(map[*MemoryPhi]MemoryAccess),
+	}
+	if clobber, _ := w.walk(access); clobber != nil {
+		return clobber
+	}
+	return access
+}
+
+// noCycle is the path depth reported by walks which reach no memory phi on the
+// current path.
+const noCycle = math.MaxInt
+
+// walker walks memory accesses upwards in search of the nearest memory access
+// clobbering a memory location.
+type walker struct {
+	mssa *MemorySSA
+	// Memory location.
+	loc *memLoc
+	// Path depth of each memory phi on the current path.
+	onPath map[*MemoryPhi]int
+	// Nearest clobbering access of each memory phi walked to completion.
+	done map[*MemoryPhi]MemoryAccess
+}
+
+// walk returns the nearest memory access clobbering the memory location,
+// starting at access. Memory phis on the current path are skipped, as cycles
+// do not introduce new clobbering accesses; nil is returned if the walk only
+// reaches such memory phis. The smallest path depth of the memory phis
+// skipped is returned, or noCycle if none.
+func (w *walker) walk(access MemoryAccess) (MemoryAccess, int) {
+	for {
+		switch a := access.(type) {
+		case *MemoryDef:
+			if a == w.mssa.LiveOnEntry || w.mssa.clobbers(a, w.loc) {
+				return a, noCycle
+			}
+			access = a.Defining
+		case *MemoryPhi:
+			if clobber, ok := w.done[a]; ok {
+				return clobber, noCycle
+			}
+			if depth, ok := w.onPath[a]; ok {
+				return nil, depth
+			}
+			depth := len(w.onPath)
+			w.onPath[a] = depth
+			clobber, low := w.walkPhi(a)
+			delete(w.onPath, a)
+			if low < depth {
+				// The result depends on memory phis still being walked.
+				return clobber, low
+			}
+			w.done[a] = clobber
+			return clobber, noCycle
+		default:
+			return access, noCycle
+		}
+	}
+}
+
+// walkPhi returns the nearest memory access clobbering the memory location
+// along the incoming accesses of the given memory phi; or the memory phi itself
+// if the incoming accesses disagree. The smallest path depth of the memory
+// phis skipped is returned, or noCycle if none.
+func (w *walker) walkPhi(phi *MemoryPhi) (MemoryAccess, int) {
+	var clobber MemoryAccess
+	low := noCycle
+	for _, inc := range phi.Incs {
+		c, l := w.walk(inc.Access)
+		if l < low {
+			low = l
+		}
+		if c == nil {
+			continue
+		}
+		if clobber != nil && c != clobber {
+			// The memory phi itself is a conservative result, independent of
+			// the current path.
+			return phi, noCycle
+		}
+		clobber = c
+	}
+	return clobber, low
+}
+
+// clobbers reports whether the given memory definition may modify the given
+// memory location.
+func (mssa *MemorySSA) clobbers(def *MemoryDef, loc *memLoc) bool {
+	aa := mssa.AA
+	switch inst := def.Inst.(type) {
+	case *llir.InstStore:
+		if loc.inst != nil {
+			return aa.AliasAccess(inst, loc.inst) != NoAlias
+		}
+		return aa.Alias(inst.Dst, loc.ptr, aa.storeSize(inst.Src.Type()), loc.size) != NoAlias
+	case *llir.InstAtomicRMW:
+		return aa.Alias(inst.Dst, loc.ptr, aa.storeSize(inst.X.Type()), loc.size) != NoAlias
+	case *llir.InstCmpXchg:
+		return aa.Alias(inst.Ptr, loc.ptr, aa.storeSize(inst.New.Type()), loc.size) != NoAlias
+	}
+	// Calls and fences.
+	return true
+}
+
+// rename links the memory accesses of the basic blocks dominated by block to
+// their defining accesses, given the memory state on entry to block.
+func (mssa *MemorySSA) rename(block *llir.Block, cur MemoryAccess) {
+	if phi, ok := mssa.phis[block]; ok {
+		cur = phi
+	}
+	for _, access := range mssa.blockAccesses[block] {
+		switch access := access.(type) {
+		case *MemoryUse:
+			access.Defining = cur
+		case *MemoryDef:
+			access.Defining = cur
+			cur = access
+		}
+	}
+	for _, succ := range mssa.Dom.Graph.Succs(block) {
+		if phi, ok := mssa.phis[succ]; ok {
+			for _, inc := range phi.Incs {
+				if inc.Pred == block {
+					inc.Access = cur
+				}
+			}
+		}
+	}
+	for _, child := range mssa.Dom.Children(block) {
+		mssa.rename(child, cur)
+	}
+}
+
+// addAccess adds the memory access of the given instruction or terminator.
+func (mssa *MemorySSA) addAccess(inst interface{}, access MemoryAccess) {
+	mssa.accesses[inst] = access
+	block := access.Block()
+	mssa.blockAccesses[block] = append(mssa.blockAccesses[block], access)
+}
+
+// memEffect is the effect of an instruction on memory.
+type memEffect uint8
+
+// Memory effects.
+const (
+	memNone memEffect = iota
+	memRead
+	memWrite
+)
+
+// effectOf returns the effect of the given instruction or terminator on
+// memory.
+func effectOf(inst interface{}) memEffect {
+	switch inst := inst.(type) {
+	case *llir.InstLoad:
+		return memRead
+	case *llir.InstStore, *llir.InstAtomicRMW, *llir.InstCmpXchg, *llir.InstFence:
+		return memWrite
+	case *llir.InstCall:
+		return callEffect(inst.Callee, inst.FuncAttrs)
+	case *llir.TermInvoke:
+		return callEffect(inst.Invokee, inst.FuncAttrs)
+	case *llir.TermCallBr:
+		return callEffect(inst.Callee, inst.FuncAttrs)
+	}
+	return memNone
+}
+
+// callEffect returns the effect on memory of a call to callee with the given
+// call site function attributes.
+func callEffect(callee value.Value, attrs []llir.FuncAttribute) memEffect {
+	if f, ok := stripCasts(callee).(*llir.Func); ok {
+		attrs = append(attrs[:len(attrs):len(attrs)], f.FuncAttrs...)
+	}
+	effect := memWrite
+	for _, attr := range flattenFuncAttrs(attrs) {
+		switch attr {
+		case enum.FuncAttrReadNone:
+			return memNone
+		case enum.FuncAttrReadOnly:
+			effect = memRead
+		}
+	}
+	return effect
+}
+
+// flattenFuncAttrs returns the given function attributes with attribute groups
+// expanded.
+func flattenFuncAttrs(attrs []llir.FuncAttribute) []llir.FuncAttribute {
+	var flat []llir.FuncAttribute
+	for _, attr := range attrs {
+		if group, ok := attr.(*llir.AttrGroupDef); ok {
+			flat = append(flat, group.FuncAttrs...)
+			continue
+		}
+		flat = append(flat, attr)
+	}
+	return flat
+}
+
+// stripCasts returns the given value with bitcast and addrspacecast constant
+// expressions stripped.
+func stripCasts(v value.Value) value.Value {
+	for {
+		switch c := v.(type) {
+		case *constant.ExprBitCast:
+			v = c.From
+		case *constant.ExprAddrSpaceCast:
+			v = c.From
+		default:
+			return v
+		}
+	}
+}
+
+// accessID returns the ID of the given memory access, as used in the string
+// representation of Memory SSA nodes.
+func accessID(access MemoryAccess) string {
+	switch access := access.(type) {
+	case *MemoryDef:
+		if access.Inst == nil {
+			return "liveOnEntry"
+		}
+		return fmt.Sprint(access.id)
+	case *MemoryPhi:
+		return fmt.Sprint(access.id)
+	}
+	return "?"
+}
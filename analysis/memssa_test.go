@@ -0,0 +1,198 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/cfg"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/llutil"
+	"github.com/wa-lang/llir/types"
+)
+
+func TestMemorySSA(t *testing.T) {
+	i32 := func(x int64) *constant.Int { return constant.NewInt(types.I32, x) }
+	m := llir.NewModule()
+	readOnly := m.NewFunc("read_only", types.Void)
+	readOnly.FuncAttrs = append(readOnly.FuncAttrs, enum.FuncAttrReadOnly)
+	readNone := m.NewFunc("read_none", types.Void)
+	readNone.FuncAttrs = append(readNone.FuncAttrs, enum.FuncAttrReadNone)
+	c := llir.NewParam("c", types.I1)
+	f := m.NewFunc("f", types.Void, c)
+	entry := f.NewBlock("entry")
+	then := f.NewBlock("then")
+	els := f.NewBlock("else")
+	join := f.NewBlock("join")
+	loop := f.NewBlock("loop")
+	exit := f.NewBlock("exit")
+	p := entry.NewAlloca(types.I32)
+	p.SetName("p")
+	q := entry.NewAlloca(types.I32)
+	q.SetName("q")
+	storeEntryP := entry.NewStore(i32(0), p)
+	storeQ := entry.NewStore(i32(1), q)
+	entry.NewCondBr(c, then, els)
+	storeP := then.NewStore(i32(2), p)
+	then.NewBr(join)
+	els.NewCall(readOnly)
+	els.NewCall(readNone)
+	els.NewBr(join)
+	loadP := join.NewLoad(types.I32, p)
+	loadP.SetName("v")
+	loadQ := join.NewLoad(types.I32, q)
+	loadQ.SetName("w")
+	join.NewBr(loop)
+	loadPLoop := loop.NewLoad(types.I32, p)
+	loadPLoop.SetName("x")
+	storeQLoop := loop.NewStore(loadPLoop, q)
+	loop.NewCondBr(c, loop, exit)
+	exit.NewRet(nil)
+
+	dom := cfg.NewDomTree(cfg.New(f))
+	mssa := NewMemorySSA(dom, NewAliasAnalysis(llutil.NewDataLayout("linux", "x86-64")))
+	const want = `entry:
+	%p = alloca i32
+	%q = alloca i32
+; 1 = MemoryDef(liveOnEntry)
+	store i32 0, i32* %p
+; 2 = MemoryDef(1)
+	store i32 1, i32* %q
+	br i1 %c, label %then, label %else
+then:
+; 3 = MemoryDef(2)
+	store i32 2, i32* %p
+	br label %join
+else:
+; MemoryUse(2)
+	call void @read_only()
+	call void @read_none()
+	br label %join
+join:
+; 4 = MemoryPhi({%then,3},{%else,2})
+; MemoryUse(4)
+	%v = load i32, i32* %p
+; MemoryUse(4)
+	%w = load i32, i32* %q
+	br label %loop
+loop:
+; 5 = MemoryPhi({%join,4},{%loop,6})
+; MemoryUse(5)
+	%x = load i32, i32* %p
+; 6 = MemoryDef(5)
+	store i32 %x, i32* %q
+	br i1 %c, label %loop, label %exit
+exit:
+	ret void
+`
+	if got := mssa.String(); got != want {
+		t.Errorf("Memory SSA mismatch; expected:\n%s\ngot:\n%s", want, got)
+	}
+
+	phi := mssa.Phi(join)
+	clobbers := []struct {
+		name string
+		inst llir.Instruction
+		want MemoryAccess
+	}{
+		{name: "load of %p in join", inst: loadP, want: phi},
+		{name: "load of %q in join", inst: loadQ, want: mssa.Access(storeQ)},
+		{name: "load of %p in loop", inst: loadPLoop, want: phi},
+		{name: "store to %p in entry", inst: storeEntryP, want: mssa.LiveOnEntry},
+		{name: "store to %p in then", inst: storeP, want: mssa.Access(storeEntryP)},
+		{name: "store to %q in loop", inst: storeQLoop, want: mssa.Phi(loop)},
+	}
+	for _, g := range clobbers {
+		if got := mssa.ClobberingAccess(g.inst); got != g.want {
+			t.Errorf("%s: clobbering access mismatch; expected %v, got %v", g.name, g.want, got)
+		}
+	}
+	if got, want := mssa.ClobberingAccessFrom(mssa.Phi(loop), q, 4), mssa.Phi(loop); got != want {
+		t.Errorf("clobbering access of %%q from loop mismatch; expected %v, got %v", want, got)
+	}
+	if got, want := mssa.ClobberingAccessFrom(mssa.Access(storeP), q, 4), mssa.Access(storeQ); got != want {
+		t.Errorf("clobbering access of %%q from then mismatch; expected %v, got %v", want, got)
+	}
+}
+
+// TestMemorySSANestedDiamond tests the clobber walk of a memory phi reached
+// along several incoming accesses of another memory phi.
+//
+//    entry -> a, m1
+//    a     -> m1
+//    x     -> y, n
+//    y     -> n
+//    n     -> m2
+//    m1    -> x, m2
+func TestMemorySSANestedDiamond(t *testing.T) {
+	i32 := func(x int64) *constant.Int { return constant.NewInt(types.I32, x) }
+	c := llir.NewParam("c", types.I1)
+	f := llir.NewFunc("f", types.Void, c)
+	entry := f.NewBlock("entry")
+	a := f.NewBlock("a")
+	x := f.NewBlock("x")
+	y := f.NewBlock("y")
+	n := f.NewBlock("n")
+	m1 := f.NewBlock("m1")
+	m2 := f.NewBlock("m2")
+	p := entry.NewAlloca(types.I32)
+	p.SetName("p")
+	q := entry.NewAlloca(types.I32)
+	q.SetName("q")
+	entry.NewStore(i32(0), p)
+	entry.NewCondBr(c, a, m1)
+	a.NewStore(i32(1), q)
+	a.NewBr(m1)
+	x.NewCondBr(c, y, n)
+	y.NewStore(i32(2), p)
+	y.NewBr(n)
+	n.NewBr(m2)
+	m1.NewCondBr(c, x, m2)
+	load := m2.NewLoad(types.I32, p)
+	m2.NewRet(nil)
+
+	dom := cfg.NewDomTree(cfg.New(f))
+	mssa := NewMemorySSA(dom, NewAliasAnalysis(llutil.NewDataLayout("linux", "x86-64")))
+	// The memory phi of m1 is reached both through the memory phi of n and
+	// directly; the stores to %p in entry and y disagree.
+	if got, want := mssa.ClobberingAccess(load), mssa.Phi(m2); got != want {
+		t.Errorf("clobbering access mismatch; expected %v, got %v\n%s", want, got, mssa)
+	}
+}
+
+func TestMemorySSAInvoke(t *testing.T) {
+	m := llir.NewModule()
+	g := m.NewGlobalDef("g", constant.NewInt(types.I32, 0))
+	h := m.NewFunc("h", types.Void)
+	readOnly := m.NewFunc("read_only", types.Void)
+	readOnly.FuncAttrs = append(readOnly.FuncAttrs, enum.FuncAttrReadOnly)
+	f := m.NewFunc("f", types.I32)
+	entry := f.NewBlock("entry")
+	cont := f.NewBlock("cont")
+	ret := f.NewBlock("ret")
+	lpad := f.NewBlock("lpad")
+	invoke := entry.NewInvoke(h, nil, cont, lpad)
+	invokeReadOnly := cont.NewInvoke(readOnly, nil, ret, lpad)
+	load := ret.NewLoad(types.I32, g)
+	ret.NewRet(load)
+	lp := lpad.NewLandingPad(types.NewStruct(types.I8Ptr, types.I32))
+	lp.Cleanup = true
+	lpad.NewResume(lp)
+
+	dom := cfg.NewDomTree(cfg.New(f))
+	mssa := NewMemorySSA(dom, NewAliasAnalysis(llutil.NewDataLayout("linux", "x86-64")))
+	def, ok := mssa.Access(invoke).(*MemoryDef)
+	if !ok {
+		t.Fatalf("expected MemoryDef of invoke, got %v", mssa.Access(invoke))
+	}
+	use, ok := mssa.Access(invokeReadOnly).(*MemoryUse)
+	if !ok {
+		t.Fatalf("expected MemoryUse of readonly invoke, got %v", mssa.Access(invokeReadOnly))
+	}
+	if use.Defining != def {
+		t.Errorf("defining access of readonly invoke mismatch; expected %v, got %v", def, use.Defining)
+	}
+	if got := mssa.ClobberingAccess(load); got != def {
+		t.Errorf("clobbering access of load mismatch; expected %v, got %v", def, got)
+	}
+}
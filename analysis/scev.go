@@ -0,0 +1,499 @@
+package analysis
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/cfg"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/types"
+	"github.com/wa-lang/llir/value"
+)
+
+// AddRec is an affine add recurrence {Start,+,Step}<Loop>; i.e. a value which
+// is Start in the first iteration of the loop, and is incremented by Step in
+// each following iteration.
+type AddRec struct {
+	// Start value; invariant in the loop.
+	Start value.Value
+	// Step value; invariant in the loop.
+	Step value.Value
+	// Loop of the recurrence.
+	Loop *cfg.Loop
+}
+
+// String returns the string representation of the add recurrence.
+func (r *AddRec) String() string {
+	return fmt.Sprintf("{%s,+,%s}<%s>", r.Start.Ident(), r.Step.Ident(), r.Loop.Header.Ident())
+}
+
+// Range is an inclusive range of signed integer values.
+type Range struct {
+	// Minimum value.
+	Min int64
+	// Maximum value.
+	Max int64
+}
+
+// String returns the string representation of the range.
+func (r Range) String() string {
+	return fmt.Sprintf("[%d, %d]", r.Min, r.Max)
+}
+
+// ScalarEvolution analyses induction variables of the loops of a function.
+//
+// Induction variables are phi instructions in loop headers, with a loop
+// invariant incoming value from outside of the loop and incoming values from
+// inside of the loop incrementing the phi by a loop invariant step (add or
+// sub). Values obtained by adding constants to induction variables are also
+// add recurrences.
+type ScalarEvolution struct {
+	// Loop nest forest of the function.
+	Forest *cfg.LoopForest
+
+	// Parent basic block of each instruction.
+	parent map[value.Value]*llir.Block
+	// Add recurrence of each analysed value; nil if not an add recurrence.
+	recs map[value.Value]*AddRec
+}
+
+// NewScalarEvolution returns a new scalar evolution analysis of the function
+// of the given loop nest forest.
+func NewScalarEvolution(forest *cfg.LoopForest) *ScalarEvolution {
+	se := &ScalarEvolution{
+		Forest: forest,
+		parent: make(map[value.Value]*llir.Block),
+		recs:   make(map[value.Value]*AddRec),
+	}
+	for _, block := range forest.Dom.Graph.Func.Blocks {
+		for _, inst := range block.Insts {
+			if v, ok := inst.(value.Value); ok {
+				se.parent[v] = block
+			}
+		}
+	}
+	return se
+}
+
+// Recurrence returns the add recurrence of the given integer value; or nil if
+// the value is not an add recurrence of a loop.
+func (se *ScalarEvolution) Recurrence(v value.Value) *AddRec {
+	if r, ok := se.recs[v]; ok {
+		return r
+	}
+	// Guard against cycles of unanalysable phi instructions.
+	se.recs[v] = nil
+	r := se.recurrence(v)
+	se.recs[v] = r
+	return r
+}
+
+// recurrence computes the add recurrence of the given value.
+func (se *ScalarEvolution) recurrence(v value.Value) *AddRec {
+	if _, ok := v.Type().(*types.IntType); !ok {
+		return nil
+	}
+	switch inst := v.(type) {
+	case *llir.InstPhi:
+		return se.phiRecurrence(inst)
+	case *llir.InstAdd:
+		if r := se.Recurrence(inst.X); r != nil {
+			return se.offsetRecurrence(r, inst.Y, false)
+		}
+		if r := se.Recurrence(inst.Y); r != nil {
+			return se.offsetRecurrence(r, inst.X, false)
+		}
+	case *llir.InstSub:
+		if r := se.Recurrence(inst.X); r != nil {
+			return se.offsetRecurrence(r, inst.Y, true)
+		}
+	}
+	return nil
+}
+
+// phiRecurrence returns the add recurrence of the given phi instruction; or
+// nil if not an induction variable.
+func (se *ScalarEvolution) phiRecurrence(phi *llir.InstPhi) *AddRec {
+	block := se.parent[phi]
+	l := se.Forest.LoopFor(block)
+	if l == nil || l.Header != block {
+		return nil
+	}
+	var start, step value.Value
+	for _, inc := range phi.Incs {
+		pred, ok := inc.Pred.(*llir.Block)
+		if !ok {
+			return nil
+		}
+		if !l.Contains(pred) {
+			if start != nil && start != inc.X {
+				return nil
+			}
+			start = inc.X
+			continue
+		}
+		s := se.incrementOf(phi, inc.X)
+		if s == nil || !se.invariant(s, l) {
+			return nil
+		}
+		if step != nil && !sameValue(step, s) {
+			return nil
+		}
+		step = s
+	}
+	if start == nil || step == nil || !se.invariant(start, l) {
+		return nil
+	}
+	return &AddRec{Start: start, Step: step, Loop: l}
+}
+
+// incrementOf returns the step by which v increments the given phi
+// instruction; or nil if v is not an increment of phi.
+func (se *ScalarEvolution) incrementOf(phi *llir.InstPhi, v value.Value) value.Value {
+	switch inst := v.(type) {
+	case *llir.InstAdd:
+		if inst.X == phi {
+			return inst.Y
+		}
+		if inst.Y == phi {
+			return inst.X
+		}
+	case *llir.InstSub:
+		if inst.X == phi {
+			if c, ok := inst.Y.(*constant.Int); ok {
+				return &constant.Int{Typ: c.Typ, X: new(big.Int).Neg(c.X)}
+			}
+		}
+	}
+	return nil
+}
+
+// offsetRecurrence returns the add recurrence of r plus (or minus if sub is
+// set) the given loop invariant offset; or nil if the start value cannot be
+// folded into a constant.
+func (se *ScalarEvolution) offsetRecurrence(r *AddRec, offset value.Value, sub bool) *AddRec {
+	start, ok := r.Start.(*constant.Int)
+	if !ok {
+		return nil
+	}
+	c, ok := offset.(*constant.Int)
+	if !ok {
+		return nil
+	}
+	x := new(big.Int)
+	if sub {
+		x.Sub(start.X, c.X)
+	} else {
+		x.Add(start.X, c.X)
+	}
+	return &AddRec{Start: &constant.Int{Typ: start.Typ, X: x}, Step: r.Step, Loop: r.Loop}
+}
+
+// invariant reports whether the given value is invariant in the loop l; i.e.
+// whether it is not defined by an instruction in the loop.
+func (se *ScalarEvolution) invariant(v value.Value, l *cfg.Loop) bool {
+	if block, ok := se.parent[v]; ok {
+		return !l.Contains(block)
+	}
+	return true
+}
+
+// BackedgeTakenCount returns the number of times the back edges of the given
+// loop are taken before the loop exits, if constant.
+//
+// The loop must have a single exiting block, which is either the header or
+// the single latch of the loop, terminated by a conditional branch on an icmp
+// instruction comparing an add recurrence of the loop with constant start and
+// step to a constant bound. The add recurrence must not wrap before the loop
+// exits.
+func (se *ScalarEvolution) BackedgeTakenCount(l *cfg.Loop) (uint64, bool) {
+	k, ok := se.exitIteration(l)
+	if !ok || !k.IsUint64() {
+		return 0, false
+	}
+	return k.Uint64(), true
+}
+
+// TripCount returns the number of times the header of the given loop is
+// executed, if constant; i.e. the backedge-taken count plus one. See
+// BackedgeTakenCount for the loops supported.
+func (se *ScalarEvolution) TripCount(l *cfg.Loop) (uint64, bool) {
+	k, ok := se.BackedgeTakenCount(l)
+	if !ok || k == ^uint64(0) {
+		return 0, false
+	}
+	return k + 1, true
+}
+
+// Range returns the range of values of the given add recurrence in its loop,
+// if the loop has a constant backedge-taken count and the recurrence has a
+// constant start and step.
+func (se *ScalarEvolution) Range(v value.Value) (Range, bool) {
+	r := se.Recurrence(v)
+	if r == nil {
+		return Range{}, false
+	}
+	start, step, ok := constStartStep(r)
+	if !ok {
+		return Range{}, false
+	}
+	k, ok := se.exitIteration(r.Loop)
+	if !ok {
+		return Range{}, false
+	}
+	end := new(big.Int).Add(start, new(big.Int).Mul(k, step))
+	lo, hi := start, end
+	if lo.Cmp(hi) > 0 {
+		lo, hi = hi, lo
+	}
+	if !lo.IsInt64() || !hi.IsInt64() {
+		return Range{}, false
+	}
+	return Range{Min: lo.Int64(), Max: hi.Int64()}, true
+}
+
+// exitIteration returns the iteration k, starting at 0, in which the given loop
+// exits.
+func (se *ScalarEvolution) exitIteration(l *cfg.Loop) (*big.Int, bool) {
+	exiting := l.ExitingBlocks()
+	latches := l.Latches()
+	if len(exiting) != 1 || len(latches) != 1 {
+		return nil, false
+	}
+	block := exiting[0]
+	if block != l.Header && block != latches[0] {
+		return nil, false
+	}
+	br, ok := block.Term.(*llir.TermCondBr)
+	if !ok {
+		return nil, false
+	}
+	cmp, ok := br.Cond.(*llir.InstICmp)
+	if !ok {
+		return nil, false
+	}
+	// Normalize to `rec pred bound`, where pred holds while the loop continues.
+	pred := cmp.Pred
+	x, y := cmp.X, cmp.Y
+	r := se.Recurrence(x)
+	if r == nil || r.Loop != l {
+		r = se.Recurrence(y)
+		if r == nil || r.Loop != l {
+			return nil, false
+		}
+		x, y = y, x
+		pred = swapPred(pred)
+	}
+	inLoop := func(target value.Value) bool {
+		block, ok := target.(*llir.Block)
+		return ok && l.Contains(block)
+	}
+	switch {
+	case inLoop(br.TargetTrue) && !inLoop(br.TargetFalse):
+		// Exit when false.
+	case !inLoop(br.TargetTrue) && inLoop(br.TargetFalse):
+		pred = invertPred(pred)
+	default:
+		return nil, false
+	}
+	bound, ok := y.(*constant.Int)
+	if !ok {
+		return nil, false
+	}
+	start, step, ok := constStartStep(r)
+	if !ok {
+		return nil, false
+	}
+	bits := bound.Typ.BitSize
+	signed := isSignedPred(pred)
+	s, d, b := normalize(start, bits, signed), normalize(step, bits, true), normalize(bound.X, bits, signed)
+	k, ok := exitIteration(s, d, b, pred)
+	if !ok {
+		return nil, false
+	}
+	// The recurrence must not wrap before the exit iteration.
+	last := new(big.Int).Add(s, new(big.Int).Mul(k, d))
+	if !inRange(last, bits, signed) {
+		return nil, false
+	}
+	return k, true
+}
+
+// exitIteration returns the first iteration k, for which `s + k*d pred b`
+// does not hold.
+func exitIteration(s, d, b *big.Int, pred enum.IPred) (*big.Int, bool) {
+	zero := new(big.Int)
+	diff := new(big.Int).Sub(b, s)
+	switch pred {
+	case enum.IPredSLT, enum.IPredULT:
+		if s.Cmp(b) >= 0 {
+			return zero, true
+		}
+		if d.Sign() <= 0 {
+			return nil, false
+		}
+		return ceilDiv(diff, d), true
+	case enum.IPredSLE, enum.IPredULE:
+		if s.Cmp(b) > 0 {
+			return zero, true
+		}
+		if d.Sign() <= 0 {
+			return nil, false
+		}
+		return new(big.Int).Add(new(big.Int).Div(diff, d), big.NewInt(1)), true
+	case enum.IPredSGT, enum.IPredUGT:
+		if s.Cmp(b) <= 0 {
+			return zero, true
+		}
+		if d.Sign() >= 0 {
+			return nil, false
+		}
+		return ceilDiv(new(big.Int).Neg(diff), new(big.Int).Neg(d)), true
+	case enum.IPredSGE, enum.IPredUGE:
+		if s.Cmp(b) < 0 {
+			return zero, true
+		}
+		if d.Sign() >= 0 {
+			return nil, false
+		}
+		return new(big.Int).Add(new(big.Int).Div(new(big.Int).Neg(diff), new(big.Int).Neg(d)), big.NewInt(1)), true
+	case enum.IPredNE:
+		if diff.Sign() == 0 {
+			return zero, true
+		}
+		if d.Sign() == 0 {
+			return nil, false
+		}
+		q, m := new(big.Int).QuoRem(diff, d, new(big.Int))
+		if m.Sign() != 0 || q.Sign() < 0 {
+			return nil, false
+		}
+		return q, true
+	case enum.IPredEQ:
+		if diff.Sign() != 0 {
+			return zero, true
+		}
+		if d.Sign() == 0 {
+			return nil, false
+		}
+		return big.NewInt(1), true
+	}
+	return nil, false
+}
+
+// constStartStep returns the constant start and step of the given add
+// recurrence.
+func constStartStep(r *AddRec) (start, step *big.Int, ok bool) {
+	s, ok := r.Start.(*constant.Int)
+	if !ok {
+		return nil, nil, false
+	}
+	d, ok := r.Step.(*constant.Int)
+	if !ok {
+		return nil, nil, false
+	}
+	return s.X, d.X, true
+}
+
+// sameValue reports whether the given values are the same value or equal
+// integer constants.
+func sameValue(a, b value.Value) bool {
+	if a == b {
+		return true
+	}
+	x, ok := a.(*constant.Int)
+	if !ok {
+		return false
+	}
+	y, ok := b.(*constant.Int)
+	return ok && x.X.Cmp(y.X) == 0
+}
+
+// ceilDiv returns x/y rounded up, for non-negative x and positive y.
+func ceilDiv(x, y *big.Int) *big.Int {
+	q := new(big.Int).Add(x, y)
+	q.Sub(q, big.NewInt(1))
+	return q.Div(q, y)
+}
+
+// normalize returns x interpreted as a signed or unsigned integer of the given
+// bit size.
+func normalize(x *big.Int, bits uint64, signed bool) *big.Int {
+	mod := new(big.Int).Lsh(big.NewInt(1), uint(bits))
+	y := new(big.Int).Mod(x, mod)
+	if signed {
+		half := new(big.Int).Rsh(mod, 1)
+		if y.Cmp(half) >= 0 {
+			y.Sub(y, mod)
+		}
+	}
+	return y
+}
+
+// inRange reports whether x is representable as a signed or unsigned integer
+// of the given bit size.
+func inRange(x *big.Int, bits uint64, signed bool) bool {
+	return normalize(x, bits, signed).Cmp(x) == 0
+}
+
+// isSignedPred reports whether the given predicate is a signed comparison;
+// equality comparisons are treated as signed.
+func isSignedPred(pred enum.IPred) bool {
+	switch pred {
+	case enum.IPredUGE, enum.IPredUGT, enum.IPredULE, enum.IPredULT:
+		return false
+	}
+	return true
+}
+
+// swapPred returns the predicate with swapped operands.
+func swapPred(pred enum.IPred) enum.IPred {
+	switch pred {
+	case enum.IPredSGE:
+		return enum.IPredSLE
+	case enum.IPredSGT:
+		return enum.IPredSLT
+	case enum.IPredSLE:
+		return enum.IPredSGE
+	case enum.IPredSLT:
+		return enum.IPredSGT
+	case enum.IPredUGE:
+		return enum.IPredULE
+	case enum.IPredUGT:
+		return enum.IPredULT
+	case enum.IPredULE:
+		return enum.IPredUGE
+	case enum.IPredULT:
+		return enum.IPredUGT
+	}
+	return pred
+}
+
+// invertPred returns the inverse of the given predicate.
+func invertPred(pred enum.IPred) enum.IPred {
+	switch pred {
+	case enum.IPredEQ:
+		return enum.IPredNE
+	case enum.IPredNE:
+		return enum.IPredEQ
+	case enum.IPredSGE:
+		return enum.IPredSLT
+	case enum.IPredSGT:
+		return enum.IPredSLE
+	case enum.IPredSLE:
+		return enum.IPredSGT
+	case enum.IPredSLT:
+		return enum.IPredSGE
+	case enum.IPredUGE:
+		return enum.IPredULT
+	case enum.IPredUGT:
+		return enum.IPredULE
+	case enum.IPredULE:
+		return enum.IPredUGT
+	case enum.IPredULT:
+		return enum.IPredUGE
+	}
+	return pred
+}
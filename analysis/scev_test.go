@@ -0,0 +1,120 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/cfg"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/types"
+	"github.com/wa-lang/llir/value"
+)
+
+func TestScalarEvolution(t *testing.T) {
+	i32 := func(x int64) *constant.Int { return constant.NewInt(types.I32, x) }
+	// newLoop returns a loop exiting from the latch:
+	//
+	//    loop:
+	//       %i = phi i32 [ start, %entry ], [ %next, %loop ]
+	//       %next = add i32 %i, step
+	//       %cond = icmp pred i32 %next, bound
+	//       br i1 %cond, label %loop, label %exit
+	newLoop := func(start, step int64, pred enum.IPred, bound value.Value) (*llir.Func, *llir.InstPhi, *llir.InstAdd) {
+		n := llir.NewParam("n", types.I32)
+		f := llir.NewFunc("f", types.Void, n)
+		entry := f.NewBlock("entry")
+		loop := f.NewBlock("loop")
+		exit := f.NewBlock("exit")
+		entry.NewBr(loop)
+		i := loop.NewPhi(llir.NewIncoming(i32(start), entry))
+		next := loop.NewAdd(i, i32(step))
+		i.Incs = append(i.Incs, llir.NewIncoming(next, loop))
+		if bound == nil {
+			bound = n
+		}
+		cond := loop.NewICmp(pred, next, bound)
+		loop.NewCondBr(cond, loop, exit)
+		exit.NewRet(nil)
+		return f, i, next
+	}
+	newSE := func(f *llir.Func) (*ScalarEvolution, *cfg.Loop) {
+		forest := cfg.NewLoopForest(cfg.NewDomTree(cfg.New(f)))
+		return NewScalarEvolution(forest), forest.Loops[0]
+	}
+
+	// for (i = 0; i < 10; i++)
+	f, i, next := newLoop(0, 1, enum.IPredSLT, i32(10))
+	se, l := newSE(f)
+	if got, want := se.Recurrence(i).String(), "{0,+,1}<%loop>"; got != want {
+		t.Errorf("recurrence of %%i mismatch; expected %q, got %q", want, got)
+	}
+	if got, want := se.Recurrence(next).String(), "{1,+,1}<%loop>"; got != want {
+		t.Errorf("recurrence of %%next mismatch; expected %q, got %q", want, got)
+	}
+	if got, ok := se.BackedgeTakenCount(l); !ok || got != 9 {
+		t.Errorf("backedge-taken count mismatch; expected 9, got %d (ok=%v)", got, ok)
+	}
+	if got, ok := se.TripCount(l); !ok || got != 10 {
+		t.Errorf("trip count mismatch; expected 10, got %d (ok=%v)", got, ok)
+	}
+	if got, ok := se.Range(i); !ok || got != (Range{Min: 0, Max: 9}) {
+		t.Errorf("range of %%i mismatch; expected [0, 9], got %v (ok=%v)", got, ok)
+	}
+
+	// for (i = 10; i > 0; i -= 2), exiting from the header.
+	f = llir.NewFunc("g", types.Void)
+	entry := f.NewBlock("entry")
+	header := f.NewBlock("header")
+	body := f.NewBlock("body")
+	exit := f.NewBlock("exit")
+	entry.NewBr(header)
+	j := header.NewPhi(llir.NewIncoming(i32(10), entry))
+	cond := header.NewICmp(enum.IPredSGT, j, i32(0))
+	header.NewCondBr(cond, body, exit)
+	dec := body.NewSub(j, i32(2))
+	j.Incs = append(j.Incs, llir.NewIncoming(dec, body))
+	body.NewBr(header)
+	exit.NewRet(nil)
+	se, l = newSE(f)
+	if got, want := se.Recurrence(j).String(), "{10,+,-2}<%header>"; got != want {
+		t.Errorf("recurrence of %%j mismatch; expected %q, got %q", want, got)
+	}
+	if got, ok := se.TripCount(l); !ok || got != 6 {
+		t.Errorf("trip count mismatch; expected 6, got %d (ok=%v)", got, ok)
+	}
+	if got, ok := se.Range(j); !ok || got != (Range{Min: 0, Max: 10}) {
+		t.Errorf("range of %%j mismatch; expected [0, 10], got %v (ok=%v)", got, ok)
+	}
+
+	// Unsupported loops.
+	unsupported := []struct {
+		name  string
+		start int64
+		step  int64
+		pred  enum.IPred
+		bound value.Value
+	}{
+		{name: "variable bound", start: 0, step: 1, pred: enum.IPredSLT, bound: nil},
+		{name: "bound not reached", start: 0, step: 2, pred: enum.IPredNE, bound: i32(7)},
+		{name: "wrap-around", start: 0, step: -1, pred: enum.IPredSLT, bound: i32(10)},
+	}
+	for _, g := range unsupported {
+		f, i, _ := newLoop(g.start, g.step, g.pred, g.bound)
+		se, l := newSE(f)
+		if se.Recurrence(i) == nil {
+			t.Errorf("%s: expected add recurrence of %%i", g.name)
+		}
+		if got, ok := se.TripCount(l); ok {
+			t.Errorf("%s: expected unknown trip count, got %d", g.name, got)
+		}
+	}
+
+	// Not an induction variable.
+	f, i, _ = newLoop(0, 1, enum.IPredSLT, i32(10))
+	i.Incs[1].X = i32(5)
+	se, _ = newSE(f)
+	if r := se.Recurrence(i); r != nil {
+		t.Errorf("expected no add recurrence of %%i, got %v", r)
+	}
+}
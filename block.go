@@ -49,7 +49,8 @@ func (block *Block) Type() types.Type {
 }
 
 // LLString returns the LLVM syntax representation of the basic block
-// definition.
+// definition. LLString panics if the basic block lacks a terminator; use
+// Module.WriteTo to report missing terminators as errors.
 //
 // Name=LabelIdentopt Insts=Instruction* Term=Terminator
 func (block *Block) LLString() string {
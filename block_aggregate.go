@@ -16,6 +16,18 @@ func (block *Block) NewExtractValue(x value.Value, indices ...uint64) *InstExtra
 	return inst
 }
 
+// TryNewExtractValue appends a new extractvalue instruction to the basic block
+// based on the given aggregate value and indicies, or returns an error if the
+// indices are invalid for the aggregate type.
+func (block *Block) TryNewExtractValue(x value.Value, indices ...uint64) (*InstExtractValue, error) {
+	inst, err := TryNewExtractValue(x, indices...)
+	if err != nil {
+		return nil, err
+	}
+	block.Insts = append(block.Insts, inst)
+	return inst, nil
+}
+
 // ~~~ [ insertvalue ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // NewInsertValue appends a new insertvalue instruction to the basic block based
@@ -25,3 +37,15 @@ func (block *Block) NewInsertValue(x, elem value.Value, indices ...uint64) *Inst
 	block.Insts = append(block.Insts, inst)
 	return inst
 }
+
+// TryNewInsertValue appends a new insertvalue instruction to the basic block
+// based on the given aggregate value, element and indicies, or returns an error
+// if the element type does not match the aggregate type at the indices.
+func (block *Block) TryNewInsertValue(x, elem value.Value, indices ...uint64) (*InstInsertValue, error) {
+	inst, err := TryNewInsertValue(x, elem, indices...)
+	if err != nil {
+		return nil, err
+	}
+	block.Insts = append(block.Insts, inst)
+	return inst, nil
+}
@@ -17,6 +17,18 @@ func (block *Block) NewTrunc(from value.Value, to types.Type) *InstTrunc {
 	return inst
 }
 
+// TryNewTrunc appends a new trunc instruction to the basic block based on the
+// given source value and target type, or returns an error if the operands are
+// not compatible.
+func (block *Block) TryNewTrunc(from value.Value, to types.Type) (*InstTrunc, error) {
+	inst, err := TryNewTrunc(from, to)
+	if err != nil {
+		return nil, err
+	}
+	block.Insts = append(block.Insts, inst)
+	return inst, nil
+}
+
 // ~~~ [ zext ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // NewZExt appends a new zext instruction to the basic block based on the given
@@ -38,6 +38,18 @@ func (block *Block) NewStore(src, dst value.Value) *InstStore {
 	return inst
 }
 
+// TryNewStore appends a new store instruction to the basic block based on the
+// given source value and destination address, or returns an error if the
+// operands are not compatible.
+func (block *Block) TryNewStore(src, dst value.Value) (*InstStore, error) {
+	inst, err := TryNewStore(src, dst)
+	if err != nil {
+		return nil, err
+	}
+	block.Insts = append(block.Insts, inst)
+	return inst, nil
+}
+
 // ~~~ [ fence ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // NewFence appends a new fence instruction to the basic block based on the
@@ -78,3 +90,15 @@ func (block *Block) NewGetElementPtr(elemType types.Type, src value.Value, indic
 	block.Insts = append(block.Insts, inst)
 	return inst
 }
+
+// TryNewGetElementPtr appends a new getelementptr instruction to the basic
+// block based on the given element type, source address and element indices,
+// or returns an error if the result type cannot be computed from the operands.
+func (block *Block) TryNewGetElementPtr(elemType types.Type, src value.Value, indices ...value.Value) (*InstGetElementPtr, error) {
+	inst, err := TryNewGetElementPtr(elemType, src, indices...)
+	if err != nil {
+		return nil, err
+	}
+	block.Insts = append(block.Insts, inst)
+	return inst, nil
+}
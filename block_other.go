@@ -18,6 +18,18 @@ func (block *Block) NewICmp(pred enum.IPred, x, y value.Value) *InstICmp {
 	return inst
 }
 
+// TryNewICmp appends a new icmp instruction to the basic block based on the
+// given integer comparison predicate and integer scalar or vector operands, or
+// returns an error if the operand type is invalid.
+func (block *Block) TryNewICmp(pred enum.IPred, x, y value.Value) (*InstICmp, error) {
+	inst, err := TryNewICmp(pred, x, y)
+	if err != nil {
+		return nil, err
+	}
+	block.Insts = append(block.Insts, inst)
+	return inst, nil
+}
+
 // ~~~ [ fcmp ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // NewFCmp appends a new fcmp instruction to the basic block based on the given
@@ -29,6 +41,18 @@ func (block *Block) NewFCmp(pred enum.FPred, x, y value.Value) *InstFCmp {
 	return inst
 }
 
+// TryNewFCmp appends a new fcmp instruction to the basic block based on the
+// given floating-point comparison predicate and floating-point scalar or vector
+// operands, or returns an error if the operand type is invalid.
+func (block *Block) TryNewFCmp(pred enum.FPred, x, y value.Value) (*InstFCmp, error) {
+	inst, err := TryNewFCmp(pred, x, y)
+	if err != nil {
+		return nil, err
+	}
+	block.Insts = append(block.Insts, inst)
+	return inst, nil
+}
+
 // ~~~ [ phi ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // NewPhi appends a new phi instruction to the basic block based on the given
@@ -61,6 +85,18 @@ func (block *Block) NewCall(callee value.Value, args ...value.Value) *InstCall {
 	return inst
 }
 
+// TryNewCall appends a new call instruction to the basic block based on the
+// given callee and function arguments, or returns an error if the callee type
+// is invalid.
+func (block *Block) TryNewCall(callee value.Value, args ...value.Value) (*InstCall, error) {
+	inst, err := TryNewCall(callee, args...)
+	if err != nil {
+		return nil, err
+	}
+	block.Insts = append(block.Insts, inst)
+	return inst, nil
+}
+
 // ~~~ [ va_arg ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // NewVAArg appends a new va_arg instruction to the basic block based on the
@@ -16,6 +16,18 @@ func (block *Block) NewExtractElement(x, index value.Value) *InstExtractElement
 	return inst
 }
 
+// TryNewExtractElement appends a new extractelement instruction to the basic
+// block based on the given vector and element index, or returns an error if x
+// is not a vector.
+func (block *Block) TryNewExtractElement(x, index value.Value) (*InstExtractElement, error) {
+	inst, err := TryNewExtractElement(x, index)
+	if err != nil {
+		return nil, err
+	}
+	block.Insts = append(block.Insts, inst)
+	return inst, nil
+}
+
 // ~~~ [ insertelement ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // NewInsertElement appends a new insertelement instruction to the basic block
@@ -26,6 +38,18 @@ func (block *Block) NewInsertElement(x, elem, index value.Value) *InstInsertElem
 	return inst
 }
 
+// TryNewInsertElement appends a new insertelement instruction to the basic
+// block based on the given vector, element and element index, or returns an
+// error if x is not a vector.
+func (block *Block) TryNewInsertElement(x, elem, index value.Value) (*InstInsertElement, error) {
+	inst, err := TryNewInsertElement(x, elem, index)
+	if err != nil {
+		return nil, err
+	}
+	block.Insts = append(block.Insts, inst)
+	return inst, nil
+}
+
 // ~~~ [ shufflevector ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // NewShuffleVector appends a new shufflevector instruction to the basic block
@@ -35,3 +59,15 @@ func (block *Block) NewShuffleVector(x, y, mask value.Value) *InstShuffleVector
 	block.Insts = append(block.Insts, inst)
 	return inst
 }
+
+// TryNewShuffleVector appends a new shufflevector instruction to the basic
+// block based on the given vectors and shuffle mask, or returns an error if x
+// or mask is not a vector.
+func (block *Block) TryNewShuffleVector(x, y, mask value.Value) (*InstShuffleVector, error) {
+	inst, err := TryNewShuffleVector(x, y, mask)
+	if err != nil {
+		return nil, err
+	}
+	block.Insts = append(block.Insts, inst)
+	return inst, nil
+}
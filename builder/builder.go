@@ -0,0 +1,156 @@
+// Package builder provides a structured control flow builder for LLVM IR
+// functions.
+//
+// The builder creates and wires the basic blocks of if/else, loop and switch
+// constructs, and inserts phi instructions for values flowing out of branches
+// and values carried across loop iterations.
+//
+// Protected regions with catch clauses and cleanups are supported for both the
+// Itanium (landingpad) and Windows (funclet) exception handling models, as are
+// switched-resume coroutines.
+package builder
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/llutil"
+	"github.com/wa-lang/llir/types"
+	"github.com/wa-lang/llir/value"
+)
+
+// Builder is a structured control flow builder of an LLVM IR function.
+//
+// Instructions are appended to the current basic block, which is embedded in
+// the builder. The current basic block is nil when the insertion point is
+// unreachable (e.g. after Ret, Break or Continue).
+type Builder struct {
+	// Current basic block; or nil if unreachable.
+	*llir.Block
+	// Function being built.
+	Func *llir.Func
+
+	// Local names in use within the function.
+	names map[string]bool
+	// Stack of enclosing loops; innermost last.
+	loops []*loop
+	// Replacement values of trivial phi instructions removed from loop headers.
+	repl map[value.Value]value.Value
+	// Exception handling model of the function.
+	eh EHModel
+	// Stack of enclosing protected regions; innermost last.
+	regions []*region
+	// Stack of enclosing funclet pads; innermost last.
+	pads []value.Value
+	// Coroutine of the function; or nil if not a coroutine.
+	coro *Coroutine
+}
+
+// New returns a new structured control flow builder for the given function.
+// The insertion point is set to the end of the last basic block of f, or to a
+// new entry basic block if f has no basic blocks.
+func New(f *llir.Func) *Builder {
+	b := &Builder{Func: f, names: llutil.LocalNames(f), repl: make(map[value.Value]value.Value)}
+	if len(f.Blocks) == 0 {
+		b.Block = b.NewBlock("entry")
+		b.startBlock(b.Block)
+	} else {
+		b.Block = f.Blocks[len(f.Blocks)-1]
+	}
+	return b
+}
+
+// NewBlock returns a new basic block with a unique label name based on the
+// given name. The basic block is not yet added to the function; use SetBlock
+// to add it and move the insertion point to it.
+func (b *Builder) NewBlock(name string) *llir.Block {
+	// Ensure unique basic block names (e.g. "if.then", "if.then1", ...).
+	block := llir.NewBlock(llutil.UniqueName(b.names, name))
+	block.Parent = b.Func
+	return block
+}
+
+// SetBlock sets the insertion point of the builder to the end of the given
+// basic block. The basic block is added to the function if not already
+// present.
+func (b *Builder) SetBlock(block *llir.Block) {
+	if block.Parent != b.Func || !b.hasBlock(block) {
+		b.startBlock(block)
+	}
+	b.Block = block
+}
+
+// Reachable reports whether the insertion point of the builder is reachable;
+// i.e. whether the current basic block exists and has not yet been terminated.
+func (b *Builder) Reachable() bool {
+	return b.Block != nil && b.Block.Term == nil
+}
+
+// Ret terminates the current basic block with a ret terminator based on the
+// given return value; a nil return value indicates a void return. The
+// insertion point becomes unreachable.
+func (b *Builder) Ret(x value.Value) *llir.TermRet {
+	term := b.current().NewRet(x)
+	b.Block = nil
+	return term
+}
+
+// Unreachable terminates the current basic block with an unreachable
+// terminator. The insertion point becomes unreachable.
+func (b *Builder) Unreachable() *llir.TermUnreachable {
+	term := b.current().NewUnreachable()
+	b.Block = nil
+	return term
+}
+
+// Finish completes the function being built. If the insertion point is
+// reachable, the current basic block is terminated by a void return, provided
+// the function has a void return type. An error is returned if any basic block
+// of the function lacks a terminator.
+func (b *Builder) Finish() error {
+	if b.Reachable() {
+		if !types.Equal(b.Func.Sig.RetType, types.Void) {
+			return errors.Errorf("missing return in basic block %q of function %q with return type %v", b.Block.Ident(), b.Func.Ident(), b.Func.Sig.RetType)
+		}
+		b.Ret(nil)
+	}
+	for _, block := range b.Func.Blocks {
+		if block.Term == nil {
+			return errors.Errorf("missing terminator in basic block %q of function %q", block.Ident(), b.Func.Ident())
+		}
+	}
+	return nil
+}
+
+// ### [ Helper functions ] ####################################################
+
+// current returns the current basic block of the builder. current panics if the
+// insertion point is unreachable.
+func (b *Builder) current() *llir.Block {
+	if b.Block == nil {
+		panic(fmt.Errorf("unable to append to unreachable insertion point in function %q", b.Func.Ident()))
+	}
+	if b.Block.Term != nil {
+		panic(fmt.Errorf("unable to append to terminated basic block %q in function %q", b.Block.Ident(), b.Func.Ident()))
+	}
+	return b.Block
+}
+
+// startBlock appends the given basic block to the function and sets the
+// insertion point to it.
+func (b *Builder) startBlock(block *llir.Block) {
+	block.Parent = b.Func
+	b.Func.Blocks = append(b.Func.Blocks, block)
+	b.Block = block
+}
+
+// hasBlock reports whether the given basic block is part of the function.
+func (b *Builder) hasBlock(block *llir.Block) bool {
+	for _, bb := range b.Func.Blocks {
+		if bb == block {
+			return true
+		}
+	}
+	return false
+}
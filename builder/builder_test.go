@@ -0,0 +1,377 @@
+package builder
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/llutil"
+	"github.com/wa-lang/llir/types"
+	"github.com/wa-lang/llir/value"
+)
+
+func TestIf(t *testing.T) {
+	m := llir.NewModule()
+	x := llir.NewParam("x", types.I32)
+	f := m.NewFunc("abs", types.I32, x)
+	b := New(f)
+	neg := b.NewICmp(enum.IPredSLT, x, constant.NewInt(types.I32, 0))
+	vals := b.If(neg, func(b *Builder) []value.Value {
+		return []value.Value{b.NewSub(constant.NewInt(types.I32, 0), x)}
+	}, func(b *Builder) []value.Value {
+		return []value.Value{x}
+	})
+	b.Ret(vals[0])
+	if err := b.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	want := `define i32 @abs(i32 %x) {
+entry:
+	%0 = icmp slt i32 %x, 0
+	br i1 %0, label %if.then, label %if.else
+
+if.then:
+	%1 = sub i32 0, %x
+	br label %if.end
+
+if.else:
+	br label %if.end
+
+if.end:
+	%2 = phi i32 [ %1, %if.then ], [ %x, %if.else ]
+	ret i32 %2
+}`
+	if got := f.LLString(); want != got {
+		t.Errorf("function mismatch; expected:\n%s\n\ngot:\n%s", want, got)
+	}
+}
+
+func TestIfUnreachable(t *testing.T) {
+	m := llir.NewModule()
+	c := llir.NewParam("c", types.I1)
+	f := m.NewFunc("f", types.I32, c)
+	b := New(f)
+	b.If(c, func(b *Builder) []value.Value {
+		b.Ret(constant.NewInt(types.I32, 1))
+		return nil
+	}, func(b *Builder) []value.Value {
+		b.Ret(constant.NewInt(types.I32, 2))
+		return nil
+	})
+	if b.Reachable() {
+		t.Fatal("expected unreachable insertion point after if/else")
+	}
+	if err := b.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	// The if.end basic block is never added.
+	if got := len(f.Blocks); got != 3 {
+		t.Errorf("invalid number of basic blocks; expected 3, got %d", got)
+	}
+}
+
+func TestWhile(t *testing.T) {
+	m := llir.NewModule()
+	n := llir.NewParam("n", types.I32)
+	f := m.NewFunc("sum", types.I32, n)
+	b := New(f)
+	zero := constant.NewInt(types.I32, 0)
+	one := constant.NewInt(types.I32, 1)
+	vals := b.While([]value.Value{zero, zero, n}, func(b *Builder, vals []value.Value) value.Value {
+		return b.NewICmp(enum.IPredSLT, vals[0], vals[2])
+	}, func(b *Builder, vals []value.Value) []value.Value {
+		i, sum := vals[0], vals[1]
+		return []value.Value{b.NewAdd(i, one), b.NewAdd(sum, i), vals[2]}
+	})
+	b.Ret(vals[1])
+	if err := b.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	// The phi of the loop invariant n is removed.
+	want := `define i32 @sum(i32 %n) {
+entry:
+	br label %while.cond
+
+while.cond:
+	%0 = phi i32 [ 0, %entry ], [ %3, %while.body ]
+	%1 = phi i32 [ 0, %entry ], [ %4, %while.body ]
+	%2 = icmp slt i32 %0, %n
+	br i1 %2, label %while.body, label %while.end
+
+while.body:
+	%3 = add i32 %0, 1
+	%4 = add i32 %1, %0
+	br label %while.cond
+
+while.end:
+	ret i32 %1
+}`
+	if got := f.LLString(); want != got {
+		t.Errorf("function mismatch; expected:\n%s\n\ngot:\n%s", want, got)
+	}
+}
+
+func TestForBreakContinue(t *testing.T) {
+	m := llir.NewModule()
+	n := llir.NewParam("n", types.I32)
+	f := m.NewFunc("f", types.I32, n)
+	b := New(f)
+	zero := constant.NewInt(types.I32, 0)
+	one := constant.NewInt(types.I32, 1)
+	vals := b.For([]value.Value{zero}, nil, func(b *Builder, vals []value.Value) []value.Value {
+		return []value.Value{b.NewAdd(vals[0], one)}
+	}, func(b *Builder, vals []value.Value) []value.Value {
+		i := vals[0]
+		b.If(b.NewICmp(enum.IPredEQ, i, n), func(b *Builder) []value.Value {
+			b.Break(i)
+			return nil
+		}, nil)
+		b.If(b.NewICmp(enum.IPredEQ, i, one), func(b *Builder) []value.Value {
+			b.Continue(i)
+			return nil
+		}, nil)
+		return []value.Value{i}
+	})
+	b.Ret(vals[0])
+	if err := b.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	s := f.LLString()
+	for _, want := range []string{
+		"for.cond:\n\t%0 = phi i32 [ 0, %entry ], [ %3, %for.inc ]\n\tbr label %for.body",
+		"for.inc:\n\t%3 = add i32 %0, 1\n\tbr label %for.cond",
+		"for.end:\n\tret i32 %0",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("unable to locate %q in function:\n%s", want, s)
+		}
+	}
+	// Both continue and fall through reach for.inc with the same value; no phi.
+	for _, block := range f.Blocks {
+		if block.Name() == "for.inc" && len(block.Insts) != 1 {
+			t.Errorf("invalid number of instructions in for.inc; expected 1, got %d", len(block.Insts))
+		}
+	}
+}
+
+func TestSwitch(t *testing.T) {
+	m := llir.NewModule()
+	x := llir.NewParam("x", types.I32)
+	f := m.NewFunc("f", types.I32, x)
+	b := New(f)
+	cases := []*Case{
+		NewCase(constant.NewInt(types.I32, 1), func(b *Builder) []value.Value {
+			return []value.Value{constant.NewInt(types.I32, 10)}
+		}),
+		NewCase(constant.NewInt(types.I32, 2), func(b *Builder) []value.Value {
+			return []value.Value{constant.NewInt(types.I32, 20)}
+		}),
+	}
+	vals := b.Switch(x, cases, func(b *Builder) []value.Value {
+		return []value.Value{x}
+	})
+	b.Ret(vals[0])
+	if err := b.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	want := "sw.epilog:\n\t%0 = phi i32 [ 10, %sw.bb ], [ 20, %sw.bb1 ], [ %x, %sw.default ]\n\tret i32 %0"
+	if s := f.LLString(); !strings.Contains(s, want) {
+		t.Errorf("unable to locate %q in function:\n%s", want, s)
+	}
+}
+
+func TestFinish(t *testing.T) {
+	m := llir.NewModule()
+	f := m.NewFunc("f", types.I32)
+	b := New(f)
+	if err := b.Finish(); err == nil {
+		t.Error("expected error for missing return in non-void function")
+	}
+	g := m.NewFunc("g", types.Void)
+	b = New(g)
+	if err := b.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := g.Blocks[0].Term.(*llir.TermRet); !ok {
+		t.Errorf("expected void return, got %T", g.Blocks[0].Term)
+	}
+}
+
+func TestMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic on mismatch in number of values")
+		}
+	}()
+	m := llir.NewModule()
+	c := llir.NewParam("c", types.I1)
+	f := m.NewFunc("f", types.Void, c)
+	b := New(f)
+	b.If(c, func(b *Builder) []value.Value {
+		return []value.Value{c}
+	}, nil)
+}
+
+func TestTryItanium(t *testing.T) {
+	m := llir.NewModule()
+	personality := m.NewFunc("__gxx_personality_v0", types.I32)
+	personality.Sig.Variadic = true
+	mayThrow := m.NewFunc("may_throw", types.Void)
+	dtor := m.NewFunc("dtor", types.Void)
+	ti := m.NewGlobal("_ZTIi", types.I8)
+	f := m.NewFunc("f", types.I32)
+	b := New(f)
+	b.SetPersonality(personality, EHItanium)
+	vals := b.Try(func(b *Builder) []value.Value {
+		b.Cleanup(func(b *Builder) []value.Value {
+			b.Call(mayThrow)
+			return nil
+		}, func(b *Builder) {
+			b.NewCall(dtor)
+		})
+		return []value.Value{constant.NewInt(types.I32, 0)}
+	}, NewCatch(ti, func(b *Builder, exn value.Value) []value.Value {
+		return []value.Value{constant.NewInt(types.I32, 1)}
+	}))
+	b.Ret(vals[0])
+	if err := b.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	if errs := llutil.Verify(m); len(errs) > 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+	if f.Personality != personality {
+		t.Errorf("personality mismatch; expected %v, got %v", personality, f.Personality)
+	}
+	s := f.LLString()
+	for _, want := range []string{
+		"invoke void @may_throw()\n\t\tto label %invoke.cont unwind label %lpad1",
+		"lpad1:\n\t%0 = landingpad { i8*, i32 }\n\t\tcleanup\n\t\tcatch i8* @_ZTIi\n\tbr label %ehcleanup",
+		"ehcleanup:\n\tcall void @dtor()\n\tbr label %catch.dispatch",
+		"catch.dispatch:\n\t%1 = extractvalue { i8*, i32 } %0, 0",
+		"catch.fallthrough:\n\tresume { i8*, i32 } %0",
+		"try.cont:\n\t%5 = phi i32 [ 0, %invoke.cont ], [ 1, %catch ]",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("unable to locate %q in function:\n%s", want, s)
+		}
+	}
+}
+
+func TestTryCatchAllNested(t *testing.T) {
+	m := llir.NewModule()
+	personality := m.NewFunc("__gxx_personality_v0", types.I32)
+	personality.Sig.Variadic = true
+	mayThrow := m.NewFunc("may_throw", types.Void)
+	f := m.NewFunc("f", types.Void)
+	b := New(f)
+	b.SetPersonality(personality, EHItanium)
+	catchAll := func(b *Builder, exn value.Value) []value.Value {
+		return nil
+	}
+	b.Try(func(b *Builder) []value.Value {
+		b.Try(func(b *Builder) []value.Value {
+			b.Call(mayThrow)
+			return nil
+		}, NewCatch(nil, catchAll))
+		return nil
+	}, NewCatch(nil, catchAll))
+	b.Ret(nil)
+	if err := b.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	s := f.LLString()
+	if want := "landingpad { i8*, i32 }\n\t\tcatch i8* null\n"; !strings.Contains(s, want) {
+		t.Errorf("unable to locate %q in function:\n%s", want, s)
+	}
+	if n := strings.Count(s, "catch i8* null"); n != 1 {
+		t.Errorf("number of catch-all clauses mismatch; expected 1, got %d in function:\n%s", n, s)
+	}
+}
+
+func TestTryFunclet(t *testing.T) {
+	m := llir.NewModule()
+	personality := m.NewFunc("__CxxFrameHandler3", types.I32)
+	personality.Sig.Variadic = true
+	mayThrow := m.NewFunc("may_throw", types.Void)
+	handle := m.NewFunc("handle", types.Void)
+	f := m.NewFunc("f", types.Void)
+	b := New(f)
+	b.SetPersonality(personality, EHFunclet)
+	null := constant.NewNull(types.I8Ptr)
+	b.Try(func(b *Builder) []value.Value {
+		b.Call(mayThrow)
+		return nil
+	}, NewFuncletCatch(func(b *Builder, exn value.Value) []value.Value {
+		b.Call(handle)
+		return nil
+	}, null, constant.NewInt(types.I32, 64), null))
+	if err := b.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	if errs := llutil.Verify(m); len(errs) > 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+	s := f.LLString()
+	for _, want := range []string{
+		"catch.dispatch:\n\t%0 = catchswitch within none [label %catch] unwind to caller",
+		"catch:\n\t%1 = catchpad within %0 [i8* null, i32 64, i8* null]\n\tcall void @handle() [ \"funclet\"(token %1) ]\n\tcatchret from %1 to label %try.cont",
+		"try.cont:\n\tret void",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("unable to locate %q in function:\n%s", want, s)
+		}
+	}
+}
+
+func TestCoroutine(t *testing.T) {
+	m := llir.NewModule()
+	malloc := m.NewFunc("malloc", types.I8Ptr, llir.NewParam("", types.I64))
+	free := m.NewFunc("free", types.Void, llir.NewParam("", types.I8Ptr))
+	print := m.NewFunc("print", types.Void, llir.NewParam("", types.I32))
+	n := llir.NewParam("n", types.I32)
+	f := m.NewFunc("f", types.I8Ptr, n)
+	b := New(f)
+	b.BeginCoroutine(func(b *Builder, size value.Value) value.Value {
+		return b.NewCall(malloc, size)
+	}, func(b *Builder, mem value.Value) {
+		b.NewCall(free, mem)
+	})
+	b.While([]value.Value{n}, nil, func(b *Builder, vals []value.Value) []value.Value {
+		b.NewCall(print, vals[0])
+		b.Suspend(false)
+		return []value.Value{b.NewAdd(vals[0], constant.NewInt(types.I32, 1))}
+	})
+	b.EndCoroutine()
+	if err := b.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyCoroutine(f); err != nil {
+		t.Fatal(err)
+	}
+	s := f.LLString()
+	for _, want := range []string{
+		"define i8* @f(i32 %n) presplitcoroutine {",
+		"%0 = call token @llvm.coro.id(i32 0, i8* null, i8* null, i8* null)",
+		"%3 = call i8* @llvm.coro.begin(token %0, i8* %2)",
+		"%5 = call i8 @llvm.coro.suspend(token none, i1 false)\n\tswitch i8 %5, label %coro.suspend [\n\t\ti8 0, label %coro.resume\n\t\ti8 1, label %coro.cleanup\n\t]",
+		"coro.cleanup:\n\t%7 = call i8* @llvm.coro.free(token %0, i8* %3)\n\tcall void @free(i8* %7)\n\tbr label %coro.suspend",
+		"coro.suspend:\n\t%8 = call i1 @llvm.coro.end(i8* %3, i1 false)\n\tret i8* %3",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("unable to locate %q in function:\n%s", want, s)
+		}
+	}
+
+	// Remove the destroy case of the suspend switch.
+	for _, block := range f.Blocks {
+		if term, ok := block.Term.(*llir.TermSwitch); ok {
+			term.Cases = term.Cases[:1]
+		}
+	}
+	if err := VerifyCoroutine(f); err == nil {
+		t.Error("expected error for suspend switch without destroy case")
+	}
+}
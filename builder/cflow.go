@@ -0,0 +1,264 @@
+package builder
+
+import (
+	"fmt"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/types"
+	"github.com/wa-lang/llir/value"
+)
+
+// BodyFunc is a function which builds the body of a structured control flow
+// construct, returning the values flowing out of the body. The returned values
+// are ignored if the insertion point is unreachable after the body has been
+// built (e.g. after Ret, Break or Continue).
+type BodyFunc func(b *Builder) []value.Value
+
+// LoopFunc is a function which builds the body of a loop, given the values
+// carried across loop iterations, returning the values of the next iteration.
+type LoopFunc func(b *Builder, vals []value.Value) []value.Value
+
+// CondFunc is a function which builds the condition of a loop, given the values
+// carried across loop iterations, returning the boolean loop condition.
+type CondFunc func(b *Builder, vals []value.Value) value.Value
+
+// loop is a loop context of the builder, tracking the targets of Break and
+// Continue.
+type loop struct {
+	// Join point of the loop exit.
+	brk *join
+	// Join point of the next loop iteration.
+	cont *join
+}
+
+// --- [ if ] ------------------------------------------------------------------
+
+// If builds an if/else construct based on the given boolean condition. The
+// values returned by the then and else bodies are merged at the end of the
+// construct (using phi instructions if the values differ) and returned.
+//
+// The else body is optional; if nil, the then body must return no values.
+//
+// The insertion point is set to the end of the construct, or unreachable if no
+// branch falls through.
+func (b *Builder) If(cond value.Value, then, els BodyFunc) []value.Value {
+	cur := b.current()
+	thenBlock := b.NewBlock("if.then")
+	var elseBlock *llir.Block
+	if els != nil {
+		elseBlock = b.NewBlock("if.else")
+	}
+	endBlock := b.NewBlock("if.end")
+	end := newJoin(endBlock)
+	if els != nil {
+		cur.NewCondBr(cond, thenBlock, elseBlock)
+	} else {
+		cur.NewCondBr(cond, thenBlock, endBlock)
+		end.addEdge(cur, nil)
+	}
+	b.body(thenBlock, then, end)
+	if els != nil {
+		b.body(elseBlock, els, end)
+	}
+	return b.seal(end)
+}
+
+// --- [ loops ] ---------------------------------------------------------------
+
+// While builds a while loop with the given initial values carried across loop
+// iterations. The condition is evaluated at the start of each iteration, and
+// the body returns the values of the next iteration. A nil condition denotes
+// an infinite loop, which may only be exited using Break or Ret.
+//
+// The values carried across loop iterations are merged using phi instructions
+// at the loop header; trivial phi instructions are removed once the loop is
+// complete. Values at loop exit (as seen by the condition, or as passed to
+// Break) are returned.
+func (b *Builder) While(vars []value.Value, cond CondFunc, body LoopFunc) []value.Value {
+	return b.loop("while", vars, cond, nil, body)
+}
+
+// For builds a for loop with the given initial values carried across loop
+// iterations. The condition is evaluated at the start of each iteration, the
+// body returns the values passed to post, and post returns the values of the
+// next iteration. A nil condition denotes an infinite loop, and a nil post
+// passes values through unchanged.
+//
+// Continue branches to post, in contrast to While where Continue branches to
+// the loop header. Values at loop exit are returned.
+func (b *Builder) For(vars []value.Value, cond CondFunc, post, body LoopFunc) []value.Value {
+	if post == nil {
+		post = func(b *Builder, vals []value.Value) []value.Value {
+			return vals
+		}
+	}
+	return b.loop("for", vars, cond, post, body)
+}
+
+// Break branches to the exit of the innermost loop, passing the given values
+// as values at loop exit. The insertion point becomes unreachable.
+func (b *Builder) Break(vals ...value.Value) {
+	l := b.innermost("break")
+	b.jump(l.brk, vals)
+}
+
+// Continue branches to the next iteration of the innermost loop, passing the
+// given values as values of the next iteration. The insertion point becomes
+// unreachable.
+func (b *Builder) Continue(vals ...value.Value) {
+	l := b.innermost("continue")
+	b.jump(l.cont, vals)
+}
+
+// loop builds a loop with the given label name prefix.
+func (b *Builder) loop(prefix string, vars []value.Value, cond CondFunc, post, body LoopFunc) []value.Value {
+	cur := b.current()
+	condBlock := b.NewBlock(prefix + ".cond")
+	bodyBlock := b.NewBlock(prefix + ".body")
+	var postBlock *llir.Block
+	if post != nil {
+		postBlock = b.NewBlock(prefix + ".inc")
+	}
+	endBlock := b.NewBlock(prefix + ".end")
+	// Loop header.
+	cur.NewBr(condBlock)
+	ts := make([]types.Type, len(vars))
+	for i, v := range vars {
+		ts[i] = v.Type()
+	}
+	header := newLoopJoin(condBlock, ts)
+	header.addEdge(cur, vars)
+	b.startBlock(condBlock)
+	vals := header.values()
+	l := &loop{brk: newJoin(endBlock), cont: header}
+	if cond != nil {
+		c := cond(b, vals)
+		condExit := b.current()
+		condExit.NewCondBr(c, bodyBlock, endBlock)
+		l.brk.addEdge(condExit, vals)
+	} else {
+		b.current().NewBr(bodyBlock)
+	}
+	// Loop body.
+	if post != nil {
+		l.cont = newJoin(postBlock)
+	}
+	b.loops = append(b.loops, l)
+	b.startBlock(bodyBlock)
+	next := body(b, vals)
+	if b.Reachable() {
+		b.jump(l.cont, next)
+	}
+	b.loops = b.loops[:len(b.loops)-1]
+	// Loop latch.
+	if post != nil {
+		if postVals, ok := l.cont.seal(b.repl); ok {
+			b.startBlock(postBlock)
+			next := post(b, postVals)
+			if b.Reachable() {
+				b.jump(header, next)
+			}
+		}
+	}
+	header.sealLoop(b.Func, b.repl)
+	return b.seal(l.brk)
+}
+
+// innermost returns the innermost loop of the builder. innermost panics if the
+// builder is not within a loop.
+func (b *Builder) innermost(stmt string) *loop {
+	if len(b.loops) == 0 {
+		panic(fmt.Errorf("%s outside of loop in function %q", stmt, b.Func.Ident()))
+	}
+	return b.loops[len(b.loops)-1]
+}
+
+// --- [ switch ] --------------------------------------------------------------
+
+// Case is a switch case of the builder.
+type Case struct {
+	// Case comparand.
+	X constant.Constant
+	// Case body.
+	Body BodyFunc
+}
+
+// NewCase returns a new switch case based on the given case comparand and
+// body.
+func NewCase(x constant.Constant, body BodyFunc) *Case {
+	return &Case{X: x, Body: body}
+}
+
+// Switch builds a switch construct based on the given control variable. Each
+// case body is executed when the control variable matches its comparand, and
+// the default body otherwise; there is no implicit fallthrough between cases.
+// The values returned by the bodies are merged at the end of the construct and
+// returned.
+//
+// The default body is optional; if nil, the case bodies must return no values.
+func (b *Builder) Switch(x value.Value, cases []*Case, dflt BodyFunc) []value.Value {
+	cur := b.current()
+	caseBlocks := make([]*llir.Block, len(cases))
+	for i := range cases {
+		caseBlocks[i] = b.NewBlock("sw.bb")
+	}
+	var dfltBlock *llir.Block
+	if dflt != nil {
+		dfltBlock = b.NewBlock("sw.default")
+	}
+	endBlock := b.NewBlock("sw.epilog")
+	end := newJoin(endBlock)
+	var termCases []*llir.Case
+	for i, c := range cases {
+		termCases = append(termCases, llir.NewCase(c.X, caseBlocks[i]))
+	}
+	if dflt != nil {
+		cur.NewSwitch(x, dfltBlock, termCases...)
+	} else {
+		cur.NewSwitch(x, endBlock, termCases...)
+		end.addEdge(cur, nil)
+	}
+	for i, c := range cases {
+		b.body(caseBlocks[i], c.Body, end)
+	}
+	if dflt != nil {
+		b.body(dfltBlock, dflt, end)
+	}
+	return b.seal(end)
+}
+
+// ### [ Helper functions ] ####################################################
+
+// body builds the given body starting at the given basic block, branching to
+// the join point if the end of the body is reachable.
+func (b *Builder) body(block *llir.Block, body BodyFunc, j *join) {
+	b.startBlock(block)
+	vals := body(b)
+	if b.Reachable() {
+		b.jump(j, vals)
+	}
+}
+
+// jump terminates the current basic block with an unconditional branch to the
+// join point, passing the given values. The insertion point becomes
+// unreachable.
+func (b *Builder) jump(j *join, vals []value.Value) {
+	cur := b.current()
+	cur.NewBr(j.block)
+	j.addEdge(cur, vals)
+	b.Block = nil
+}
+
+// seal completes the given join point, setting the insertion point to the join
+// basic block and returning the merged values. The insertion point becomes
+// unreachable if the join point has no incoming edges.
+func (b *Builder) seal(j *join) []value.Value {
+	vals, ok := j.seal(b.repl)
+	if !ok {
+		b.Block = nil
+		return nil
+	}
+	b.startBlock(j.block)
+	return vals
+}
@@ -0,0 +1,232 @@
+package builder
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/intrinsics"
+	"github.com/wa-lang/llir/types"
+	"github.com/wa-lang/llir/value"
+)
+
+// Coroutine is a switched-resume coroutine of the builder.
+//
+// The coroutine skeleton consists of the coroutine ramp (llvm.coro.id,
+// llvm.coro.size, frame allocation and llvm.coro.begin), suspend points
+// (llvm.coro.suspend followed by a switch terminator), the cleanup basic
+// block (llvm.coro.free and frame deallocation), and the suspend basic block
+// (llvm.coro.end and return of the coroutine handle).
+//
+// ref: https://llvm.org/docs/Coroutines.html#switched-resume-lowering
+type Coroutine struct {
+	// Coroutine identity; llvm.coro.id call.
+	ID *llir.InstCall
+	// Coroutine handle; llvm.coro.begin call.
+	Handle *llir.InstCall
+	// Cleanup basic block; reached when the coroutine is destroyed.
+	Cleanup *llir.Block
+	// Suspend basic block; returns the coroutine handle to the caller.
+	Suspend *llir.Block
+
+	// Function which deallocates the coroutine frame.
+	free func(b *Builder, mem value.Value)
+}
+
+// BeginCoroutine builds the ramp of a switched-resume coroutine at the
+// insertion point, and marks the function with the presplitcoroutine
+// attribute. The coroutine frame is allocated using alloc, given the frame
+// size in bytes (i64), and deallocated using free on destruction.
+//
+// The function must have an i8* return type, as the coroutine handle is
+// returned to the caller on suspension.
+func (b *Builder) BeginCoroutine(alloc func(b *Builder, size value.Value) value.Value, free func(b *Builder, mem value.Value)) *Coroutine {
+	if b.coro != nil {
+		panic(fmt.Errorf("coroutine of function %q already begun", b.Func.Ident()))
+	}
+	if !types.Equal(b.Func.Sig.RetType, types.I8Ptr) {
+		panic(fmt.Errorf("invalid return type of coroutine %q; expected %v, got %v", b.Func.Ident(), types.I8Ptr, b.Func.Sig.RetType))
+	}
+	if !hasFuncAttr(b.Func, enum.FuncAttrPreSplitCoroutine) {
+		b.Func.FuncAttrs = append(b.Func.FuncAttrs, enum.FuncAttrPreSplitCoroutine)
+	}
+	co := &Coroutine{
+		Cleanup: b.NewBlock("coro.cleanup"),
+		Suspend: b.NewBlock("coro.suspend"),
+		free:    free,
+	}
+	co.ID = intrinsics.CoroID(b.current(), 0, nil)
+	size := intrinsics.CoroSize(b.Block, types.I64)
+	mem := alloc(b, size)
+	co.Handle = intrinsics.CoroBegin(b.current(), co.ID, mem)
+	b.coro = co
+	return co
+}
+
+// Suspend builds a suspend point of the coroutine. For non-final suspend
+// points, the insertion point is moved to the basic block executed on resume.
+// Final suspend points may not be resumed, and the insertion point becomes
+// unreachable.
+func (b *Builder) Suspend(final bool) {
+	co := b.coroutine()
+	res := intrinsics.CoroSuspend(b.current(), nil, final)
+	name := "coro.resume"
+	if final {
+		name = "coro.final"
+	}
+	resume := b.NewBlock(name)
+	b.NewSwitch(res, co.Suspend,
+		llir.NewCase(constant.NewInt(types.I8, 0), resume),
+		llir.NewCase(constant.NewInt(types.I8, 1), co.Cleanup),
+	)
+	b.startBlock(resume)
+	if final {
+		// Resuming a coroutine suspended at its final suspend point is
+		// undefined behaviour.
+		b.Unreachable()
+	}
+}
+
+// EndCoroutine completes the coroutine, building its cleanup and suspend basic
+// blocks. If the insertion point is reachable, a final suspend point is built
+// first.
+func (b *Builder) EndCoroutine() {
+	co := b.coroutine()
+	if b.Reachable() {
+		b.Suspend(true)
+	}
+	b.startBlock(co.Cleanup)
+	mem := intrinsics.CoroFree(b.Block, co.ID, co.Handle)
+	co.free(b, mem)
+	b.current().NewBr(co.Suspend)
+	b.startBlock(co.Suspend)
+	intrinsics.CoroEnd(b.Block, co.Handle, false)
+	b.Ret(co.Handle)
+	b.coro = nil
+}
+
+// coroutine returns the coroutine of the builder. coroutine panics if the
+// coroutine has not been begun.
+func (b *Builder) coroutine() *Coroutine {
+	if b.coro == nil {
+		panic(fmt.Errorf("coroutine of function %q not yet begun", b.Func.Ident()))
+	}
+	return b.coro
+}
+
+// VerifyCoroutine verifies the structure of the given switched-resume
+// coroutine; i.e. that the function has the presplitcoroutine attribute, a
+// single llvm.coro.id and llvm.coro.begin in its entry basic block, that each
+// llvm.coro.suspend is followed by a switch terminator with resume (0) and
+// destroy (1) cases, and that llvm.coro.free and llvm.coro.end refer to the
+// coroutine.
+func VerifyCoroutine(f *llir.Func) error {
+	if !hasFuncAttr(f, enum.FuncAttrPreSplitCoroutine) {
+		return errors.Errorf("missing presplitcoroutine attribute of coroutine %q", f.Ident())
+	}
+	if len(f.Blocks) == 0 {
+		return errors.Errorf("missing body of coroutine %q", f.Ident())
+	}
+	var id, hdl *llir.InstCall
+	ends := 0
+	for _, block := range f.Blocks {
+		for i, inst := range block.Insts {
+			call, ok := inst.(*llir.InstCall)
+			if !ok {
+				continue
+			}
+			switch calleeName(call) {
+			case "llvm.coro.id":
+				if id != nil {
+					return errors.Errorf("multiple llvm.coro.id calls in coroutine %q", f.Ident())
+				}
+				if block != f.Blocks[0] {
+					return errors.Errorf("llvm.coro.id call in basic block %q of coroutine %q; expected entry basic block", block.Ident(), f.Ident())
+				}
+				id = call
+			case "llvm.coro.begin":
+				if hdl != nil {
+					return errors.Errorf("multiple llvm.coro.begin calls in coroutine %q", f.Ident())
+				}
+				if id == nil || call.Args[0] != value.Value(id) {
+					return errors.Errorf("llvm.coro.begin call in basic block %q of coroutine %q does not refer to llvm.coro.id", block.Ident(), f.Ident())
+				}
+				hdl = call
+			case "llvm.coro.suspend":
+				if err := verifySuspend(f, block, i, call); err != nil {
+					return err
+				}
+			case "llvm.coro.free":
+				if id == nil || call.Args[0] != value.Value(id) {
+					return errors.Errorf("llvm.coro.free call in basic block %q of coroutine %q does not refer to llvm.coro.id", block.Ident(), f.Ident())
+				}
+			case "llvm.coro.end":
+				if hdl == nil || call.Args[0] != value.Value(hdl) {
+					return errors.Errorf("llvm.coro.end call in basic block %q of coroutine %q does not refer to llvm.coro.begin", block.Ident(), f.Ident())
+				}
+				ends++
+			}
+		}
+	}
+	switch {
+	case id == nil:
+		return errors.Errorf("missing llvm.coro.id call in coroutine %q", f.Ident())
+	case hdl == nil:
+		return errors.Errorf("missing llvm.coro.begin call in coroutine %q", f.Ident())
+	case ends == 0:
+		return errors.Errorf("missing llvm.coro.end call in coroutine %q", f.Ident())
+	}
+	return nil
+}
+
+// verifySuspend verifies the suspend point of the given llvm.coro.suspend
+// call, the i-th instruction of block.
+func verifySuspend(f *llir.Func, block *llir.Block, i int, call *llir.InstCall) error {
+	if i != len(block.Insts)-1 {
+		return errors.Errorf("llvm.coro.suspend call in basic block %q of coroutine %q not followed by terminator", block.Ident(), f.Ident())
+	}
+	term, ok := block.Term.(*llir.TermSwitch)
+	if !ok || term.X != value.Value(call) {
+		return errors.Errorf("llvm.coro.suspend call in basic block %q of coroutine %q not followed by switch on its result", block.Ident(), f.Ident())
+	}
+	var resume, destroy bool
+	for _, c := range term.Cases {
+		x, ok := c.X.(*constant.Int)
+		if !ok {
+			continue
+		}
+		switch x.X.Int64() {
+		case 0:
+			resume = true
+		case 1:
+			destroy = true
+		default:
+			return errors.Errorf("invalid case %v of suspend switch in basic block %q of coroutine %q; expected 0 (resume) or 1 (destroy)", x.X, block.Ident(), f.Ident())
+		}
+	}
+	if !resume || !destroy {
+		return errors.Errorf("missing resume or destroy case of suspend switch in basic block %q of coroutine %q", block.Ident(), f.Ident())
+	}
+	return nil
+}
+
+// calleeName returns the name of the function called by the given call
+// instruction; or the empty string if the callee is not a function.
+func calleeName(call *llir.InstCall) string {
+	if f, ok := call.Callee.(*llir.Func); ok {
+		return f.Name()
+	}
+	return ""
+}
+
+// hasFuncAttr reports whether the given function has the function attribute.
+func hasFuncAttr(f *llir.Func, attr enum.FuncAttr) bool {
+	for _, a := range f.FuncAttrs {
+		if a == attr {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,379 @@
+package builder
+
+import (
+	"fmt"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/intrinsics"
+	"github.com/wa-lang/llir/types"
+	"github.com/wa-lang/llir/value"
+)
+
+// EHModel specifies the exception handling model of a function.
+type EHModel uint8
+
+// Exception handling models.
+const (
+	// No exception handling; personality function not yet set.
+	EHNone EHModel = iota
+	// Itanium C++ ABI exception handling, using landingpad instructions.
+	EHItanium
+	// Windows exception handling, using funclet pads (catchswitch, catchpad and
+	// cleanuppad).
+	EHFunclet
+)
+
+// landingPadType is the result type of landingpad instructions; an exception
+// pointer and a type info selector.
+var landingPadType = types.NewStruct(types.I8Ptr, types.I32)
+
+// SetPersonality sets the personality function of the function being built,
+// and the exception handling model used by Try and Cleanup.
+func (b *Builder) SetPersonality(personality constant.Constant, model EHModel) {
+	b.Func.Personality = personality
+	b.eh = model
+}
+
+// CatchFunc is a function which builds the body of an exception handler, given
+// the caught exception; the exception pointer (Itanium) or the catchpad token
+// (funclet). The values returned by the body flow out of the protected region.
+type CatchFunc func(b *Builder, exn value.Value) []value.Value
+
+// Catch is a typed catch clause of a protected region.
+type Catch struct {
+	// Type info of caught exceptions (Itanium); or nil to catch all exceptions.
+	TypeInfo constant.Constant
+	// Exception arguments of the catchpad (funclet); e.g. type descriptor,
+	// adjectives and exception object.
+	Args []value.Value
+	// Exception handler body.
+	Body CatchFunc
+}
+
+// NewCatch returns a new catch clause of the Itanium exception handling model,
+// based on the given type info and exception handler body. A nil type info
+// catches all exceptions.
+func NewCatch(typeInfo constant.Constant, body CatchFunc) *Catch {
+	return &Catch{TypeInfo: typeInfo, Body: body}
+}
+
+// NewFuncletCatch returns a new catch clause of the funclet exception handling
+// model, based on the given exception handler body and catchpad exception
+// arguments.
+func NewFuncletCatch(body CatchFunc, args ...value.Value) *Catch {
+	return &Catch{Args: args, Body: body}
+}
+
+// region is a protected region of the builder; calls within the region unwind
+// to its exception handlers.
+type region struct {
+	// Catch clauses of the region; empty for cleanup regions.
+	catches []*Catch
+	// Cleanup of the region; nil for try regions.
+	cleanup func(b *Builder)
+	// Unwind destination of calls within the region; the landingpad basic block
+	// (Itanium), or the catchswitch or cleanuppad basic block (funclet).
+	unwind *llir.Block
+	// Reports whether any call unwinds to the region.
+	used bool
+	// Join point of exception dispatch, carrying the landingpad value of
+	// exceptions unwinding to the region (Itanium).
+	dispatch *join
+	// Parent exception pad of the region (funclet).
+	parentPad value.Value
+}
+
+// Call appends a call to the given callee. Within a protected region, the call
+// is emitted as an invoke which unwinds to the exception handlers of the
+// region, and the insertion point is moved to the normal return basic block.
+// Within a funclet pad, the call is associated with the pad using a "funclet"
+// operand bundle.
+//
+// The result is either an *llir.InstCall or an *llir.TermInvoke.
+func (b *Builder) Call(callee value.Value, args ...value.Value) value.Value {
+	cur := b.current()
+	var bundles []*llir.OperandBundle
+	if n := len(b.pads); n > 0 && b.eh == EHFunclet {
+		bundles = append(bundles, llir.NewOperandBundle("funclet", b.pads[n-1]))
+	}
+	if unwind := b.unwindTarget(); unwind != nil {
+		cont := b.NewBlock("invoke.cont")
+		term := cur.NewInvoke(callee, args, cont, unwind)
+		term.OperandBundles = bundles
+		b.startBlock(cont)
+		return term
+	}
+	inst := cur.NewCall(callee, args...)
+	inst.OperandBundles = bundles
+	return inst
+}
+
+// Try builds a protected region with the given body and catch clauses. Calls
+// within the body (made using Call) unwind to the catch clauses, which are
+// tried in order; exceptions not caught continue to unwind to the enclosing
+// protected region, or to the caller. The values returned by the body and the
+// exception handler bodies are merged at the end of the region and returned.
+//
+// Exception handlers are only built if a call within the body may unwind.
+func (b *Builder) Try(body BodyFunc, catches ...*Catch) []value.Value {
+	if len(catches) == 0 {
+		panic(fmt.Errorf("missing catch clauses of protected region in function %q", b.Func.Ident()))
+	}
+	r := b.pushRegion(catches, nil)
+	vals := body(b)
+	b.popRegion()
+	end := newJoin(b.NewBlock("try.cont"))
+	if b.Reachable() {
+		b.jump(end, vals)
+	}
+	switch b.eh {
+	case EHItanium:
+		b.landingPad(r, end)
+	case EHFunclet:
+		b.catchSwitch(r, end)
+	}
+	return b.seal(end)
+}
+
+// Cleanup builds a protected region with the given body and cleanup. The
+// cleanup is run when an exception unwinds out of the body, after which the
+// exception continues to unwind to the enclosing protected region, or to the
+// caller. The values returned by the body are returned.
+//
+// The cleanup is not run on normal exit from the body; and is only built if a
+// call within the body may unwind.
+func (b *Builder) Cleanup(body BodyFunc, cleanup func(b *Builder)) []value.Value {
+	r := b.pushRegion(nil, cleanup)
+	vals := body(b)
+	b.popRegion()
+	cont := b.Block
+	switch b.eh {
+	case EHItanium:
+		b.landingPad(r, nil)
+	case EHFunclet:
+		b.cleanupPad(r)
+	}
+	b.Block = cont
+	if cont == nil {
+		return nil
+	}
+	return vals
+}
+
+// ### [ Helper functions ] ####################################################
+
+// pushRegion pushes a new protected region with the given catch clauses or
+// cleanup onto the region stack.
+func (b *Builder) pushRegion(catches []*Catch, cleanup func(b *Builder)) *region {
+	r := &region{catches: catches, cleanup: cleanup}
+	switch b.eh {
+	case EHItanium:
+		r.unwind = b.NewBlock("lpad")
+		name := "catch.dispatch"
+		if cleanup != nil {
+			name = "ehcleanup"
+		}
+		r.dispatch = newJoin(b.NewBlock(name))
+	case EHFunclet:
+		if cleanup != nil {
+			r.unwind = b.NewBlock("ehcleanup")
+		} else {
+			r.unwind = b.NewBlock("catch.dispatch")
+		}
+		r.parentPad = constant.None
+		if n := len(b.pads); n > 0 {
+			r.parentPad = b.pads[n-1]
+		}
+	default:
+		panic(fmt.Errorf("unable to create protected region in function %q; personality function not set", b.Func.Ident()))
+	}
+	b.regions = append(b.regions, r)
+	return r
+}
+
+// popRegion pops the innermost protected region from the region stack.
+func (b *Builder) popRegion() {
+	b.regions = b.regions[:len(b.regions)-1]
+}
+
+// unwindTarget returns the unwind destination of calls at the insertion point;
+// or nil if calls unwind to the caller.
+func (b *Builder) unwindTarget() *llir.Block {
+	n := len(b.regions)
+	if n == 0 {
+		return nil
+	}
+	r := b.regions[n-1]
+	r.used = true
+	return r.unwind
+}
+
+// --- [ Itanium ] -------------------------------------------------------------
+
+// landingPad builds the landingpad and exception dispatch of the given
+// protected region (Itanium). Exception handlers branch to the end join point;
+// nil for cleanup regions.
+func (b *Builder) landingPad(r *region, end *join) {
+	if r.used {
+		b.startBlock(r.unwind)
+		lp := b.NewLandingPad(landingPadType, b.clauses(r)...)
+		lp.Cleanup = b.hasCleanup(r)
+		b.jump(r.dispatch, []value.Value{lp})
+	}
+	vals := b.seal(r.dispatch)
+	if vals == nil {
+		return
+	}
+	lp := vals[0]
+	if r.cleanup != nil {
+		r.cleanup(b)
+		if b.Reachable() {
+			b.resume(lp)
+		}
+		return
+	}
+	exn := b.NewExtractValue(lp, 0)
+	sel := b.NewExtractValue(lp, 1)
+	handlers := make([]*llir.Block, len(r.catches))
+	catchAll := false
+	for i, c := range r.catches {
+		handlers[i] = b.NewBlock("catch")
+		if c.TypeInfo == nil {
+			b.NewBr(handlers[i])
+			catchAll = true
+			break
+		}
+		id := intrinsics.EHTypeIDFor(b.Block, typeInfo(c.TypeInfo))
+		matches := b.NewICmp(enum.IPredEQ, sel, id)
+		next := b.NewBlock("catch.fallthrough")
+		b.NewCondBr(matches, handlers[i], next)
+		b.startBlock(next)
+	}
+	if !catchAll {
+		b.resume(lp)
+	}
+	for i, c := range r.catches {
+		if handlers[i] == nil {
+			break
+		}
+		b.startBlock(handlers[i])
+		vals := c.Body(b, exn)
+		if b.Reachable() {
+			b.jump(end, vals)
+		}
+	}
+	b.Block = nil
+}
+
+// resume continues to unwind the exception of the given landingpad value, to
+// the exception dispatch of the enclosing protected region, or to the caller.
+// The insertion point becomes unreachable.
+func (b *Builder) resume(lp value.Value) {
+	if n := len(b.regions); n > 0 {
+		b.jump(b.regions[n-1].dispatch, []value.Value{lp})
+		return
+	}
+	b.current().NewResume(lp)
+	b.Block = nil
+}
+
+// clauses returns the landingpad clauses of the given protected region; the
+// catch clauses of the region and of each enclosing protected region, as the
+// landingpad must catch any exception caught within the function.
+func (b *Builder) clauses(r *region) []*llir.Clause {
+	var clauses []*llir.Clause
+	seen := make(map[constant.Constant]bool)
+	catchAll := false
+	add := func(r *region) {
+		for _, c := range r.catches {
+			ti := c.TypeInfo
+			if ti == nil {
+				// Catch-all clause.
+				if catchAll {
+					continue
+				}
+				catchAll = true
+				ti = constant.NewNull(types.I8Ptr)
+			} else if seen[ti] {
+				continue
+			}
+			seen[ti] = true
+			clauses = append(clauses, llir.NewClause(enum.ClauseTypeCatch, typeInfo(ti)))
+		}
+	}
+	add(r)
+	for i := len(b.regions) - 1; i >= 0; i-- {
+		add(b.regions[i])
+	}
+	return clauses
+}
+
+// hasCleanup reports whether the given protected region or any enclosing
+// protected region has a cleanup.
+func (b *Builder) hasCleanup(r *region) bool {
+	if r.cleanup != nil {
+		return true
+	}
+	for _, r := range b.regions {
+		if r.cleanup != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// typeInfo returns the given type info as an i8* constant.
+func typeInfo(ti constant.Constant) constant.Constant {
+	if types.Equal(ti.Type(), types.I8Ptr) {
+		return ti
+	}
+	return constant.NewBitCast(ti, types.I8Ptr)
+}
+
+// --- [ Funclet ] -------------------------------------------------------------
+
+// catchSwitch builds the catchswitch and catchpad exception handlers of the
+// given protected region (funclet). Exception handlers return to the end join
+// point.
+func (b *Builder) catchSwitch(r *region, end *join) {
+	if !r.used {
+		return
+	}
+	b.startBlock(r.unwind)
+	handlers := make([]*llir.Block, len(r.catches))
+	for i := range r.catches {
+		handlers[i] = b.NewBlock("catch")
+	}
+	cs := b.NewCatchSwitch(r.parentPad, handlers, b.unwindTarget())
+	for i, c := range r.catches {
+		b.startBlock(handlers[i])
+		pad := b.NewCatchPad(cs, c.Args...)
+		b.pads = append(b.pads, pad)
+		vals := c.Body(b, pad)
+		b.pads = b.pads[:len(b.pads)-1]
+		if b.Reachable() {
+			cur := b.Block
+			cur.NewCatchRet(pad, end.block)
+			end.addEdge(cur, vals)
+		}
+	}
+	b.Block = nil
+}
+
+// cleanupPad builds the cleanuppad of the given protected region (funclet).
+func (b *Builder) cleanupPad(r *region) {
+	if !r.used {
+		return
+	}
+	b.startBlock(r.unwind)
+	pad := b.NewCleanupPad(r.parentPad)
+	b.pads = append(b.pads, pad)
+	r.cleanup(b)
+	b.pads = b.pads[:len(b.pads)-1]
+	if b.Reachable() {
+		b.NewCleanupRet(pad, b.unwindTarget())
+	}
+	b.Block = nil
+}
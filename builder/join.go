@@ -0,0 +1,182 @@
+package builder
+
+import (
+	"fmt"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/llutil"
+	"github.com/wa-lang/llir/types"
+	"github.com/wa-lang/llir/value"
+)
+
+// join is a control flow join point; a basic block with one or more incoming
+// edges, each of which carries a list of values. Values that differ between
+// incoming edges are merged using phi instructions.
+type join struct {
+	// Join basic block.
+	block *llir.Block
+	// Predecessor basic blocks of incoming edges.
+	preds []*llir.Block
+	// Values carried by incoming edges; one list per predecessor.
+	vals [][]value.Value
+	// Phi instructions created eagerly for values carried across loop
+	// iterations; nil for lazy joins.
+	phis []*llir.InstPhi
+}
+
+// newJoin returns a new lazy join point at the given basic block. Phi
+// instructions are created on seal, once all incoming edges are known.
+func newJoin(block *llir.Block) *join {
+	return &join{block: block}
+}
+
+// newLoopJoin returns a new eager join point at the given loop header basic
+// block, with phi instructions for values of the given types. The phi
+// instructions are placed at the start of the basic block, and may be used
+// before all incoming edges are known.
+func newLoopJoin(block *llir.Block, ts []types.Type) *join {
+	j := &join{block: block}
+	for _, t := range ts {
+		phi := &llir.InstPhi{Typ: t}
+		block.Insts = append(block.Insts, phi)
+		j.phis = append(j.phis, phi)
+	}
+	return j
+}
+
+// values returns the values of the join point as seen within its basic block;
+// the eager phi instructions of a loop join.
+func (j *join) values() []value.Value {
+	vals := make([]value.Value, len(j.phis))
+	for i, phi := range j.phis {
+		vals[i] = phi
+	}
+	return vals
+}
+
+// addEdge adds an incoming edge from pred carrying the given values to the join
+// point. The terminator of pred must already branch to the join block.
+func (j *join) addEdge(pred *llir.Block, vals []value.Value) {
+	if j.phis != nil || len(j.preds) > 0 {
+		want := len(j.phis)
+		if len(j.preds) > 0 {
+			want = len(j.vals[0])
+		}
+		if len(vals) != want {
+			panic(fmt.Errorf("mismatch in number of values flowing from basic block %q into %q; expected %d, got %d", pred.Ident(), j.block.Ident(), want, len(vals)))
+		}
+	}
+	if len(j.preds) > 0 {
+		for i, v := range vals {
+			if want := j.vals[0][i].Type(); !v.Type().Equal(want) {
+				panic(fmt.Errorf("mismatch in type of value %d flowing from basic block %q into %q; expected %v, got %v", i, pred.Ident(), j.block.Ident(), want, v.Type()))
+			}
+		}
+	}
+	for i, phi := range j.phis {
+		if !vals[i].Type().Equal(phi.Typ) {
+			panic(fmt.Errorf("mismatch in type of value %d flowing from basic block %q into %q; expected %v, got %v", i, pred.Ident(), j.block.Ident(), phi.Typ, vals[i].Type()))
+		}
+		phi.Incs = append(phi.Incs, llir.NewIncoming(vals[i], pred))
+	}
+	j.preds = append(j.preds, pred)
+	j.vals = append(j.vals, vals)
+}
+
+// seal completes the lazy join point, placing phi instructions at the start of
+// the join basic block for values which differ between incoming edges. The
+// merged values are returned. The boolean result reports whether the join
+// point is reachable; i.e. whether it has at least one incoming edge.
+//
+// Incoming values which refer to removed phi instructions are first substituted
+// using repl.
+func (j *join) seal(repl map[value.Value]value.Value) ([]value.Value, bool) {
+	if len(j.preds) == 0 {
+		return nil, false
+	}
+	for _, vs := range j.vals {
+		resolve(vs, repl)
+	}
+	vals := make([]value.Value, len(j.vals[0]))
+	for i := range vals {
+		vals[i] = j.vals[0][i]
+		same := true
+		for _, vs := range j.vals[1:] {
+			if vs[i] != vals[i] {
+				same = false
+				break
+			}
+		}
+		if same {
+			continue
+		}
+		phi := &llir.InstPhi{Typ: vals[i].Type()}
+		for k, pred := range j.preds {
+			phi.Incs = append(phi.Incs, llir.NewIncoming(j.vals[k][i], pred))
+		}
+		j.block.Insts = append(j.block.Insts, phi)
+		vals[i] = phi
+	}
+	return vals, true
+}
+
+// sealLoop completes the eager join point of a loop header, removing trivial
+// phi instructions; i.e. phi instructions whose incoming values are all the
+// same value, or the phi instruction itself. Uses of trivial phi instructions
+// within f are replaced by the merged value. The replacement of each removed
+// phi instruction is recorded in repl.
+func (j *join) sealLoop(f *llir.Func, repl map[value.Value]value.Value) {
+	for changed := true; changed; {
+		changed = false
+		for _, phi := range j.phis {
+			if _, ok := repl[phi]; ok {
+				continue
+			}
+			v, ok := trivialValue(phi)
+			if !ok {
+				continue
+			}
+			llutil.ReplaceAllUses(f, phi, v)
+			for old, new := range repl {
+				if new == value.Value(phi) {
+					repl[old] = v
+				}
+			}
+			repl[phi] = v
+			llutil.RemoveInst(j.block, phi)
+			changed = true
+		}
+	}
+}
+
+// trivialValue returns the unique value merged by the given phi instruction,
+// ignoring self-references. The boolean result reports whether the phi
+// instruction is trivial.
+func trivialValue(phi *llir.InstPhi) (value.Value, bool) {
+	var v value.Value
+	for _, inc := range phi.Incs {
+		if inc.X == value.Value(phi) || inc.X == v {
+			continue
+		}
+		if v != nil {
+			return nil, false
+		}
+		v = inc.X
+	}
+	if v == nil {
+		// phi only references itself; unreachable.
+		return nil, false
+	}
+	return v, true
+}
+
+// resolve returns the values with replaced (removed) phi instructions
+// substituted by their replacement values.
+func resolve(vals []value.Value, repl map[value.Value]value.Value) []value.Value {
+	for i, v := range vals {
+		if new, ok := repl[v]; ok {
+			vals[i] = new
+		}
+	}
+	return vals
+}
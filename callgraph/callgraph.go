@@ -0,0 +1,323 @@
+// Package callgraph provides call graph analysis of LLVM IR modules.
+//
+// The call graph has a node per function of the module, and two external
+// nodes: the external caller node, which calls every function that may be
+// called from outside of the module (i.e. externally visible and address-taken
+// functions), and the external callee node, which is called by function
+// declarations and indirect calls, representing code outside of the module.
+//
+// Indirect calls are modelled as calls to every address-taken function of the
+// module with the same signature as the call site, and to the external callee
+// node.
+package callgraph
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/llutil"
+	"github.com/wa-lang/llir/types"
+	"github.com/wa-lang/llir/value"
+)
+
+// Graph is the call graph of a module.
+type Graph struct {
+	// Module of the call graph.
+	Module *llir.Module
+	// Function nodes, in module order.
+	Nodes []*Node
+	// External caller node, calling functions which may be called from outside
+	// of the module.
+	ExternalCaller *Node
+	// External callee node, representing code outside of the module.
+	ExternalCallee *Node
+
+	// Node of each function.
+	nodes map[*llir.Func]*Node
+	// Address-taken functions.
+	addrTaken map[*llir.Func]bool
+}
+
+// Node is a node of the call graph.
+type Node struct {
+	// Function of the node; or nil for external nodes.
+	Func *llir.Func
+	// Outgoing call edges, in order of call sites.
+	Calls []*Edge
+	// Callers, without duplicates.
+	Callers []*Node
+
+	// Index of the node in module order; -1 for external nodes.
+	index int
+	// Name of external nodes.
+	name string
+}
+
+// String returns the string representation of the call graph node.
+func (n *Node) String() string {
+	if n.Func == nil {
+		return n.name
+	}
+	return n.Func.Ident()
+}
+
+// Callees returns the callees of the node, without duplicates and in order of
+// first call site.
+func (n *Node) Callees() []*Node {
+	var callees []*Node
+	seen := make(map[*Node]bool)
+	for _, e := range n.Calls {
+		if !seen[e.Callee] {
+			seen[e.Callee] = true
+			callees = append(callees, e.Callee)
+		}
+	}
+	return callees
+}
+
+// Edge is a call edge of the call graph.
+type Edge struct {
+	// Calling node.
+	Caller *Node
+	// Called node.
+	Callee *Node
+	// Call site; *llir.InstCall, *llir.TermInvoke or *llir.TermCallBr, or nil
+	// for calls from the external caller node and to the external callee node
+	// by function declarations.
+	Site interface{}
+	// Indirect call.
+	Indirect bool
+}
+
+// New returns the call graph of the given module.
+func New(m *llir.Module) *Graph {
+	g := &Graph{
+		Module:         m,
+		ExternalCaller: &Node{index: -1, name: "<external caller>"},
+		ExternalCallee: &Node{index: -1, name: "<external callee>"},
+		nodes:          make(map[*llir.Func]*Node),
+		addrTaken:      make(map[*llir.Func]bool),
+	}
+	for i, f := range m.Funcs {
+		n := &Node{Func: f, index: i}
+		g.Nodes = append(g.Nodes, n)
+		g.nodes[f] = n
+	}
+	g.findAddrTaken()
+	for _, n := range g.Nodes {
+		f := n.Func
+		if !isLocal(f.Linkage) || g.addrTaken[f] {
+			g.addEdge(g.ExternalCaller, n, nil, false)
+		}
+		if len(f.Blocks) == 0 {
+			g.addEdge(n, g.ExternalCallee, nil, false)
+			continue
+		}
+		for _, block := range f.Blocks {
+			for _, inst := range block.Insts {
+				if call, ok := inst.(*llir.InstCall); ok {
+					g.addCall(n, call, call.Callee)
+				}
+			}
+			switch term := block.Term.(type) {
+			case *llir.TermInvoke:
+				g.addCall(n, term, term.Invokee)
+			case *llir.TermCallBr:
+				g.addCall(n, term, term.Callee)
+			}
+		}
+	}
+	return g
+}
+
+// Node returns the call graph node of the given function; or nil if not part
+// of the module.
+func (g *Graph) Node(f *llir.Func) *Node {
+	return g.nodes[f]
+}
+
+// AddrTaken reports whether the address of the given function is taken; i.e.
+// whether the function is used other than as the callee of a call site.
+func (g *Graph) AddrTaken(f *llir.Func) bool {
+	return g.addrTaken[f]
+}
+
+// DeadFuncs returns the functions of the module unreachable from the external
+// caller node, in module order. Such functions are never called and may be
+// removed.
+func (g *Graph) DeadFuncs() []*llir.Func {
+	reachable := make(map[*Node]bool)
+	work := []*Node{g.ExternalCaller}
+	for len(work) > 0 {
+		n := work[len(work)-1]
+		work = work[:len(work)-1]
+		for _, e := range n.Calls {
+			if !reachable[e.Callee] {
+				reachable[e.Callee] = true
+				work = append(work, e.Callee)
+			}
+		}
+	}
+	var dead []*llir.Func
+	for _, n := range g.Nodes {
+		if !reachable[n] {
+			dead = append(dead, n.Func)
+		}
+	}
+	return dead
+}
+
+// DOT returns the call graph in Graphviz DOT format. Function nodes are
+// output in module order, each followed by its calls to other functions.
+// Indirect calls are dashed, and the external nodes are omitted.
+func (g *Graph) DOT() string {
+	buf := &strings.Builder{}
+	buf.WriteString("digraph {\n")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(buf, "\t%q\n", n)
+		direct := make(map[*Node]bool)
+		for _, e := range n.Calls {
+			if !e.Indirect {
+				direct[e.Callee] = true
+			}
+		}
+		seen := make(map[*Node]bool)
+		for _, e := range n.Calls {
+			if e.Callee.Func == nil || seen[e.Callee] {
+				continue
+			}
+			if e.Indirect && direct[e.Callee] {
+				// Output direct calls as solid edges.
+				continue
+			}
+			seen[e.Callee] = true
+			if e.Indirect {
+				fmt.Fprintf(buf, "\t%q -> %q [style=dashed]\n", n, e.Callee)
+			} else {
+				fmt.Fprintf(buf, "\t%q -> %q\n", n, e.Callee)
+			}
+		}
+	}
+	buf.WriteString("}")
+	return buf.String()
+}
+
+// addCall adds the call edges of the given call site with the specified
+// callee.
+func (g *Graph) addCall(caller *Node, site interface{}, callee value.Value) {
+	if f, ok := stripPointerCasts(callee).(*llir.Func); ok {
+		if n, ok := g.nodes[f]; ok {
+			g.addEdge(caller, n, site, false)
+			return
+		}
+	}
+	if _, ok := callee.(*llir.InlineAsm); ok {
+		return
+	}
+	// Indirect call.
+	var sig *types.FuncType
+	if t, ok := callee.Type().(*types.PointerType); ok {
+		sig, _ = t.ElemType.(*types.FuncType)
+	}
+	for _, n := range g.Nodes {
+		if g.addrTaken[n.Func] && sig != nil && n.Func.Sig.Equal(sig) {
+			g.addEdge(caller, n, site, true)
+		}
+	}
+	g.addEdge(caller, g.ExternalCallee, site, true)
+}
+
+// addEdge adds a call edge from caller to callee.
+func (g *Graph) addEdge(caller, callee *Node, site interface{}, indirect bool) {
+	e := &Edge{Caller: caller, Callee: callee, Site: site, Indirect: indirect}
+	caller.Calls = append(caller.Calls, e)
+	for _, n := range callee.Callers {
+		if n == caller {
+			return
+		}
+	}
+	callee.Callers = append(callee.Callers, caller)
+}
+
+// findAddrTaken records the functions of the module used other than as the
+// callee of a direct call.
+func (g *Graph) findAddrTaken() {
+	visit := func(v value.Value) {
+		llutil.Walk(v, func(n interface{}) bool {
+			switch n := n.(type) {
+			case *llir.Func:
+				if _, ok := g.nodes[n]; ok {
+					g.addrTaken[n] = true
+				}
+				return false
+			case *llir.Global, *llir.Alias, *llir.IFunc, *llir.Block, *llir.Param, llir.Instruction, llir.Terminator:
+				return false
+			}
+			return true
+		})
+	}
+	for _, global := range g.Module.Globals {
+		if global.Init != nil {
+			visit(global.Init)
+		}
+	}
+	for _, alias := range g.Module.Aliases {
+		visit(alias.Aliasee)
+	}
+	for _, ifunc := range g.Module.IFuncs {
+		visit(ifunc.Resolver)
+	}
+	for _, f := range g.Module.Funcs {
+		for _, block := range f.Blocks {
+			for _, inst := range block.Insts {
+				visitOperands(inst, visit)
+			}
+			if block.Term != nil {
+				visitOperands(block.Term, visit)
+			}
+		}
+	}
+}
+
+// visitOperands invokes visit for each operand of the given instruction or
+// terminator, except for the callee of direct call sites.
+func visitOperands(inst interface {
+	Operands() []*value.Value
+}, visit func(v value.Value)) {
+	ops := llutil.Operands(inst)
+	switch inst.(type) {
+	case *llir.InstCall, *llir.TermInvoke, *llir.TermCallBr:
+		// The callee is the first operand of call sites.
+		if _, ok := stripPointerCasts(*ops[0]).(*llir.Func); ok {
+			ops = ops[1:]
+		}
+	}
+	for _, op := range ops {
+		if *op != nil {
+			visit(*op)
+		}
+	}
+}
+
+// stripPointerCasts returns the given value with bitcast and addrspacecast
+// constant expressions stripped.
+func stripPointerCasts(v value.Value) value.Value {
+	for {
+		switch c := v.(type) {
+		case *constant.ExprBitCast:
+			v = c.From
+		case *constant.ExprAddrSpaceCast:
+			v = c.From
+		default:
+			return v
+		}
+	}
+}
+
+// isLocal reports whether the given linkage is local to the module.
+func isLocal(linkage enum.Linkage) bool {
+	return linkage == enum.LinkagePrivate || linkage == enum.LinkageInternal
+}
@@ -0,0 +1,127 @@
+package callgraph_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/callgraph"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/types"
+)
+
+func Example() {
+	// Create module corresponding to testdata/eval.ll.
+	m := llir.NewModule()
+	format := m.NewGlobalDef("format", constant.NewCharArrayFromString("%08X\n\x00"))
+	format.Immutable = true
+	printf := llir.NewFunc("printf", types.I32, llir.NewParam("", types.I8Ptr))
+	printf.Sig.Variadic = true
+	newBinFunc := func(name string) (*llir.Func, *llir.Block) {
+		a, b := llir.NewParam("a", types.I32), llir.NewParam("b", types.I32)
+		f := m.NewFunc(name, types.I32, a, b)
+		return f, f.NewBlock("")
+	}
+	add, block := newBinFunc("add")
+	block.NewRet(block.NewAdd(add.Params[0], add.Params[1]))
+	sub, block := newBinFunc("sub")
+	block.NewRet(block.NewSub(sub.Params[0], sub.Params[1]))
+	f, block := newBinFunc("f")
+	zero := constant.NewInt(types.I32, 0)
+	block.NewCall(printf, constant.NewGetElementPtr(format.ContentType, format, zero, zero), f.Params[0])
+	block.NewRet(f.Params[0])
+	main := m.NewFunc("main", types.I32)
+	entry := main.NewBlock("")
+	tmp1 := entry.NewCall(add, constant.NewInt(types.I32, -1), constant.NewInt(types.I32, 3))
+	tmp2 := entry.NewCall(sub, constant.NewInt(types.I32, 13), constant.NewInt(types.I32, 5))
+	result := entry.NewCall(f, tmp1, tmp2)
+	entry.NewRet(result)
+	m.Funcs = append(m.Funcs, printf)
+
+	// Produce call graph of module.
+	g := callgraph.New(m)
+	// Output call graph in Graphviz DOT format.
+	fmt.Println(g.DOT())
+
+	// Output:
+	//
+	// digraph {
+	// 	"@add"
+	// 	"@sub"
+	// 	"@f"
+	// 	"@f" -> "@printf"
+	// 	"@main"
+	// 	"@main" -> "@add"
+	// 	"@main" -> "@sub"
+	// 	"@main" -> "@f"
+	// 	"@printf"
+	// }
+}
+
+func TestGraph(t *testing.T) {
+	m := llir.NewModule()
+	// Mutually recursive functions.
+	even := m.NewFunc("even", types.I32, llir.NewParam("x", types.I32))
+	odd := m.NewFunc("odd", types.I32, llir.NewParam("x", types.I32))
+	evenEntry := even.NewBlock("")
+	evenEntry.NewRet(evenEntry.NewCall(odd, even.Params[0]))
+	oddEntry := odd.NewBlock("")
+	oddEntry.NewRet(oddEntry.NewCall(even, odd.Params[0]))
+	even.Linkage = enum.LinkageInternal
+	odd.Linkage = enum.LinkageInternal
+	// Address-taken callback, called indirectly.
+	cb := m.NewFunc("cb", types.I32, llir.NewParam("x", types.I32))
+	cb.Linkage = enum.LinkageInternal
+	cb.NewBlock("").NewRet(cb.Params[0])
+	fp := llir.NewParam("fp", types.NewPointer(cb.Sig))
+	apply := m.NewFunc("apply", types.I32, fp)
+	apply.Linkage = enum.LinkageInternal
+	applyEntry := apply.NewBlock("")
+	applyEntry.NewRet(applyEntry.NewCall(fp, constant.NewInt(types.I32, 1)))
+	// Dead function.
+	dead := m.NewFunc("dead", types.Void)
+	dead.Linkage = enum.LinkageInternal
+	dead.NewBlock("").NewRet(nil)
+	main := m.NewFunc("main", types.I32)
+	entry := main.NewBlock("")
+	entry.NewCall(apply, cb)
+	entry.NewRet(entry.NewCall(even, constant.NewInt(types.I32, 10)))
+
+	g := callgraph.New(m)
+	if !g.AddrTaken(cb) || g.AddrTaken(apply) {
+		t.Errorf("invalid address-taken functions")
+	}
+	if got, want := fmt.Sprint(g.Node(apply).Callees()), "[@cb <external callee>]"; got != want {
+		t.Errorf("callees mismatch of @apply; expected %q, got %q", want, got)
+	}
+	if got, want := fmt.Sprint(g.ExternalCaller.Callees()), "[@cb @main]"; got != want {
+		t.Errorf("callees mismatch of external caller; expected %q, got %q", want, got)
+	}
+	var sccs []string
+	for _, scc := range g.SCCs() {
+		sccs = append(sccs, fmt.Sprintf("%v:%v", scc, callgraph.Recursive(scc)))
+	}
+	if got, want := strings.Join(sccs, " "), "[@even @odd]:true [@cb]:false [@apply]:false [@dead]:false [@main]:false"; got != want {
+		t.Errorf("SCCs mismatch; expected %q, got %q", want, got)
+	}
+	if got := g.DeadFuncs(); len(got) != 1 || got[0] != dead {
+		t.Errorf("dead functions mismatch; expected [%v], got %v", dead.Ident(), got)
+	}
+	if got, want := g.DOT(), `digraph {
+	"@even"
+	"@even" -> "@odd"
+	"@odd"
+	"@odd" -> "@even"
+	"@cb"
+	"@apply"
+	"@apply" -> "@cb" [style=dashed]
+	"@dead"
+	"@main"
+	"@main" -> "@apply"
+	"@main" -> "@even"
+}`; got != want {
+		t.Errorf("DOT mismatch; expected:\n%s\ngot:\n%s", want, got)
+	}
+}
@@ -0,0 +1,95 @@
+package callgraph
+
+import (
+	"sort"
+)
+
+// SCCs returns the strongly connected components of the function nodes of the
+// call graph, computed using Tarjan's algorithm. The components are returned
+// bottom-up; i.e. each component precedes the components calling into it, as
+// required for bottom-up inlining. The nodes of each component are in module
+// order.
+//
+// The external nodes are not part of any component.
+func (g *Graph) SCCs() [][]*Node {
+	t := &tarjan{
+		index:   make(map[*Node]int),
+		lowlink: make(map[*Node]int),
+		onStack: make(map[*Node]bool),
+	}
+	for _, n := range g.Nodes {
+		if _, ok := t.index[n]; !ok {
+			t.visit(n)
+		}
+	}
+	return t.sccs
+}
+
+// Recursive reports whether the given strongly connected component is
+// recursive; i.e. whether it contains multiple nodes or a self call.
+func Recursive(scc []*Node) bool {
+	if len(scc) > 1 {
+		return true
+	}
+	for _, n := range scc {
+		for _, e := range n.Calls {
+			if e.Callee == n {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// tarjan tracks the state of Tarjan's strongly connected components algorithm.
+type tarjan struct {
+	// Depth-first search index of each visited node.
+	index map[*Node]int
+	// Smallest index reachable from each visited node.
+	lowlink map[*Node]int
+	// Stack of nodes of the components being computed.
+	stack   []*Node
+	onStack map[*Node]bool
+	// Strongly connected components found.
+	sccs [][]*Node
+}
+
+// visit visits the given node and its callees.
+func (t *tarjan) visit(n *Node) {
+	t.index[n] = len(t.index)
+	t.lowlink[n] = t.index[n]
+	t.stack = append(t.stack, n)
+	t.onStack[n] = true
+	for _, callee := range n.Callees() {
+		if callee.Func == nil {
+			// Skip external nodes.
+			continue
+		}
+		if _, ok := t.index[callee]; !ok {
+			t.visit(callee)
+			if t.lowlink[callee] < t.lowlink[n] {
+				t.lowlink[n] = t.lowlink[callee]
+			}
+		} else if t.onStack[callee] && t.index[callee] < t.lowlink[n] {
+			t.lowlink[n] = t.index[callee]
+		}
+	}
+	if t.lowlink[n] != t.index[n] {
+		return
+	}
+	// Pop component rooted at n.
+	var scc []*Node
+	for {
+		m := t.stack[len(t.stack)-1]
+		t.stack = t.stack[:len(t.stack)-1]
+		t.onStack[m] = false
+		scc = append(scc, m)
+		if m == n {
+			break
+		}
+	}
+	sort.Slice(scc, func(i, j int) bool {
+		return scc[i].index < scc[j].index
+	})
+	t.sccs = append(t.sccs, scc)
+}
@@ -0,0 +1,167 @@
+// Package cfg provides control flow graph analysis of LLVM IR functions.
+//
+// A Graph is a snapshot of the control flow graph of a function; it is not
+// updated when the function is modified and should be recomputed using New
+// after changes to the control flow (e.g. after SplitCriticalEdges).
+package cfg
+
+import (
+	"github.com/wa-lang/llir"
+)
+
+// Graph is the control flow graph of a function.
+type Graph struct {
+	// Function of the control flow graph.
+	Func *llir.Func
+	// Entry basic block; or nil if the function is a declaration.
+	Entry *llir.Block
+
+	// Successors of each basic block, without duplicates.
+	succs map[*llir.Block][]*llir.Block
+	// Predecessors of each basic block, without duplicates.
+	preds map[*llir.Block][]*llir.Block
+	// Basic blocks reachable from entry, in post-order.
+	post []*llir.Block
+	// Post-order index of each reachable basic block.
+	postIndex map[*llir.Block]int
+	// Classification of each edge between reachable basic blocks.
+	kinds map[Edge]EdgeKind
+	// Edges between reachable basic blocks, in depth-first order.
+	edges []Edge
+}
+
+// New returns the control flow graph of the given function.
+func New(f *llir.Func) *Graph {
+	g := &Graph{
+		Func:      f,
+		succs:     make(map[*llir.Block][]*llir.Block),
+		preds:     make(map[*llir.Block][]*llir.Block),
+		postIndex: make(map[*llir.Block]int),
+		kinds:     make(map[Edge]EdgeKind),
+	}
+	if len(f.Blocks) == 0 {
+		return g
+	}
+	g.Entry = f.Blocks[0]
+	for _, block := range f.Blocks {
+		if block.Term == nil {
+			continue
+		}
+		seen := make(map[*llir.Block]bool)
+		for _, succ := range block.Term.Succs() {
+			if seen[succ] {
+				continue
+			}
+			seen[succ] = true
+			g.succs[block] = append(g.succs[block], succ)
+			g.preds[succ] = append(g.preds[succ], block)
+		}
+	}
+	g.dfs()
+	return g
+}
+
+// Succs returns the successors of the given basic block, without duplicates and
+// in order of first occurrence in the terminator.
+func (g *Graph) Succs(block *llir.Block) []*llir.Block {
+	return g.succs[block]
+}
+
+// Preds returns the predecessors of the given basic block, without duplicates
+// and in function order.
+func (g *Graph) Preds(block *llir.Block) []*llir.Block {
+	return g.preds[block]
+}
+
+// PostOrder returns the basic blocks reachable from entry in post-order.
+func (g *Graph) PostOrder() []*llir.Block {
+	return g.post
+}
+
+// ReversePostOrder returns the basic blocks reachable from entry in reverse
+// post-order.
+func (g *Graph) ReversePostOrder() []*llir.Block {
+	rpo := make([]*llir.Block, len(g.post))
+	for i, block := range g.post {
+		rpo[len(g.post)-1-i] = block
+	}
+	return rpo
+}
+
+// PostIndex returns the post-order index of the given basic block; or -1 if
+// unreachable from entry.
+func (g *Graph) PostIndex(block *llir.Block) int {
+	if i, ok := g.postIndex[block]; ok {
+		return i
+	}
+	return -1
+}
+
+// Reachable reports whether the given basic block is reachable from entry.
+func (g *Graph) Reachable(block *llir.Block) bool {
+	_, ok := g.postIndex[block]
+	return ok
+}
+
+// Unreachable returns the basic blocks unreachable from entry, in function
+// order.
+func (g *Graph) Unreachable() []*llir.Block {
+	var blocks []*llir.Block
+	for _, block := range g.Func.Blocks {
+		if !g.Reachable(block) {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// dfs performs a depth-first search from entry, recording the post-order of
+// basic blocks and classifying edges.
+func (g *Graph) dfs() {
+	// Pre-order index of visited basic blocks.
+	pre := make(map[*llir.Block]int)
+	// Basic blocks on the depth-first search stack.
+	onStack := make(map[*llir.Block]bool)
+	type frame struct {
+		block *llir.Block
+		// Index of the next successor to visit.
+		next int
+	}
+	pre[g.Entry] = 0
+	onStack[g.Entry] = true
+	stack := []*frame{{block: g.Entry}}
+	for len(stack) > 0 {
+		top := stack[len(stack)-1]
+		succs := g.succs[top.block]
+		if top.next == len(succs) {
+			stack = stack[:len(stack)-1]
+			onStack[top.block] = false
+			g.postIndex[top.block] = len(g.post)
+			g.post = append(g.post, top.block)
+			continue
+		}
+		succ := succs[top.next]
+		top.next++
+		e := Edge{From: top.block, To: succ}
+		g.edges = append(g.edges, e)
+		switch {
+		case onStack[succ]:
+			g.kinds[e] = BackEdge
+		case !visited(pre, succ):
+			g.kinds[e] = TreeEdge
+			pre[succ] = len(pre)
+			onStack[succ] = true
+			stack = append(stack, &frame{block: succ})
+		case pre[top.block] < pre[succ]:
+			g.kinds[e] = ForwardEdge
+		default:
+			g.kinds[e] = CrossEdge
+		}
+	}
+}
+
+// visited reports whether the given basic block has been visited.
+func visited(pre map[*llir.Block]int, block *llir.Block) bool {
+	_, ok := pre[block]
+	return ok
+}
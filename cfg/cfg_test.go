@@ -0,0 +1,376 @@
+package cfg
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/types"
+)
+
+// newLoopFunc returns a function with a loop, a forward edge, a cross edge, and
+// an unreachable basic block.
+//
+//    entry -> loop, exit
+//    loop  -> body, exit
+//    body  -> loop, exit
+//    exit  -> ret
+//    dead  -> exit
+func newLoopFunc() *llir.Func {
+	x := llir.NewParam("x", types.I32)
+	f := llir.NewFunc("f", types.I32, x)
+	entry := f.NewBlock("entry")
+	loop := f.NewBlock("loop")
+	body := f.NewBlock("body")
+	exit := f.NewBlock("exit")
+	dead := f.NewBlock("dead")
+	cond := entry.NewICmp(enum.IPredEQ, x, constant.NewInt(types.I32, 0))
+	entry.NewCondBr(cond, loop, exit)
+	i := loop.NewPhi(llir.NewIncoming(x, entry), llir.NewIncoming(x, body))
+	loop.NewCondBr(cond, body, exit)
+	body.NewCondBr(cond, loop, exit)
+	exit.NewPhi(llir.NewIncoming(x, entry), llir.NewIncoming(i, loop), llir.NewIncoming(x, body), llir.NewIncoming(x, dead))
+	exit.NewRet(x)
+	dead.NewBr(exit)
+	return f
+}
+
+func names(blocks []*llir.Block) string {
+	var ss []string
+	for _, block := range blocks {
+		ss = append(ss, block.Name())
+	}
+	return strings.Join(ss, " ")
+}
+
+func TestGraph(t *testing.T) {
+	f := newLoopFunc()
+	entry, loop, body, exit, dead := f.Blocks[0], f.Blocks[1], f.Blocks[2], f.Blocks[3], f.Blocks[4]
+	g := New(f)
+	if got, want := names(g.Preds(exit)), "entry loop body dead"; got != want {
+		t.Errorf("preds mismatch; expected %q, got %q", want, got)
+	}
+	if got, want := names(g.PostOrder()), "exit body loop entry"; got != want {
+		t.Errorf("post-order mismatch; expected %q, got %q", want, got)
+	}
+	if got, want := names(g.ReversePostOrder()), "entry loop body exit"; got != want {
+		t.Errorf("reverse post-order mismatch; expected %q, got %q", want, got)
+	}
+	if got, want := names(g.Unreachable()), "dead"; got != want {
+		t.Errorf("unreachable mismatch; expected %q, got %q", want, got)
+	}
+	golden := []struct {
+		from, to *llir.Block
+		want     EdgeKind
+	}{
+		{from: entry, to: loop, want: TreeEdge},
+		{from: loop, to: body, want: TreeEdge},
+		{from: body, to: loop, want: BackEdge},
+		{from: body, to: exit, want: TreeEdge},
+		{from: loop, to: exit, want: ForwardEdge},
+		{from: entry, to: exit, want: ForwardEdge},
+		{from: dead, to: exit, want: NoEdge},
+	}
+	for _, g2 := range golden {
+		if got := g.EdgeKind(g2.from, g2.to); got != g2.want {
+			t.Errorf("edge kind mismatch of %s -> %s; expected %v, got %v", g2.from.Name(), g2.to.Name(), g2.want, got)
+		}
+	}
+	var edges []string
+	for _, e := range g.CriticalEdges() {
+		edges = append(edges, fmt.Sprintf("%s->%s", e.From.Name(), e.To.Name()))
+	}
+	if got, want := strings.Join(edges, " "), "entry->loop entry->exit loop->exit body->loop body->exit"; got != want {
+		t.Errorf("critical edges mismatch; expected %q, got %q", want, got)
+	}
+}
+
+func TestCrossEdge(t *testing.T) {
+	f := llir.NewFunc("f", types.Void)
+	entry := f.NewBlock("entry")
+	a := f.NewBlock("a")
+	b := f.NewBlock("b")
+	cond := constant.True
+	entry.NewCondBr(cond, a, b)
+	a.NewRet(nil)
+	b.NewBr(a)
+	g := New(f)
+	if got := g.EdgeKind(b, a); got != CrossEdge {
+		t.Errorf("edge kind mismatch of b -> a; expected %v, got %v", CrossEdge, got)
+	}
+}
+
+func TestSplitCriticalEdges(t *testing.T) {
+	f := newLoopFunc()
+	blocks := SplitCriticalEdges(f)
+	if got, want := names(blocks), "entry.loop_crit_edge entry.exit_crit_edge loop.exit_crit_edge body.loop_crit_edge body.exit_crit_edge"; got != want {
+		t.Errorf("split blocks mismatch; expected %q, got %q", want, got)
+	}
+	g := New(f)
+	if edges := g.CriticalEdges(); len(edges) != 0 {
+		t.Errorf("unexpected critical edges after split; %v", edges)
+	}
+	want := `
+define i32 @f(i32 %x) {
+entry:
+	%0 = icmp eq i32 %x, 0
+	br i1 %0, label %entry.loop_crit_edge, label %entry.exit_crit_edge
+
+entry.exit_crit_edge:
+	br label %exit
+
+entry.loop_crit_edge:
+	br label %loop
+
+loop:
+	%1 = phi i32 [ %x, %entry.loop_crit_edge ], [ %x, %body.loop_crit_edge ]
+	br i1 %0, label %body, label %loop.exit_crit_edge
+
+loop.exit_crit_edge:
+	br label %exit
+
+body:
+	br i1 %0, label %body.loop_crit_edge, label %body.exit_crit_edge
+
+body.exit_crit_edge:
+	br label %exit
+
+body.loop_crit_edge:
+	br label %loop
+
+exit:
+	%2 = phi i32 [ %x, %entry.exit_crit_edge ], [ %1, %loop.exit_crit_edge ], [ %x, %body.exit_crit_edge ], [ %x, %dead ]
+	ret i32 %x
+
+dead:
+	br label %exit
+}`
+	if got := f.LLString(); strings.TrimSpace(got) != strings.TrimSpace(want) {
+		t.Errorf("function mismatch; expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+// TestSplitEdgeSwitch tests splitting the edges of a switch with multiple cases
+// to the same target.
+func TestSplitEdgeSwitch(t *testing.T) {
+	x := llir.NewParam("x", types.I32)
+	f := llir.NewFunc("f", types.I32, x)
+	entry := f.NewBlock("entry")
+	exit := f.NewBlock("exit")
+	entry.NewSwitch(x, exit, llir.NewCase(constant.NewInt(types.I32, 1), exit), llir.NewCase(constant.NewInt(types.I32, 2), exit))
+	phi := exit.NewPhi(llir.NewIncoming(x, entry), llir.NewIncoming(x, entry), llir.NewIncoming(x, entry))
+	exit.NewRet(phi)
+	SplitEdge(entry, exit)
+	want := `
+define i32 @f(i32 %x) {
+entry:
+	switch i32 %x, label %entry.exit_crit_edge [
+		i32 1, label %entry.exit_crit_edge
+		i32 2, label %entry.exit_crit_edge
+	]
+
+entry.exit_crit_edge:
+	br label %exit
+
+exit:
+	%0 = phi i32 [ %x, %entry.exit_crit_edge ]
+	ret i32 %0
+}`
+	if got := f.LLString(); strings.TrimSpace(got) != strings.TrimSpace(want) {
+		t.Errorf("function mismatch; expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+// newDiamondFunc returns a function with a diamond followed by a loop with two
+// exits.
+//
+//    entry -> a, b
+//    a     -> join
+//    b     -> join
+//    join  -> loop
+//    loop  -> latch, ret1
+//    latch -> loop, ret2
+func newDiamondFunc() *llir.Func {
+	f := llir.NewFunc("f", types.Void)
+	entry := f.NewBlock("entry")
+	a := f.NewBlock("a")
+	b := f.NewBlock("b")
+	join := f.NewBlock("join")
+	loop := f.NewBlock("loop")
+	latch := f.NewBlock("latch")
+	ret1 := f.NewBlock("ret1")
+	ret2 := f.NewBlock("ret2")
+	cond := constant.True
+	entry.NewCondBr(cond, a, b)
+	a.NewBr(join)
+	b.NewBr(join)
+	join.NewBr(loop)
+	loop.NewCondBr(cond, latch, ret1)
+	latch.NewCondBr(cond, loop, ret2)
+	ret1.NewRet(nil)
+	ret2.NewUnreachable()
+	return f
+}
+
+func TestDomTree(t *testing.T) {
+	f := newDiamondFunc()
+	entry, a, b, join, loop, latch, ret1, ret2 := f.Blocks[0], f.Blocks[1], f.Blocks[2], f.Blocks[3], f.Blocks[4], f.Blocks[5], f.Blocks[6], f.Blocks[7]
+	g := New(f)
+	dom := NewDomTree(g)
+	idoms := map[*llir.Block]*llir.Block{entry: nil, a: entry, b: entry, join: entry, loop: join, latch: loop, ret1: loop, ret2: latch}
+	for block, want := range idoms {
+		if got := dom.IDom(block); got != want {
+			t.Errorf("idom mismatch of %s; expected %v, got %v", block.Name(), want, got)
+		}
+	}
+	if !dom.Dominates(join, ret2) || dom.Dominates(a, join) || !dom.Dominates(loop, loop) || dom.StrictlyDominates(loop, loop) {
+		t.Error("invalid dominance query results")
+	}
+	frontiers := map[*llir.Block]string{entry: "", a: "join", b: "join", join: "", loop: "loop", latch: "loop"}
+	for block, want := range frontiers {
+		if got := names(dom.Frontier(block)); got != want {
+			t.Errorf("dominance frontier mismatch of %s; expected %q, got %q", block.Name(), want, got)
+		}
+	}
+	if got, want := names(dom.IteratedFrontier([]*llir.Block{a, latch})), "join loop"; got != want {
+		t.Errorf("iterated dominance frontier mismatch; expected %q, got %q", want, got)
+	}
+
+	pdom := NewPostDomTree(g)
+	if got, want := names(pdom.Roots()), "ret1 ret2"; got != want {
+		t.Errorf("post-dominator tree roots mismatch; expected %q, got %q", want, got)
+	}
+	ipdoms := map[*llir.Block]*llir.Block{entry: join, a: join, b: join, join: loop, loop: nil, latch: nil, ret1: nil, ret2: nil}
+	for block, want := range ipdoms {
+		if got := pdom.IDom(block); got != want {
+			t.Errorf("ipdom mismatch of %s; expected %v, got %v", block.Name(), want, got)
+		}
+	}
+	// Control dependence.
+	cds := map[*llir.Block]string{a: "entry", b: "entry", join: "", loop: "latch", latch: "loop", ret1: "loop", ret2: "latch"}
+	for block, want := range cds {
+		if got := names(pdom.Frontier(block)); got != want {
+			t.Errorf("post-dominance frontier mismatch of %s; expected %q, got %q", block.Name(), want, got)
+		}
+	}
+
+	// Instruction dominance.
+	br, ret := join.Term, ret1.Term
+	if !dom.DominatesInst(br, ret) || dom.DominatesInst(ret, br) || !pdom.DominatesInst(br, a.Term) {
+		t.Error("invalid instruction dominance query results")
+	}
+}
+
+func TestPostDomTreeInfiniteLoop(t *testing.T) {
+	f := llir.NewFunc("f", types.Void)
+	entry := f.NewBlock("entry")
+	loop := f.NewBlock("loop")
+	exit := f.NewBlock("exit")
+	entry.NewCondBr(constant.True, loop, exit)
+	loop.NewBr(loop)
+	exit.NewRet(nil)
+	pdom := NewPostDomTree(New(f))
+	if got, want := names(pdom.Roots()), "exit loop"; got != want {
+		t.Errorf("post-dominator tree roots mismatch; expected %q, got %q", want, got)
+	}
+	if got := pdom.IDom(entry); got != nil {
+		t.Errorf("ipdom mismatch of entry; expected nil, got %v", got)
+	}
+}
+
+func TestLoopForest(t *testing.T) {
+	x := llir.NewParam("x", types.I32)
+	f := llir.NewFunc("f", types.I32, x)
+	entry := f.NewBlock("entry")
+	pre := f.NewBlock("pre")
+	outer := f.NewBlock("outer")
+	inner := f.NewBlock("inner")
+	latch := f.NewBlock("latch")
+	exit := f.NewBlock("exit")
+	cond := entry.NewICmp(enum.IPredEQ, x, constant.NewInt(types.I32, 0))
+	// entry -> pre, outer, exit
+	entry.NewSwitch(x, exit, llir.NewCase(constant.NewInt(types.I32, 1), pre), llir.NewCase(constant.NewInt(types.I32, 2), outer))
+	pre.NewBr(outer)
+	i := outer.NewPhi(llir.NewIncoming(x, entry), llir.NewIncoming(constant.NewInt(types.I32, 1), pre), llir.NewIncoming(x, latch))
+	outer.NewBr(inner)
+	inner.NewCondBr(cond, inner, latch)
+	latch.NewCondBr(cond, outer, exit)
+	r := exit.NewPhi(llir.NewIncoming(x, entry), llir.NewIncoming(i, latch))
+	exit.NewRet(r)
+
+	lf := NewLoopForest(NewDomTree(New(f)))
+	if len(lf.Loops) != 1 {
+		t.Fatalf("expected 1 outermost loop, got %d", len(lf.Loops))
+	}
+	l := lf.Loops[0]
+	if l.Header != outer || names(l.Blocks) != "outer inner latch" || names(l.Latches()) != "latch" {
+		t.Errorf("invalid outer loop; header %s, blocks %q, latches %q", l.Header.Name(), names(l.Blocks), names(l.Latches()))
+	}
+	if got, want := names(l.Exits()), "exit"; got != want {
+		t.Errorf("exits mismatch; expected %q, got %q", want, got)
+	}
+	if got, want := names(l.ExitingBlocks()), "latch"; got != want {
+		t.Errorf("exiting blocks mismatch; expected %q, got %q", want, got)
+	}
+	if l.Preheader() != nil {
+		t.Errorf("unexpected preheader %v", l.Preheader())
+	}
+	if len(l.Children) != 1 || l.Children[0].Header != inner || l.Children[0].Depth != 2 {
+		t.Fatalf("invalid inner loop; %v", l.Children)
+	}
+	if lf.LoopFor(inner) != l.Children[0] || lf.Depth(latch) != 1 || lf.Depth(exit) != 0 {
+		t.Error("invalid loop membership")
+	}
+	if l.Children[0].Preheader() != outer {
+		t.Errorf("preheader mismatch of inner loop; expected %v, got %v", outer, l.Children[0].Preheader())
+	}
+
+	preheader := InsertPreheader(l)
+	exits := InsertDedicatedExits(l)
+	if preheader == nil || len(exits) != 1 {
+		t.Fatalf("expected preheader and one dedicated exit, got %v and %v", preheader, exits)
+	}
+	want := `
+define i32 @f(i32 %x) {
+entry:
+	%0 = icmp eq i32 %x, 0
+	switch i32 %x, label %exit [
+		i32 1, label %pre
+		i32 2, label %outer.preheader
+	]
+
+pre:
+	br label %outer.preheader
+
+outer.preheader:
+	%1 = phi i32 [ %x, %entry ], [ 1, %pre ]
+	br label %outer
+
+outer:
+	%2 = phi i32 [ %x, %latch ], [ %1, %outer.preheader ]
+	br label %inner
+
+inner:
+	br i1 %0, label %inner, label %latch
+
+latch:
+	br i1 %0, label %outer, label %exit.loopexit
+
+exit.loopexit:
+	br label %exit
+
+exit:
+	%3 = phi i32 [ %x, %entry ], [ %2, %exit.loopexit ]
+	ret i32 %3
+}`
+	if got := f.LLString(); strings.TrimSpace(got) != strings.TrimSpace(want) {
+		t.Errorf("function mismatch; expected:\n%s\ngot:\n%s", want, got)
+	}
+	l = NewLoopForest(NewDomTree(New(f))).Loops[0]
+	if l.Preheader() != preheader {
+		t.Errorf("preheader mismatch; expected %v, got %v", preheader, l.Preheader())
+	}
+}
@@ -0,0 +1,397 @@
+package cfg
+
+import (
+	"github.com/wa-lang/llir"
+)
+
+// DomTree is a dominator tree or post-dominator tree of a function.
+//
+// The tree only contains basic blocks reachable from entry. Its root is a
+// virtual node, represented by nil, whose children are the roots of the tree;
+// the entry basic block for dominator trees, and the exit basic blocks for
+// post-dominator trees.
+//
+// Dominator trees are computed using the algorithm of Cooper, Harvey and
+// Kennedy; see "A Simple, Fast Dominance Algorithm".
+type DomTree struct {
+	// Control flow graph of the tree.
+	Graph *Graph
+	// Post-dominator tree.
+	post bool
+	// Roots of the tree.
+	roots []*llir.Block
+	// Immediate dominator of each basic block; nil for roots.
+	idom map[*llir.Block]*llir.Block
+	// Children of each basic block in the tree, and of the virtual root (nil).
+	children map[*llir.Block][]*llir.Block
+	// Pre-order and post-order numbering of the tree, for constant time
+	// dominance queries.
+	in, out map[*llir.Block]int
+	// Dominance frontier of each basic block; computed on first use.
+	frontier map[*llir.Block][]*llir.Block
+	// Basic block and index of each instruction and terminator; computed on
+	// first use.
+	insts map[interface{}]instPos
+}
+
+// instPos is the position of an instruction or terminator.
+type instPos struct {
+	// Parent basic block.
+	block *llir.Block
+	// Index in the basic block; len(block.Insts) for terminators.
+	index int
+}
+
+// NewDomTree returns the dominator tree of the given control flow graph.
+func NewDomTree(g *Graph) *DomTree {
+	var roots []*llir.Block
+	if g.Entry != nil {
+		roots = append(roots, g.Entry)
+	}
+	return newDomTree(g, false, roots, g.Succs, g.Preds)
+}
+
+// NewPostDomTree returns the post-dominator tree of the given control flow
+// graph.
+//
+// The roots of the tree are the exit basic blocks (i.e. basic blocks without
+// successors, such as those terminated by ret or unreachable). Basic blocks
+// from which no exit is reachable (i.e. infinite loops) are rooted at the last
+// such basic block in function order.
+func NewPostDomTree(g *Graph) *DomTree {
+	var exits []*llir.Block
+	for _, block := range g.Func.Blocks {
+		if g.Reachable(block) && len(g.Succs(block)) == 0 {
+			exits = append(exits, block)
+		}
+	}
+	// Predecessors in the reverse control flow graph, restricted to basic
+	// blocks reachable from entry.
+	preds := func(block *llir.Block) []*llir.Block {
+		var preds []*llir.Block
+		for _, pred := range g.Preds(block) {
+			if g.Reachable(pred) {
+				preds = append(preds, pred)
+			}
+		}
+		return preds
+	}
+	return newDomTree(g, true, exits, preds, g.Succs)
+}
+
+// newDomTree returns the dominator tree of the given graph, with the
+// specified roots and successor and predecessor functions.
+func newDomTree(g *Graph, post bool, roots []*llir.Block, succs, preds func(*llir.Block) []*llir.Block) *DomTree {
+	t := &DomTree{
+		Graph:    g,
+		post:     post,
+		idom:     make(map[*llir.Block]*llir.Block),
+		children: make(map[*llir.Block][]*llir.Block),
+		in:       make(map[*llir.Block]int),
+		out:      make(map[*llir.Block]int),
+	}
+	// Post-order numbering of the graph, as visited from the virtual root.
+	order := make(map[*llir.Block]int)
+	var nodes []*llir.Block
+	visit := func(root *llir.Block) {
+		type frame struct {
+			block *llir.Block
+			next  int
+		}
+		order[root] = -1
+		stack := []*frame{{block: root}}
+		for len(stack) > 0 {
+			top := stack[len(stack)-1]
+			ss := succs(top.block)
+			if top.next == len(ss) {
+				stack = stack[:len(stack)-1]
+				order[top.block] = len(nodes)
+				nodes = append(nodes, top.block)
+				continue
+			}
+			succ := ss[top.next]
+			top.next++
+			if _, ok := order[succ]; !ok {
+				order[succ] = -1
+				stack = append(stack, &frame{block: succ})
+			}
+		}
+	}
+	for _, root := range roots {
+		if _, ok := order[root]; !ok {
+			visit(root)
+		}
+	}
+	if post {
+		// Root basic blocks unable to reach an exit.
+		for i := len(g.Func.Blocks) - 1; i >= 0; i-- {
+			block := g.Func.Blocks[i]
+			if _, ok := order[block]; !ok && g.Reachable(block) {
+				roots = append(roots, block)
+				visit(block)
+			}
+		}
+	}
+	t.roots = roots
+	isRoot := make(map[*llir.Block]bool)
+	for _, root := range roots {
+		isRoot[root] = true
+	}
+	// Compute immediate dominators; the virtual root has post-order number
+	// len(nodes) and is represented by -1 in doms.
+	virtual := len(nodes)
+	doms := make([]int, len(nodes)+1)
+	for i := range doms {
+		doms[i] = -1
+	}
+	doms[virtual] = virtual
+	intersect := func(a, b int) int {
+		for a != b {
+			for a < b {
+				a = doms[a]
+			}
+			for b < a {
+				b = doms[b]
+			}
+		}
+		return a
+	}
+	for changed := true; changed; {
+		changed = false
+		// Reverse post-order.
+		for i := len(nodes) - 1; i >= 0; i-- {
+			block := nodes[i]
+			newIDom := -1
+			if isRoot[block] {
+				newIDom = virtual
+			}
+			for _, pred := range preds(block) {
+				p, ok := order[pred]
+				if !ok || doms[p] == -1 {
+					// Skip unprocessed predecessors.
+					continue
+				}
+				if newIDom == -1 {
+					newIDom = p
+				} else {
+					newIDom = intersect(p, newIDom)
+				}
+			}
+			if doms[i] != newIDom {
+				doms[i] = newIDom
+				changed = true
+			}
+		}
+	}
+	// Record tree, with children in function order.
+	for _, block := range g.Func.Blocks {
+		i, ok := order[block]
+		if !ok {
+			continue
+		}
+		var idom *llir.Block
+		if doms[i] != virtual {
+			idom = nodes[doms[i]]
+		}
+		t.idom[block] = idom
+		t.children[idom] = append(t.children[idom], block)
+	}
+	// Number tree nodes.
+	n := 0
+	var number func(block *llir.Block)
+	number = func(block *llir.Block) {
+		t.in[block] = n
+		n++
+		for _, child := range t.children[block] {
+			number(child)
+		}
+		t.out[block] = n
+		n++
+	}
+	for _, root := range t.children[nil] {
+		number(root)
+	}
+	return t
+}
+
+// IsPostDom reports whether the tree is a post-dominator tree.
+func (t *DomTree) IsPostDom() bool {
+	return t.post
+}
+
+// Roots returns the roots of the tree; i.e. the children of the virtual root.
+func (t *DomTree) Roots() []*llir.Block {
+	return t.roots
+}
+
+// Contains reports whether the given basic block is in the tree; i.e. whether
+// it is reachable from entry.
+func (t *DomTree) Contains(block *llir.Block) bool {
+	_, ok := t.in[block]
+	return ok
+}
+
+// IDom returns the immediate (post-)dominator of the given basic block; or nil
+// if the basic block is a root or not in the tree.
+func (t *DomTree) IDom(block *llir.Block) *llir.Block {
+	return t.idom[block]
+}
+
+// Children returns the children of the given basic block in the tree, in
+// function order. The children of nil are the roots of the tree.
+func (t *DomTree) Children(block *llir.Block) []*llir.Block {
+	return t.children[block]
+}
+
+// Dominates reports whether a (post-)dominates b. Every basic block dominates
+// itself. Dominates reports false if either a or b is not in the tree.
+func (t *DomTree) Dominates(a, b *llir.Block) bool {
+	ain, ok := t.in[a]
+	if !ok {
+		return false
+	}
+	bin, ok := t.in[b]
+	if !ok {
+		return false
+	}
+	return ain <= bin && t.out[b] <= t.out[a]
+}
+
+// StrictlyDominates reports whether a (post-)dominates b, and a is not b.
+func (t *DomTree) StrictlyDominates(a, b *llir.Block) bool {
+	return a != b && t.Dominates(a, b)
+}
+
+// DominatesInst reports whether the instruction or terminator a
+// (post-)dominates the instruction or terminator b; i.e. whether every path
+// from entry to b passes through a (or every path from b to an exit passes
+// through a). Every instruction dominates itself. DominatesInst reports false
+// if either a or b is not in a basic block of the tree.
+func (t *DomTree) DominatesInst(a, b interface{}) bool {
+	if t.insts == nil {
+		t.insts = make(map[interface{}]instPos)
+		for _, block := range t.Graph.Func.Blocks {
+			for i, inst := range block.Insts {
+				t.insts[inst] = instPos{block: block, index: i}
+			}
+			if block.Term != nil {
+				t.insts[block.Term] = instPos{block: block, index: len(block.Insts)}
+			}
+		}
+	}
+	apos, ok := t.insts[a]
+	if !ok {
+		return false
+	}
+	bpos, ok := t.insts[b]
+	if !ok {
+		return false
+	}
+	if apos.block != bpos.block {
+		return t.Dominates(apos.block, bpos.block)
+	}
+	if !t.Contains(apos.block) {
+		return false
+	}
+	if t.post {
+		return apos.index >= bpos.index
+	}
+	return apos.index <= bpos.index
+}
+
+// Frontier returns the (post-)dominance frontier of the given basic block, in
+// function order. The post-dominance frontier of a basic block is the set of
+// basic blocks it is control dependent on.
+func (t *DomTree) Frontier(block *llir.Block) []*llir.Block {
+	if t.frontier == nil {
+		t.computeFrontiers()
+	}
+	return t.frontier[block]
+}
+
+// IteratedFrontier returns the iterated (post-)dominance frontier of the given
+// basic blocks, in function order. The iterated dominance frontier of the
+// definition blocks of a variable is the set of basic blocks requiring phi
+// instructions for the variable.
+func (t *DomTree) IteratedFrontier(blocks []*llir.Block) []*llir.Block {
+	in := make(map[*llir.Block]bool)
+	queued := make(map[*llir.Block]bool)
+	var work []*llir.Block
+	for _, block := range blocks {
+		if !queued[block] {
+			queued[block] = true
+			work = append(work, block)
+		}
+	}
+	for len(work) > 0 {
+		block := work[len(work)-1]
+		work = work[:len(work)-1]
+		for _, df := range t.Frontier(block) {
+			if in[df] {
+				continue
+			}
+			in[df] = true
+			if !queued[df] {
+				queued[df] = true
+				work = append(work, df)
+			}
+		}
+	}
+	return t.inFuncOrder(in)
+}
+
+// computeFrontiers computes the (post-)dominance frontier of each basic block.
+func (t *DomTree) computeFrontiers() {
+	sets := make(map[*llir.Block]map[*llir.Block]bool)
+	isRoot := make(map[*llir.Block]bool)
+	for _, root := range t.roots {
+		isRoot[root] = true
+	}
+	for _, block := range t.Graph.Func.Blocks {
+		if !t.Contains(block) {
+			continue
+		}
+		// Predecessors in the (reverse) control flow graph.
+		var preds []*llir.Block
+		if t.post {
+			preds = t.Graph.Succs(block)
+		} else {
+			preds = t.Graph.Preds(block)
+		}
+		n := len(preds)
+		if isRoot[block] {
+			// Edge from virtual root.
+			n++
+		}
+		if n < 2 {
+			continue
+		}
+		idom := t.idom[block]
+		for _, pred := range preds {
+			if !t.Contains(pred) {
+				continue
+			}
+			for runner := pred; runner != nil && runner != idom; runner = t.idom[runner] {
+				if sets[runner] == nil {
+					sets[runner] = make(map[*llir.Block]bool)
+				}
+				sets[runner][block] = true
+			}
+		}
+	}
+	t.frontier = make(map[*llir.Block][]*llir.Block)
+	for block, set := range sets {
+		t.frontier[block] = t.inFuncOrder(set)
+	}
+}
+
+// inFuncOrder returns the given set of basic blocks in function order.
+func (t *DomTree) inFuncOrder(set map[*llir.Block]bool) []*llir.Block {
+	var blocks []*llir.Block
+	for _, block := range t.Graph.Func.Blocks {
+		if set[block] {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
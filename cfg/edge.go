@@ -0,0 +1,178 @@
+package cfg
+
+import (
+	"fmt"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/llutil"
+)
+
+// Edge is a control flow edge between two basic blocks.
+type Edge struct {
+	// Source basic block.
+	From *llir.Block
+	// Target basic block.
+	To *llir.Block
+}
+
+// String returns the string representation of the control flow edge.
+func (e Edge) String() string {
+	return fmt.Sprintf("%s -> %s", e.From.Ident(), e.To.Ident())
+}
+
+// EdgeKind is the classification of a control flow edge by a depth-first
+// search from entry.
+type EdgeKind uint8
+
+// Edge kinds.
+const (
+	// Edge of unreachable basic block.
+	NoEdge EdgeKind = iota
+	// Edge to a basic block first discovered through the edge.
+	TreeEdge
+	// Edge to an ancestor in the depth-first spanning tree (including self
+	// loops).
+	BackEdge
+	// Edge to a proper descendant in the depth-first spanning tree, which is
+	// not a tree edge.
+	ForwardEdge
+	// Edge between basic blocks without ancestor relationship.
+	CrossEdge
+)
+
+// String returns the string representation of the edge kind.
+func (kind EdgeKind) String() string {
+	switch kind {
+	case NoEdge:
+		return "none"
+	case TreeEdge:
+		return "tree"
+	case BackEdge:
+		return "back"
+	case ForwardEdge:
+		return "forward"
+	case CrossEdge:
+		return "cross"
+	}
+	return fmt.Sprintf("EdgeKind(%d)", uint8(kind))
+}
+
+// Edges returns the edges between basic blocks reachable from entry, in
+// depth-first order.
+func (g *Graph) Edges() []Edge {
+	return g.edges
+}
+
+// EdgeKind returns the depth-first classification of the edge from -> to; or
+// NoEdge if not an edge between basic blocks reachable from entry.
+func (g *Graph) EdgeKind(from, to *llir.Block) EdgeKind {
+	return g.kinds[Edge{From: from, To: to}]
+}
+
+// IsCriticalEdge reports whether the edge from -> to is critical, that is
+// whether from has multiple successors and to has multiple predecessors.
+func (g *Graph) IsCriticalEdge(from, to *llir.Block) bool {
+	return len(g.succs[from]) > 1 && len(g.preds[to]) > 1
+}
+
+// CriticalEdges returns the critical edges of the control flow graph, in
+// function order.
+func (g *Graph) CriticalEdges() []Edge {
+	var edges []Edge
+	for _, from := range g.Func.Blocks {
+		for _, to := range g.succs[from] {
+			if g.IsCriticalEdge(from, to) {
+				edges = append(edges, Edge{From: from, To: to})
+			}
+		}
+	}
+	return edges
+}
+
+// SplitCriticalEdges splits the critical edges of the given function, and
+// returns the basic blocks inserted.
+//
+// Edges from basic blocks terminated by indirectbr or callbr are not split, as
+// the block addresses of their targets cannot be rewritten. Neither are edges
+// to exception handling pads, which must be the direct unwind destination of
+// their predecessors.
+func SplitCriticalEdges(f *llir.Func) []*llir.Block {
+	var blocks []*llir.Block
+	for _, e := range New(f).CriticalEdges() {
+		switch e.From.Term.(type) {
+		case *llir.TermIndirectBr, *llir.TermCallBr:
+			continue
+		}
+		if isEHPad(e.To) {
+			continue
+		}
+		blocks = append(blocks, SplitEdge(e.From, e.To))
+	}
+	return blocks
+}
+
+// isEHPad reports whether the given basic block is an exception handling pad.
+func isEHPad(block *llir.Block) bool {
+	if _, ok := block.Term.(*llir.TermCatchSwitch); ok {
+		return true
+	}
+	if len(block.Insts) == 0 {
+		return false
+	}
+	switch block.Insts[0].(type) {
+	case *llir.InstLandingPad, *llir.InstCatchPad, *llir.InstCleanupPad:
+		return true
+	}
+	return false
+}
+
+// SplitEdge splits the edge from -> to by inserting a new basic block directly
+// after from, which branches unconditionally to to. All terminator operands of
+// from referring to to are redirected to the new basic block, and incoming
+// values of phi instructions in to are updated accordingly; as the new basic
+// block has a single edge to to, one incoming value per phi instruction is kept
+// for it. The new basic block is returned.
+func SplitEdge(from, to *llir.Block) *llir.Block {
+	f := from.Parent
+	block := llir.NewBlock("")
+	if !from.IsUnnamed() && !to.IsUnnamed() {
+		block.SetName(llutil.UniqueName(llutil.LocalNames(f), fmt.Sprintf("%s.%s_crit_edge", from.LocalName, to.LocalName)))
+	}
+	block.Parent = f
+	block.NewBr(to)
+	// Insert new basic block after from.
+	for i, b := range f.Blocks {
+		if b == from {
+			f.Blocks = append(f.Blocks[:i+1], append([]*llir.Block{block}, f.Blocks[i+1:]...)...)
+			break
+		}
+	}
+	// Redirect edges.
+	for i, op := range from.Term.Operands() {
+		if *op == to {
+			from.Term.SetOperand(i, block)
+		}
+	}
+	// Update phi instructions.
+	for _, inst := range to.Insts {
+		phi, ok := inst.(*llir.InstPhi)
+		if !ok {
+			break
+		}
+		incs := phi.Incs[:0]
+		found := false
+		for _, inc := range phi.Incs {
+			if inc.Pred == from {
+				if found {
+					// Drop duplicate incoming values of multiple edges from -> to.
+					continue
+				}
+				found = true
+				inc.Pred = block
+			}
+			incs = append(incs, inc)
+		}
+		phi.Incs = incs
+	}
+	return block
+}
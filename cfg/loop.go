@@ -0,0 +1,351 @@
+package cfg
+
+import (
+	"fmt"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/llutil"
+	"github.com/wa-lang/llir/value"
+)
+
+// Loop is a natural loop.
+type Loop struct {
+	// Loop header; the single entry of the loop, dominating all basic blocks of
+	// the loop.
+	Header *llir.Block
+	// Basic blocks of the loop (including the header and the basic blocks of
+	// nested loops), in function order.
+	Blocks []*llir.Block
+	// Enclosing loop; or nil if outermost.
+	Parent *Loop
+	// Immediately nested loops, in function order of their headers.
+	Children []*Loop
+	// Nesting depth; 1 for outermost loops.
+	Depth int
+
+	// Control flow graph of the loop.
+	g *Graph
+	// Basic blocks of the loop.
+	contains map[*llir.Block]bool
+}
+
+// String returns the string representation of the loop.
+func (l *Loop) String() string {
+	return fmt.Sprintf("loop %s (depth %d)", l.Header.Ident(), l.Depth)
+}
+
+// Contains reports whether the given basic block is part of the loop.
+func (l *Loop) Contains(block *llir.Block) bool {
+	return l.contains[block]
+}
+
+// Latches returns the basic blocks of the loop branching back to the header,
+// in function order.
+func (l *Loop) Latches() []*llir.Block {
+	var latches []*llir.Block
+	for _, pred := range l.g.Preds(l.Header) {
+		if l.Contains(pred) {
+			latches = append(latches, pred)
+		}
+	}
+	return latches
+}
+
+// ExitingBlocks returns the basic blocks of the loop with a successor outside
+// of the loop, in function order.
+func (l *Loop) ExitingBlocks() []*llir.Block {
+	var exiting []*llir.Block
+	for _, block := range l.Blocks {
+		for _, succ := range l.g.Succs(block) {
+			if !l.Contains(succ) {
+				exiting = append(exiting, block)
+				break
+			}
+		}
+	}
+	return exiting
+}
+
+// Exits returns the basic blocks outside of the loop with a predecessor in the
+// loop, in function order.
+func (l *Loop) Exits() []*llir.Block {
+	set := make(map[*llir.Block]bool)
+	for _, block := range l.Blocks {
+		for _, succ := range l.g.Succs(block) {
+			if !l.Contains(succ) {
+				set[succ] = true
+			}
+		}
+	}
+	var exits []*llir.Block
+	for _, block := range l.g.Func.Blocks {
+		if set[block] {
+			exits = append(exits, block)
+		}
+	}
+	return exits
+}
+
+// Preheader returns the preheader of the loop; or nil if not present. The
+// preheader is the single predecessor of the header outside of the loop, and
+// has the header as its single successor.
+func (l *Loop) Preheader() *llir.Block {
+	var preheader *llir.Block
+	for _, pred := range l.g.Preds(l.Header) {
+		if l.Contains(pred) {
+			continue
+		}
+		if preheader != nil {
+			return nil
+		}
+		preheader = pred
+	}
+	if preheader == nil || len(l.g.Succs(preheader)) != 1 {
+		return nil
+	}
+	return preheader
+}
+
+// LoopForest is the loop nest forest of a function.
+type LoopForest struct {
+	// Dominator tree of the function.
+	Dom *DomTree
+	// Outermost loops, in function order of their headers.
+	Loops []*Loop
+	// Innermost loop of each basic block.
+	loopOf map[*llir.Block]*Loop
+}
+
+// NewLoopForest returns the loop nest forest of the function of the given
+// dominator tree.
+//
+// Natural loops are identified by back edges whose target dominates their
+// source; back edges to the same header form a single loop. Irreducible cycles
+// (i.e. cycles with multiple entries) are not loops.
+func NewLoopForest(dom *DomTree) *LoopForest {
+	g := dom.Graph
+	lf := &LoopForest{Dom: dom, loopOf: make(map[*llir.Block]*Loop)}
+	var loops []*Loop
+	for _, header := range g.Func.Blocks {
+		var latches []*llir.Block
+		for _, pred := range g.Preds(header) {
+			if dom.Dominates(header, pred) {
+				latches = append(latches, pred)
+			}
+		}
+		if len(latches) == 0 {
+			continue
+		}
+		l := &Loop{Header: header, g: g, contains: map[*llir.Block]bool{header: true}}
+		// Walk backwards from the latches to the header.
+		work := latches
+		for len(work) > 0 {
+			block := work[len(work)-1]
+			work = work[:len(work)-1]
+			if l.contains[block] {
+				continue
+			}
+			l.contains[block] = true
+			for _, pred := range g.Preds(block) {
+				if g.Reachable(pred) && !l.contains[pred] {
+					work = append(work, pred)
+				}
+			}
+		}
+		for _, block := range g.Func.Blocks {
+			if l.contains[block] {
+				l.Blocks = append(l.Blocks, block)
+			}
+		}
+		loops = append(loops, l)
+	}
+	// The parent of a loop is the smallest other loop containing its header.
+	for _, l := range loops {
+		for _, other := range loops {
+			if other == l || !other.Contains(l.Header) {
+				continue
+			}
+			if l.Parent == nil || len(other.Blocks) < len(l.Parent.Blocks) {
+				l.Parent = other
+			}
+		}
+	}
+	for _, l := range loops {
+		if l.Parent == nil {
+			lf.Loops = append(lf.Loops, l)
+		} else {
+			l.Parent.Children = append(l.Parent.Children, l)
+		}
+	}
+	var walk func(ls []*Loop, depth int)
+	walk = func(ls []*Loop, depth int) {
+		for _, l := range ls {
+			l.Depth = depth
+			// Inner loops are visited after outer loops, overriding the innermost
+			// loop of their basic blocks.
+			for _, block := range l.Blocks {
+				lf.loopOf[block] = l
+			}
+			walk(l.Children, depth+1)
+		}
+	}
+	walk(lf.Loops, 1)
+	return lf
+}
+
+// AllLoops returns the loops of the forest in pre-order; i.e. outer loops
+// before inner loops.
+func (lf *LoopForest) AllLoops() []*Loop {
+	var loops []*Loop
+	var walk func(ls []*Loop)
+	walk = func(ls []*Loop) {
+		for _, l := range ls {
+			loops = append(loops, l)
+			walk(l.Children)
+		}
+	}
+	walk(lf.Loops)
+	return loops
+}
+
+// LoopFor returns the innermost loop containing the given basic block; or nil
+// if not part of a loop.
+func (lf *LoopForest) LoopFor(block *llir.Block) *Loop {
+	return lf.loopOf[block]
+}
+
+// Depth returns the loop nesting depth of the given basic block; or 0 if not
+// part of a loop.
+func (lf *LoopForest) Depth(block *llir.Block) int {
+	if l := lf.loopOf[block]; l != nil {
+		return l.Depth
+	}
+	return 0
+}
+
+// InsertPreheader inserts a preheader for the given loop if not already
+// present, and returns the preheader. The predecessors of the header outside of
+// the loop are redirected to the preheader, and the incoming values of phi
+// instructions in the header are merged accordingly. InsertPreheader returns
+// nil if a preheader cannot be inserted; i.e. if the header is an exception
+// handling pad or if a predecessor is terminated by indirectbr or callbr.
+//
+// The control flow graph and analyses of the function are invalidated when a
+// preheader is inserted.
+func InsertPreheader(l *Loop) *llir.Block {
+	if preheader := l.Preheader(); preheader != nil {
+		return preheader
+	}
+	var preds []*llir.Block
+	for _, pred := range l.g.Preds(l.Header) {
+		if !l.Contains(pred) {
+			preds = append(preds, pred)
+		}
+	}
+	if len(preds) == 0 || !canSplitPreds(l.Header, preds) {
+		return nil
+	}
+	return splitPreds(l.Header, preds, ".preheader")
+}
+
+// InsertDedicatedExits ensures that each exit of the given loop only has
+// predecessors inside the loop, by inserting new exit blocks between the loop
+// and exits with predecessors outside of the loop. The inserted exit blocks
+// are returned. Exits which cannot be split (see InsertPreheader) are left
+// unchanged.
+//
+// The control flow graph and analyses of the function are invalidated when an
+// exit block is inserted.
+func InsertDedicatedExits(l *Loop) []*llir.Block {
+	var blocks []*llir.Block
+	for _, exit := range l.Exits() {
+		var inside []*llir.Block
+		dedicated := true
+		for _, pred := range l.g.Preds(exit) {
+			if l.Contains(pred) {
+				inside = append(inside, pred)
+			} else {
+				dedicated = false
+			}
+		}
+		if dedicated || !canSplitPreds(exit, inside) {
+			continue
+		}
+		blocks = append(blocks, splitPreds(exit, inside, ".loopexit"))
+	}
+	return blocks
+}
+
+// canSplitPreds reports whether the edges from the given predecessors to block
+// may be redirected through a new basic block.
+func canSplitPreds(block *llir.Block, preds []*llir.Block) bool {
+	if isEHPad(block) {
+		return false
+	}
+	for _, pred := range preds {
+		switch pred.Term.(type) {
+		case *llir.TermIndirectBr, *llir.TermCallBr:
+			return false
+		}
+	}
+	return true
+}
+
+// splitPreds redirects the edges from the given predecessors to block through a
+// new basic block inserted directly before block, and returns the new basic
+// block. The name of the new basic block is based on the name of block and the
+// given suffix. Incoming values of phi instructions in block from the given
+// predecessors are moved to the new basic block, merged by new phi
+// instructions if they differ.
+func splitPreds(block *llir.Block, preds []*llir.Block, suffix string) *llir.Block {
+	f := block.Parent
+	nb := llir.NewBlock("")
+	if !block.IsUnnamed() {
+		nb.SetName(llutil.UniqueName(llutil.LocalNames(f), block.LocalName+suffix))
+	}
+	nb.Parent = f
+	// Insert new basic block before block.
+	for i, b := range f.Blocks {
+		if b == block {
+			f.Blocks = append(f.Blocks[:i], append([]*llir.Block{nb}, f.Blocks[i:]...)...)
+			break
+		}
+	}
+	isPred := make(map[value.Value]bool)
+	for _, pred := range preds {
+		isPred[pred] = true
+		for i, op := range pred.Term.Operands() {
+			if *op == block {
+				pred.Term.SetOperand(i, nb)
+			}
+		}
+	}
+	// Move incoming values of phi instructions.
+	for _, inst := range block.Insts {
+		phi, ok := inst.(*llir.InstPhi)
+		if !ok {
+			break
+		}
+		var moved, kept []*llir.Incoming
+		for _, inc := range phi.Incs {
+			if isPred[inc.Pred] {
+				moved = append(moved, inc)
+			} else {
+				kept = append(kept, inc)
+			}
+		}
+		if len(moved) == 0 {
+			continue
+		}
+		x := moved[0].X
+		for _, inc := range moved[1:] {
+			if inc.X != x {
+				x = nb.NewPhi(moved...)
+				break
+			}
+		}
+		phi.Incs = append(kept, llir.NewIncoming(x, nb))
+	}
+	nb.NewBr(block)
+	return nb
+}
@@ -1,5 +1,11 @@
 package constant
 
+import (
+	"testing"
+
+	"github.com/wa-lang/llir/types"
+)
+
 // Assert that each constant implements the constant.Constant interface.
 var (
 	// Constant expressions.
@@ -70,3 +76,18 @@ var (
 	_ Expression = (*ExprFCmp)(nil)
 	_ Expression = (*ExprSelect)(nil)
 )
+
+func TestExprOperands(t *testing.T) {
+	x := NewInt(types.I32, 1)
+	y := NewInt(types.I32, 2)
+	z := NewInt(types.I32, 3)
+	e := NewAdd(x, y)
+	ops := e.Operands()
+	if len(ops) != 2 || *ops[0] != x || *ops[1] != y {
+		t.Fatalf("invalid add operands; expected [%v, %v], got %v", x, y, ops)
+	}
+	e.SetOperand(1, z)
+	if want, got := "add (i32 1, i32 3)", e.Ident(); want != got {
+		t.Errorf("operand mismatch; expected %q, got %q", want, got)
+	}
+}
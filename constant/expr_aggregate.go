@@ -60,6 +60,17 @@ func (e *ExprExtractValue) Ident() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprExtractValue) Operands() []*Constant {
+	return []*Constant{&e.X}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprExtractValue) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ insertvalue ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprInsertValue is an LLVM IR insertvalue expression.
@@ -114,6 +125,17 @@ func (e *ExprInsertValue) Ident() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprInsertValue) Operands() []*Constant {
+	return []*Constant{&e.X, &e.Elem}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprInsertValue) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ### [ Helper functions ] ####################################################
 
 // aggregateElemType returns the element type at the position in the aggregate
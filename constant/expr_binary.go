@@ -60,6 +60,17 @@ func (e *ExprAdd) Ident() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprAdd) Operands() []*Constant {
+	return []*Constant{&e.X, &e.Y}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprAdd) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ fadd ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprFAdd is an LLVM IR fadd expression.
@@ -102,6 +113,17 @@ func (e *ExprFAdd) Ident() string {
 	return fmt.Sprintf("fadd (%s, %s)", e.X, e.Y)
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprFAdd) Operands() []*Constant {
+	return []*Constant{&e.X, &e.Y}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprFAdd) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ sub ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprSub is an LLVM IR sub expression.
@@ -152,6 +174,17 @@ func (e *ExprSub) Ident() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprSub) Operands() []*Constant {
+	return []*Constant{&e.X, &e.Y}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprSub) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ fsub ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprFSub is an LLVM IR fsub expression.
@@ -194,6 +227,17 @@ func (e *ExprFSub) Ident() string {
 	return fmt.Sprintf("fsub (%s, %s)", e.X, e.Y)
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprFSub) Operands() []*Constant {
+	return []*Constant{&e.X, &e.Y}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprFSub) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ mul ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprMul is an LLVM IR mul expression.
@@ -244,6 +288,17 @@ func (e *ExprMul) Ident() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprMul) Operands() []*Constant {
+	return []*Constant{&e.X, &e.Y}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprMul) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ fmul ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprFMul is an LLVM IR fmul expression.
@@ -286,6 +341,17 @@ func (e *ExprFMul) Ident() string {
 	return fmt.Sprintf("fmul (%s, %s)", e.X, e.Y)
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprFMul) Operands() []*Constant {
+	return []*Constant{&e.X, &e.Y}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprFMul) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ udiv ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprUDiv is an LLVM IR udiv expression.
@@ -336,6 +402,17 @@ func (e *ExprUDiv) Ident() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprUDiv) Operands() []*Constant {
+	return []*Constant{&e.X, &e.Y}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprUDiv) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ sdiv ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprSDiv is an LLVM IR sdiv expression.
@@ -386,6 +463,17 @@ func (e *ExprSDiv) Ident() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprSDiv) Operands() []*Constant {
+	return []*Constant{&e.X, &e.Y}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprSDiv) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ fdiv ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprFDiv is an LLVM IR fdiv expression.
@@ -428,6 +516,17 @@ func (e *ExprFDiv) Ident() string {
 	return fmt.Sprintf("fdiv (%s, %s)", e.X, e.Y)
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprFDiv) Operands() []*Constant {
+	return []*Constant{&e.X, &e.Y}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprFDiv) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ urem ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprURem is an LLVM IR urem expression.
@@ -470,6 +569,17 @@ func (e *ExprURem) Ident() string {
 	return fmt.Sprintf("urem (%s, %s)", e.X, e.Y)
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprURem) Operands() []*Constant {
+	return []*Constant{&e.X, &e.Y}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprURem) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ srem ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprSRem is an LLVM IR srem expression.
@@ -512,6 +622,17 @@ func (e *ExprSRem) Ident() string {
 	return fmt.Sprintf("srem (%s, %s)", e.X, e.Y)
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprSRem) Operands() []*Constant {
+	return []*Constant{&e.X, &e.Y}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprSRem) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ frem ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprFRem is an LLVM IR frem expression.
@@ -553,3 +674,14 @@ func (e *ExprFRem) Ident() string {
 	// 'frem' '(' X=TypeConst ',' Y=TypeConst ')'
 	return fmt.Sprintf("frem (%s, %s)", e.X, e.Y)
 }
+
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprFRem) Operands() []*Constant {
+	return []*Constant{&e.X, &e.Y}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprFRem) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
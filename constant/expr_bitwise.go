@@ -60,6 +60,17 @@ func (e *ExprShl) Ident() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprShl) Operands() []*Constant {
+	return []*Constant{&e.X, &e.Y}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprShl) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ lshr ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprLShr is an LLVM IR lshr expression.
@@ -111,6 +122,17 @@ func (e *ExprLShr) Ident() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprLShr) Operands() []*Constant {
+	return []*Constant{&e.X, &e.Y}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprLShr) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ ashr ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprAShr is an LLVM IR ashr expression.
@@ -162,6 +184,17 @@ func (e *ExprAShr) Ident() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprAShr) Operands() []*Constant {
+	return []*Constant{&e.X, &e.Y}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprAShr) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ and ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprAnd is an LLVM IR and expression.
@@ -204,6 +237,17 @@ func (e *ExprAnd) Ident() string {
 	return fmt.Sprintf("and (%s, %s)", e.X, e.Y)
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprAnd) Operands() []*Constant {
+	return []*Constant{&e.X, &e.Y}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprAnd) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ or ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprOr is an LLVM IR or expression.
@@ -246,6 +290,17 @@ func (e *ExprOr) Ident() string {
 	return fmt.Sprintf("or (%s, %s)", e.X, e.Y)
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprOr) Operands() []*Constant {
+	return []*Constant{&e.X, &e.Y}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprOr) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ xor ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprXor is an LLVM IR xor expression.
@@ -287,3 +342,14 @@ func (e *ExprXor) Ident() string {
 	// 'xor' '(' X=TypeConst ',' Y=TypeConst ')'
 	return fmt.Sprintf("xor (%s, %s)", e.X, e.Y)
 }
+
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprXor) Operands() []*Constant {
+	return []*Constant{&e.X, &e.Y}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprXor) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
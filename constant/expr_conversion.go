@@ -44,6 +44,17 @@ func (e *ExprTrunc) Ident() string {
 	return fmt.Sprintf("trunc (%s to %s)", e.From, e.To)
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprTrunc) Operands() []*Constant {
+	return []*Constant{&e.From}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprTrunc) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ zext ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprZExt is an LLVM IR zext expression.
@@ -80,6 +91,17 @@ func (e *ExprZExt) Ident() string {
 	return fmt.Sprintf("zext (%s to %s)", e.From, e.To)
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprZExt) Operands() []*Constant {
+	return []*Constant{&e.From}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprZExt) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ sext ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprSExt is an LLVM IR sext expression.
@@ -116,6 +138,17 @@ func (e *ExprSExt) Ident() string {
 	return fmt.Sprintf("sext (%s to %s)", e.From, e.To)
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprSExt) Operands() []*Constant {
+	return []*Constant{&e.From}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprSExt) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ fptrunc ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprFPTrunc is an LLVM IR fptrunc expression.
@@ -152,6 +185,17 @@ func (e *ExprFPTrunc) Ident() string {
 	return fmt.Sprintf("fptrunc (%s to %s)", e.From, e.To)
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprFPTrunc) Operands() []*Constant {
+	return []*Constant{&e.From}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprFPTrunc) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ fpext ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprFPExt is an LLVM IR fpext expression.
@@ -188,6 +232,17 @@ func (e *ExprFPExt) Ident() string {
 	return fmt.Sprintf("fpext (%s to %s)", e.From, e.To)
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprFPExt) Operands() []*Constant {
+	return []*Constant{&e.From}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprFPExt) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ fptoui ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprFPToUI is an LLVM IR fptoui expression.
@@ -224,6 +279,17 @@ func (e *ExprFPToUI) Ident() string {
 	return fmt.Sprintf("fptoui (%s to %s)", e.From, e.To)
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprFPToUI) Operands() []*Constant {
+	return []*Constant{&e.From}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprFPToUI) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ fptosi ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprFPToSI is an LLVM IR fptosi expression.
@@ -260,6 +326,17 @@ func (e *ExprFPToSI) Ident() string {
 	return fmt.Sprintf("fptosi (%s to %s)", e.From, e.To)
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprFPToSI) Operands() []*Constant {
+	return []*Constant{&e.From}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprFPToSI) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ uitofp ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprUIToFP is an LLVM IR uitofp expression.
@@ -296,6 +373,17 @@ func (e *ExprUIToFP) Ident() string {
 	return fmt.Sprintf("uitofp (%s to %s)", e.From, e.To)
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprUIToFP) Operands() []*Constant {
+	return []*Constant{&e.From}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprUIToFP) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ sitofp ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprSIToFP is an LLVM IR sitofp expression.
@@ -332,6 +420,17 @@ func (e *ExprSIToFP) Ident() string {
 	return fmt.Sprintf("sitofp (%s to %s)", e.From, e.To)
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprSIToFP) Operands() []*Constant {
+	return []*Constant{&e.From}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprSIToFP) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ ptrtoint ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprPtrToInt is an LLVM IR ptrtoint expression.
@@ -368,6 +467,17 @@ func (e *ExprPtrToInt) Ident() string {
 	return fmt.Sprintf("ptrtoint (%s to %s)", e.From, e.To)
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprPtrToInt) Operands() []*Constant {
+	return []*Constant{&e.From}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprPtrToInt) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ inttoptr ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprIntToPtr is an LLVM IR inttoptr expression.
@@ -404,6 +514,17 @@ func (e *ExprIntToPtr) Ident() string {
 	return fmt.Sprintf("inttoptr (%s to %s)", e.From, e.To)
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprIntToPtr) Operands() []*Constant {
+	return []*Constant{&e.From}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprIntToPtr) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ bitcast ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprBitCast is an LLVM IR bitcast expression.
@@ -440,6 +561,17 @@ func (e *ExprBitCast) Ident() string {
 	return fmt.Sprintf("bitcast (%s to %s)", e.From, e.To)
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprBitCast) Operands() []*Constant {
+	return []*Constant{&e.From}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprBitCast) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ addrspacecast ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprAddrSpaceCast is an LLVM IR addrspacecast expression.
@@ -475,3 +607,14 @@ func (e *ExprAddrSpaceCast) Ident() string {
 	// 'addrspacecast' '(' From=TypeConst 'to' To=Type ')'
 	return fmt.Sprintf("addrspacecast (%s to %s)", e.From, e.To)
 }
+
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprAddrSpaceCast) Operands() []*Constant {
+	return []*Constant{&e.From}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprAddrSpaceCast) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
@@ -71,6 +71,21 @@ func (e *ExprGetElementPtr) Ident() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprGetElementPtr) Operands() []*Constant {
+	ops := []*Constant{&e.Src}
+	for i := range e.Indices {
+		ops = append(ops, &e.Indices[i])
+	}
+	return ops
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprGetElementPtr) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ___ [ gep indices ] _________________________________________________________
 
 // Index is an index of a getelementptr constant expression.
@@ -61,6 +61,17 @@ func (e *ExprICmp) Ident() string {
 	return fmt.Sprintf("icmp %s (%s, %s)", e.Pred, e.X, e.Y)
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprICmp) Operands() []*Constant {
+	return []*Constant{&e.X, &e.Y}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprICmp) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ fcmp ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprFCmp is an LLVM IR fcmp expression.
@@ -113,6 +124,17 @@ func (e *ExprFCmp) Ident() string {
 	return fmt.Sprintf("fcmp %s (%s, %s)", e.Pred, e.X, e.Y)
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprFCmp) Operands() []*Constant {
+	return []*Constant{&e.X, &e.Y}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprFCmp) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ select ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprSelect is an LLVM IR select expression.
@@ -157,3 +179,14 @@ func (e *ExprSelect) Ident() string {
 	// 'select' '(' Cond=TypeConst ',' X=TypeConst ',' Y=TypeConst ')'
 	return fmt.Sprintf("select (%s, %s, %s)", e.Cond, e.X, e.Y)
 }
+
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprSelect) Operands() []*Constant {
+	return []*Constant{&e.Cond, &e.X, &e.Y}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprSelect) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
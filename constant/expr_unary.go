@@ -49,3 +49,14 @@ func (e *ExprFNeg) Ident() string {
 	// 'fneg' '(' X=TypeConst ')'
 	return fmt.Sprintf("fneg (%s)", e.X)
 }
+
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprFNeg) Operands() []*Constant {
+	return []*Constant{&e.X}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprFNeg) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
@@ -57,6 +57,17 @@ func (e *ExprExtractElement) Ident() string {
 	return fmt.Sprintf("extractelement (%s, %s)", e.X, e.Index)
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprExtractElement) Operands() []*Constant {
+	return []*Constant{&e.X, &e.Index}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprExtractElement) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ insertelement ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprInsertElement is an LLVM IR insertelement expression.
@@ -108,6 +119,17 @@ func (e *ExprInsertElement) Ident() string {
 	return fmt.Sprintf("insertelement (%s, %s, %s)", e.X, e.Elem, e.Index)
 }
 
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprInsertElement) Operands() []*Constant {
+	return []*Constant{&e.X, &e.Elem, &e.Index}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprInsertElement) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
+
 // ~~~ [ shufflevector ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ExprShuffleVector is an LLVM IR shufflevector expression.
@@ -160,3 +182,14 @@ func (e *ExprShuffleVector) Ident() string {
 	// 'shufflevector' '(' X=TypeConst ',' Y=TypeConst ',' Mask=TypeConst ')'
 	return fmt.Sprintf("shufflevector (%s, %s, %s)", e.X, e.Y, e.Mask)
 }
+
+// Operands returns a mutable list of operands of the given constant
+// expression.
+func (e *ExprShuffleVector) Operands() []*Constant {
+	return []*Constant{&e.X, &e.Y, &e.Mask}
+}
+
+// SetOperand sets the i-th operand of the given constant expression to v.
+func (e *ExprShuffleVector) SetOperand(i int, v Constant) {
+	setOperand(e.Operands(), i, v)
+}
@@ -1,5 +1,7 @@
 package constant
 
+import "fmt"
+
 // === [ Expressions ] =========================================================
 
 // Expression is an LLVM IR constant expression.
@@ -88,7 +90,25 @@ package constant
 //    *constant.ExprSelect   // https://godoc.org/github.com/wa-lang/llir/constant#ExprSelect
 type Expression interface {
 	Constant
+	// Operands returns a mutable list of operands of the constant expression.
+	// Each element points to an operand slot, and may thus be used to rewrite
+	// the operand in place.
+	//
+	// Note, operands of constant expressions are constants, hence the element
+	// type is *Constant rather than *value.Value.
+	Operands() []*Constant
+	// SetOperand sets the i-th operand of the constant expression to v.
+	SetOperand(i int, v Constant)
 	// IsExpression ensures that only constants expressions can be assigned to
 	// the constant.Expression interface.
 	IsExpression()
 }
+
+// setOperand sets the i-th operand of ops to v. An out-of-range operand index
+// panics with a descriptive error.
+func setOperand(ops []*Constant, i int, v Constant) {
+	if i < 0 || i >= len(ops) {
+		panic(fmt.Errorf("invalid operand index %d; expected 0 <= index < %d", i, len(ops)))
+	}
+	*ops[i] = v
+}
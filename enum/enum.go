@@ -752,6 +752,7 @@ const (
 	FuncAttrOptForFuzzing                               // optforfuzzing
 	FuncAttrOptNone                                     // optnone
 	FuncAttrOptSize                                     // optsize
+	FuncAttrPreSplitCoroutine                           // presplitcoroutine
 	FuncAttrReadNone                                    // readnone
 	FuncAttrReadOnly                                    // readonly
 	FuncAttrReturnsTwice                                // returns_twice
@@ -36,30 +36,31 @@ func _() {
 	_ = x[FuncAttrOptForFuzzing-25]
 	_ = x[FuncAttrOptNone-26]
 	_ = x[FuncAttrOptSize-27]
-	_ = x[FuncAttrReadNone-28]
-	_ = x[FuncAttrReadOnly-29]
-	_ = x[FuncAttrReturnsTwice-30]
-	_ = x[FuncAttrSafeStack-31]
-	_ = x[FuncAttrSanitizeAddress-32]
-	_ = x[FuncAttrSanitizeHWAddress-33]
-	_ = x[FuncAttrSanitizeMemory-34]
-	_ = x[FuncAttrSanitizeMemTag-35]
-	_ = x[FuncAttrSanitizeThread-36]
-	_ = x[FuncAttrShadowCallStack-37]
-	_ = x[FuncAttrSpeculatable-38]
-	_ = x[FuncAttrSpeculativeLoadHardening-39]
-	_ = x[FuncAttrSSP-40]
-	_ = x[FuncAttrSSPReq-41]
-	_ = x[FuncAttrSSPStrong-42]
-	_ = x[FuncAttrStrictFP-43]
-	_ = x[FuncAttrUwtable-44]
-	_ = x[FuncAttrWillReturn-45]
-	_ = x[FuncAttrWriteOnly-46]
+	_ = x[FuncAttrPreSplitCoroutine-28]
+	_ = x[FuncAttrReadNone-29]
+	_ = x[FuncAttrReadOnly-30]
+	_ = x[FuncAttrReturnsTwice-31]
+	_ = x[FuncAttrSafeStack-32]
+	_ = x[FuncAttrSanitizeAddress-33]
+	_ = x[FuncAttrSanitizeHWAddress-34]
+	_ = x[FuncAttrSanitizeMemory-35]
+	_ = x[FuncAttrSanitizeMemTag-36]
+	_ = x[FuncAttrSanitizeThread-37]
+	_ = x[FuncAttrShadowCallStack-38]
+	_ = x[FuncAttrSpeculatable-39]
+	_ = x[FuncAttrSpeculativeLoadHardening-40]
+	_ = x[FuncAttrSSP-41]
+	_ = x[FuncAttrSSPReq-42]
+	_ = x[FuncAttrSSPStrong-43]
+	_ = x[FuncAttrStrictFP-44]
+	_ = x[FuncAttrUwtable-45]
+	_ = x[FuncAttrWillReturn-46]
+	_ = x[FuncAttrWriteOnly-47]
 }
 
-const _FuncAttr_name = "alwaysinlineargmemonlybuiltincoldconvergentinaccessiblememonlyinaccessiblemem_or_argmemonlyinlinehintjumptableminsizenakednobuiltinnocf_checknoduplicatenofreenoimplicitfloatnoinlinenomergenonlazybindnorecursenoredzonenoreturnnosyncnounwindnull_pointer_is_validoptforfuzzingoptnoneoptsizereadnonereadonlyreturns_twicesafestacksanitize_addresssanitize_hwaddresssanitize_memorysanitize_memtagsanitize_threadshadowcallstackspeculatablespeculative_load_hardeningsspsspreqsspstrongstrictfpuwtablewillreturnwriteonly"
+const _FuncAttr_name = "alwaysinlineargmemonlybuiltincoldconvergentinaccessiblememonlyinaccessiblemem_or_argmemonlyinlinehintjumptableminsizenakednobuiltinnocf_checknoduplicatenofreenoimplicitfloatnoinlinenomergenonlazybindnorecursenoredzonenoreturnnosyncnounwindnull_pointer_is_validoptforfuzzingoptnoneoptsizepresplitcoroutinereadnonereadonlyreturns_twicesafestacksanitize_addresssanitize_hwaddresssanitize_memorysanitize_memtagsanitize_threadshadowcallstackspeculatablespeculative_load_hardeningsspsspreqsspstrongstrictfpuwtablewillreturnwriteonly"
 
-var _FuncAttr_index = [...]uint16{0, 12, 22, 29, 33, 43, 62, 91, 101, 110, 117, 122, 131, 141, 152, 158, 173, 181, 188, 199, 208, 217, 225, 231, 239, 260, 273, 280, 287, 295, 303, 316, 325, 341, 359, 374, 389, 404, 419, 431, 457, 460, 466, 475, 483, 490, 500, 509}
+var _FuncAttr_index = [...]uint16{0, 12, 22, 29, 33, 43, 62, 91, 101, 110, 117, 122, 131, 141, 152, 158, 173, 181, 188, 199, 208, 217, 225, 231, 239, 260, 273, 280, 287, 304, 312, 320, 333, 342, 358, 376, 391, 406, 421, 436, 448, 474, 477, 483, 492, 500, 507, 517, 526}
 
 func (i FuncAttr) String() string {
 	if i >= FuncAttr(len(_FuncAttr_index)-1) {
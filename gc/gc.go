@@ -0,0 +1,76 @@
+// Package gc provides precise garbage collection support for LLVM IR
+// functions; shadow stack roots (llvm.gcroot) and statepoints
+// (llvm.experimental.gc.statepoint).
+//
+// ref: https://llvm.org/docs/GarbageCollection.html
+package gc
+
+import (
+	"fmt"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/intrinsics"
+	"github.com/wa-lang/llir/types"
+)
+
+// Garbage collection strategies of LLVM; used as the gc name of functions.
+const (
+	// Shadow stack strategy, using llvm.gcroot.
+	ShadowStack = "shadow-stack"
+	// Example statepoint strategy; garbage collected pointers are in address
+	// space 1.
+	StatepointExample = "statepoint-example"
+	// CoreCLR strategy, using statepoints.
+	CoreCLR = "coreclr"
+	// Erlang strategy, using llvm.gcroot.
+	Erlang = "erlang"
+	// OCaml strategy, using llvm.gcroot.
+	OCaml = "ocaml"
+)
+
+// UsesStatepoints reports whether the given garbage collection strategy uses
+// statepoints to record live garbage collected pointers.
+func UsesStatepoints(strategy string) bool {
+	switch strategy {
+	case StatepointExample, CoreCLR:
+		return true
+	}
+	return false
+}
+
+// IsGCPointer reports whether the given type is a garbage collected pointer
+// type; i.e. a pointer type in address space 1.
+func IsGCPointer(t types.Type) bool {
+	if t, ok := t.(*types.PointerType); ok {
+		return t.AddrSpace == 1
+	}
+	return false
+}
+
+// NewRoot returns a new garbage collection root of the given pointer type in
+// the entry basic block of f, registered using llvm.gcroot with the given
+// metadata; or nil if no metadata. The root is initialized to null, and loads
+// and stores through the returned stack slot access the root.
+//
+// The function must use a garbage collection strategy based on llvm.gcroot
+// (e.g. ShadowStack).
+func NewRoot(f *llir.Func, typ *types.PointerType, metadata constant.Constant) *llir.InstAlloca {
+	if len(f.GC) == 0 || UsesStatepoints(f.GC) {
+		panic(fmt.Errorf("invalid garbage collection strategy %q of function %q for llvm.gcroot", f.GC, f.Ident()))
+	}
+	if len(f.Blocks) == 0 {
+		panic(fmt.Errorf("missing entry basic block of function %q", f.Ident()))
+	}
+	entry := f.Blocks[0]
+	// Build the root at the end of the entry basic block, and move it to the
+	// start.
+	insts := entry.Insts
+	entry.Insts = nil
+	slot := entry.NewAlloca(typ)
+	ptrLoc := entry.NewBitCast(slot, types.NewPointer(types.I8Ptr))
+	intrinsics.GCRoot(entry, ptrLoc, metadata)
+	entry.NewStore(constant.NewNull(typ), slot)
+	entry.Insts = append(entry.Insts, insts...)
+	return slot
+}
@@ -0,0 +1,134 @@
+package gc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/types"
+)
+
+// gcPtr is a garbage collected pointer type.
+var gcPtr = &types.PointerType{ElemType: types.I8, AddrSpace: 1}
+
+func TestRewriteFunc(t *testing.T) {
+	m := llir.NewModule()
+	foo := m.NewFunc("foo", types.I32)
+	cond := m.NewFunc("cond", types.I1)
+	p := llir.NewParam("p", gcPtr)
+	f := m.NewFunc("f", gcPtr, p)
+	f.GC = StatepointExample
+	entry := f.NewBlock("entry")
+	loop := f.NewBlock("loop")
+	exit := f.NewBlock("exit")
+	entry.NewBr(loop)
+	r := loop.NewCall(foo)
+	r.SetName("r")
+	c := loop.NewCall(cond)
+	loop.NewCondBr(c, loop, exit)
+	exit.NewRet(p)
+	if err := RewriteStatepoints(m); err != nil {
+		t.Fatalf("unable to rewrite statepoints; %+v", err)
+	}
+	want := `define i8 addrspace(1)* @f(i8 addrspace(1)* %p) gc "statepoint-example" {
+entry:
+	br label %loop
+
+loop:
+	%0 = phi i8 addrspace(1)* [ %p, %entry ], [ %5, %loop ]
+	%1 = call token (i64, i32, i32 ()*, i32, i32, ...) @llvm.experimental.gc.statepoint.p0f_i32f(i64 2882400000, i32 0, i32 ()* @foo, i32 0, i32 0, i32 0, i32 0) [ "gc-live"(i8 addrspace(1)* %0) ]
+	%r = call i32 @llvm.experimental.gc.result.i32(token %1)
+	%2 = call i8 addrspace(1)* @llvm.experimental.gc.relocate.p1i8(token %1, i32 0, i32 0)
+	%3 = call token (i64, i32, i1 ()*, i32, i32, ...) @llvm.experimental.gc.statepoint.p0f_i1f(i64 2882400000, i32 0, i1 ()* @cond, i32 0, i32 0, i32 0, i32 0) [ "gc-live"(i8 addrspace(1)* %2) ]
+	%4 = call i1 @llvm.experimental.gc.result.i1(token %3)
+	%5 = call i8 addrspace(1)* @llvm.experimental.gc.relocate.p1i8(token %3, i32 0, i32 0)
+	br i1 %4, label %loop, label %exit
+
+exit:
+	ret i8 addrspace(1)* %5
+}`
+	if got := f.LLString(); want != got {
+		t.Errorf("function mismatch; expected:\n%s\n\ngot:\n%s", want, got)
+	}
+}
+
+// TestRewriteFuncResult tests a garbage collected pointer returned by a
+// rewritten call, which is live across later safepoints.
+func TestRewriteFuncResult(t *testing.T) {
+	m := llir.NewModule()
+	alloc := m.NewFunc("alloc", gcPtr)
+	foo := m.NewFunc("foo", types.Void)
+	f := m.NewFunc("f", gcPtr)
+	f.GC = StatepointExample
+	entry := f.NewBlock("entry")
+	exit := f.NewBlock("exit")
+	p := entry.NewCall(alloc)
+	p.SetName("p")
+	p.CallingConv = enum.CallingConvFast
+	p.ReturnAttrs = append(p.ReturnAttrs, enum.ReturnAttrNoAlias)
+	p.FuncAttrs = append(p.FuncAttrs, enum.FuncAttrNoUnwind)
+	entry.NewCall(foo)
+	entry.NewBr(exit)
+	exit.NewCall(foo)
+	exit.NewRet(p)
+	if err := RewriteStatepoints(m); err != nil {
+		t.Fatalf("unable to rewrite statepoints; %+v", err)
+	}
+	want := `define i8 addrspace(1)* @f() gc "statepoint-example" {
+entry:
+	%0 = call fastcc token (i64, i32, i8 addrspace(1)* ()*, i32, i32, ...) @llvm.experimental.gc.statepoint.p0f_p1i8f(i64 2882400000, i32 0, i8 addrspace(1)* ()* @alloc, i32 0, i32 0, i32 0, i32 0) nounwind [ "gc-live"() ]
+	%p = call noalias i8 addrspace(1)* @llvm.experimental.gc.result.p1i8(token %0)
+	%1 = call token (i64, i32, void ()*, i32, i32, ...) @llvm.experimental.gc.statepoint.p0f_isVoidf(i64 2882400000, i32 0, void ()* @foo, i32 0, i32 0, i32 0, i32 0) [ "gc-live"(i8 addrspace(1)* %p) ]
+	%2 = call i8 addrspace(1)* @llvm.experimental.gc.relocate.p1i8(token %1, i32 0, i32 0)
+	br label %exit
+
+exit:
+	%3 = call token (i64, i32, void ()*, i32, i32, ...) @llvm.experimental.gc.statepoint.p0f_isVoidf(i64 2882400000, i32 0, void ()* @foo, i32 0, i32 0, i32 0, i32 0) [ "gc-live"(i8 addrspace(1)* %2) ]
+	%4 = call i8 addrspace(1)* @llvm.experimental.gc.relocate.p1i8(token %3, i32 0, i32 0)
+	ret i8 addrspace(1)* %4
+}`
+	if got := f.LLString(); want != got {
+		t.Errorf("function mismatch; expected:\n%s\n\ngot:\n%s", want, got)
+	}
+}
+
+// TestRewriteFuncInvoke tests that functions with invoke terminators calling
+// safepoints are rejected and left unchanged.
+func TestRewriteFuncInvoke(t *testing.T) {
+	m := llir.NewModule()
+	foo := m.NewFunc("foo", types.Void)
+	p := llir.NewParam("p", gcPtr)
+	f := m.NewFunc("f", gcPtr, p)
+	f.GC = StatepointExample
+	entry := f.NewBlock("entry")
+	normal := f.NewBlock("normal")
+	unwind := f.NewBlock("unwind")
+	entry.NewInvoke(foo, nil, normal, unwind)
+	normal.NewRet(p)
+	lp := unwind.NewLandingPad(types.NewStruct(types.I8Ptr, types.I32))
+	lp.Cleanup = true
+	unwind.NewResume(lp)
+	want := f.LLString()
+	if err := RewriteFunc(f); err == nil {
+		t.Errorf("expected error for invoke of safepoint, got nil")
+	}
+	if got := f.LLString(); want != got {
+		t.Errorf("function modified; expected:\n%s\n\ngot:\n%s", want, got)
+	}
+}
+
+func TestNewRoot(t *testing.T) {
+	m := llir.NewModule()
+	f := m.NewFunc("f", types.Void)
+	f.GC = ShadowStack
+	entry := f.NewBlock("entry")
+	entry.NewRet(nil)
+	root := NewRoot(f, gcPtr, nil)
+	root.SetName("root")
+	s := f.LLString()
+	want := "entry:\n\t%root = alloca i8 addrspace(1)*\n\t%0 = bitcast i8 addrspace(1)** %root to i8**\n\tcall void @llvm.gcroot(i8** %0, i8* null)\n\tstore i8 addrspace(1)* null, i8 addrspace(1)** %root\n\tret void"
+	if !strings.Contains(s, want) {
+		t.Errorf("unable to locate %q in function:\n%s", want, s)
+	}
+}
@@ -0,0 +1,479 @@
+package gc
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/intrinsics"
+	"github.com/wa-lang/llir/llutil"
+	"github.com/wa-lang/llir/types"
+	"github.com/wa-lang/llir/value"
+)
+
+// DefaultStatepointID is the default ID of statepoints; as used by LLVM.
+const DefaultStatepointID = 0xABCDEF00
+
+// RewriteStatepoints rewrites the calls of each function of m which uses a
+// statepoint based garbage collection strategy into statepoints. See
+// RewriteFunc.
+func RewriteStatepoints(m *llir.Module) error {
+	for _, f := range m.Funcs {
+		if UsesStatepoints(f.GC) {
+			if err := RewriteFunc(f); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+	return nil
+}
+
+// RewriteFunc rewrites the calls of f into statepoints, recording the garbage
+// collected pointers live across each call in a "gc-live" operand bundle. The
+// result of the call is retrieved using llvm.experimental.gc.result, and each
+// live pointer is relocated using llvm.experimental.gc.relocate; uses of live
+// pointers after the call are updated to refer to the relocated pointers,
+// inserting phi instructions as needed. The calling convention and function
+// attributes of the call are kept on the statepoint, and its return attributes
+// on the gc.result call.
+//
+// Calls to intrinsics, inline assembly, variadic functions and functions with
+// the "gc-leaf-function" attribute are not rewritten. Invoke and callbr
+// terminators are not supported; an error is returned, and f left unchanged, if
+// any of them would be a safepoint. Each live pointer is reported as its own
+// base pointer; derived pointers should not be kept live across calls.
+func RewriteFunc(f *llir.Func) error {
+	for _, block := range f.Blocks {
+		switch term := block.Term.(type) {
+		case *llir.TermInvoke:
+			if isSafepoint(term.Sig(), term.Invokee, term.FuncAttrs, term.OperandBundles) {
+				return errors.Errorf("unable to rewrite invoke terminator of basic block %s in function %q into statepoint", block.Ident(), f.Ident())
+			}
+		case *llir.TermCallBr:
+			if isSafepoint(term.Sig(), term.Callee, term.FuncAttrs, term.OperandBundles) {
+				return errors.Errorf("unable to rewrite callbr terminator of basic block %s in function %q into statepoint", block.Ident(), f.Ident())
+			}
+		}
+	}
+	live := newLiveness(f)
+	// Relocated pointers of each live pointer.
+	relocs := make(map[value.Value][]value.Value)
+	for _, block := range f.Blocks {
+		for i := 0; i < len(block.Insts); i++ {
+			call, ok := block.Insts[i].(*llir.InstCall)
+			if !ok || !isSafepoint(call.Sig(), call.Callee, call.FuncAttrs, call.OperandBundles) {
+				continue
+			}
+			liveVals := live.liveAcross(block, i)
+			tail := append([]llir.Instruction(nil), block.Insts[i+1:]...)
+			block.Insts = block.Insts[:i]
+			token := intrinsics.GCStatepoint(block, DefaultStatepointID, 0, call.Callee, call.Args, liveVals)
+			token.CallingConv = call.CallingConv
+			token.FuncAttrs = append(token.FuncAttrs, call.FuncAttrs...)
+			token.Metadata = call.Metadata
+			if !types.Equal(call.Sig().RetType, types.Void) {
+				result := intrinsics.GCResult(block, token, call.Sig().RetType)
+				if !call.IsUnnamed() {
+					result.SetName(call.Name())
+				}
+				result.ReturnAttrs = append(result.ReturnAttrs, call.ReturnAttrs...)
+				llutil.ReplaceAllUses(f, call, result)
+				live.replace(call, result)
+			}
+			for j, v := range liveVals {
+				reloc := intrinsics.GCRelocate(block, token, j, j, v.Type())
+				relocs[v] = append(relocs[v], reloc)
+			}
+			i = len(block.Insts) - 1
+			block.Insts = append(block.Insts, tail...)
+		}
+	}
+	// Repair SSA form, in order of definition.
+	var vs []value.Value
+	for v := range relocs {
+		vs = append(vs, v)
+	}
+	sort.Slice(vs, func(i, j int) bool {
+		return live.order[vs[i]] < live.order[vs[j]]
+	})
+	preds := predecessors(f)
+	for _, v := range vs {
+		newUpdater(f, preds, v, relocs[v]).update()
+	}
+	return nil
+}
+
+// isSafepoint reports whether a call site with the given signature, callee,
+// function attributes and operand bundles is rewritten into a statepoint.
+func isSafepoint(sig *types.FuncType, callee value.Value, funcAttrs []llir.FuncAttribute, bundles []*llir.OperandBundle) bool {
+	if sig.Variadic {
+		return false
+	}
+	for _, bundle := range bundles {
+		if bundle.Tag == "gc-live" {
+			return false
+		}
+	}
+	f, ok := callee.(*llir.Func)
+	if !ok {
+		_, isAsm := callee.(*llir.InlineAsm)
+		return !isAsm
+	}
+	if strings.HasPrefix(f.Name(), "llvm.") {
+		return false
+	}
+	for _, attrs := range [][]llir.FuncAttribute{f.FuncAttrs, funcAttrs} {
+		for _, attr := range attrs {
+			if attr, ok := attr.(llir.AttrString); ok && attr == "gc-leaf-function" {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// --- [ Liveness ] ------------------------------------------------------------
+
+// liveness tracks the garbage collected pointers live at the end of each basic
+// block of a function.
+type liveness struct {
+	// Live garbage collected pointers at the end of each basic block.
+	liveOut map[*llir.Block]map[value.Value]bool
+	// Order of definition of each garbage collected pointer of the function.
+	order map[value.Value]int
+}
+
+// newLiveness returns the liveness of garbage collected pointers in f.
+func newLiveness(f *llir.Func) *liveness {
+	l := &liveness{
+		liveOut: make(map[*llir.Block]map[value.Value]bool),
+		order:   make(map[value.Value]int),
+	}
+	for _, param := range f.Params {
+		if IsGCPointer(param.Type()) {
+			l.order[param] = len(l.order)
+		}
+	}
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			if v, ok := inst.(value.Value); ok && IsGCPointer(v.Type()) {
+				l.order[v] = len(l.order)
+			}
+		}
+	}
+	// Upward exposed uses and definitions of each basic block, and uses by phi
+	// instructions on incoming edges.
+	uses := make(map[*llir.Block]map[value.Value]bool)
+	defs := make(map[*llir.Block]map[value.Value]bool)
+	phiUses := make(map[*llir.Block]map[value.Value]bool)
+	for _, block := range f.Blocks {
+		uses[block] = make(map[value.Value]bool)
+		defs[block] = make(map[value.Value]bool)
+		l.liveOut[block] = make(map[value.Value]bool)
+	}
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			if phi, ok := inst.(*llir.InstPhi); ok {
+				for _, inc := range phi.Incs {
+					pred, ok := inc.Pred.(*llir.Block)
+					if !ok || !l.isTracked(inc.X) {
+						continue
+					}
+					if phiUses[pred] == nil {
+						phiUses[pred] = make(map[value.Value]bool)
+					}
+					phiUses[pred][inc.X] = true
+				}
+			} else {
+				for _, op := range llutil.Operands(inst) {
+					if l.isTracked(*op) && !defs[block][*op] {
+						uses[block][*op] = true
+					}
+				}
+			}
+			if v, ok := inst.(value.Value); ok && l.isTracked(v) {
+				defs[block][v] = true
+			}
+		}
+		if block.Term != nil {
+			for _, op := range llutil.Operands(block.Term) {
+				if l.isTracked(*op) && !defs[block][*op] {
+					uses[block][*op] = true
+				}
+			}
+		}
+	}
+	// Iterate to fixed point.
+	for changed := true; changed; {
+		changed = false
+		for i := len(f.Blocks) - 1; i >= 0; i-- {
+			block := f.Blocks[i]
+			out := l.liveOut[block]
+			add := func(v value.Value) {
+				if !out[v] {
+					out[v] = true
+					changed = true
+				}
+			}
+			for v := range phiUses[block] {
+				add(v)
+			}
+			if block.Term == nil {
+				continue
+			}
+			for _, succ := range block.Term.Succs() {
+				for v := range uses[succ] {
+					add(v)
+				}
+				for v := range l.liveOut[succ] {
+					if !defs[succ][v] {
+						add(v)
+					}
+				}
+			}
+		}
+	}
+	return l
+}
+
+// liveAcross returns the garbage collected pointers live across the i-th
+// instruction of the given basic block, in order of definition.
+func (l *liveness) liveAcross(block *llir.Block, i int) []value.Value {
+	live := make(map[value.Value]bool)
+	for v := range l.liveOut[block] {
+		live[v] = true
+	}
+	if block.Term != nil {
+		for _, op := range llutil.Operands(block.Term) {
+			if l.isTracked(*op) {
+				live[*op] = true
+			}
+		}
+	}
+	for j := len(block.Insts) - 1; j > i; j-- {
+		inst := block.Insts[j]
+		if v, ok := inst.(value.Value); ok {
+			delete(live, v)
+		}
+		for _, op := range llutil.Operands(inst) {
+			if l.isTracked(*op) {
+				live[*op] = true
+			}
+		}
+	}
+	if v, ok := block.Insts[i].(value.Value); ok {
+		delete(live, v)
+	}
+	var vs []value.Value
+	for v := range live {
+		vs = append(vs, v)
+	}
+	sort.Slice(vs, func(i, j int) bool {
+		return l.order[vs[i]] < l.order[vs[j]]
+	})
+	return vs
+}
+
+// replace replaces the garbage collected pointer old with new, after the uses
+// of old have been replaced with new (e.g. a rewritten call with the result of
+// its statepoint).
+func (l *liveness) replace(old, new value.Value) {
+	order, ok := l.order[old]
+	if !ok {
+		return
+	}
+	delete(l.order, old)
+	l.order[new] = order
+	for _, out := range l.liveOut {
+		if out[old] {
+			delete(out, old)
+			out[new] = true
+		}
+	}
+}
+
+// isTracked reports whether v is a garbage collected pointer defined by the
+// function; i.e. a parameter or instruction.
+func (l *liveness) isTracked(v value.Value) bool {
+	_, ok := l.order[v]
+	return ok
+}
+
+// --- [ SSA repair ] ----------------------------------------------------------
+
+// updater repairs the SSA form of a function after relocation of a garbage
+// collected pointer, such that each use of the pointer refers to its reaching
+// definition; the original pointer, a relocated pointer or a phi instruction
+// merging these.
+type updater struct {
+	// Function being updated.
+	f *llir.Func
+	// Predecessors of each basic block.
+	preds map[*llir.Block][]*llir.Block
+	// Original pointer.
+	v value.Value
+	// Definitions of the pointer; the original pointer and its relocations.
+	defs map[value.Value]bool
+	// Reaching definition at the start of each basic block.
+	start map[*llir.Block]value.Value
+	// Phi instructions inserted by the updater.
+	phis []*llir.InstPhi
+	// Reports whether the given phi instruction was inserted by the updater.
+	inserted map[*llir.InstPhi]bool
+}
+
+// newUpdater returns a new SSA updater of the given pointer and relocations.
+func newUpdater(f *llir.Func, preds map[*llir.Block][]*llir.Block, v value.Value, relocs []value.Value) *updater {
+	u := &updater{
+		f:        f,
+		preds:    preds,
+		v:        v,
+		defs:     map[value.Value]bool{v: true},
+		start:    make(map[*llir.Block]value.Value),
+		inserted: make(map[*llir.InstPhi]bool),
+	}
+	for _, reloc := range relocs {
+		u.defs[reloc] = true
+	}
+	return u
+}
+
+// update rewrites each use of the original pointer to its reaching definition.
+func (u *updater) update() {
+	for _, block := range u.f.Blocks {
+		var cur value.Value
+		for _, inst := range block.Insts {
+			if phi, ok := inst.(*llir.InstPhi); ok {
+				if u.inserted[phi] {
+					continue
+				}
+				for _, inc := range phi.Incs {
+					if inc.X == u.v {
+						inc.X = u.readEnd(inc.Pred.(*llir.Block))
+					}
+				}
+			} else {
+				for _, op := range llutil.Operands(inst) {
+					if *op != u.v {
+						continue
+					}
+					if cur == nil {
+						cur = u.readStart(block)
+					}
+					*op = cur
+				}
+			}
+			if v, ok := inst.(value.Value); ok && u.defs[v] {
+				cur = v
+			}
+		}
+		if block.Term == nil {
+			continue
+		}
+		for _, op := range llutil.Operands(block.Term) {
+			if *op != u.v {
+				continue
+			}
+			if cur == nil {
+				cur = u.readStart(block)
+			}
+			*op = cur
+		}
+	}
+	u.removeTrivialPhis()
+}
+
+// readStart returns the reaching definition at the start of the given basic
+// block.
+func (u *updater) readStart(block *llir.Block) value.Value {
+	if v, ok := u.start[block]; ok {
+		return v
+	}
+	preds := u.preds[block]
+	switch len(preds) {
+	case 0:
+		// Entry basic block (or unreachable).
+		u.start[block] = u.v
+		return u.v
+	case 1:
+		// Guard against cycles of unreachable basic blocks.
+		u.start[block] = u.v
+		v := u.readEnd(preds[0])
+		u.start[block] = v
+		return v
+	}
+	phi := &llir.InstPhi{Typ: u.v.Type()}
+	u.start[block] = phi
+	u.phis = append(u.phis, phi)
+	u.inserted[phi] = true
+	block.Insts = append([]llir.Instruction{phi}, block.Insts...)
+	for _, pred := range preds {
+		phi.Incs = append(phi.Incs, llir.NewIncoming(u.readEnd(pred), pred))
+	}
+	return phi
+}
+
+// readEnd returns the reaching definition at the end of the given basic block.
+func (u *updater) readEnd(block *llir.Block) value.Value {
+	for i := len(block.Insts) - 1; i >= 0; i-- {
+		if v, ok := block.Insts[i].(value.Value); ok && u.defs[v] {
+			return v
+		}
+	}
+	if param, ok := u.v.(*llir.Param); ok && block == u.f.Blocks[0] {
+		return param
+	}
+	return u.readStart(block)
+}
+
+// removeTrivialPhis removes inserted phi instructions which merge a single
+// value (ignoring self-references), replacing their uses with the value.
+func (u *updater) removeTrivialPhis() {
+	removed := make(map[*llir.InstPhi]bool)
+	for changed := true; changed; {
+		changed = false
+		for _, phi := range u.phis {
+			if removed[phi] {
+				continue
+			}
+			var same value.Value
+			trivial := true
+			for _, inc := range phi.Incs {
+				if inc.X == value.Value(phi) || inc.X == same {
+					continue
+				}
+				if same != nil {
+					trivial = false
+					break
+				}
+				same = inc.X
+			}
+			if !trivial || same == nil {
+				continue
+			}
+			llutil.ReplaceAllUses(u.f, phi, same)
+			for _, block := range u.f.Blocks {
+				if llutil.RemoveInst(block, phi) {
+					break
+				}
+			}
+			removed[phi] = true
+			changed = true
+		}
+	}
+}
+
+// predecessors returns the predecessor basic blocks of each basic block of f.
+func predecessors(f *llir.Func) map[*llir.Block][]*llir.Block {
+	preds := make(map[*llir.Block][]*llir.Block)
+	for _, block := range f.Blocks {
+		if block.Term == nil {
+			continue
+		}
+		for _, succ := range block.Term.Succs() {
+			preds[succ] = append(preds[succ], block)
+		}
+	}
+	return preds
+}
@@ -318,6 +318,18 @@ func (o *OperandBundle) String() string {
 	return buf.String()
 }
 
+// operandBundleOperands returns a mutable list of the input operands of the
+// given operand bundles.
+func operandBundleOperands(operandBundles []*OperandBundle) []*value.Value {
+	var ops []*value.Value
+	for _, operandBundle := range operandBundles {
+		for i := range operandBundle.Inputs {
+			ops = append(ops, &operandBundle.Inputs[i])
+		}
+	}
+	return ops
+}
+
 // ParamAttribute is a parameter attribute.
 //
 // A ParamAttribute has one of the following underlying types.
@@ -501,3 +513,12 @@ type namedVar interface {
 	// IsUnnamed reports whether the local identifier is unnamed.
 	IsUnnamed() bool
 }
+
+// setOperand sets the i-th operand of ops to v. An out-of-range operand index
+// panics with a descriptive error.
+func setOperand(ops []*value.Value, i int, v value.Value) {
+	if i < 0 || i >= len(ops) {
+		panic(fmt.Errorf("invalid operand index %d; expected 0 <= index < %d", i, len(ops)))
+	}
+	*ops[i] = v
+}
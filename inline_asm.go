@@ -2,9 +2,12 @@ package llir
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/wa-lang/llir/types"
+	"github.com/wa-lang/llir/value"
 )
 
 // InlineAsm is an inline assembler expression.
@@ -24,6 +27,8 @@ type InlineAsm struct {
 	AlignStack bool
 	// (optional) Intel dialect.
 	IntelDialect bool
+	// (optional) May unwind.
+	Unwind bool
 }
 
 // NewInlineAsm returns a new inline assembler expression based on the given
@@ -45,7 +50,7 @@ func (asm *InlineAsm) Type() types.Type {
 
 // Ident returns the identifier associated with the inline assembler expression.
 func (asm *InlineAsm) Ident() string {
-	// "asm" OptSideEffect OptAlignStack OptIntelDialect StringLit "," StringLit
+	// "asm" OptSideEffect OptAlignStack OptIntelDialect OptUnwind StringLit "," StringLit
 	buf := &strings.Builder{}
 	buf.WriteString("asm")
 	if asm.SideEffect {
@@ -57,6 +62,329 @@ func (asm *InlineAsm) Ident() string {
 	if asm.IntelDialect {
 		buf.WriteString(" inteldialect")
 	}
+	if asm.Unwind {
+		buf.WriteString(" unwind")
+	}
 	fmt.Fprintf(buf, " %s, %s", quote(asm.Asm), quote(asm.Constraint))
 	return buf.String()
 }
+
+// Constraints returns the parsed constraints of the inline assembler
+// expression.
+func (asm *InlineAsm) Constraints() (*AsmConstraints, error) {
+	return ParseAsmConstraints(asm.Constraint)
+}
+
+// Validate validates the constraints of the inline assembler expression
+// against its function type, and the given call arguments against the
+// parameter types of the function type.
+//
+// The non-indirect output constraints determine the return type (void for
+// none, the output type for one, and a struct of output types for more). Each
+// indirect output and each input constraint consume one argument, in order;
+// indirect operands must be pointers, and matching input constraints must
+// refer to an output of the same type; the pointer type for indirect outputs.
+func (asm *InlineAsm) Validate(args []value.Value) error {
+	cs, err := asm.Constraints()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	ptr, ok := asm.Typ.(*types.PointerType)
+	if !ok {
+		return errors.Errorf("invalid inline asm type; expected *types.PointerType, got %T", asm.Typ)
+	}
+	sig, ok := ptr.ElemType.(*types.FuncType)
+	if !ok {
+		return errors.Errorf("invalid inline asm type; expected *types.FuncType, got %T", ptr.ElemType)
+	}
+	if sig.Variadic {
+		return errors.Errorf("invalid variadic inline asm type %v", sig)
+	}
+	// Check return type against direct outputs.
+	var directTypes []types.Type
+	var direct []*AsmConstraint
+	for _, c := range cs.Outputs {
+		if !c.Indirect {
+			direct = append(direct, c)
+		}
+	}
+	switch len(direct) {
+	case 0:
+		if !sig.RetType.Equal(types.Void) {
+			return errors.Errorf("invalid inline asm return type; expected void for no outputs, got %v", sig.RetType)
+		}
+	case 1:
+		switch sig.RetType.(type) {
+		case *types.VoidType, *types.StructType:
+			return errors.Errorf("invalid inline asm return type; expected non-void non-struct type for one output, got %v", sig.RetType)
+		}
+		directTypes = append(directTypes, sig.RetType)
+	default:
+		st, ok := sig.RetType.(*types.StructType)
+		if !ok || len(st.Fields) != len(direct) {
+			return errors.Errorf("invalid inline asm return type; expected struct type with %d fields for %d outputs, got %v", len(direct), len(direct), sig.RetType)
+		}
+		directTypes = append(directTypes, st.Fields...)
+	}
+	// Check parameter types against indirect outputs and inputs.
+	var operands []*AsmConstraint
+	for _, c := range cs.Outputs {
+		if c.Indirect {
+			operands = append(operands, c)
+		}
+	}
+	operands = append(operands, cs.Inputs...)
+	if len(sig.Params) != len(operands) {
+		return errors.Errorf("invalid number of inline asm parameters; expected %d for constraints %q, got %d", len(operands), asm.Constraint, len(sig.Params))
+	}
+	// Types of outputs, indexed by matching constraints; the result type of
+	// direct outputs, and the pointer parameter type of indirect outputs.
+	var outTypes []types.Type
+	nDirect, nIndirect := 0, 0
+	for _, c := range cs.Outputs {
+		if c.Indirect {
+			outTypes = append(outTypes, sig.Params[nIndirect])
+			nIndirect++
+		} else {
+			outTypes = append(outTypes, directTypes[nDirect])
+			nDirect++
+		}
+	}
+	for i, c := range operands {
+		param := sig.Params[i]
+		if c.Indirect && !types.IsPointer(param) {
+			return errors.Errorf("invalid type of indirect inline asm operand %d (%q); expected pointer type, got %v", i, c, param)
+		}
+		if c.Tied != -1 {
+			if c.Tied >= len(outTypes) {
+				return errors.Errorf("invalid matching inline asm constraint %q of operand %d; output %d not present", c, i, c.Tied)
+			}
+			if want := outTypes[c.Tied]; !param.Equal(want) {
+				return errors.Errorf("type mismatch of matching inline asm operand %d (%q); expected %v, got %v", i, c, want, param)
+			}
+		}
+	}
+	// Check call arguments against parameter types.
+	if len(args) != len(sig.Params) {
+		return errors.Errorf("invalid number of inline asm arguments; expected %d, got %d", len(sig.Params), len(args))
+	}
+	for i, arg := range args {
+		if want := sig.Params[i]; !arg.Type().Equal(want) {
+			return errors.Errorf("type mismatch of inline asm argument %d; expected %v, got %v", i, want, arg.Type())
+		}
+	}
+	return nil
+}
+
+// ___ [ Inline assembler constraints ] ________________________________________
+
+// AsmConstraintKind specifies the kind of an inline assembler constraint.
+type AsmConstraintKind uint8
+
+// Inline assembler constraint kinds.
+const (
+	// Input operand.
+	AsmInput AsmConstraintKind = iota
+	// Output operand (=).
+	AsmOutput
+	// Clobbered register or memory (~).
+	AsmClobber
+	// Label operand of callbr (!).
+	AsmLabel
+)
+
+// AsmConstraints is the parsed constraint string of an inline assembler
+// expression, partitioned by kind.
+type AsmConstraints struct {
+	// Output constraints; direct outputs are returned by the call, while
+	// indirect outputs (e.g. "=*m") are stored through pointer arguments.
+	Outputs []*AsmConstraint
+	// Input constraints.
+	Inputs []*AsmConstraint
+	// Clobber constraints.
+	Clobbers []*AsmConstraint
+	// Label constraints.
+	Labels []*AsmConstraint
+}
+
+// AsmConstraint is a single inline assembler constraint.
+//
+// ref: https://llvm.org/docs/LangRef.html#inline-asm-constraint-string
+type AsmConstraint struct {
+	// Constraint kind.
+	Kind AsmConstraintKind
+	// Indirect operand (*); passed as a pointer to the operand.
+	Indirect bool
+	// Early clobber output (&); written before all inputs are consumed.
+	EarlyClobber bool
+	// Commutative input (%); may be swapped with the following input.
+	Commutative bool
+	// Constraint codes; e.g. "r", "m", "i", "{eax}" or "^Wc". Codes of
+	// alternatives separated by '|' are concatenated.
+	Codes []string
+	// Index of output tied to a matching input constraint (e.g. "0"); or -1 if
+	// not tied.
+	Tied int
+}
+
+// String returns the string representation of the inline assembler
+// constraint.
+func (c *AsmConstraint) String() string {
+	buf := &strings.Builder{}
+	switch c.Kind {
+	case AsmOutput:
+		buf.WriteString("=")
+	case AsmClobber:
+		buf.WriteString("~")
+	case AsmLabel:
+		buf.WriteString("!")
+	}
+	if c.Indirect {
+		buf.WriteString("*")
+	}
+	if c.EarlyClobber {
+		buf.WriteString("&")
+	}
+	if c.Commutative {
+		buf.WriteString("%")
+	}
+	for _, code := range c.Codes {
+		buf.WriteString(code)
+	}
+	return buf.String()
+}
+
+// ParseAsmConstraints parses the given comma-separated inline assembler
+// constraint string. Output constraints must precede input constraints, and
+// clobber constraints must follow all other constraints.
+func ParseAsmConstraints(s string) (*AsmConstraints, error) {
+	cs := &AsmConstraints{}
+	if len(s) == 0 {
+		return cs, nil
+	}
+	prev := AsmOutput
+	for i, field := range strings.Split(s, ",") {
+		c, err := parseAsmConstraint(field)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid constraint %d of %q", i, s)
+		}
+		// Validate order of constraints; outputs, inputs (and labels), clobbers.
+		switch {
+		case c.Kind == AsmOutput && prev != AsmOutput:
+			return nil, errors.Errorf("invalid constraint %d of %q; output constraint %q after input or clobber constraint", i, s, field)
+		case c.Kind != AsmClobber && prev == AsmClobber:
+			return nil, errors.Errorf("invalid constraint %d of %q; constraint %q after clobber constraint", i, s, field)
+		}
+		prev = c.Kind
+		switch c.Kind {
+		case AsmOutput:
+			cs.Outputs = append(cs.Outputs, c)
+		case AsmInput:
+			cs.Inputs = append(cs.Inputs, c)
+		case AsmClobber:
+			cs.Clobbers = append(cs.Clobbers, c)
+		case AsmLabel:
+			cs.Labels = append(cs.Labels, c)
+		}
+	}
+	for _, c := range cs.Inputs {
+		if c.Tied >= len(cs.Outputs) {
+			return nil, errors.Errorf("invalid matching constraint %q of %q; output %d not present", c, s, c.Tied)
+		}
+	}
+	return cs, nil
+}
+
+// parseAsmConstraint parses the given inline assembler constraint.
+func parseAsmConstraint(s string) (*AsmConstraint, error) {
+	c := &AsmConstraint{Kind: AsmInput, Tied: -1}
+	i := 0
+	if i < len(s) {
+		switch s[i] {
+		case '=':
+			c.Kind = AsmOutput
+			i++
+		case '~':
+			c.Kind = AsmClobber
+			i++
+		case '!':
+			c.Kind = AsmLabel
+			i++
+		}
+	}
+	if i < len(s) && s[i] == '*' {
+		c.Indirect = true
+		i++
+	}
+	// Modifiers.
+loop:
+	for i < len(s) {
+		switch s[i] {
+		case '&':
+			if c.Kind != AsmOutput {
+				return nil, errors.Errorf("early clobber modifier of non-output constraint %q", s)
+			}
+			c.EarlyClobber = true
+		case '%':
+			if c.Kind != AsmInput {
+				return nil, errors.Errorf("commutative modifier of non-input constraint %q", s)
+			}
+			c.Commutative = true
+		default:
+			break loop
+		}
+		i++
+	}
+	// Constraint codes.
+	for i < len(s) {
+		switch ch := s[i]; {
+		case ch == '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end == -1 {
+				return nil, errors.Errorf("unterminated register constraint %q", s)
+			}
+			c.Codes = append(c.Codes, s[i:i+end+1])
+			i += end + 1
+		case '0' <= ch && ch <= '9':
+			end := i
+			for end < len(s) && '0' <= s[end] && s[end] <= '9' {
+				end++
+			}
+			if c.Kind != AsmInput || c.Indirect {
+				return nil, errors.Errorf("matching constraint %q must be a direct input", s)
+			}
+			tied, err := strconv.Atoi(s[i:end])
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			c.Tied = tied
+			c.Codes = append(c.Codes, s[i:end])
+			i = end
+		case ch == '^':
+			if i+3 > len(s) {
+				return nil, errors.Errorf("incomplete two-letter constraint code in %q", s)
+			}
+			c.Codes = append(c.Codes, s[i:i+3])
+			i += 3
+		case ch == '|':
+			// Alternative separator.
+			i++
+		case ch == '}' || ch == ' ' || ch == '=' || ch == '~' || ch == '*' || ch == '&' || ch == '%' || ch == '!':
+			return nil, errors.Errorf("unexpected %q in constraint %q", ch, s)
+		default:
+			c.Codes = append(c.Codes, s[i:i+1])
+			i++
+		}
+	}
+	if len(c.Codes) == 0 {
+		return nil, errors.Errorf("missing constraint code in %q", s)
+	}
+	if c.Kind == AsmClobber {
+		for _, code := range c.Codes {
+			if !strings.HasPrefix(code, "{") {
+				return nil, errors.Errorf("invalid clobber constraint %q; expected register or memory in braces", s)
+			}
+		}
+	}
+	return c, nil
+}
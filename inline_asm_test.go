@@ -0,0 +1,105 @@
+package llir
+
+import (
+	"testing"
+
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/types"
+	"github.com/wa-lang/llir/value"
+)
+
+func TestParseAsmConstraints(t *testing.T) {
+	cs, err := ParseAsmConstraints("=&r,=*m,0,%r,i,{eax}|m,~{memory},~{dirflag}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 2, len(cs.Outputs); want != got {
+		t.Fatalf("number of outputs mismatch; expected %d, got %d", want, got)
+	}
+	if want, got := 4, len(cs.Inputs); want != got {
+		t.Fatalf("number of inputs mismatch; expected %d, got %d", want, got)
+	}
+	if want, got := 2, len(cs.Clobbers); want != got {
+		t.Fatalf("number of clobbers mismatch; expected %d, got %d", want, got)
+	}
+	if out := cs.Outputs[0]; !out.EarlyClobber || out.Indirect {
+		t.Errorf("invalid output constraint %q; expected direct early clobber", out)
+	}
+	if out := cs.Outputs[1]; !out.Indirect {
+		t.Errorf("invalid output constraint %q; expected indirect", out)
+	}
+	if in := cs.Inputs[0]; in.Tied != 0 {
+		t.Errorf("invalid tied output of %q; expected 0, got %d", in, in.Tied)
+	}
+	if in := cs.Inputs[1]; !in.Commutative {
+		t.Errorf("invalid input constraint %q; expected commutative", in)
+	}
+	if want, got := "{eax}m", cs.Inputs[3].String(); want != got {
+		t.Errorf("constraint mismatch; expected %q, got %q", want, got)
+	}
+
+	for _, s := range []string{
+		"r,=r",        // output after input
+		"~{memory},r", // input after clobber
+		"=&",          // missing code
+		"=0",          // matching output
+		"1",           // missing tied output
+		"{eax",        // unterminated register
+		"~r",          // clobber without braces
+		"&r",          // early clobber input
+	} {
+		if _, err := ParseAsmConstraints(s); err == nil {
+			t.Errorf("expected error for constraint string %q", s)
+		}
+	}
+}
+
+func TestInlineAsmValidate(t *testing.T) {
+	x := constant.NewInt(types.I32, 1)
+	ptr := constant.NewNull(types.I32Ptr)
+	golden := []struct {
+		sig        *types.FuncType
+		constraint string
+		args       []value.Value
+		ok         bool
+	}{
+		{sig: types.NewFunc(types.I32, types.I32), constraint: "=r,0", args: []value.Value{x}, ok: true},
+		{sig: types.NewFunc(types.Void, types.I32Ptr, types.I32), constraint: "=*m,r,~{memory}", args: []value.Value{ptr, x}, ok: true},
+		{sig: types.NewFunc(types.NewStruct(types.I32, types.I32)), constraint: "={eax},={edx}", ok: true},
+		// Matching inputs are numbered among all outputs, including indirect
+		// outputs.
+		{sig: types.NewFunc(types.I32, types.I32Ptr, types.I32), constraint: "=*m,=r,1", args: []value.Value{ptr, x}, ok: true},
+		{sig: types.NewFunc(types.Void, types.I32Ptr, types.I32Ptr), constraint: "=*m,0", args: []value.Value{ptr, ptr}, ok: true},
+		// Missing return type of output.
+		{sig: types.NewFunc(types.Void, types.I32), constraint: "=r,r", args: []value.Value{x}},
+		// Struct return type field count mismatch.
+		{sig: types.NewFunc(types.NewStruct(types.I32)), constraint: "=r,=r"},
+		// Non-pointer indirect operand.
+		{sig: types.NewFunc(types.Void, types.I32), constraint: "*m", args: []value.Value{x}},
+		// Matching input type mismatch.
+		{sig: types.NewFunc(types.I32, types.I64), constraint: "=r,0", args: []value.Value{constant.NewInt(types.I64, 1)}},
+		{sig: types.NewFunc(types.I32, types.I32Ptr, types.I32), constraint: "=*m,=r,0", args: []value.Value{ptr, x}},
+		// Parameter count mismatch.
+		{sig: types.NewFunc(types.Void), constraint: "r"},
+		// Argument type mismatch.
+		{sig: types.NewFunc(types.Void, types.I32), constraint: "r", args: []value.Value{ptr}},
+	}
+	for _, g := range golden {
+		asm := NewInlineAsm(types.NewPointer(g.sig), "", g.constraint)
+		err := asm.Validate(g.args)
+		if g.ok && err != nil {
+			t.Errorf("unexpected error for constraints %q of %v; %v", g.constraint, g.sig, err)
+		} else if !g.ok && err == nil {
+			t.Errorf("expected error for constraints %q of %v", g.constraint, g.sig)
+		}
+	}
+}
+
+func TestInlineAsmIdent(t *testing.T) {
+	asm := NewInlineAsm(types.NewPointer(types.NewFunc(types.Void)), "nop", "~{memory}")
+	asm.SideEffect = true
+	asm.Unwind = true
+	if want, got := `asm sideeffect unwind "nop", "~{memory}"`, asm.Ident(); want != got {
+		t.Errorf("inline asm mismatch; expected %q, got %q", want, got)
+	}
+}
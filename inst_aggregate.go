@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/wa-lang/llir/types"
 	"github.com/wa-lang/llir/value"
 )
@@ -30,7 +31,8 @@ type InstExtractValue struct {
 }
 
 // NewExtractValue returns a new extractvalue instruction based on the given
-// aggregate value and indicies.
+// aggregate value and indicies. NewExtractValue panics if the indices are
+// invalid for the aggregate type.
 func NewExtractValue(x value.Value, indices ...uint64) *InstExtractValue {
 	inst := &InstExtractValue{X: x, Indices: indices}
 	// Compute type.
@@ -38,6 +40,17 @@ func NewExtractValue(x value.Value, indices ...uint64) *InstExtractValue {
 	return inst
 }
 
+// TryNewExtractValue returns a new extractvalue instruction based on the given
+// aggregate value and indicies, or an error if the indices are invalid for the
+// aggregate type.
+func TryNewExtractValue(x value.Value, indices ...uint64) (*InstExtractValue, error) {
+	typ, err := tryAggregateElemType(x.Type(), indices)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &InstExtractValue{X: x, Indices: indices, Typ: typ}, nil
+}
+
 // String returns the LLVM syntax representation of the instruction as a
 // type-value pair.
 func (inst *InstExtractValue) String() string {
@@ -69,6 +82,16 @@ func (inst *InstExtractValue) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstExtractValue) Operands() []*value.Value {
+	return []*value.Value{&inst.X}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstExtractValue) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ insertvalue ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstInsertValue is an LLVM IR insertvalue instruction.
@@ -91,16 +114,31 @@ type InstInsertValue struct {
 }
 
 // NewInsertValue returns a new insertvalue instruction based on the given
-// aggregate value, element and indicies.
+// aggregate value, element and indicies. NewInsertValue panics if the element
+// type does not match the aggregate type at the indices.
 func NewInsertValue(x, elem value.Value, indices ...uint64) *InstInsertValue {
-	elemType := aggregateElemType(x.Type(), indices)
+	inst, err := TryNewInsertValue(x, elem, indices...)
+	if err != nil {
+		panic(err)
+	}
+	return inst
+}
+
+// TryNewInsertValue returns a new insertvalue instruction based on the given
+// aggregate value, element and indicies, or an error if the element type does
+// not match the aggregate type at the indices.
+func TryNewInsertValue(x, elem value.Value, indices ...uint64) (*InstInsertValue, error) {
+	elemType, err := tryAggregateElemType(x.Type(), indices)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
 	if !elemType.Equal(elem.Type()) {
-		panic(fmt.Errorf("insertvalue elem type mismatch, expected %v, got %v", elemType, elem.Type()))
+		return nil, errors.Errorf("insertvalue elem type mismatch, expected %v, got %v", elemType, elem.Type())
 	}
 	inst := &InstInsertValue{X: x, Elem: elem, Indices: indices}
 	// Compute type.
 	inst.Type()
-	return inst
+	return inst, nil
 }
 
 // String returns the LLVM syntax representation of the instruction as a
@@ -134,23 +172,50 @@ func (inst *InstInsertValue) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstInsertValue) Operands() []*value.Value {
+	return []*value.Value{&inst.X, &inst.Elem}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstInsertValue) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ### [ Helper functions ] ####################################################
 
 // aggregateElemType returns the element type at the position in the aggregate
 // type specified by the given indices.
 func aggregateElemType(t types.Type, indices []uint64) types.Type {
+	elemType, err := tryAggregateElemType(t, indices)
+	if err != nil {
+		panic(err)
+	}
+	return elemType
+}
+
+// tryAggregateElemType returns the element type at the position in the
+// aggregate type specified by the given indices, or an error if the indices are
+// invalid for the aggregate type.
+func tryAggregateElemType(t types.Type, indices []uint64) (types.Type, error) {
 	// Base case.
 	if len(indices) == 0 {
-		return t
+		return t, nil
 	}
 	switch t := t.(type) {
 	case *types.ArrayType:
-		return aggregateElemType(t.ElemType, indices[1:])
+		if indices[0] >= t.Len {
+			return nil, errors.Errorf("array index %d out of bounds of %v", indices[0], t)
+		}
+		return tryAggregateElemType(t.ElemType, indices[1:])
 	case *types.StructType:
-		return aggregateElemType(t.Fields[indices[0]], indices[1:])
+		if indices[0] >= uint64(len(t.Fields)) {
+			return nil, errors.Errorf("struct index %d out of bounds of %v", indices[0], t)
+		}
+		return tryAggregateElemType(t.Fields[indices[0]], indices[1:])
 	case *types.PointerType:
-		return aggregateElemType(t.ElemType, indices[1:])
+		return tryAggregateElemType(t.ElemType, indices[1:])
 	default:
-		panic(fmt.Errorf("support for aggregate type %T not yet implemented", t))
+		return nil, errors.Errorf("support for aggregate type %T not yet implemented", t)
 	}
 }
@@ -44,3 +44,22 @@ func TestTypeCheckInstExtractValue(t *testing.T) {
 		t.Errorf("expected %q, got %q", expected, got)
 	}
 }
+
+func TestTryNewExtractValue(t *testing.T) {
+	structType := types.NewStruct(types.I32, types.I64)
+	v := constant.NewUndef(structType)
+	if _, err := TryNewExtractValue(v, 1); err != nil {
+		t.Errorf("unexpected error; %v", err)
+	}
+	if _, err := TryNewExtractValue(v, 2); err == nil {
+		t.Error("expected error for out of bounds index")
+	}
+	if _, err := TryNewExtractValue(constant.NewInt(types.I32, 0), 0); err == nil {
+		t.Error("expected error for non-aggregate operand")
+	}
+	_, err := TryNewInsertValue(v, constant.NewInt(types.I32, 1), 1)
+	expected := "insertvalue elem type mismatch, expected i64, got i32"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected %q, got %v", expected, err)
+	}
+}
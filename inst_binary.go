@@ -70,6 +70,16 @@ func (inst *InstAdd) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstAdd) Operands() []*value.Value {
+	return []*value.Value{&inst.X, &inst.Y}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstAdd) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ fadd ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstFAdd is an LLVM IR fadd instruction.
@@ -129,6 +139,16 @@ func (inst *InstFAdd) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstFAdd) Operands() []*value.Value {
+	return []*value.Value{&inst.X, &inst.Y}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstFAdd) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ sub ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstSub is an LLVM IR sub instruction.
@@ -188,6 +208,16 @@ func (inst *InstSub) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstSub) Operands() []*value.Value {
+	return []*value.Value{&inst.X, &inst.Y}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstSub) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ fsub ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstFSub is an LLVM IR fsub instruction.
@@ -247,6 +277,16 @@ func (inst *InstFSub) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstFSub) Operands() []*value.Value {
+	return []*value.Value{&inst.X, &inst.Y}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstFSub) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ mul ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstMul is an LLVM IR mul instruction.
@@ -306,6 +346,16 @@ func (inst *InstMul) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstMul) Operands() []*value.Value {
+	return []*value.Value{&inst.X, &inst.Y}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstMul) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ fmul ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstFMul is an LLVM IR fmul instruction.
@@ -365,6 +415,16 @@ func (inst *InstFMul) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstFMul) Operands() []*value.Value {
+	return []*value.Value{&inst.X, &inst.Y}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstFMul) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ udiv ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstUDiv is an LLVM IR udiv instruction.
@@ -424,6 +484,16 @@ func (inst *InstUDiv) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstUDiv) Operands() []*value.Value {
+	return []*value.Value{&inst.X, &inst.Y}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstUDiv) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ sdiv ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstSDiv is an LLVM IR sdiv instruction.
@@ -483,6 +553,16 @@ func (inst *InstSDiv) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstSDiv) Operands() []*value.Value {
+	return []*value.Value{&inst.X, &inst.Y}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstSDiv) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ fdiv ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstFDiv is an LLVM IR fdiv instruction.
@@ -542,6 +622,16 @@ func (inst *InstFDiv) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstFDiv) Operands() []*value.Value {
+	return []*value.Value{&inst.X, &inst.Y}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstFDiv) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ urem ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstURem is an LLVM IR urem instruction.
@@ -595,6 +685,16 @@ func (inst *InstURem) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstURem) Operands() []*value.Value {
+	return []*value.Value{&inst.X, &inst.Y}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstURem) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ srem ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstSRem is an LLVM IR srem instruction.
@@ -648,6 +748,16 @@ func (inst *InstSRem) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstSRem) Operands() []*value.Value {
+	return []*value.Value{&inst.X, &inst.Y}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstSRem) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ frem ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstFRem is an LLVM IR frem instruction.
@@ -706,3 +816,13 @@ func (inst *InstFRem) LLString() string {
 	}
 	return buf.String()
 }
+
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstFRem) Operands() []*value.Value {
+	return []*value.Value{&inst.X, &inst.Y}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstFRem) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
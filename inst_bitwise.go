@@ -70,6 +70,16 @@ func (inst *InstShl) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstShl) Operands() []*value.Value {
+	return []*value.Value{&inst.X, &inst.Y}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstShl) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ lshr ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstLShr is an LLVM IR lshr instruction.
@@ -129,6 +139,16 @@ func (inst *InstLShr) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstLShr) Operands() []*value.Value {
+	return []*value.Value{&inst.X, &inst.Y}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstLShr) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ ashr ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstAShr is an LLVM IR ashr instruction.
@@ -188,6 +208,16 @@ func (inst *InstAShr) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstAShr) Operands() []*value.Value {
+	return []*value.Value{&inst.X, &inst.Y}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstAShr) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ and ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstAnd is an LLVM IR and instruction.
@@ -241,6 +271,16 @@ func (inst *InstAnd) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstAnd) Operands() []*value.Value {
+	return []*value.Value{&inst.X, &inst.Y}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstAnd) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ or ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstOr is an LLVM IR or instruction.
@@ -294,6 +334,16 @@ func (inst *InstOr) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstOr) Operands() []*value.Value {
+	return []*value.Value{&inst.X, &inst.Y}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstOr) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ xor ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstXor is an LLVM IR xor instruction.
@@ -346,3 +396,13 @@ func (inst *InstXor) LLString() string {
 	}
 	return buf.String()
 }
+
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstXor) Operands() []*value.Value {
+	return []*value.Value{&inst.X, &inst.Y}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstXor) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
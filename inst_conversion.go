@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/wa-lang/llir/types"
 	"github.com/wa-lang/llir/value"
 )
@@ -28,8 +29,18 @@ type InstTrunc struct {
 }
 
 // NewTrunc returns a new trunc instruction based on the given source value and
-// target type.
+// target type. NewTrunc panics if the operands are not compatible.
 func NewTrunc(from value.Value, to types.Type) *InstTrunc {
+	inst, err := TryNewTrunc(from, to)
+	if err != nil {
+		panic(err)
+	}
+	return inst
+}
+
+// TryNewTrunc returns a new trunc instruction based on the given source value
+// and target type, or an error if the operands are not compatible.
+func TryNewTrunc(from value.Value, to types.Type) (*InstTrunc, error) {
 	// Type-check operands.
 	fromType := from.Type()
 	// Note: intentional alias, so we can use it for checking
@@ -38,10 +49,10 @@ func NewTrunc(from value.Value, to types.Type) *InstTrunc {
 	if fromVectorT, ok := fromType.(*types.VectorType); ok {
 		toVectorT, ok := toType.(*types.VectorType)
 		if !ok {
-			panic(fmt.Errorf("trunc operands are not compatible: from=%v; to=%v", fromVectorT, to))
+			return nil, errors.Errorf("trunc operands are not compatible: from=%v; to=%v", fromVectorT, to)
 		}
 		if fromVectorT.Len != toVectorT.Len {
-			panic(fmt.Errorf("trunc vector operand length mismatch: from=%v; to=%v", from.Type(), to))
+			return nil, errors.Errorf("trunc vector operand length mismatch: from=%v; to=%v", from.Type(), to)
 		}
 		fromType = fromVectorT.ElemType
 		toType = toVectorT.ElemType
@@ -49,15 +60,15 @@ func NewTrunc(from value.Value, to types.Type) *InstTrunc {
 	if fromIntT, ok := fromType.(*types.IntType); ok {
 		toIntT, ok := toType.(*types.IntType)
 		if !ok {
-			panic(fmt.Errorf("trunc operands are not compatible: from=%v; to=%T", fromIntT, to))
+			return nil, errors.Errorf("trunc operands are not compatible: from=%v; to=%T", fromIntT, to)
 		}
 		fromSize := fromIntT.BitSize
 		toSize := toIntT.BitSize
 		if fromSize < toSize {
-			panic(fmt.Errorf("invalid trunc operands: from.BitSize < to.BitSize (%v is smaller than %v)", from.Type(), to))
+			return nil, errors.Errorf("invalid trunc operands: from.BitSize < to.BitSize (%v is smaller than %v)", from.Type(), to)
 		}
 	}
-	return &InstTrunc{From: from, To: to}
+	return &InstTrunc{From: from, To: to}, nil
 }
 
 // String returns the LLVM syntax representation of the instruction as a
@@ -84,6 +95,16 @@ func (inst *InstTrunc) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstTrunc) Operands() []*value.Value {
+	return []*value.Value{&inst.From}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstTrunc) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ zext ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstZExt is an LLVM IR zext instruction.
@@ -131,6 +152,16 @@ func (inst *InstZExt) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstZExt) Operands() []*value.Value {
+	return []*value.Value{&inst.From}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstZExt) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ sext ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstSExt is an LLVM IR sext instruction.
@@ -178,6 +209,16 @@ func (inst *InstSExt) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstSExt) Operands() []*value.Value {
+	return []*value.Value{&inst.From}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstSExt) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ fptrunc ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstFPTrunc is an LLVM IR fptrunc instruction.
@@ -225,6 +266,16 @@ func (inst *InstFPTrunc) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstFPTrunc) Operands() []*value.Value {
+	return []*value.Value{&inst.From}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstFPTrunc) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ fpext ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstFPExt is an LLVM IR fpext instruction.
@@ -272,6 +323,16 @@ func (inst *InstFPExt) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstFPExt) Operands() []*value.Value {
+	return []*value.Value{&inst.From}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstFPExt) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ fptoui ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstFPToUI is an LLVM IR fptoui instruction.
@@ -319,6 +380,16 @@ func (inst *InstFPToUI) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstFPToUI) Operands() []*value.Value {
+	return []*value.Value{&inst.From}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstFPToUI) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ fptosi ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstFPToSI is an LLVM IR fptosi instruction.
@@ -366,6 +437,16 @@ func (inst *InstFPToSI) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstFPToSI) Operands() []*value.Value {
+	return []*value.Value{&inst.From}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstFPToSI) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ uitofp ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstUIToFP is an LLVM IR uitofp instruction.
@@ -413,6 +494,16 @@ func (inst *InstUIToFP) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstUIToFP) Operands() []*value.Value {
+	return []*value.Value{&inst.From}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstUIToFP) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ sitofp ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstSIToFP is an LLVM IR sitofp instruction.
@@ -460,6 +551,16 @@ func (inst *InstSIToFP) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstSIToFP) Operands() []*value.Value {
+	return []*value.Value{&inst.From}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstSIToFP) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ ptrtoint ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstPtrToInt is an LLVM IR ptrtoint instruction.
@@ -507,6 +608,16 @@ func (inst *InstPtrToInt) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstPtrToInt) Operands() []*value.Value {
+	return []*value.Value{&inst.From}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstPtrToInt) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ inttoptr ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstIntToPtr is an LLVM IR inttoptr instruction.
@@ -554,6 +665,16 @@ func (inst *InstIntToPtr) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstIntToPtr) Operands() []*value.Value {
+	return []*value.Value{&inst.From}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstIntToPtr) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ bitcast ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstBitCast is an LLVM IR bitcast instruction.
@@ -601,6 +722,16 @@ func (inst *InstBitCast) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstBitCast) Operands() []*value.Value {
+	return []*value.Value{&inst.From}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstBitCast) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ addrspacecast ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstAddrSpaceCast is an LLVM IR addrspacecast instruction.
@@ -647,3 +778,13 @@ func (inst *InstAddrSpaceCast) LLString() string {
 	}
 	return buf.String()
 }
+
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstAddrSpaceCast) Operands() []*value.Value {
+	return []*value.Value{&inst.From}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstAddrSpaceCast) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
@@ -49,3 +49,14 @@ func TestTypeCheckTrunc(t *testing.T) {
 		})
 	}
 }
+
+func TestTryNewTrunc(t *testing.T) {
+	if _, err := TryNewTrunc(constant.NewInt(types.I64, 0), types.I1); err != nil {
+		t.Errorf("unexpected error; %v", err)
+	}
+	_, err := TryNewTrunc(constant.NewInt(types.I32, 0), types.I64)
+	expected := "invalid trunc operands: from.BitSize < to.BitSize (i32 is smaller than i64)"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected %q, got %v", expected, err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/wa-lang/llir/constant"
 	"github.com/wa-lang/llir/enum"
 	"github.com/wa-lang/llir/internal/gep"
@@ -94,6 +95,19 @@ func (inst *InstAlloca) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstAlloca) Operands() []*value.Value {
+	if inst.NElems != nil {
+		return []*value.Value{&inst.NElems}
+	}
+	return nil
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstAlloca) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ load ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstLoad is an LLVM IR load instruction.
@@ -174,6 +188,16 @@ func (inst *InstLoad) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstLoad) Operands() []*value.Value {
+	return []*value.Value{&inst.Src}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstLoad) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ store ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstStore is an LLVM IR store instruction.
@@ -200,17 +224,27 @@ type InstStore struct {
 }
 
 // NewStore returns a new store instruction based on the given source value and
-// destination address.
+// destination address. NewStore panics if the operands are not compatible.
 func NewStore(src, dst value.Value) *InstStore {
+	inst, err := TryNewStore(src, dst)
+	if err != nil {
+		panic(err)
+	}
+	return inst
+}
+
+// TryNewStore returns a new store instruction based on the given source value
+// and destination address, or an error if the operands are not compatible.
+func TryNewStore(src, dst value.Value) (*InstStore, error) {
 	// Type-check operands.
 	dstPtrType, ok := dst.Type().(*types.PointerType)
 	if !ok {
-		panic(fmt.Errorf("invalid store dst operand type; expected *types.Pointer, got %T", dst.Type()))
+		return nil, errors.Errorf("invalid store dst operand type; expected *types.Pointer, got %T", dst.Type())
 	}
 	if !src.Type().Equal(dstPtrType.ElemType) {
-		panic(fmt.Errorf("store operands are not compatible: src=%v; dst=%v", src.Type(), dst.Type()))
+		return nil, errors.Errorf("store operands are not compatible: src=%v; dst=%v", src.Type(), dst.Type())
 	}
-	return &InstStore{Src: src, Dst: dst}
+	return &InstStore{Src: src, Dst: dst}, nil
 }
 
 // LLString returns the LLVM syntax representation of the instruction.
@@ -247,6 +281,16 @@ func (inst *InstStore) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstStore) Operands() []*value.Value {
+	return []*value.Value{&inst.Src, &inst.Dst}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstStore) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ fence ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstFence is an LLVM IR fence instruction.
@@ -283,6 +327,17 @@ func (inst *InstFence) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstFence) Operands() []*value.Value {
+	// no operands.
+	return nil
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstFence) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ cmpxchg ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstCmpXchg is an LLVM IR cmpxchg instruction.
@@ -368,6 +423,16 @@ func (inst *InstCmpXchg) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstCmpXchg) Operands() []*value.Value {
+	return []*value.Value{&inst.Ptr, &inst.Cmp, &inst.New}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstCmpXchg) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ atomicrmw ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstAtomicRMW is an LLVM IR atomicrmw instruction.
@@ -444,6 +509,16 @@ func (inst *InstAtomicRMW) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstAtomicRMW) Operands() []*value.Value {
+	return []*value.Value{&inst.Dst, &inst.X}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstAtomicRMW) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ getelementptr ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstGetElementPtr is an LLVM IR getelementptr instruction.
@@ -476,6 +551,18 @@ func NewGetElementPtr(elemType types.Type, src value.Value, indices ...value.Val
 	return inst
 }
 
+// TryNewGetElementPtr returns a new getelementptr instruction based on the
+// given element type, source address and element indices, or an error if the
+// result type cannot be computed from the operands.
+func TryNewGetElementPtr(elemType types.Type, src value.Value, indices ...value.Value) (*InstGetElementPtr, error) {
+	typ, err := tryGEPInstType(elemType, src.Type(), indices)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	inst := &InstGetElementPtr{ElemType: elemType, Src: src, Indices: indices, Typ: typ}
+	return inst, nil
+}
+
 // String returns the LLVM syntax representation of the instruction as a
 // type-value pair.
 func (inst *InstGetElementPtr) String() string {
@@ -511,12 +598,38 @@ func (inst *InstGetElementPtr) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstGetElementPtr) Operands() []*value.Value {
+	ops := []*value.Value{&inst.Src}
+	for i := range inst.Indices {
+		ops = append(ops, &inst.Indices[i])
+	}
+	return ops
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstGetElementPtr) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ### [ Helper functions ] ####################################################
 
 // gepInstType computes the result type of a getelementptr instruction.
 //
 //    getelementptr ElemType, Src, Indices
 func gepInstType(elemType, src types.Type, indices []value.Value) types.Type {
+	t, err := tryGEPInstType(elemType, src, indices)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// tryGEPInstType computes the result type of a getelementptr instruction, or
+// returns an error if the operands are invalid.
+//
+//    getelementptr ElemType, Src, Indices
+func tryGEPInstType(elemType, src types.Type, indices []value.Value) (types.Type, error) {
 	var idxs []gep.Index
 	for _, index := range indices {
 		var idx gep.Index
@@ -532,7 +645,7 @@ func gepInstType(elemType, src types.Type, indices []value.Value) types.Type {
 		}
 		idxs = append(idxs, idx)
 	}
-	return gep.ResultType(elemType, src, idxs)
+	return gep.TryResultType(elemType, src, idxs)
 }
 
 // NOTE: keep getIndex in sync with getIndex in:
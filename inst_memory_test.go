@@ -44,3 +44,35 @@ func TestTypeCheckStore(t *testing.T) {
 		})
 	}
 }
+
+func TestTryNewStore(t *testing.T) {
+	ptr := NewGlobal("x", types.I32)
+	if _, err := TryNewStore(constant.NewInt(types.I32, 1), ptr); err != nil {
+		t.Errorf("unexpected error; %v", err)
+	}
+	if _, err := TryNewStore(constant.NewInt(types.I64, 1), ptr); err == nil {
+		t.Error("expected error for store of i64 to i32*")
+	}
+	if _, err := TryNewStore(constant.NewInt(types.I32, 1), constant.NewInt(types.I32, 0)); err == nil {
+		t.Error("expected error for store to non-pointer")
+	}
+}
+
+func TestTryNewGetElementPtr(t *testing.T) {
+	structType := types.NewStruct(types.I32, types.I64)
+	src := NewGlobal("s", structType)
+	zero := constant.NewInt(types.I32, 0)
+	gep, err := TryNewGetElementPtr(structType, src, zero, constant.NewInt(types.I32, 1))
+	if err != nil {
+		t.Fatalf("unexpected error; %v", err)
+	}
+	if got, want := gep.Type().String(), "i64*"; got != want {
+		t.Errorf("result type mismatch; expected %q, got %q", want, got)
+	}
+	if _, err := TryNewGetElementPtr(structType, src, zero, constant.NewInt(types.I32, 2)); err == nil {
+		t.Error("expected error for out of bounds struct index")
+	}
+	if _, err := TryNewGetElementPtr(types.I32, zero, zero); err == nil {
+		t.Error("expected error for non-pointer source")
+	}
+}
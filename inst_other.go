@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/wa-lang/llir/enum"
 	"github.com/wa-lang/llir/types"
 	"github.com/wa-lang/llir/value"
@@ -31,14 +32,27 @@ type InstICmp struct {
 }
 
 // NewICmp returns a new icmp instruction based on the given integer comparison
-// predicate and integer scalar or vector operands.
+// predicate and integer scalar or vector operands. NewICmp panics if the
+// operand type is invalid.
 func NewICmp(pred enum.IPred, x, y value.Value) *InstICmp {
-	inst := &InstICmp{Pred: pred, X: x, Y: y}
-	// Compute type.
-	inst.Type()
+	inst, err := TryNewICmp(pred, x, y)
+	if err != nil {
+		panic(err)
+	}
 	return inst
 }
 
+// TryNewICmp returns a new icmp instruction based on the given integer
+// comparison predicate and integer scalar or vector operands, or an error if
+// the operand type is invalid.
+func TryNewICmp(pred enum.IPred, x, y value.Value) (*InstICmp, error) {
+	typ, err := icmpType(x.Type())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &InstICmp{Pred: pred, X: x, Y: y, Typ: typ}, nil
+}
+
 // String returns the LLVM syntax representation of the instruction as a
 // type-value pair.
 func (inst *InstICmp) String() string {
@@ -50,14 +64,11 @@ func (inst *InstICmp) String() string {
 func (inst *InstICmp) Type() types.Type {
 	// Cache type if not present.
 	if inst.Typ == nil {
-		switch xType := inst.X.Type().(type) {
-		case *types.IntType, *types.PointerType:
-			inst.Typ = types.I1
-		case *types.VectorType:
-			inst.Typ = types.NewVector(xType.Len, types.I1)
-		default:
-			panic(fmt.Errorf("invalid icmp operand type; expected *types.IntType, *types.PointerType or *types.VectorType, got %T", xType))
+		typ, err := icmpType(inst.X.Type())
+		if err != nil {
+			panic(err)
 		}
+		inst.Typ = typ
 	}
 	return inst.Typ
 }
@@ -75,6 +86,29 @@ func (inst *InstICmp) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstICmp) Operands() []*value.Value {
+	return []*value.Value{&inst.X, &inst.Y}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstICmp) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
+// icmpType returns the result type of an icmp instruction with operands of the
+// given type, or an error if the operand type is invalid.
+func icmpType(xType types.Type) (types.Type, error) {
+	switch xType := xType.(type) {
+	case *types.IntType, *types.PointerType:
+		return types.I1, nil
+	case *types.VectorType:
+		return types.NewVector(xType.Len, types.I1), nil
+	default:
+		return nil, errors.Errorf("invalid icmp operand type; expected *types.IntType, *types.PointerType or *types.VectorType, got %T", xType)
+	}
+}
+
 // ~~~ [ fcmp ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstFCmp is an LLVM IR fcmp instruction.
@@ -97,14 +131,27 @@ type InstFCmp struct {
 }
 
 // NewFCmp returns a new fcmp instruction based on the given floating-point
-// comparison predicate and floating-point scalar or vector operands.
+// comparison predicate and floating-point scalar or vector operands. NewFCmp
+// panics if the operand type is invalid.
 func NewFCmp(pred enum.FPred, x, y value.Value) *InstFCmp {
-	inst := &InstFCmp{Pred: pred, X: x, Y: y}
-	// Compute type.
-	inst.Type()
+	inst, err := TryNewFCmp(pred, x, y)
+	if err != nil {
+		panic(err)
+	}
 	return inst
 }
 
+// TryNewFCmp returns a new fcmp instruction based on the given floating-point
+// comparison predicate and floating-point scalar or vector operands, or an
+// error if the operand type is invalid.
+func TryNewFCmp(pred enum.FPred, x, y value.Value) (*InstFCmp, error) {
+	typ, err := fcmpType(x.Type())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &InstFCmp{Pred: pred, X: x, Y: y, Typ: typ}, nil
+}
+
 // String returns the LLVM syntax representation of the instruction as a
 // type-value pair.
 func (inst *InstFCmp) String() string {
@@ -116,14 +163,11 @@ func (inst *InstFCmp) String() string {
 func (inst *InstFCmp) Type() types.Type {
 	// Cache type if not present.
 	if inst.Typ == nil {
-		switch xType := inst.X.Type().(type) {
-		case *types.FloatType:
-			inst.Typ = types.I1
-		case *types.VectorType:
-			inst.Typ = types.NewVector(xType.Len, types.I1)
-		default:
-			panic(fmt.Errorf("invalid fcmp operand type; expected *types.FloatType or *types.VectorType, got %T", xType))
+		typ, err := fcmpType(inst.X.Type())
+		if err != nil {
+			panic(err)
 		}
+		inst.Typ = typ
 	}
 	return inst.Typ
 }
@@ -145,6 +189,29 @@ func (inst *InstFCmp) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstFCmp) Operands() []*value.Value {
+	return []*value.Value{&inst.X, &inst.Y}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstFCmp) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
+// fcmpType returns the result type of an fcmp instruction with operands of the
+// given type, or an error if the operand type is invalid.
+func fcmpType(xType types.Type) (types.Type, error) {
+	switch xType := xType.(type) {
+	case *types.FloatType:
+		return types.I1, nil
+	case *types.VectorType:
+		return types.NewVector(xType.Len, types.I1), nil
+	default:
+		return nil, errors.Errorf("invalid fcmp operand type; expected *types.FloatType or *types.VectorType, got %T", xType)
+	}
+}
+
 // ~~~ [ phi ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstPhi is an LLVM IR phi instruction.
@@ -213,6 +280,20 @@ func (inst *InstPhi) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstPhi) Operands() []*value.Value {
+	ops := make([]*value.Value, 0, 2*len(inst.Incs))
+	for _, inc := range inst.Incs {
+		ops = append(ops, &inc.X, &inc.Pred)
+	}
+	return ops
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstPhi) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ___ [ Incoming value ] ______________________________________________________
 
 // Incoming is an incoming value of a phi instruction.
@@ -299,6 +380,16 @@ func (inst *InstSelect) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstSelect) Operands() []*value.Value {
+	return []*value.Value{&inst.Cond, &inst.ValueTrue, &inst.ValueFalse}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstSelect) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ freeze ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstFreeze is an LLVM IR freeze instruction.
@@ -352,6 +443,16 @@ func (inst *InstFreeze) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstFreeze) Operands() []*value.Value {
+	return []*value.Value{&inst.X}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstFreeze) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ call ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstCall is an LLVM IR call instruction.
@@ -392,16 +493,28 @@ type InstCall struct {
 }
 
 // NewCall returns a new call instruction based on the given callee and function
-// arguments.
+// arguments. NewCall panics if the callee type is invalid.
 //
 // TODO: specify the set of underlying types of callee.
 func NewCall(callee value.Value, args ...value.Value) *InstCall {
-	inst := &InstCall{Callee: callee, Args: args}
-	// Compute type.
-	inst.Type()
+	inst, err := TryNewCall(callee, args...)
+	if err != nil {
+		panic(err)
+	}
 	return inst
 }
 
+// TryNewCall returns a new call instruction based on the given callee and
+// function arguments, or an error if the callee type is invalid; i.e. not a
+// pointer to a function type.
+func TryNewCall(callee value.Value, args ...value.Value) (*InstCall, error) {
+	sig, err := calleeSig(callee)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &InstCall{Callee: callee, Args: args, Typ: sig.RetType}, nil
+}
+
 // String returns the LLVM syntax representation of the instruction as a
 // type-value pair.
 func (inst *InstCall) String() string {
@@ -475,17 +588,41 @@ func (inst *InstCall) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstCall) Operands() []*value.Value {
+	ops := []*value.Value{&inst.Callee}
+	for i := range inst.Args {
+		ops = append(ops, &inst.Args[i])
+	}
+	return append(ops, operandBundleOperands(inst.OperandBundles)...)
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstCall) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // Sig returns the function signature of the callee.
 func (inst *InstCall) Sig() *types.FuncType {
-	t, ok := inst.Callee.Type().(*types.PointerType)
+	sig, err := calleeSig(inst.Callee)
+	if err != nil {
+		panic(err)
+	}
+	return sig
+}
+
+// calleeSig returns the function signature of the given callee, or an error if
+// the callee type is invalid.
+func calleeSig(callee value.Value) (*types.FuncType, error) {
+	t, ok := callee.Type().(*types.PointerType)
 	if !ok {
-		panic(fmt.Errorf("invalid callee type; expected *types.PointerType, got %T", inst.Callee.Type()))
+		return nil, errors.Errorf("invalid callee type; expected *types.PointerType, got %T", callee.Type())
 	}
 	sig, ok := t.ElemType.(*types.FuncType)
 	if !ok {
-		panic(fmt.Errorf("invalid callee type; expected *types.FuncType, got %T", t.ElemType))
+		return nil, errors.Errorf("invalid callee type; expected *types.FuncType, got %T", t.ElemType)
 	}
-	return sig
+	return sig, nil
 }
 
 // ~~~ [ va_arg ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -535,6 +672,16 @@ func (inst *InstVAArg) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstVAArg) Operands() []*value.Value {
+	return []*value.Value{&inst.ArgList}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstVAArg) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ landingpad ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstLandingPad is an LLVM IR landingpad instruction.
@@ -591,6 +738,20 @@ func (inst *InstLandingPad) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstLandingPad) Operands() []*value.Value {
+	ops := make([]*value.Value, 0, len(inst.Clauses))
+	for _, clause := range inst.Clauses {
+		ops = append(ops, &clause.X)
+	}
+	return ops
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstLandingPad) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ___ [ Landingpad clause ] ___________________________________________________
 
 // Clause is a landingpad catch or filter clause.
@@ -670,6 +831,20 @@ func (inst *InstCatchPad) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstCatchPad) Operands() []*value.Value {
+	ops := []*value.Value{&inst.CatchSwitch}
+	for i := range inst.Args {
+		ops = append(ops, &inst.Args[i])
+	}
+	return ops
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstCatchPad) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ cleanuppad ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstCleanupPad is an LLVM IR cleanuppad instruction.
@@ -727,3 +902,17 @@ func (inst *InstCleanupPad) LLString() string {
 	}
 	return buf.String()
 }
+
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstCleanupPad) Operands() []*value.Value {
+	ops := []*value.Value{&inst.ParentPad}
+	for i := range inst.Args {
+		ops = append(ops, &inst.Args[i])
+	}
+	return ops
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstCleanupPad) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
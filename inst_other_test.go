@@ -0,0 +1,57 @@
+package llir
+
+import (
+	"testing"
+
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/types"
+)
+
+func TestTryNewICmp(t *testing.T) {
+	x := constant.NewInt(types.I32, 1)
+	cmp, err := TryNewICmp(enum.IPredEQ, x, x)
+	if err != nil {
+		t.Fatalf("unexpected error; %v", err)
+	}
+	if !cmp.Type().Equal(types.I1) {
+		t.Errorf("result type mismatch; expected i1, got %v", cmp.Type())
+	}
+	f := constant.NewFloat(types.Double, 1)
+	if _, err := TryNewICmp(enum.IPredEQ, f, f); err == nil {
+		t.Error("expected error for icmp of double operands")
+	}
+}
+
+func TestTryNewFCmp(t *testing.T) {
+	vec := constant.NewZeroInitializer(types.NewVector(4, types.Float))
+	cmp, err := TryNewFCmp(enum.FPredOEQ, vec, vec)
+	if err != nil {
+		t.Fatalf("unexpected error; %v", err)
+	}
+	if got, want := cmp.Type().String(), "<4 x i1>"; got != want {
+		t.Errorf("result type mismatch; expected %q, got %q", want, got)
+	}
+	x := constant.NewInt(types.I32, 1)
+	if _, err := TryNewFCmp(enum.FPredOEQ, x, x); err == nil {
+		t.Error("expected error for fcmp of i32 operands")
+	}
+}
+
+func TestTryNewCall(t *testing.T) {
+	f := NewFunc("f", types.I64, NewParam("x", types.I32))
+	x := constant.NewInt(types.I32, 1)
+	call, err := TryNewCall(f, x)
+	if err != nil {
+		t.Fatalf("unexpected error; %v", err)
+	}
+	if !call.Type().Equal(types.I64) {
+		t.Errorf("result type mismatch; expected i64, got %v", call.Type())
+	}
+	if _, err := TryNewCall(x); err == nil {
+		t.Error("expected error for call of non-pointer callee")
+	}
+	if _, err := TryNewCall(NewGlobal("g", types.I32)); err == nil {
+		t.Error("expected error for call of non-function pointer callee")
+	}
+}
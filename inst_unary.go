@@ -69,3 +69,13 @@ func (inst *InstFNeg) LLString() string {
 	}
 	return buf.String()
 }
+
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstFNeg) Operands() []*value.Value {
+	return []*value.Value{&inst.X}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstFNeg) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
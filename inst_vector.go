@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/wa-lang/llir/types"
 	"github.com/wa-lang/llir/value"
 )
@@ -30,14 +31,25 @@ type InstExtractElement struct {
 }
 
 // NewExtractElement returns a new extractelement instruction based on the given
-// vector and element index.
+// vector and element index. NewExtractElement panics if x is not a vector.
 func NewExtractElement(x, index value.Value) *InstExtractElement {
-	inst := &InstExtractElement{X: x, Index: index}
-	// Compute type.
-	inst.Type()
+	inst, err := TryNewExtractElement(x, index)
+	if err != nil {
+		panic(err)
+	}
 	return inst
 }
 
+// TryNewExtractElement returns a new extractelement instruction based on the
+// given vector and element index, or an error if x is not a vector.
+func TryNewExtractElement(x, index value.Value) (*InstExtractElement, error) {
+	t, err := vectorType(x)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &InstExtractElement{X: x, Index: index, Typ: t.ElemType}, nil
+}
+
 // String returns the LLVM syntax representation of the instruction as a
 // type-value pair.
 func (inst *InstExtractElement) String() string {
@@ -48,9 +60,9 @@ func (inst *InstExtractElement) String() string {
 func (inst *InstExtractElement) Type() types.Type {
 	// Cache type if not present.
 	if inst.Typ == nil {
-		t, ok := inst.X.Type().(*types.VectorType)
-		if !ok {
-			panic(fmt.Errorf("invalid vector type; expected *types.VectorType, got %T", inst.X.Type()))
+		t, err := vectorType(inst.X)
+		if err != nil {
+			panic(err)
 		}
 		inst.Typ = t.ElemType
 	}
@@ -70,6 +82,16 @@ func (inst *InstExtractElement) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstExtractElement) Operands() []*value.Value {
+	return []*value.Value{&inst.X, &inst.Index}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstExtractElement) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ insertelement ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstInsertElement is an LLVM IR insertelement instruction.
@@ -92,14 +114,26 @@ type InstInsertElement struct {
 }
 
 // NewInsertElement returns a new insertelement instruction based on the given
-// vector, element and element index.
+// vector, element and element index. NewInsertElement panics if x is not a
+// vector.
 func NewInsertElement(x, elem, index value.Value) *InstInsertElement {
-	inst := &InstInsertElement{X: x, Elem: elem, Index: index}
-	// Compute type.
-	inst.Type()
+	inst, err := TryNewInsertElement(x, elem, index)
+	if err != nil {
+		panic(err)
+	}
 	return inst
 }
 
+// TryNewInsertElement returns a new insertelement instruction based on the
+// given vector, element and element index, or an error if x is not a vector.
+func TryNewInsertElement(x, elem, index value.Value) (*InstInsertElement, error) {
+	t, err := vectorType(x)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &InstInsertElement{X: x, Elem: elem, Index: index, Typ: t}, nil
+}
+
 // String returns the LLVM syntax representation of the instruction as a
 // type-value pair.
 func (inst *InstInsertElement) String() string {
@@ -110,9 +144,9 @@ func (inst *InstInsertElement) String() string {
 func (inst *InstInsertElement) Type() types.Type {
 	// Cache type if not present.
 	if inst.Typ == nil {
-		t, ok := inst.X.Type().(*types.VectorType)
-		if !ok {
-			panic(fmt.Errorf("invalid vector type; expected *types.VectorType, got %T", inst.X.Type()))
+		t, err := vectorType(inst.X)
+		if err != nil {
+			panic(err)
 		}
 		inst.Typ = t
 	}
@@ -133,6 +167,16 @@ func (inst *InstInsertElement) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstInsertElement) Operands() []*value.Value {
+	return []*value.Value{&inst.X, &inst.Elem, &inst.Index}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstInsertElement) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
 // ~~~ [ shufflevector ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstShuffleVector is an LLVM IR shufflevector instruction.
@@ -153,14 +197,26 @@ type InstShuffleVector struct {
 }
 
 // NewShuffleVector returns a new shufflevector instruction based on the given
-// vectors and shuffle mask.
+// vectors and shuffle mask. NewShuffleVector panics if x or mask is not a
+// vector.
 func NewShuffleVector(x, y, mask value.Value) *InstShuffleVector {
-	inst := &InstShuffleVector{X: x, Y: y, Mask: mask}
-	// Compute type.
-	inst.Type()
+	inst, err := TryNewShuffleVector(x, y, mask)
+	if err != nil {
+		panic(err)
+	}
 	return inst
 }
 
+// TryNewShuffleVector returns a new shufflevector instruction based on the
+// given vectors and shuffle mask, or an error if x or mask is not a vector.
+func TryNewShuffleVector(x, y, mask value.Value) (*InstShuffleVector, error) {
+	typ, err := shuffleVectorType(x, mask)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &InstShuffleVector{X: x, Y: y, Mask: mask, Typ: typ}, nil
+}
+
 // String returns the LLVM syntax representation of the instruction as a
 // type-value pair.
 func (inst *InstShuffleVector) String() string {
@@ -171,15 +227,11 @@ func (inst *InstShuffleVector) String() string {
 func (inst *InstShuffleVector) Type() types.Type {
 	// Cache type if not present.
 	if inst.Typ == nil {
-		xType, ok := inst.X.Type().(*types.VectorType)
-		if !ok {
-			panic(fmt.Errorf("invalid vector type; expected *types.VectorType, got %T", inst.X.Type()))
+		typ, err := shuffleVectorType(inst.X, inst.Mask)
+		if err != nil {
+			panic(err)
 		}
-		maskType, ok := inst.Mask.Type().(*types.VectorType)
-		if !ok {
-			panic(fmt.Errorf("invalid vector type; expected *types.VectorType, got %T", inst.Mask.Type()))
-		}
-		inst.Typ = types.NewVector(maskType.Len, xType.ElemType)
+		inst.Typ = typ
 	}
 	return inst.Typ
 }
@@ -197,3 +249,38 @@ func (inst *InstShuffleVector) LLString() string {
 	}
 	return buf.String()
 }
+
+// Operands returns a mutable list of operands of the given instruction.
+func (inst *InstShuffleVector) Operands() []*value.Value {
+	return []*value.Value{&inst.X, &inst.Y, &inst.Mask}
+}
+
+// SetOperand sets the i-th operand of the given instruction to v.
+func (inst *InstShuffleVector) SetOperand(i int, v value.Value) {
+	setOperand(inst.Operands(), i, v)
+}
+
+// shuffleVectorType returns the result type of a shufflevector instruction
+// with the given vector and shuffle mask, or an error if x or mask is not a
+// vector.
+func shuffleVectorType(x, mask value.Value) (*types.VectorType, error) {
+	xType, err := vectorType(x)
+	if err != nil {
+		return nil, err
+	}
+	maskType, err := vectorType(mask)
+	if err != nil {
+		return nil, err
+	}
+	return types.NewVector(maskType.Len, xType.ElemType), nil
+}
+
+// vectorType returns the vector type of the given value, or an error if the
+// value is not a vector.
+func vectorType(x value.Value) (*types.VectorType, error) {
+	t, ok := x.Type().(*types.VectorType)
+	if !ok {
+		return nil, errors.Errorf("invalid vector type; expected *types.VectorType, got %T", x.Type())
+	}
+	return t, nil
+}
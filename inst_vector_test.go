@@ -0,0 +1,45 @@
+package llir
+
+import (
+	"testing"
+
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/types"
+)
+
+func TestTryNewVectorInsts(t *testing.T) {
+	vec := constant.NewZeroInitializer(types.NewVector(4, types.I32))
+	mask := constant.NewZeroInitializer(types.NewVector(2, types.I32))
+	x := constant.NewInt(types.I32, 1)
+	zero := constant.NewInt(types.I32, 0)
+	extract, err := TryNewExtractElement(vec, zero)
+	if err != nil {
+		t.Fatalf("unexpected error; %v", err)
+	}
+	if !extract.Type().Equal(types.I32) {
+		t.Errorf("extractelement result type mismatch; expected i32, got %v", extract.Type())
+	}
+	insert, err := TryNewInsertElement(vec, x, zero)
+	if err != nil {
+		t.Fatalf("unexpected error; %v", err)
+	}
+	if !insert.Type().Equal(vec.Type()) {
+		t.Errorf("insertelement result type mismatch; expected %v, got %v", vec.Type(), insert.Type())
+	}
+	shuffle, err := TryNewShuffleVector(vec, vec, mask)
+	if err != nil {
+		t.Fatalf("unexpected error; %v", err)
+	}
+	if got, want := shuffle.Type().String(), "<2 x i32>"; got != want {
+		t.Errorf("shufflevector result type mismatch; expected %q, got %q", want, got)
+	}
+	if _, err := TryNewExtractElement(x, zero); err == nil {
+		t.Error("expected error for extractelement of non-vector")
+	}
+	if _, err := TryNewInsertElement(x, x, zero); err == nil {
+		t.Error("expected error for insertelement of non-vector")
+	}
+	if _, err := TryNewShuffleVector(vec, vec, x); err == nil {
+		t.Error("expected error for shufflevector with non-vector mask")
+	}
+}
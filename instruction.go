@@ -1,5 +1,7 @@
 package llir
 
+import "github.com/wa-lang/llir/value"
+
 // === [ Instructions ] ========================================================
 
 // Instruction is an LLVM IR instruction. All instructions (except store and
@@ -103,6 +105,12 @@ package llir
 //    *ir.InstCleanupPad   // https://godoc.org/github.com/wa-lang/llir#InstCleanupPad
 type Instruction interface {
 	LLStringer
+	// Operands returns a mutable list of operands of the instruction. Each
+	// element points to an operand slot, and may thus be used to rewrite the
+	// operand in place.
+	Operands() []*value.Value
+	// SetOperand sets the i-th operand of the instruction to v.
+	SetOperand(i int, v value.Value)
 	// isInstruction ensures that only instructions can be assigned to the
 	// instruction.Instruction interface.
 	isInstruction()
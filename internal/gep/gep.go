@@ -35,10 +35,22 @@ func NewIndex(val int64) Index {
 }
 
 // ResultType computes the result type of a getelementptr instruction or
-// constant expression.
+// constant expression. ResultType panics if the operands are invalid.
 //
 //    getelementptr (ElemType, Src, Indices)
 func ResultType(elemType, src types.Type, indices []Index) types.Type {
+	t, err := TryResultType(elemType, src, indices)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// TryResultType computes the result type of a getelementptr instruction or
+// constant expression, or returns an error if the operands are invalid.
+//
+//    getelementptr (ElemType, Src, Indices)
+func TryResultType(elemType, src types.Type, indices []Index) (types.Type, error) {
 	// ref: http://llvm.org/docs/GetElementPtr.html#what-effect-do-address-spaces-have-on-geps
 	//
 	// > the address space qualifier on the second operand pointer type always
@@ -58,12 +70,12 @@ func ResultType(elemType, src types.Type, indices []Index) types.Type {
 	case *types.VectorType:
 		vectorElemType, ok := src.ElemType.(*types.PointerType)
 		if !ok {
-			panic(fmt.Errorf("invalid gep source vector element type; expected *types.PointerType, got %T", src.ElemType))
+			return nil, fmt.Errorf("invalid gep source vector element type; expected *types.PointerType, got %T", src.ElemType)
 		}
 		addrSpace = vectorElemType.AddrSpace
 		resultVectorLength = src.Len
 	default:
-		panic(fmt.Errorf("invalid gep source type; expected pointer or vector of pointers type, got %T", src))
+		return nil, fmt.Errorf("invalid gep source type; expected pointer or vector of pointers type, got %T", src)
 	}
 	// ref: https://llvm.org/docs/LangRef.html#getelementptr-instruction
 	//
@@ -79,7 +91,7 @@ func ResultType(elemType, src types.Type, indices []Index) types.Type {
 		// > and every scalar argument will be effectively broadcast into a vector
 		// > during address calculation.
 		if index.VectorLen != 0 && resultVectorLength != 0 && index.VectorLen != resultVectorLength {
-			panic(fmt.Errorf("vector length mismatch of index vector (%d) and result type vector (%d)", index.VectorLen, resultVectorLength))
+			return nil, fmt.Errorf("vector length mismatch of index vector (%d) and result type vector (%d)", index.VectorLen, resultVectorLength)
 		}
 		if resultVectorLength == 0 && index.VectorLen != 0 {
 			resultVectorLength = index.VectorLen
@@ -93,7 +105,7 @@ func ResultType(elemType, src types.Type, indices []Index) types.Type {
 		}
 		switch elm := e.(type) {
 		case *types.PointerType:
-			panic(fmt.Errorf("cannot index into pointer type at %d:th gep index, only valid at 0:th gep index; see https://llvm.org/docs/GetElementPtr.html#what-is-dereferenced-by-gep", i))
+			return nil, fmt.Errorf("cannot index into pointer type at %d:th gep index, only valid at 0:th gep index; see https://llvm.org/docs/GetElementPtr.html#what-is-dereferenced-by-gep", i)
 		case *types.VectorType:
 			// ref: https://llvm.org/docs/GetElementPtr.html#can-gep-index-into-vector-elements
 			//
@@ -112,18 +124,21 @@ func ResultType(elemType, src types.Type, indices []Index) types.Type {
 			// > integer constants are allowed (when using a vector of indices they
 			// > must all be the same i32 integer constant).
 			if !index.HasVal {
-				panic(fmt.Errorf("unable to index into struct type `%v` using gep with non-constant index", e))
+				return nil, fmt.Errorf("unable to index into struct type `%v` using gep with non-constant index", e)
+			}
+			if index.Val < 0 || index.Val >= int64(len(elm.Fields)) {
+				return nil, fmt.Errorf("struct index %d out of bounds of struct type `%v` at %d:th gep index", index.Val, e, i)
 			}
 			e = elm.Fields[index.Val]
 		default:
-			panic(fmt.Errorf("cannot index into type %T using gep", e))
+			return nil, fmt.Errorf("cannot index into type %T using gep", e)
 		}
 	}
 	ptr := types.NewPointer(e)
 	ptr.AddrSpace = addrSpace
 	if resultVectorLength != 0 {
 		vec := types.NewVector(resultVectorLength, ptr)
-		return vec
+		return vec, nil
 	}
-	return ptr
+	return ptr, nil
 }
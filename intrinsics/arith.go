@@ -0,0 +1,296 @@
+package intrinsics
+
+import (
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/types"
+	"github.com/wa-lang/llir/value"
+)
+
+// --- [ Arithmetic with overflow intrinsics ] ---------------------------------
+
+// SAddWithOverflow appends a call to llvm.sadd.with.overflow to the basic block,
+// which performs a signed addition of x and y. The result is a struct of the
+// sum and a boolean indicating whether signed overflow occurred.
+//
+//    declare {i32, i1} @llvm.sadd.with.overflow.i32(i32, i32)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-sadd-with-overflow-intrinsics
+func SAddWithOverflow(block *llir.Block, x, y value.Value) *llir.InstCall {
+	return withOverflow(block, "llvm.sadd.with.overflow", x, y)
+}
+
+// UAddWithOverflow appends a call to llvm.uadd.with.overflow to the basic
+// block, which performs an unsigned addition of x and y. The result is a struct
+// of the sum and a boolean indicating whether unsigned overflow occurred.
+//
+//    declare {i32, i1} @llvm.uadd.with.overflow.i32(i32, i32)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-uadd-with-overflow-intrinsics
+func UAddWithOverflow(block *llir.Block, x, y value.Value) *llir.InstCall {
+	return withOverflow(block, "llvm.uadd.with.overflow", x, y)
+}
+
+// SSubWithOverflow appends a call to llvm.ssub.with.overflow to the basic block,
+// which performs a signed subtraction of x and y. The result is a struct of the
+// difference and a boolean indicating whether signed overflow occurred.
+//
+//    declare {i32, i1} @llvm.ssub.with.overflow.i32(i32, i32)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-ssub-with-overflow-intrinsics
+func SSubWithOverflow(block *llir.Block, x, y value.Value) *llir.InstCall {
+	return withOverflow(block, "llvm.ssub.with.overflow", x, y)
+}
+
+// USubWithOverflow appends a call to llvm.usub.with.overflow to the basic
+// block, which performs an unsigned subtraction of x and y. The result is a
+// struct of the difference and a boolean indicating whether unsigned overflow
+// occurred.
+//
+//    declare {i32, i1} @llvm.usub.with.overflow.i32(i32, i32)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-usub-with-overflow-intrinsics
+func USubWithOverflow(block *llir.Block, x, y value.Value) *llir.InstCall {
+	return withOverflow(block, "llvm.usub.with.overflow", x, y)
+}
+
+// SMulWithOverflow appends a call to llvm.smul.with.overflow to the basic block,
+// which performs a signed multiplication of x and y. The result is a struct of
+// the product and a boolean indicating whether signed overflow occurred.
+//
+//    declare {i32, i1} @llvm.smul.with.overflow.i32(i32, i32)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-smul-with-overflow-intrinsics
+func SMulWithOverflow(block *llir.Block, x, y value.Value) *llir.InstCall {
+	return withOverflow(block, "llvm.smul.with.overflow", x, y)
+}
+
+// UMulWithOverflow appends a call to llvm.umul.with.overflow to the basic
+// block, which performs an unsigned multiplication of x and y. The result is a
+// struct of the product and a boolean indicating whether unsigned overflow
+// occurred.
+//
+//    declare {i32, i1} @llvm.umul.with.overflow.i32(i32, i32)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-umul-with-overflow-intrinsics
+func UMulWithOverflow(block *llir.Block, x, y value.Value) *llir.InstCall {
+	return withOverflow(block, "llvm.umul.with.overflow", x, y)
+}
+
+// withOverflow appends a call to the given arithmetic with overflow intrinsic
+// to the basic block.
+func withOverflow(block *llir.Block, base string, x, y value.Value) *llir.InstCall {
+	t := x.Type()
+	name := Name(base, t)
+	retType := types.NewStruct(t, overflowType(t))
+	return call(block, name, retType, params(t, t), pureAttrs, x, y)
+}
+
+// overflowType returns the type of the overflow bit of an arithmetic with
+// overflow intrinsic with operands of the given type.
+func overflowType(t types.Type) types.Type {
+	if t, ok := t.(*types.VectorType); ok {
+		return &types.VectorType{Scalable: t.Scalable, Len: t.Len, ElemType: types.I1}
+	}
+	return types.I1
+}
+
+// --- [ Saturation arithmetic intrinsics ] ------------------------------------
+
+// SAddSat appends a call to llvm.sadd.sat to the basic block, which performs a
+// signed saturating addition of x and y.
+//
+//    declare i32 @llvm.sadd.sat.i32(i32, i32)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-sadd-sat-intrinsics
+func SAddSat(block *llir.Block, x, y value.Value) *llir.InstCall {
+	return binary(block, "llvm.sadd.sat", x, y)
+}
+
+// UAddSat appends a call to llvm.uadd.sat to the basic block, which performs an
+// unsigned saturating addition of x and y.
+//
+//    declare i32 @llvm.uadd.sat.i32(i32, i32)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-uadd-sat-intrinsics
+func UAddSat(block *llir.Block, x, y value.Value) *llir.InstCall {
+	return binary(block, "llvm.uadd.sat", x, y)
+}
+
+// SSubSat appends a call to llvm.ssub.sat to the basic block, which performs a
+// signed saturating subtraction of x and y.
+//
+//    declare i32 @llvm.ssub.sat.i32(i32, i32)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-ssub-sat-intrinsics
+func SSubSat(block *llir.Block, x, y value.Value) *llir.InstCall {
+	return binary(block, "llvm.ssub.sat", x, y)
+}
+
+// USubSat appends a call to llvm.usub.sat to the basic block, which performs an
+// unsigned saturating subtraction of x and y.
+//
+//    declare i32 @llvm.usub.sat.i32(i32, i32)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-usub-sat-intrinsics
+func USubSat(block *llir.Block, x, y value.Value) *llir.InstCall {
+	return binary(block, "llvm.usub.sat", x, y)
+}
+
+// --- [ Integer min/max intrinsics ] ------------------------------------------
+
+// SMax appends a call to llvm.smax to the basic block, which returns the larger
+// of x and y, comparing the values as signed integers.
+//
+//    declare i32 @llvm.smax.i32(i32, i32)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-smax-intrinsic
+func SMax(block *llir.Block, x, y value.Value) *llir.InstCall {
+	return binary(block, "llvm.smax", x, y)
+}
+
+// SMin appends a call to llvm.smin to the basic block, which returns the
+// smaller of x and y, comparing the values as signed integers.
+//
+//    declare i32 @llvm.smin.i32(i32, i32)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-smin-intrinsic
+func SMin(block *llir.Block, x, y value.Value) *llir.InstCall {
+	return binary(block, "llvm.smin", x, y)
+}
+
+// UMax appends a call to llvm.umax to the basic block, which returns the larger
+// of x and y, comparing the values as unsigned integers.
+//
+//    declare i32 @llvm.umax.i32(i32, i32)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-umax-intrinsic
+func UMax(block *llir.Block, x, y value.Value) *llir.InstCall {
+	return binary(block, "llvm.umax", x, y)
+}
+
+// UMin appends a call to llvm.umin to the basic block, which returns the
+// smaller of x and y, comparing the values as unsigned integers.
+//
+//    declare i32 @llvm.umin.i32(i32, i32)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-umin-intrinsic
+func UMin(block *llir.Block, x, y value.Value) *llir.InstCall {
+	return binary(block, "llvm.umin", x, y)
+}
+
+// Abs appends a call to llvm.abs to the basic block, which returns the absolute
+// value of x. If isIntMinPoison is set, the result is poison if x is the
+// minimum signed integer value.
+//
+//    declare i32 @llvm.abs.i32(i32, i1 immarg)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-abs-intrinsic
+func Abs(block *llir.Block, x value.Value, isIntMinPoison bool) *llir.InstCall {
+	t := x.Type()
+	ps := []*Param{NewParam(t), NewParam(types.I1, enum.ParamAttrImmArg)}
+	return call(block, Name("llvm.abs", t), t, ps, pureAttrs, x, boolArg(isIntMinPoison))
+}
+
+// --- [ Bit manipulation intrinsics ] -----------------------------------------
+
+// Ctpop appends a call to llvm.ctpop to the basic block, which counts the
+// number of bits set in x.
+//
+//    declare i64 @llvm.ctpop.i64(i64)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-ctpop-intrinsic
+func Ctpop(block *llir.Block, x value.Value) *llir.InstCall {
+	return unary(block, "llvm.ctpop", x)
+}
+
+// Ctlz appends a call to llvm.ctlz to the basic block, which counts the number
+// of leading zero bits of x. If isZeroPoison is set, the result is poison if x
+// is zero.
+//
+//    declare i32 @llvm.ctlz.i32(i32, i1 immarg)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-ctlz-intrinsic
+func Ctlz(block *llir.Block, x value.Value, isZeroPoison bool) *llir.InstCall {
+	t := x.Type()
+	ps := []*Param{NewParam(t), NewParam(types.I1, enum.ParamAttrImmArg)}
+	return call(block, Name("llvm.ctlz", t), t, ps, pureAttrs, x, boolArg(isZeroPoison))
+}
+
+// Cttz appends a call to llvm.cttz to the basic block, which counts the number
+// of trailing zero bits of x. If isZeroPoison is set, the result is poison if x
+// is zero.
+//
+//    declare i32 @llvm.cttz.i32(i32, i1 immarg)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-cttz-intrinsic
+func Cttz(block *llir.Block, x value.Value, isZeroPoison bool) *llir.InstCall {
+	t := x.Type()
+	ps := []*Param{NewParam(t), NewParam(types.I1, enum.ParamAttrImmArg)}
+	return call(block, Name("llvm.cttz", t), t, ps, pureAttrs, x, boolArg(isZeroPoison))
+}
+
+// Bswap appends a call to llvm.bswap to the basic block, which swaps the byte
+// order of x.
+//
+//    declare i32 @llvm.bswap.i32(i32)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-bswap-intrinsics
+func Bswap(block *llir.Block, x value.Value) *llir.InstCall {
+	return unary(block, "llvm.bswap", x)
+}
+
+// Bitreverse appends a call to llvm.bitreverse to the basic block, which
+// reverses the bit order of x.
+//
+//    declare i32 @llvm.bitreverse.i32(i32)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-bitreverse-intrinsics
+func Bitreverse(block *llir.Block, x value.Value) *llir.InstCall {
+	return unary(block, "llvm.bitreverse", x)
+}
+
+// Fshl appends a call to llvm.fshl to the basic block, which concatenates x and
+// y, shifts the result left by the shift amount (modulo the bit width), and
+// returns the most significant bits.
+//
+//    declare i32 @llvm.fshl.i32(i32, i32, i32)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-fshl-intrinsic
+func Fshl(block *llir.Block, x, y, shift value.Value) *llir.InstCall {
+	return ternary(block, "llvm.fshl", x, y, shift)
+}
+
+// Fshr appends a call to llvm.fshr to the basic block, which concatenates x and
+// y, shifts the result right by the shift amount (modulo the bit width), and
+// returns the least significant bits.
+//
+//    declare i32 @llvm.fshr.i32(i32, i32, i32)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-fshr-intrinsic
+func Fshr(block *llir.Block, x, y, shift value.Value) *llir.InstCall {
+	return ternary(block, "llvm.fshr", x, y, shift)
+}
+
+// ### [ Helper functions ] ####################################################
+
+// unary appends a call to the given pure intrinsic, which is overloaded on the
+// type of its only operand and result, to the basic block.
+func unary(block *llir.Block, base string, x value.Value) *llir.InstCall {
+	t := x.Type()
+	return call(block, Name(base, t), t, params(t), pureAttrs, x)
+}
+
+// binary appends a call to the given pure intrinsic, which is overloaded on the
+// type of its two operands and result, to the basic block.
+func binary(block *llir.Block, base string, x, y value.Value) *llir.InstCall {
+	t := x.Type()
+	return call(block, Name(base, t), t, params(t, t), pureAttrs, x, y)
+}
+
+// ternary appends a call to the given pure intrinsic, which is overloaded on
+// the type of its three operands and result, to the basic block.
+func ternary(block *llir.Block, base string, x, y, z value.Value) *llir.InstCall {
+	t := x.Type()
+	return call(block, Name(base, t), t, params(t, t, t), pureAttrs, x, y, z)
+}
@@ -0,0 +1,123 @@
+package intrinsics
+
+import (
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/types"
+	"github.com/wa-lang/llir/value"
+)
+
+// --- [ Coroutine intrinsics ] ------------------------------------------------
+
+// Attributes of coroutine intrinsics which must not be moved or merged by
+// optimizations prior to coroutine splitting.
+var coroAttrs = []llir.FuncAttribute{enum.FuncAttrNoUnwind}
+
+// CoroID appends a call to llvm.coro.id to the basic block, which returns a
+// token identifying the switched-resume coroutine of the function. The
+// alignment of the coroutine frame (or 0 for the default alignment) and an
+// optional promise alloca are specified; the remaining arguments are filled in
+// by coroutine lowering.
+//
+//    declare token @llvm.coro.id(i32, i8* readnone, i8* nocapture readonly, i8*)
+//
+// ref: https://llvm.org/docs/Coroutines.html#llvm-coro-id-intrinsic
+func CoroID(block *llir.Block, align int64, promise value.Value) *llir.InstCall {
+	null := constant.NewNull(types.I8Ptr)
+	if promise == nil {
+		promise = null
+	}
+	ps := []*Param{
+		NewParam(types.I32),
+		NewParam(types.I8Ptr, enum.ParamAttrReadNone),
+		NewParam(types.I8Ptr, enum.ParamAttrNoCapture, enum.ParamAttrReadOnly),
+		NewParam(types.I8Ptr),
+	}
+	attrs := []llir.FuncAttribute{enum.FuncAttrArgMemOnly, enum.FuncAttrNoUnwind, enum.FuncAttrReadOnly}
+	return call(block, "llvm.coro.id", types.Token, ps, attrs, constant.NewInt(types.I32, align), promise, null, null)
+}
+
+// CoroAlloc appends a call to llvm.coro.alloc to the basic block, which reports
+// whether the coroutine frame of the given coroutine requires dynamic
+// allocation.
+//
+//    declare i1 @llvm.coro.alloc(token)
+//
+// ref: https://llvm.org/docs/Coroutines.html#llvm-coro-alloc-intrinsic
+func CoroAlloc(block *llir.Block, id value.Value) *llir.InstCall {
+	return call(block, "llvm.coro.alloc", types.I1, params(types.Token), coroAttrs, id)
+}
+
+// CoroSize appends a call to llvm.coro.size to the basic block, which returns
+// the size in bytes of the coroutine frame, as an integer of the given type.
+//
+//    declare i32 @llvm.coro.size.i32()
+//    declare i64 @llvm.coro.size.i64()
+//
+// ref: https://llvm.org/docs/Coroutines.html#llvm-coro-size-intrinsic
+func CoroSize(block *llir.Block, typ *types.IntType) *llir.InstCall {
+	attrs := []llir.FuncAttribute{enum.FuncAttrNoUnwind, enum.FuncAttrReadNone}
+	return call(block, Name("llvm.coro.size", typ), typ, nil, attrs)
+}
+
+// CoroBegin appends a call to llvm.coro.begin to the basic block, which
+// returns the coroutine handle of the given coroutine, with its frame stored
+// in the given memory.
+//
+//    declare i8* @llvm.coro.begin(token, i8* writeonly)
+//
+// ref: https://llvm.org/docs/Coroutines.html#llvm-coro-begin-intrinsic
+func CoroBegin(block *llir.Block, id, mem value.Value) *llir.InstCall {
+	ps := []*Param{NewParam(types.Token), NewParam(types.I8Ptr, enum.ParamAttrWriteOnly)}
+	return call(block, "llvm.coro.begin", types.I8Ptr, ps, coroAttrs, id, mem)
+}
+
+// CoroSave appends a call to llvm.coro.save to the basic block, which marks the
+// point at which the coroutine with the given handle is considered suspended.
+//
+//    declare token @llvm.coro.save(i8*)
+//
+// ref: https://llvm.org/docs/Coroutines.html#llvm-coro-save-intrinsic
+func CoroSave(block *llir.Block, hdl value.Value) *llir.InstCall {
+	return call(block, "llvm.coro.save", types.Token, params(types.I8Ptr), coroAttrs, hdl)
+}
+
+// CoroSuspend appends a call to llvm.coro.suspend to the basic block, which
+// suspends the coroutine. The save token is the result of llvm.coro.save, or
+// nil for an implicit save. The result is -1 on suspend, 0 on resume and 1 on
+// destroy.
+//
+//    declare i8 @llvm.coro.suspend(token, i1)
+//
+// ref: https://llvm.org/docs/Coroutines.html#llvm-coro-suspend-intrinsic
+func CoroSuspend(block *llir.Block, save value.Value, final bool) *llir.InstCall {
+	if save == nil {
+		save = constant.None
+	}
+	return call(block, "llvm.coro.suspend", types.I8, params(types.Token, types.I1), coroAttrs, save, boolArg(final))
+}
+
+// CoroEnd appends a call to llvm.coro.end to the basic block, which marks the
+// point where the coroutine with the given handle returns control to its
+// caller; unwind specifies whether the end is reached on an unwind path.
+//
+//    declare i1 @llvm.coro.end(i8*, i1)
+//
+// ref: https://llvm.org/docs/Coroutines.html#llvm-coro-end-intrinsic
+func CoroEnd(block *llir.Block, hdl value.Value, unwind bool) *llir.InstCall {
+	return call(block, "llvm.coro.end", types.I1, params(types.I8Ptr, types.I1), coroAttrs, hdl, boolArg(unwind))
+}
+
+// CoroFree appends a call to llvm.coro.free to the basic block, which returns
+// the memory of the coroutine frame to deallocate; or null if the frame was not
+// dynamically allocated.
+//
+//    declare i8* @llvm.coro.free(token, i8* nocapture readonly)
+//
+// ref: https://llvm.org/docs/Coroutines.html#llvm-coro-free-intrinsic
+func CoroFree(block *llir.Block, id, hdl value.Value) *llir.InstCall {
+	ps := []*Param{NewParam(types.Token), NewParam(types.I8Ptr, enum.ParamAttrNoCapture, enum.ParamAttrReadOnly)}
+	attrs := []llir.FuncAttribute{enum.FuncAttrArgMemOnly, enum.FuncAttrNoUnwind, enum.FuncAttrReadOnly}
+	return call(block, "llvm.coro.free", types.I8Ptr, ps, attrs, id, hdl)
+}
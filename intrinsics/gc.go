@@ -0,0 +1,99 @@
+package intrinsics
+
+import (
+	"fmt"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/types"
+	"github.com/wa-lang/llir/value"
+)
+
+// --- [ Garbage collection intrinsics ] ---------------------------------------
+
+// GCRoot appends a call to llvm.gcroot to the basic block, which registers the
+// stack slot ptrLoc (an alloca in the entry basic block) as a garbage
+// collection root with the given metadata; or nil if no metadata.
+//
+//    declare void @llvm.gcroot(i8**, i8*)
+//
+// ref: https://llvm.org/docs/GarbageCollection.html#gcroot
+func GCRoot(block *llir.Block, ptrLoc value.Value, metadata constant.Constant) *llir.InstCall {
+	if metadata == nil {
+		metadata = constant.NewNull(types.I8Ptr)
+	}
+	ps := params(types.NewPointer(types.I8Ptr), types.I8Ptr)
+	return call(block, "llvm.gcroot", types.Void, ps, []llir.FuncAttribute{enum.FuncAttrNoUnwind}, ptrLoc, metadata)
+}
+
+// GCStatepoint appends a call to llvm.experimental.gc.statepoint to the basic
+// block, which calls the given callee with the given arguments at a safepoint.
+// The live garbage collected pointers are recorded in a "gc-live" operand
+// bundle, in order; the i-th live pointer has index i for GCRelocate.
+//
+//    declare token @llvm.experimental.gc.statepoint.p0f_isVoidf(i64 immarg, i32 immarg, void ()*, i32 immarg, i32 immarg, ...)
+//
+// ref: https://llvm.org/docs/Statepoints.html#llvm-experimental-gc-statepoint-intrinsic
+func GCStatepoint(block *llir.Block, id uint64, numPatchBytes int32, callee value.Value, args, live []value.Value) *llir.InstCall {
+	calleeType, ok := callee.Type().(*types.PointerType)
+	if !ok {
+		panic(fmt.Errorf("invalid callee type of statepoint; expected pointer to function type, got %v", callee.Type()))
+	}
+	ps := []*Param{
+		NewParam(types.I64, enum.ParamAttrImmArg),
+		NewParam(types.I32, enum.ParamAttrImmArg),
+		NewParam(calleeType),
+		NewParam(types.I32, enum.ParamAttrImmArg),
+		NewParam(types.I32, enum.ParamAttrImmArg),
+	}
+	name := Name("llvm.experimental.gc.statepoint", calleeType)
+	f := declare(parentModule(block), name, types.Token, ps, true)
+	callArgs := []value.Value{
+		constant.NewInt(types.I64, int64(id)),
+		constant.NewInt(types.I32, int64(numPatchBytes)),
+		callee,
+		constant.NewInt(types.I32, int64(len(args))),
+		// flags.
+		constant.NewInt(types.I32, 0),
+	}
+	callArgs = append(callArgs, args...)
+	// Number of transition arguments and deopt arguments; both passed through
+	// operand bundles.
+	callArgs = append(callArgs, constant.NewInt(types.I32, 0), constant.NewInt(types.I32, 0))
+	inst := block.NewCall(f, callArgs...)
+	inst.OperandBundles = append(inst.OperandBundles, llir.NewOperandBundle("gc-live", live...))
+	return inst
+}
+
+// GCResult appends a call to llvm.experimental.gc.result to the basic block,
+// which returns the result of type retType of the call performed by the given
+// statepoint.
+//
+//    declare i32 @llvm.experimental.gc.result.i32(token)
+//
+// ref: https://llvm.org/docs/Statepoints.html#llvm-experimental-gc-result
+func GCResult(block *llir.Block, statepoint value.Value, retType types.Type) *llir.InstCall {
+	attrs := []llir.FuncAttribute{enum.FuncAttrNoUnwind, enum.FuncAttrReadNone}
+	return call(block, Name("llvm.experimental.gc.result", retType), retType, params(types.Token), attrs, statepoint)
+}
+
+// GCRelocate appends a call to llvm.experimental.gc.relocate to the basic
+// block, which returns the relocated derived pointer of type typ, at the given
+// index of the "gc-live" operand bundle of the statepoint; with the base
+// pointer at baseIndex.
+//
+//    declare i8 addrspace(1)* @llvm.experimental.gc.relocate.p1i8(token, i32 immarg, i32 immarg)
+//
+// ref: https://llvm.org/docs/Statepoints.html#llvm-experimental-gc-relocate
+func GCRelocate(block *llir.Block, statepoint value.Value, baseIndex, derivedIndex int, typ types.Type) *llir.InstCall {
+	ps := []*Param{
+		NewParam(types.Token),
+		NewParam(types.I32, enum.ParamAttrImmArg),
+		NewParam(types.I32, enum.ParamAttrImmArg),
+	}
+	attrs := []llir.FuncAttribute{enum.FuncAttrNoUnwind, enum.FuncAttrReadNone}
+	base := constant.NewInt(types.I32, int64(baseIndex))
+	derived := constant.NewInt(types.I32, int64(derivedIndex))
+	return call(block, Name("llvm.experimental.gc.relocate", typ), typ, ps, attrs, statepoint, base, derived)
+}
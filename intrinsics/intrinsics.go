@@ -0,0 +1,139 @@
+// Package intrinsics provides typed constructors for calls to LLVM intrinsic
+// functions.
+//
+// Each constructor appends a call instruction to the given basic block, and
+// declares the intrinsic in the parent module of the basic block on demand,
+// with the correct signature, attributes and mangled name. Existing
+// declarations of the intrinsic are reused.
+//
+// ref: https://llvm.org/docs/LangRef.html#intrinsic-functions
+package intrinsics
+
+import (
+	"fmt"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/types"
+	"github.com/wa-lang/llir/value"
+)
+
+// Common sets of intrinsic function attributes.
+var (
+	// Attributes of intrinsics which only access memory through their pointer
+	// arguments (e.g. llvm.memcpy).
+	argMemAttrs = []llir.FuncAttribute{enum.FuncAttrArgMemOnly, enum.FuncAttrNoFree, enum.FuncAttrNoUnwind, enum.FuncAttrWillReturn}
+	// Attributes of intrinsics which compute a pure function of their arguments
+	// (e.g. llvm.ctpop).
+	pureAttrs = []llir.FuncAttribute{enum.FuncAttrNoFree, enum.FuncAttrNoSync, enum.FuncAttrNoUnwind, enum.FuncAttrReadNone, enum.FuncAttrSpeculatable, enum.FuncAttrWillReturn}
+	// Attributes of intrinsics with side effects which are not captured by
+	// memory effects (e.g. llvm.lifetime.start).
+	sideEffectAttrs = []llir.FuncAttribute{enum.FuncAttrNoFree, enum.FuncAttrNoSync, enum.FuncAttrNoUnwind, enum.FuncAttrWillReturn}
+)
+
+// Param is a parameter of an intrinsic function declaration.
+type Param struct {
+	// Parameter type.
+	Typ types.Type
+	// (optional) Parameter attributes.
+	Attrs []llir.ParamAttribute
+}
+
+// NewParam returns a new intrinsic function parameter based on the given type
+// and parameter attributes.
+func NewParam(typ types.Type, attrs ...llir.ParamAttribute) *Param {
+	return &Param{Typ: typ, Attrs: attrs}
+}
+
+// Declare returns the declaration of the intrinsic function with the given
+// name in m, based on the given return type, parameters and function
+// attributes. If m already contains a function of the same name, it is reused.
+//
+// Declare panics if the signature of an existing declaration does not match
+// the given signature.
+func Declare(m *llir.Module, name string, retType types.Type, params []*Param, attrs ...llir.FuncAttribute) *llir.Func {
+	return declare(m, name, retType, params, false, attrs...)
+}
+
+// Name returns the name of the overloaded intrinsic function with the given
+// base name (e.g. "llvm.memcpy") and overloaded types.
+//
+// Example:
+//
+//    Name("llvm.memcpy", types.I8Ptr, types.I8Ptr, types.I64) // "llvm.memcpy.p0i8.p0i8.i64"
+func Name(base string, overloads ...types.Type) string {
+	name := base
+	for _, t := range overloads {
+		name += "." + MangleType(t)
+	}
+	return name
+}
+
+// ### [ Helper functions ] ####################################################
+
+// declare returns the declaration of the intrinsic function with the given
+// name in m, based on the given return type, parameters, variadic flag and
+// function attributes.
+func declare(m *llir.Module, name string, retType types.Type, params []*Param, variadic bool, attrs ...llir.FuncAttribute) *llir.Func {
+	paramTypes := make([]types.Type, len(params))
+	for i, param := range params {
+		paramTypes[i] = param.Typ
+	}
+	sig := types.NewFunc(retType, paramTypes...)
+	sig.Variadic = variadic
+	for _, f := range m.Funcs {
+		if f.Name() != name {
+			continue
+		}
+		if !f.Sig.Equal(sig) {
+			panic(fmt.Errorf("signature mismatch of intrinsic %q; expected %v, got %v", name, sig, f.Sig))
+		}
+		return f
+	}
+	var ps []*llir.Param
+	for _, param := range params {
+		p := llir.NewParam("", param.Typ)
+		p.Attrs = param.Attrs
+		ps = append(ps, p)
+	}
+	f := m.NewFunc(name, retType, ps...)
+	f.Sig.Variadic = variadic
+	f.FuncAttrs = append(f.FuncAttrs, attrs...)
+	return f
+}
+
+// call declares the intrinsic function with the given name, return type,
+// parameters and function attributes in the parent module of block, and
+// appends a call to the intrinsic with the given arguments to the basic block.
+func call(block *llir.Block, name string, retType types.Type, params []*Param, attrs []llir.FuncAttribute, args ...value.Value) *llir.InstCall {
+	m := parentModule(block)
+	f := Declare(m, name, retType, params, attrs...)
+	return block.NewCall(f, args...)
+}
+
+// parentModule returns the parent module of the given basic block.
+func parentModule(block *llir.Block) *llir.Module {
+	if block.Parent == nil {
+		panic(fmt.Errorf("unable to locate parent function of basic block %q", block.Ident()))
+	}
+	if block.Parent.Parent == nil {
+		panic(fmt.Errorf("unable to locate parent module of function %q", block.Parent.Ident()))
+	}
+	return block.Parent.Parent
+}
+
+// params returns intrinsic function parameters without parameter attributes
+// based on the given types.
+func params(ts ...types.Type) []*Param {
+	ps := make([]*Param, len(ts))
+	for i, t := range ts {
+		ps[i] = NewParam(t)
+	}
+	return ps
+}
+
+// boolArg returns the boolean constant corresponding to x.
+func boolArg(x bool) value.Value {
+	return constant.NewBool(x)
+}
@@ -0,0 +1,65 @@
+package intrinsics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/types"
+)
+
+func TestMangleType(t *testing.T) {
+	golden := []struct {
+		in   types.Type
+		want string
+	}{
+		{in: types.I32, want: "i32"},
+		{in: types.Double, want: "f64"},
+		{in: types.I8Ptr, want: "p0i8"},
+		{in: &types.PointerType{ElemType: types.I32, AddrSpace: 1}, want: "p1i32"},
+		{in: types.NewVector(4, types.Float), want: "v4f32"},
+		{in: types.NewArray(3, types.I64), want: "a3i64"},
+		{in: types.NewStruct(types.I32, types.I1), want: "sl_i32i1s"},
+		{in: &types.StructType{TypeName: "foo"}, want: "s_foo"},
+		{in: types.NewFunc(types.Void, types.I8Ptr), want: "f_isVoidp0i8f"},
+	}
+	for _, g := range golden {
+		got := MangleType(g.in)
+		if g.want != got {
+			t.Errorf("mangled type mismatch of %v; expected %q, got %q", g.in, g.want, got)
+		}
+	}
+}
+
+func TestDeclare(t *testing.T) {
+	m := llir.NewModule()
+	f := m.NewFunc("f", types.Void, llir.NewParam("dst", types.I8Ptr), llir.NewParam("src", types.I8Ptr), llir.NewParam("x", types.I64))
+	dst, src, x := f.Params[0], f.Params[1], f.Params[2]
+	entry := f.NewBlock("")
+	n := constant.NewInt(types.I64, 16)
+	Memcpy(entry, dst, src, n, false)
+	Memcpy(entry, src, dst, n, true)
+	Ctpop(entry, x)
+	sum := SAddWithOverflow(entry, x, x)
+	entry.NewRet(nil)
+
+	// Intrinsic declarations are reused.
+	if got := len(m.Funcs); got != 4 {
+		t.Fatalf("invalid number of functions; expected 4, got %d", got)
+	}
+	if want, got := "{ i64, i1 }", sum.Type().String(); want != got {
+		t.Errorf("result type mismatch; expected %q, got %q", want, got)
+	}
+	s := m.String()
+	for _, want := range []string{
+		"declare void @llvm.memcpy.p0i8.p0i8.i64(i8* noalias nocapture writeonly %0, i8* noalias nocapture readonly %1, i64 %2, i1 immarg %3) argmemonly nofree nounwind willreturn",
+		"declare i64 @llvm.ctpop.i64(i64 %0) nofree nosync nounwind readnone speculatable willreturn",
+		"declare { i64, i1 } @llvm.sadd.with.overflow.i64(i64 %0, i64 %1)",
+		"call void @llvm.memcpy.p0i8.p0i8.i64(i8* %src, i8* %dst, i64 16, i1 true)",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("unable to locate %q in module:\n%s", want, s)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package intrinsics
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wa-lang/llir/types"
+)
+
+// MangleType returns the mangled name of the given type, as used in the names
+// of overloaded intrinsic functions.
+//
+// ref: getMangledTypeStr in llvm/lib/IR/Function.cpp
+func MangleType(t types.Type) string {
+	switch t := t.(type) {
+	case *types.VoidType:
+		return "isVoid"
+	case *types.IntType:
+		return fmt.Sprintf("i%d", t.BitSize)
+	case *types.FloatType:
+		switch t.Kind {
+		case types.FloatKindHalf:
+			return "f16"
+		case types.FloatKindFloat:
+			return "f32"
+		case types.FloatKindDouble:
+			return "f64"
+		case types.FloatKindX86_FP80:
+			return "f80"
+		case types.FloatKindFP128:
+			return "f128"
+		case types.FloatKindPPC_FP128:
+			return "ppcf128"
+		default:
+			panic(fmt.Errorf("support for floating-point kind %v not yet implemented", t.Kind))
+		}
+	case *types.MMXType:
+		return "x86mmx"
+	case *types.MetadataType:
+		return "Metadata"
+	case *types.TokenType:
+		return "token"
+	case *types.PointerType:
+		return fmt.Sprintf("p%d%s", uint64(t.AddrSpace), MangleType(t.ElemType))
+	case *types.VectorType:
+		if t.Scalable {
+			return fmt.Sprintf("nxv%d%s", t.Len, MangleType(t.ElemType))
+		}
+		return fmt.Sprintf("v%d%s", t.Len, MangleType(t.ElemType))
+	case *types.ArrayType:
+		return fmt.Sprintf("a%d%s", t.Len, MangleType(t.ElemType))
+	case *types.StructType:
+		if len(t.TypeName) > 0 {
+			return "s_" + t.TypeName
+		}
+		buf := &strings.Builder{}
+		buf.WriteString("sl_")
+		for _, field := range t.Fields {
+			buf.WriteString(MangleType(field))
+		}
+		// Ensure nested structs are distinguishable.
+		buf.WriteString("s")
+		return buf.String()
+	case *types.FuncType:
+		buf := &strings.Builder{}
+		buf.WriteString("f_")
+		buf.WriteString(MangleType(t.RetType))
+		for _, param := range t.Params {
+			buf.WriteString(MangleType(param))
+		}
+		if t.Variadic {
+			buf.WriteString("vararg")
+		}
+		// Ensure nested function types are distinguishable.
+		buf.WriteString("f")
+		return buf.String()
+	default:
+		panic(fmt.Errorf("support for mangling type %T not yet implemented", t))
+	}
+}
@@ -0,0 +1,159 @@
+package intrinsics
+
+import (
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/value"
+)
+
+// --- [ Floating-point math intrinsics ] --------------------------------------
+
+// Sqrt appends a call to llvm.sqrt to the basic block, which returns the square
+// root of x.
+//
+//    declare double @llvm.sqrt.f64(double)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-sqrt-intrinsic
+func Sqrt(block *llir.Block, x value.Value) *llir.InstCall {
+	return unary(block, "llvm.sqrt", x)
+}
+
+// FAbs appends a call to llvm.fabs to the basic block, which returns the
+// absolute value of x.
+//
+//    declare double @llvm.fabs.f64(double)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-fabs-intrinsic
+func FAbs(block *llir.Block, x value.Value) *llir.InstCall {
+	return unary(block, "llvm.fabs", x)
+}
+
+// Floor appends a call to llvm.floor to the basic block, which returns the
+// largest integral value not greater than x.
+//
+//    declare double @llvm.floor.f64(double)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-floor-intrinsic
+func Floor(block *llir.Block, x value.Value) *llir.InstCall {
+	return unary(block, "llvm.floor", x)
+}
+
+// Ceil appends a call to llvm.ceil to the basic block, which returns the
+// smallest integral value not less than x.
+//
+//    declare double @llvm.ceil.f64(double)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-ceil-intrinsic
+func Ceil(block *llir.Block, x value.Value) *llir.InstCall {
+	return unary(block, "llvm.ceil", x)
+}
+
+// Trunc appends a call to llvm.trunc to the basic block, which returns x
+// rounded to the nearest integral value not larger in magnitude than x.
+//
+//    declare double @llvm.trunc.f64(double)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-trunc-intrinsic
+func Trunc(block *llir.Block, x value.Value) *llir.InstCall {
+	return unary(block, "llvm.trunc", x)
+}
+
+// Round appends a call to llvm.round to the basic block, which returns x
+// rounded to the nearest integral value, with halfway cases rounded away from
+// zero.
+//
+//    declare double @llvm.round.f64(double)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-round-intrinsic
+func Round(block *llir.Block, x value.Value) *llir.InstCall {
+	return unary(block, "llvm.round", x)
+}
+
+// Sin appends a call to llvm.sin to the basic block, which returns the sine of
+// x.
+//
+//    declare double @llvm.sin.f64(double)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-sin-intrinsic
+func Sin(block *llir.Block, x value.Value) *llir.InstCall {
+	return unary(block, "llvm.sin", x)
+}
+
+// Cos appends a call to llvm.cos to the basic block, which returns the cosine
+// of x.
+//
+//    declare double @llvm.cos.f64(double)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-cos-intrinsic
+func Cos(block *llir.Block, x value.Value) *llir.InstCall {
+	return unary(block, "llvm.cos", x)
+}
+
+// Exp appends a call to llvm.exp to the basic block, which returns the base-e
+// exponential of x.
+//
+//    declare double @llvm.exp.f64(double)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-exp-intrinsic
+func Exp(block *llir.Block, x value.Value) *llir.InstCall {
+	return unary(block, "llvm.exp", x)
+}
+
+// Log appends a call to llvm.log to the basic block, which returns the base-e
+// logarithm of x.
+//
+//    declare double @llvm.log.f64(double)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-log-intrinsic
+func Log(block *llir.Block, x value.Value) *llir.InstCall {
+	return unary(block, "llvm.log", x)
+}
+
+// Pow appends a call to llvm.pow to the basic block, which returns x raised to
+// the y power.
+//
+//    declare double @llvm.pow.f64(double, double)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-pow-intrinsic
+func Pow(block *llir.Block, x, y value.Value) *llir.InstCall {
+	return binary(block, "llvm.pow", x, y)
+}
+
+// Copysign appends a call to llvm.copysign to the basic block, which returns x
+// with the sign of y.
+//
+//    declare double @llvm.copysign.f64(double, double)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-copysign-intrinsic
+func Copysign(block *llir.Block, x, y value.Value) *llir.InstCall {
+	return binary(block, "llvm.copysign", x, y)
+}
+
+// MinNum appends a call to llvm.minnum to the basic block, which returns the
+// smaller of x and y, following the IEEE-754 semantics of minNum.
+//
+//    declare double @llvm.minnum.f64(double, double)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-minnum-intrinsic
+func MinNum(block *llir.Block, x, y value.Value) *llir.InstCall {
+	return binary(block, "llvm.minnum", x, y)
+}
+
+// MaxNum appends a call to llvm.maxnum to the basic block, which returns the
+// larger of x and y, following the IEEE-754 semantics of maxNum.
+//
+//    declare double @llvm.maxnum.f64(double, double)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-maxnum-intrinsic
+func MaxNum(block *llir.Block, x, y value.Value) *llir.InstCall {
+	return binary(block, "llvm.maxnum", x, y)
+}
+
+// FMA appends a call to llvm.fma to the basic block, which returns the fused
+// multiply-add x*y+z, rounded once.
+//
+//    declare double @llvm.fma.f64(double, double, double)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-fma-intrinsic
+func FMA(block *llir.Block, x, y, z value.Value) *llir.InstCall {
+	return ternary(block, "llvm.fma", x, y, z)
+}
@@ -0,0 +1,138 @@
+package intrinsics
+
+import (
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/types"
+	"github.com/wa-lang/llir/value"
+)
+
+// --- [ Standard C library intrinsics ] ---------------------------------------
+
+// ~~~ [ llvm.memcpy ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Memcpy appends a call to llvm.memcpy to the basic block, which copies n bytes
+// from src to dst. The source and destination memory locations must not
+// overlap.
+//
+//    declare void @llvm.memcpy.p0i8.p0i8.i64(i8* noalias nocapture writeonly, i8* noalias nocapture readonly, i64, i1 immarg)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-memcpy-intrinsic
+func Memcpy(block *llir.Block, dst, src, n value.Value, volatile bool) *llir.InstCall {
+	name := Name("llvm.memcpy", dst.Type(), src.Type(), n.Type())
+	ps := []*Param{
+		NewParam(dst.Type(), enum.ParamAttrNoAlias, enum.ParamAttrNoCapture, enum.ParamAttrWriteOnly),
+		NewParam(src.Type(), enum.ParamAttrNoAlias, enum.ParamAttrNoCapture, enum.ParamAttrReadOnly),
+		NewParam(n.Type()),
+		NewParam(types.I1, enum.ParamAttrImmArg),
+	}
+	return call(block, name, types.Void, ps, argMemAttrs, dst, src, n, boolArg(volatile))
+}
+
+// ~~~ [ llvm.memmove ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Memmove appends a call to llvm.memmove to the basic block, which copies n
+// bytes from src to dst. The source and destination memory locations may
+// overlap.
+//
+//    declare void @llvm.memmove.p0i8.p0i8.i64(i8* nocapture writeonly, i8* nocapture readonly, i64, i1 immarg)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-memmove-intrinsic
+func Memmove(block *llir.Block, dst, src, n value.Value, volatile bool) *llir.InstCall {
+	name := Name("llvm.memmove", dst.Type(), src.Type(), n.Type())
+	ps := []*Param{
+		NewParam(dst.Type(), enum.ParamAttrNoCapture, enum.ParamAttrWriteOnly),
+		NewParam(src.Type(), enum.ParamAttrNoCapture, enum.ParamAttrReadOnly),
+		NewParam(n.Type()),
+		NewParam(types.I1, enum.ParamAttrImmArg),
+	}
+	return call(block, name, types.Void, ps, argMemAttrs, dst, src, n, boolArg(volatile))
+}
+
+// ~~~ [ llvm.memset ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Memset appends a call to llvm.memset to the basic block, which fills n bytes
+// of dst with the byte value val.
+//
+//    declare void @llvm.memset.p0i8.i64(i8* nocapture writeonly, i8, i64, i1 immarg)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-memset-intrinsics
+func Memset(block *llir.Block, dst, val, n value.Value, volatile bool) *llir.InstCall {
+	name := Name("llvm.memset", dst.Type(), n.Type())
+	ps := []*Param{
+		NewParam(dst.Type(), enum.ParamAttrNoCapture, enum.ParamAttrWriteOnly),
+		NewParam(types.I8),
+		NewParam(n.Type()),
+		NewParam(types.I1, enum.ParamAttrImmArg),
+	}
+	attrs := append([]llir.FuncAttribute{enum.FuncAttrWriteOnly}, argMemAttrs...)
+	return call(block, name, types.Void, ps, attrs, dst, val, n, boolArg(volatile))
+}
+
+// --- [ Memory use marker intrinsics ] ----------------------------------------
+
+// ~~~ [ llvm.lifetime.start ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// LifetimeStart appends a call to llvm.lifetime.start to the basic block, which
+// marks the beginning of the lifetime of the memory object ptr of the given
+// size in bytes; or -1 if the size is variable.
+//
+//    declare void @llvm.lifetime.start.p0i8(i64 immarg, i8* nocapture)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-lifetime-start-intrinsic
+func LifetimeStart(block *llir.Block, size int64, ptr value.Value) *llir.InstCall {
+	return lifetime(block, "llvm.lifetime.start", size, ptr)
+}
+
+// ~~~ [ llvm.lifetime.end ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// LifetimeEnd appends a call to llvm.lifetime.end to the basic block, which
+// marks the end of the lifetime of the memory object ptr of the given size in
+// bytes; or -1 if the size is variable.
+//
+//    declare void @llvm.lifetime.end.p0i8(i64 immarg, i8* nocapture)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-lifetime-end-intrinsic
+func LifetimeEnd(block *llir.Block, size int64, ptr value.Value) *llir.InstCall {
+	return lifetime(block, "llvm.lifetime.end", size, ptr)
+}
+
+// lifetime appends a call to the given lifetime marker intrinsic to the basic
+// block.
+func lifetime(block *llir.Block, base string, size int64, ptr value.Value) *llir.InstCall {
+	name := Name(base, ptr.Type())
+	ps := []*Param{
+		NewParam(types.I64, enum.ParamAttrImmArg),
+		NewParam(ptr.Type(), enum.ParamAttrNoCapture),
+	}
+	attrs := append([]llir.FuncAttribute{enum.FuncAttrArgMemOnly}, sideEffectAttrs...)
+	return call(block, name, types.Void, ps, attrs, constant.NewInt(types.I64, size), ptr)
+}
+
+// --- [ Stack intrinsics ] ----------------------------------------------------
+
+// ~~~ [ llvm.stacksave ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// StackSave appends a call to llvm.stacksave to the basic block, which returns
+// the current state of the function stack.
+//
+//    declare i8* @llvm.stacksave()
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-stacksave-intrinsic
+func StackSave(block *llir.Block) *llir.InstCall {
+	return call(block, "llvm.stacksave", types.I8Ptr, nil, sideEffectAttrs)
+}
+
+// ~~~ [ llvm.stackrestore ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// StackRestore appends a call to llvm.stackrestore to the basic block, which
+// restores the state of the function stack to the state saved by a
+// corresponding call to llvm.stacksave.
+//
+//    declare void @llvm.stackrestore(i8*)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-stackrestore-intrinsic
+func StackRestore(block *llir.Block, ptr value.Value) *llir.InstCall {
+	return call(block, "llvm.stackrestore", types.Void, params(types.I8Ptr), sideEffectAttrs, ptr)
+}
@@ -0,0 +1,102 @@
+package intrinsics
+
+import (
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/types"
+	"github.com/wa-lang/llir/value"
+)
+
+// --- [ Variable argument handling intrinsics ] -------------------------------
+
+// VAStart appends a call to llvm.va_start to the basic block, which initializes
+// the va_list pointed to by argList for use by va_arg.
+//
+//    declare void @llvm.va_start(i8*)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-va-start-intrinsic
+func VAStart(block *llir.Block, argList value.Value) *llir.InstCall {
+	return call(block, "llvm.va_start", types.Void, params(types.I8Ptr), sideEffectAttrs, argList)
+}
+
+// VAEnd appends a call to llvm.va_end to the basic block, which destroys the
+// va_list pointed to by argList.
+//
+//    declare void @llvm.va_end(i8*)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-va-end-intrinsic
+func VAEnd(block *llir.Block, argList value.Value) *llir.InstCall {
+	return call(block, "llvm.va_end", types.Void, params(types.I8Ptr), sideEffectAttrs, argList)
+}
+
+// VACopy appends a call to llvm.va_copy to the basic block, which copies the
+// position of the source va_list to the destination va_list.
+//
+//    declare void @llvm.va_copy(i8*, i8*)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-va-copy-intrinsic
+func VACopy(block *llir.Block, dst, src value.Value) *llir.InstCall {
+	return call(block, "llvm.va_copy", types.Void, params(types.I8Ptr, types.I8Ptr), sideEffectAttrs, dst, src)
+}
+
+// --- [ General intrinsics ] --------------------------------------------------
+
+// Trap appends a call to llvm.trap to the basic block, which causes the program
+// to abort in a target specific manner.
+//
+//    declare void @llvm.trap()
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-trap-intrinsic
+func Trap(block *llir.Block) *llir.InstCall {
+	attrs := []llir.FuncAttribute{enum.FuncAttrCold, enum.FuncAttrNoReturn, enum.FuncAttrNoUnwind}
+	return call(block, "llvm.trap", types.Void, nil, attrs)
+}
+
+// DebugTrap appends a call to llvm.debugtrap to the basic block, which causes
+// an execution trap intended to be caught by a debugger.
+//
+//    declare void @llvm.debugtrap()
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-debugtrap-intrinsic
+func DebugTrap(block *llir.Block) *llir.InstCall {
+	attrs := []llir.FuncAttribute{enum.FuncAttrNoUnwind}
+	return call(block, "llvm.debugtrap", types.Void, nil, attrs)
+}
+
+// Assume appends a call to llvm.assume to the basic block, which allows the
+// optimizer to assume that cond is true.
+//
+//    declare void @llvm.assume(i1 noundef)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-assume-intrinsic
+func Assume(block *llir.Block, cond value.Value) *llir.InstCall {
+	ps := []*Param{NewParam(types.I1, enum.ParamAttrNoUndef)}
+	attrs := append([]llir.FuncAttribute{enum.FuncAttrInaccessibleMemOnly}, sideEffectAttrs...)
+	return call(block, "llvm.assume", types.Void, ps, attrs, cond)
+}
+
+// Expect appends a call to llvm.expect to the basic block, which returns x and
+// informs the optimizer that x is expected to be equal to expected.
+//
+//    declare i64 @llvm.expect.i64(i64, i64)
+//
+// ref: https://llvm.org/docs/LangRef.html#llvm-expect-intrinsic
+func Expect(block *llir.Block, x, expected value.Value) *llir.InstCall {
+	t := x.Type()
+	attrs := []llir.FuncAttribute{enum.FuncAttrNoFree, enum.FuncAttrNoSync, enum.FuncAttrNoUnwind, enum.FuncAttrReadNone, enum.FuncAttrWillReturn}
+	return call(block, Name("llvm.expect", t), t, params(t, t), attrs, x, expected)
+}
+
+// --- [ Exception handling intrinsics ] ---------------------------------------
+
+// EHTypeIDFor appends a call to llvm.eh.typeid.for to the basic block, which
+// returns the type info index of the given type info in the exception table of
+// the function; for comparison against the selector of a landingpad.
+//
+//    declare i32 @llvm.eh.typeid.for(i8*)
+//
+// ref: https://llvm.org/docs/ExceptionHandling.html#llvm-eh-typeid-for
+func EHTypeIDFor(block *llir.Block, typeInfo value.Value) *llir.InstCall {
+	attrs := []llir.FuncAttribute{enum.FuncAttrNoUnwind, enum.FuncAttrReadNone}
+	return call(block, "llvm.eh.typeid.for", types.I32, params(types.I8Ptr), attrs, typeInfo)
+}
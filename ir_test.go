@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/enum"
 	"github.com/wa-lang/llir/metadata"
 	"github.com/wa-lang/llir/types"
 	"github.com/wa-lang/llir/value"
@@ -120,6 +121,7 @@ var (
 	_ Terminator = (*TermSwitch)(nil)
 	_ Terminator = (*TermIndirectBr)(nil)
 	_ Terminator = (*TermInvoke)(nil)
+	_ Terminator = (*TermCallBr)(nil)
 	_ Terminator = (*TermResume)(nil)
 	_ Terminator = (*TermCatchSwitch)(nil)
 	_ Terminator = (*TermCatchRet)(nil)
@@ -212,3 +214,137 @@ var (
 	_ value.Named = (*TermInvoke)(nil)
 	_ value.Named = (*TermCatchSwitch)(nil) // token result used by catchpad
 )
+
+func TestOperands(t *testing.T) {
+	f := NewFunc("f", types.I32, NewParam("x", types.I32), NewParam("y", types.I32))
+	x, y := f.Params[0], f.Params[1]
+	entry := f.NewBlock("entry")
+	exit := f.NewBlock("exit")
+	other := f.NewBlock("other")
+	add := entry.NewAdd(x, y)
+	cond := entry.NewICmp(enum.IPredEQ, add, constant.NewInt(types.I32, 0))
+	br := entry.NewCondBr(cond, exit, exit)
+	phi := exit.NewPhi(NewIncoming(add, entry))
+	exit.NewRet(phi)
+
+	// Rewrite instruction operands in place.
+	ops := add.Operands()
+	if len(ops) != 2 || *ops[0] != x || *ops[1] != y {
+		t.Fatalf("invalid add operands; expected [%v, %v], got %v", x, y, ops)
+	}
+	*ops[1] = x
+	if add.Y != x {
+		t.Errorf("add operand not rewritten in place; expected %v, got %v", x, add.Y)
+	}
+	add.SetOperand(0, y)
+	if add.X != y {
+		t.Errorf("add operand not set; expected %v, got %v", y, add.X)
+	}
+
+	// Phi operands include incoming values and predecessors.
+	if got := len(phi.Operands()); got != 2 {
+		t.Errorf("invalid number of phi operands; expected 2, got %d", got)
+	}
+
+	// Terminator operands include target basic blocks, and successors follow
+	// rewritten targets.
+	if got := len(br.Succs()); got != 2 || br.Succs()[1] != exit {
+		t.Fatalf("invalid successors of br; got %v", br.Succs())
+	}
+	br.SetOperand(2, other)
+	if got := br.Succs()[1]; got != other {
+		t.Errorf("stale successors of br; expected %v, got %v", other, got)
+	}
+	jmp := other.NewBr(exit)
+	if got := jmp.Succs()[0]; got != exit {
+		t.Fatalf("invalid successor of br; expected %v, got %v", exit, got)
+	}
+	*jmp.Operands()[0] = entry
+	if got := jmp.Succs()[0]; got != entry {
+		t.Errorf("stale successor of br rewritten through operands; expected %v, got %v", entry, got)
+	}
+
+	// Out-of-range operand indices panic the same way on every user.
+	golden := []struct {
+		user interface{ SetOperand(i int, v value.Value) }
+		i    int
+	}{
+		{user: add, i: 2},
+		{user: br, i: -1},
+		{user: NewRet(nil), i: 0},
+		{user: NewUnreachable(), i: 0},
+		{user: NewFence(enum.AtomicOrderingSeqCst), i: 0},
+	}
+	for _, g := range golden {
+		func() {
+			defer func() {
+				e := recover()
+				err, ok := e.(error)
+				if !ok || !strings.HasPrefix(err.Error(), "invalid operand index") {
+					t.Errorf("%T: invalid panic for operand index %d; got %v", g.user, g.i, e)
+				}
+			}()
+			g.user.SetOperand(g.i, x)
+		}()
+	}
+}
+
+func TestWriteToErrors(t *testing.T) {
+	// Missing terminator.
+	m := NewModule()
+	f := m.NewFunc("f", types.Void)
+	f.NewBlock("entry")
+	var buf strings.Builder
+	_, err := m.WriteTo(&buf)
+	expected := `missing terminator in basic block "%entry" of function "@f"`
+	if err == nil || !strings.Contains(err.Error(), expected) {
+		t.Errorf("expected error containing %q, got %v", expected, err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("unexpected output; %q", buf.String())
+	}
+
+	// ID conflict.
+	m = NewModule()
+	f = m.NewFunc("g", types.Void)
+	entry := f.NewBlock("")
+	entry.LocalID = 1
+	entry.NewRet(nil)
+	if _, err := m.WriteTo(&buf); err == nil {
+		t.Error("expected error for local ID conflict")
+	}
+
+	// Invalid operands.
+	golden := []struct {
+		inst Instruction
+		want string
+	}{
+		{
+			inst: &InstAdd{Y: constant.NewInt(types.I32, 1)},
+			want: "nil operand at index 0",
+		},
+		{
+			inst: &InstExtractElement{X: constant.NewInt(types.I32, 1), Index: constant.NewInt(types.I32, 0)},
+			want: "invalid vector type",
+		},
+		{
+			inst: &InstCall{Callee: constant.NewInt(types.I32, 1)},
+			want: "invalid callee type",
+		},
+	}
+	for _, g := range golden {
+		m = NewModule()
+		f = m.NewFunc("h", types.Void)
+		entry = f.NewBlock("entry")
+		entry.Insts = append(entry.Insts, g.inst)
+		entry.NewRet(nil)
+		buf.Reset()
+		_, err := m.WriteTo(&buf)
+		if err == nil || !strings.Contains(err.Error(), g.want) {
+			t.Errorf("expected error containing %q, got %v", g.want, err)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("unexpected output; %q", buf.String())
+		}
+	}
+}
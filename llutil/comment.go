@@ -1,9 +1,11 @@
 package llutil
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/value"
 )
 
 // Comment is an LLVM IR comment represented as a pseudo-instruction. Comment
@@ -23,6 +25,18 @@ func (c *Comment) LLString() string {
 	return "; " + text
 }
 
+// Operands returns a mutable list of operands of the comment. Comments have no
+// operands.
+func (c *Comment) Operands() []*value.Value {
+	return nil
+}
+
+// SetOperand sets the i-th operand of the comment to v. Comments have no
+// operands, so SetOperand always panics with an out-of-range operand index.
+func (c *Comment) SetOperand(i int, v value.Value) {
+	panic(fmt.Errorf("invalid operand index %d; expected 0 <= index < 0", i))
+}
+
 // NewComment returns a new LLVM IR comment represented as a pseudo-instruction.
 // Text may contain multiple lines.
 func NewComment(text string) *Comment {
@@ -0,0 +1,201 @@
+package llutil
+
+import (
+	"fmt"
+
+	"github.com/wa-lang/llir/types"
+)
+
+// TypeSizeInBits returns the size in bits of the given sized type, as
+// specified by the data layout. TypeSizeInBits panics if the type is unsized;
+// see IsSized.
+func (dl *DataLayout) TypeSizeInBits(typ types.Type) uint64 {
+	switch typ := typ.(type) {
+	case *types.IntType:
+		return typ.BitSize
+	case *types.FloatType:
+		return floatBitSize(typ)
+	case *types.PointerType:
+		return dl.pointerSizeAlignment(typ.AddrSpace).Size
+	case *types.VectorType:
+		return typ.Len * dl.TypeSizeInBits(typ.ElemType)
+	case *types.ArrayType, *types.StructType:
+		return 8 * dl.TypeAllocSize(typ)
+	}
+	panic(fmt.Errorf("support for size of type %T not yet implemented", typ))
+}
+
+// IsSized reports whether values of the given type have a size in memory. Void,
+// function, label, metadata and token types, as well as opaque struct types and
+// aggregates containing them, are unsized.
+func IsSized(typ types.Type) bool {
+	switch typ := typ.(type) {
+	case *types.IntType, *types.FloatType, *types.PointerType:
+		return true
+	case *types.VectorType:
+		return IsSized(typ.ElemType)
+	case *types.ArrayType:
+		return IsSized(typ.ElemType)
+	case *types.StructType:
+		if typ.Opaque {
+			return false
+		}
+		for _, field := range typ.Fields {
+			if !IsSized(field) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// TypeStoreSize returns the maximum number of bytes that may be overwritten by
+// storing a value of the given type.
+func (dl *DataLayout) TypeStoreSize(typ types.Type) uint64 {
+	return (dl.TypeSizeInBits(typ) + 7) / 8
+}
+
+// TypeAllocSize returns the offset in bytes between successive values of the
+// given type in memory (e.g. array elements), including alignment padding.
+// TypeAllocSize panics if the type is unsized; see IsSized.
+func (dl *DataLayout) TypeAllocSize(typ types.Type) uint64 {
+	switch typ := typ.(type) {
+	case *types.ArrayType:
+		return typ.Len * dl.TypeAllocSize(typ.ElemType)
+	case *types.StructType:
+		if typ.Opaque {
+			panic(fmt.Errorf("support for size of opaque struct type %v not yet implemented", typ))
+		}
+		size := uint64(0)
+		for _, field := range typ.Fields {
+			if !typ.Packed {
+				size = alignTo(size, dl.ABIAlignment(field))
+			}
+			size += dl.TypeAllocSize(field)
+		}
+		return alignTo(size, dl.ABIAlignment(typ))
+	}
+	return alignTo(dl.TypeStoreSize(typ), dl.ABIAlignment(typ))
+}
+
+// ABIAlignment returns the minimum ABI-required alignment in bytes of the
+// given type.
+func (dl *DataLayout) ABIAlignment(typ types.Type) uint64 {
+	switch typ := typ.(type) {
+	case *types.IntType:
+		// Use the smallest integer alignment of at least the bit size, or the
+		// largest if none.
+		var best, largest *IntegerSizeAlignment
+		for _, a := range dl.IntegerSizeAlignment {
+			if a.Size >= typ.BitSize && (best == nil || a.Size < best.Size) {
+				best = a
+			}
+			if largest == nil || a.Size > largest.Size {
+				largest = a
+			}
+		}
+		if best == nil {
+			best = largest
+		}
+		if best == nil {
+			return naturalAlignment(typ.BitSize)
+		}
+		return best.ABIAlignment / 8
+	case *types.FloatType:
+		size := floatBitSize(typ)
+		if a, ok := dl.FloatingPointSizeAlignment[size]; ok {
+			return a.ABIAlignment / 8
+		}
+		return naturalAlignment(size)
+	case *types.PointerType:
+		return dl.pointerSizeAlignment(typ.AddrSpace).ABIAlignment / 8
+	case *types.VectorType:
+		size := dl.TypeSizeInBits(typ)
+		if a, ok := dl.VectorSizeAlignment[size]; ok {
+			return a.ABIAlignment / 8
+		}
+		return naturalAlignment(size)
+	case *types.ArrayType:
+		return dl.ABIAlignment(typ.ElemType)
+	case *types.StructType:
+		if typ.Packed {
+			return 1
+		}
+		align := uint64(1)
+		if dl.AggregateAlignment != nil && dl.AggregateAlignment.ABIAlignment/8 > align {
+			align = dl.AggregateAlignment.ABIAlignment / 8
+		}
+		for _, field := range typ.Fields {
+			if a := dl.ABIAlignment(field); a > align {
+				align = a
+			}
+		}
+		return align
+	}
+	panic(fmt.Errorf("support for alignment of type %T not yet implemented", typ))
+}
+
+// StructFieldOffset returns the offset in bytes of the i-th field of the given
+// struct type.
+func (dl *DataLayout) StructFieldOffset(typ *types.StructType, i int) uint64 {
+	offset := uint64(0)
+	for j, field := range typ.Fields {
+		if !typ.Packed {
+			offset = alignTo(offset, dl.ABIAlignment(field))
+		}
+		if j == i {
+			break
+		}
+		offset += dl.TypeAllocSize(field)
+	}
+	return offset
+}
+
+// pointerSizeAlignment returns the size and alignment of pointers in the given
+// address space; defaulting to those of address space 0.
+func (dl *DataLayout) pointerSizeAlignment(addrSpace types.AddrSpace) *PointerSizeAlignment {
+	if a, ok := dl.PointerSizeAlignment[uint64(addrSpace)]; ok {
+		return a
+	}
+	if a, ok := dl.PointerSizeAlignment[0]; ok {
+		return a
+	}
+	return NewPointerSizeAlignment(0, 64, 64, 64, 0)
+}
+
+// floatBitSize returns the size in bits of the given floating-point type.
+func floatBitSize(typ *types.FloatType) uint64 {
+	switch typ.Kind {
+	case types.FloatKindHalf:
+		return 16
+	case types.FloatKindFloat:
+		return 32
+	case types.FloatKindDouble:
+		return 64
+	case types.FloatKindX86_FP80:
+		return 80
+	case types.FloatKindFP128, types.FloatKindPPC_FP128:
+		return 128
+	}
+	panic(fmt.Errorf("support for size of floating-point type of kind %v not yet implemented", typ.Kind))
+}
+
+// naturalAlignment returns the natural alignment in bytes of a type of the
+// given size in bits; i.e. the size in bytes rounded up to a power of two.
+func naturalAlignment(size uint64) uint64 {
+	n := (size + 7) / 8
+	align := uint64(1)
+	for align < n {
+		align *= 2
+	}
+	return align
+}
+
+// alignTo returns x rounded up to a multiple of align.
+func alignTo(x, align uint64) uint64 {
+	if align == 0 {
+		return x
+	}
+	return (x + align - 1) / align * align
+}
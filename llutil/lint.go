@@ -0,0 +1,289 @@
+package llutil
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/metadata"
+	"github.com/wa-lang/llir/types"
+	"github.com/wa-lang/llir/value"
+)
+
+// LintError is an error reported by Lint, locating obvious undefined
+// behaviour.
+type LintError struct {
+	// Location of the instruction or terminator in the IR.
+	VerifyError
+	// Source position of the instruction or terminator, as given by its !dbg
+	// attachment; or zero value if not present.
+	Pos Position
+}
+
+// Error returns the string representation of the lint error.
+func (e *LintError) Error() string {
+	if e.Pos.Line == 0 {
+		return e.VerifyError.Error()
+	}
+	return fmt.Sprintf("%s: %s", e.Pos, e.VerifyError.Error())
+}
+
+// Position is a source position.
+type Position struct {
+	// File name; or empty if unknown.
+	File string
+	// Line number, starting at 1; or 0 if unknown.
+	Line int64
+	// Column number, starting at 1; or 0 if unknown.
+	Column int64
+}
+
+// String returns the string representation of the source position, in the
+// form "file:line:column".
+func (pos Position) String() string {
+	buf := &strings.Builder{}
+	if len(pos.File) > 0 {
+		buf.WriteString(pos.File)
+		buf.WriteString(":")
+	}
+	fmt.Fprintf(buf, "%d", pos.Line)
+	if pos.Column != 0 {
+		fmt.Fprintf(buf, ":%d", pos.Column)
+	}
+	return buf.String()
+}
+
+// Lint checks the functions of the given module for obvious undefined
+// behaviour, and returns the *LintError errors found; or nil if none was
+// found.
+//
+// The following are reported:
+//
+//    * loads and stores through null pointers;
+//    * stores to constant global variables;
+//    * division and remainder by constant zero;
+//    * shift amounts of at least the bit width of the shifted type;
+//    * calls whose signature does not match the signature of the callee
+//      function (e.g. calls through bitcasted functions);
+//    * unreachable terminators reached right after function entry.
+//
+// Source positions are taken from !dbg attachments where present.
+func Lint(m *llir.Module) []error {
+	l := &linter{}
+	for _, f := range m.Funcs {
+		if len(f.Blocks) == 0 {
+			continue
+		}
+		// Assign local IDs to refer to unnamed local variables in errors.
+		if err := f.AssignIDs(); err != nil {
+			l.errs = append(l.errs, &LintError{VerifyError: VerifyError{Func: f.Ident(), Msg: err.Error()}})
+			continue
+		}
+		for _, block := range f.Blocks {
+			for _, inst := range block.Insts {
+				l.lintInst(f, block, inst)
+			}
+			if block.Term != nil {
+				l.lintTerm(f, block, block.Term)
+			}
+		}
+		// Unreachable reached right after entry; calls are assumed to
+		// potentially not return.
+		entry := f.Blocks[0]
+		if _, ok := entry.Term.(*llir.TermUnreachable); ok && !hasCall(entry) {
+			l.errorf(f, entry, entry.Term, "unreachable reached right after function entry")
+		}
+	}
+	return l.errs
+}
+
+// linter is a module linter.
+type linter struct {
+	// Errors found.
+	errs []error
+}
+
+// errorf records a lint error located at the given function, basic block and
+// instruction or terminator.
+func (l *linter) errorf(f *llir.Func, block *llir.Block, inst interface{}, format string, args ...interface{}) {
+	e := &LintError{
+		VerifyError: VerifyError{
+			Func:  f.Ident(),
+			Block: block.Ident(),
+			Inst:  llString(inst),
+			Msg:   fmt.Sprintf(format, args...),
+		},
+	}
+	if loc := dbgLocation(inst); loc != nil {
+		e.Pos = Position{File: scopeFile(loc.Scope), Line: loc.Line, Column: loc.Column}
+	}
+	l.errs = append(l.errs, e)
+}
+
+// lintInst checks the given instruction for obvious undefined behaviour.
+func (l *linter) lintInst(f *llir.Func, block *llir.Block, inst llir.Instruction) {
+	switch inst := inst.(type) {
+	case *llir.InstLoad:
+		if isNull(inst.Src) {
+			l.errorf(f, block, inst, "load through null pointer")
+		}
+	case *llir.InstStore:
+		if isNull(inst.Dst) {
+			l.errorf(f, block, inst, "store through null pointer")
+		}
+		if g, ok := stripPointerCasts(inst.Dst).(*llir.Global); ok && g.Immutable {
+			l.errorf(f, block, inst, "store to constant global variable %s", g.Ident())
+		}
+	case *llir.InstUDiv:
+		l.lintDiv(f, block, inst, inst.Y)
+	case *llir.InstSDiv:
+		l.lintDiv(f, block, inst, inst.Y)
+	case *llir.InstURem:
+		l.lintDiv(f, block, inst, inst.Y)
+	case *llir.InstSRem:
+		l.lintDiv(f, block, inst, inst.Y)
+	case *llir.InstShl:
+		l.lintShift(f, block, inst, inst.X, inst.Y)
+	case *llir.InstLShr:
+		l.lintShift(f, block, inst, inst.X, inst.Y)
+	case *llir.InstAShr:
+		l.lintShift(f, block, inst, inst.X, inst.Y)
+	case *llir.InstCall:
+		l.lintCall(f, block, inst, inst.Callee)
+	}
+}
+
+// lintTerm checks the given terminator for obvious undefined behaviour.
+func (l *linter) lintTerm(f *llir.Func, block *llir.Block, term llir.Terminator) {
+	switch term := term.(type) {
+	case *llir.TermInvoke:
+		l.lintCall(f, block, term, term.Invokee)
+	case *llir.TermCallBr:
+		l.lintCall(f, block, term, term.Callee)
+	}
+}
+
+// lintDiv checks the divisor y of the given division or remainder instruction.
+func (l *linter) lintDiv(f *llir.Func, block *llir.Block, inst llir.Instruction, y value.Value) {
+	if isZero(y) {
+		l.errorf(f, block, inst, "division by zero")
+	}
+}
+
+// lintShift checks the shift amount y of the given shift instruction with
+// shifted operand x.
+func (l *linter) lintShift(f *llir.Func, block *llir.Block, inst llir.Instruction, x, y value.Value) {
+	t, ok := x.Type().(*types.IntType)
+	if !ok {
+		return
+	}
+	amount, ok := y.(*constant.Int)
+	if !ok {
+		return
+	}
+	// Shift amounts are interpreted as unsigned.
+	if amount.X.Sign() < 0 || amount.X.Cmp(big.NewInt(int64(t.BitSize))) >= 0 {
+		l.errorf(f, block, inst, "shift amount %v of at least bit width %d", amount.X, t.BitSize)
+	}
+}
+
+// lintCall checks that the signature of the given call, invoke or callbr
+// matches the signature of its callee function.
+func (l *linter) lintCall(f *llir.Func, block *llir.Block, inst interface{}, callee value.Value) {
+	fn, ok := stripPointerCasts(callee).(*llir.Func)
+	if !ok {
+		return
+	}
+	sig := callSig(callee)
+	if sig == nil {
+		return
+	}
+	if !sig.Equal(fn.Sig) {
+		l.errorf(f, block, inst, "call signature %v does not match signature %v of callee %s", sig, fn.Sig, fn.Ident())
+	}
+}
+
+// callSig returns the function signature of the given callee; or nil if the
+// callee is not of pointer to function type.
+func callSig(callee value.Value) *types.FuncType {
+	t, ok := callee.Type().(*types.PointerType)
+	if !ok {
+		return nil
+	}
+	sig, _ := t.ElemType.(*types.FuncType)
+	return sig
+}
+
+// stripPointerCasts returns the given value with bitcast, addrspacecast and
+// all-zero getelementptr constant expressions stripped.
+func stripPointerCasts(v value.Value) value.Value {
+	for {
+		switch c := v.(type) {
+		case *constant.ExprBitCast:
+			v = c.From
+		case *constant.ExprAddrSpaceCast:
+			v = c.From
+		case *constant.ExprGetElementPtr:
+			for _, index := range c.Indices {
+				if !isZero(index) {
+					return v
+				}
+			}
+			v = c.Src
+		default:
+			return v
+		}
+	}
+}
+
+// isNull reports whether the given pointer value is null, after stripping
+// pointer casts.
+func isNull(v value.Value) bool {
+	switch stripPointerCasts(v).(type) {
+	case *constant.Null, *constant.ZeroInitializer:
+		return true
+	}
+	return false
+}
+
+// isZero reports whether the given value is a constant zero integer, or a
+// zeroinitializer.
+func isZero(v value.Value) bool {
+	switch c := v.(type) {
+	case *constant.Int:
+		return c.X.Sign() == 0
+	case *constant.ZeroInitializer:
+		return true
+	}
+	return false
+}
+
+// hasCall reports whether the given basic block contains a call instruction.
+func hasCall(block *llir.Block) bool {
+	for _, inst := range block.Insts {
+		if _, ok := inst.(*llir.InstCall); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// scopeFile returns the file name of the given debug information scope; or the
+// empty string if not present.
+func scopeFile(scope metadata.Field) string {
+	var file *metadata.DIFile
+	switch s := scope.(type) {
+	case *metadata.DISubprogram:
+		file = s.File
+	case *metadata.DILexicalBlock:
+		file = s.File
+	case *metadata.DILexicalBlockFile:
+		file = s.File
+	}
+	if file == nil {
+		return ""
+	}
+	return file.Filename
+}
@@ -0,0 +1,52 @@
+package llutil
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/metadata"
+	"github.com/wa-lang/llir/types"
+)
+
+func TestLint(t *testing.T) {
+	m := llir.NewModule()
+	g := m.NewGlobalDef("g", constant.NewInt(types.I32, 0))
+	g.Immutable = true
+	callee := m.NewFunc("callee", types.Void, llir.NewParam("x", types.I32))
+	x := llir.NewParam("x", types.I32)
+	f := m.NewFunc("f", types.I32, x)
+	entry := f.NewBlock("entry")
+	null := constant.NewNull(types.NewPointer(types.I32))
+	load := entry.NewLoad(types.I32, null)
+	file := &metadata.DIFile{MetadataID: -1, Filename: "a.c"}
+	sp := &metadata.DISubprogram{MetadataID: -1, Name: "f", File: file}
+	load.Metadata = append(load.Metadata, &metadata.Attachment{Name: "dbg", Node: &metadata.DILocation{MetadataID: -1, Line: 3, Column: 7, Scope: sp}})
+	entry.NewStore(x, g)
+	entry.NewSDiv(x, constant.NewInt(types.I32, 0))
+	entry.NewShl(x, constant.NewInt(types.I32, 32))
+	entry.NewLShr(x, constant.NewInt(types.I32, 31))
+	entry.NewCall(constant.NewBitCast(callee, types.NewPointer(types.NewFunc(types.Void))))
+	entry.NewCall(callee, x)
+	entry.NewRet(load)
+	h := m.NewFunc("h", types.Void)
+	h.NewBlock("entry").NewUnreachable()
+
+	var got []string
+	for _, err := range Lint(m) {
+		got = append(got, err.Error())
+	}
+	s := strings.Join(got, "\n")
+	want := []string{
+		`a.c:3:7: function @f: block %entry: "%0 = load i32, i32* null, !dbg !DILocation(line: 3, column: 7, scope: !DISubprogram(name: \"f\", file: !DIFile(filename: \"a.c\", directory: \"\"), isDefinition: false))": load through null pointer`,
+		`function @f: block %entry: "store i32 %x, i32* @g": store to constant global variable @g`,
+		`function @f: block %entry: "%1 = sdiv i32 %x, 0": division by zero`,
+		`function @f: block %entry: "%2 = shl i32 %x, 32": shift amount 32 of at least bit width 32`,
+		`function @f: block %entry: "call void bitcast (void (i32)* @callee to void ()*)()": call signature void () does not match signature void (i32) of callee @callee`,
+		`function @h: block %entry: "unreachable": unreachable reached right after function entry`,
+	}
+	if s != strings.Join(want, "\n") {
+		t.Errorf("lint errors mismatch; expected:\n%s\ngot:\n%s", strings.Join(want, "\n"), s)
+	}
+}
@@ -1,6 +1,8 @@
 package llutil
 
 import (
+	"fmt"
+
 	"github.com/wa-lang/llir"
 	"github.com/wa-lang/llir/value"
 )
@@ -37,5 +39,48 @@ func ResetNames(f *llir.Func) {
 				}
 			}
 		}
+		if term, ok := block.Term.(Ident); ok {
+			// clear ID of unnamed terminator (e.g. invoke).
+			if term.IsUnnamed() {
+				term.SetName("")
+			}
+		}
+	}
+}
+
+// LocalNames returns the set of local names in use in the given function; the
+// names of parameters, basic blocks, instructions and terminators.
+func LocalNames(f *llir.Func) map[string]bool {
+	names := make(map[string]bool)
+	for _, param := range f.Params {
+		if !param.IsUnnamed() {
+			names[param.LocalName] = true
+		}
+	}
+	for _, block := range f.Blocks {
+		if !block.IsUnnamed() {
+			names[block.LocalName] = true
+		}
+		for _, inst := range block.Insts {
+			if inst, ok := inst.(Ident); ok && !inst.IsUnnamed() {
+				names[inst.Name()] = true
+			}
+		}
+		if term, ok := block.Term.(Ident); ok && !term.IsUnnamed() {
+			names[term.Name()] = true
+		}
+	}
+	return names
+}
+
+// UniqueName returns a local name based on the given name which is not in the
+// given set of local names in use, and adds it to the set. A numeric suffix is
+// appended to the name if already in use (e.g. "x", "x1", "x2").
+func UniqueName(names map[string]bool, name string) string {
+	s := name
+	for i := 1; names[s]; i++ {
+		s = fmt.Sprintf("%s%d", name, i)
 	}
+	names[s] = true
+	return s
 }
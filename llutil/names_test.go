@@ -0,0 +1,33 @@
+package llutil
+
+import (
+	"testing"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/types"
+)
+
+func TestUniqueName(t *testing.T) {
+	x := llir.NewParam("x", types.I32)
+	f := llir.NewFunc("f", types.I32, x)
+	entry := f.NewBlock("entry")
+	y := entry.NewAdd(x, x)
+	y.SetName("x1")
+	entry.NewAdd(y, x)
+	entry.NewRet(y)
+	names := LocalNames(f)
+	golden := []struct {
+		name string
+		want string
+	}{
+		{name: "x", want: "x2"},
+		{name: "x", want: "x3"},
+		{name: "entry", want: "entry1"},
+		{name: "y", want: "y"},
+	}
+	for _, g := range golden {
+		if got := UniqueName(names, g.name); got != g.want {
+			t.Errorf("unique name of %q mismatch; expected %q, got %q", g.name, g.want, got)
+		}
+	}
+}
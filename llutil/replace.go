@@ -0,0 +1,56 @@
+package llutil
+
+import (
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/value"
+)
+
+// ReplaceAllUses replaces each use of old with new in the instructions and
+// terminators of the given function.
+func ReplaceAllUses(f *llir.Func, old, new value.Value) {
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			replaceOperands(Operands(inst), old, new)
+		}
+		if block.Term != nil {
+			replaceOperands(Operands(block.Term), old, new)
+		}
+	}
+}
+
+// RemoveInst removes the given instruction from its basic block. The boolean
+// return value reports whether the instruction was found.
+func RemoveInst(block *llir.Block, inst llir.Instruction) bool {
+	for i, v := range block.Insts {
+		if v == inst {
+			block.Insts = append(block.Insts[:i], block.Insts[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Operands returns the operands of the given instruction or terminator, as
+// returned by its Operands method, except that call arguments with parameter
+// attributes (*llir.Arg) are unwrapped; the operand refers to the value of the
+// argument.
+func Operands(user interface {
+	Operands() []*value.Value
+}) []*value.Value {
+	ops := user.Operands()
+	for i, op := range ops {
+		if arg, ok := (*op).(*llir.Arg); ok {
+			ops[i] = &arg.Value
+		}
+	}
+	return ops
+}
+
+// replaceOperands replaces each operand equal to old with new.
+func replaceOperands(ops []*value.Value, old, new value.Value) {
+	for _, op := range ops {
+		if *op == old {
+			*op = new
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package llutil
+
+import (
+	"testing"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/types"
+)
+
+func TestReplaceAllUses(t *testing.T) {
+	m := llir.NewModule()
+	g := m.NewFunc("g", types.Void, llir.NewParam("x", types.I32))
+	a := llir.NewParam("a", types.I32)
+	b := llir.NewParam("b", types.I32)
+	f := m.NewFunc("f", types.Void, a, b)
+	entry := f.NewBlock("entry")
+	add := entry.NewAdd(a, a)
+	// Argument with parameter attributes.
+	call := entry.NewCall(g, llir.NewArg(a, enum.ParamAttrNoUndef))
+	entry.NewRet(nil)
+	ReplaceAllUses(f, a, b)
+	if add.X != b || add.Y != b {
+		t.Errorf("operands of %q not replaced", add.LLString())
+	}
+	arg, ok := call.Args[0].(*llir.Arg)
+	if !ok || arg.Value != b {
+		t.Errorf("argument of %q not replaced", call.LLString())
+	}
+}
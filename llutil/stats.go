@@ -0,0 +1,364 @@
+package llutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/metadata"
+	"github.com/wa-lang/llir/value"
+)
+
+// ModuleStats holds statistics of a module. The statistics may be marshalled
+// to JSON.
+type ModuleStats struct {
+	// Number of function definitions.
+	Funcs int `json:"funcs"`
+	// Number of function declarations.
+	FuncDecls int `json:"func_decls"`
+	// Number of global variables.
+	Globals int `json:"globals"`
+	// Number of global variables by linkage; global variables without explicit
+	// linkage are counted as external.
+	GlobalsByLinkage map[string]int `json:"globals_by_linkage"`
+	// Number of aliases.
+	Aliases int `json:"aliases"`
+	// Number of indirect functions.
+	IFuncs int `json:"ifuncs"`
+	// Number of named metadata definitions.
+	NamedMetadata int `json:"named_metadata"`
+	// Number of unnamed metadata definitions by kind (e.g. "Tuple",
+	// "DILocation"); only top-level metadata definitions of the module are
+	// counted, not metadata nodes specified inline.
+	MetadataNodes map[string]int `json:"metadata_nodes"`
+	// Number of metadata attachments of instructions, functions and global
+	// variables by name (e.g. "dbg", "tbaa").
+	MetadataAttachments map[string]int `json:"metadata_attachments"`
+	// Statistics summed over all function definitions.
+	Total *FuncStats `json:"total"`
+	// Statistics of each function definition, in module order.
+	FuncStats []*FuncStats `json:"functions"`
+}
+
+// FuncStats holds statistics of a function definition.
+type FuncStats struct {
+	// Function name (without '@' prefix); empty for module totals.
+	Name string `json:"name,omitempty"`
+	// Number of basic blocks.
+	Blocks int `json:"blocks"`
+	// Number of instructions, including terminators; comments are not
+	// counted.
+	Insts int `json:"insts"`
+	// Number of instructions by opcode (e.g. "add", "br").
+	Opcodes map[string]int `json:"opcodes"`
+	// Number of phi instructions.
+	Phis int `json:"phis"`
+	// Number of call sites; call instructions and invoke and callbr
+	// terminators.
+	Calls int `json:"calls"`
+	// Number of direct call sites; calls to functions (possibly through
+	// pointer casts) and inline assembly.
+	DirectCalls int `json:"direct_calls"`
+	// Number of indirect call sites.
+	IndirectCalls int `json:"indirect_calls"`
+	// Number of alloca instructions.
+	Allocas int `json:"allocas"`
+}
+
+// Stats returns the statistics of the given module.
+func Stats(m *llir.Module) *ModuleStats {
+	s := &ModuleStats{
+		GlobalsByLinkage:    make(map[string]int),
+		MetadataNodes:       make(map[string]int),
+		MetadataAttachments: make(map[string]int),
+		Total:               newFuncStats(""),
+	}
+	for _, g := range m.Globals {
+		s.Globals++
+		linkage := g.Linkage
+		if linkage == enum.LinkageNone {
+			linkage = enum.LinkageExternal
+		}
+		s.GlobalsByLinkage[linkage.String()]++
+		s.addAttachments(g.Metadata)
+	}
+	s.Aliases = len(m.Aliases)
+	s.IFuncs = len(m.IFuncs)
+	s.NamedMetadata = len(m.NamedMetadataDefs)
+	for _, md := range m.MetadataDefs {
+		s.MetadataNodes[metadataKind(md)]++
+	}
+	for _, f := range m.Funcs {
+		s.addAttachments(f.Metadata)
+		if len(f.Blocks) == 0 {
+			s.FuncDecls++
+			continue
+		}
+		s.Funcs++
+		fs := FuncStatsOf(f)
+		s.FuncStats = append(s.FuncStats, fs)
+		s.Total.add(fs)
+		for _, block := range f.Blocks {
+			for _, inst := range block.Insts {
+				s.addAttachments(mdAttachments(inst))
+			}
+			if block.Term != nil {
+				s.addAttachments(mdAttachments(block.Term))
+			}
+		}
+	}
+	return s
+}
+
+// JSON returns the JSON encoding of the module statistics.
+func (s *ModuleStats) JSON() ([]byte, error) {
+	return json.MarshalIndent(s, "", "\t")
+}
+
+// FuncStatsOf returns the statistics of the given function definition.
+func FuncStatsOf(f *llir.Func) *FuncStats {
+	s := newFuncStats(f.Name())
+	for _, block := range f.Blocks {
+		s.Blocks++
+		for _, inst := range block.Insts {
+			s.addInst(inst)
+		}
+		if block.Term != nil {
+			s.addInst(block.Term)
+		}
+	}
+	return s
+}
+
+// newFuncStats returns new empty function statistics.
+func newFuncStats(name string) *FuncStats {
+	return &FuncStats{Name: name, Opcodes: make(map[string]int)}
+}
+
+// addInst adds the given instruction or terminator to the function
+// statistics.
+func (s *FuncStats) addInst(inst interface{}) {
+	if _, ok := inst.(*Comment); ok {
+		return
+	}
+	s.Insts++
+	s.Opcodes[Opcode(inst)]++
+	switch inst := inst.(type) {
+	case *llir.InstPhi:
+		s.Phis++
+	case *llir.InstAlloca:
+		s.Allocas++
+	case *llir.InstCall:
+		s.addCall(inst.Callee)
+	case *llir.TermInvoke:
+		s.addCall(inst.Invokee)
+	case *llir.TermCallBr:
+		s.addCall(inst.Callee)
+	}
+}
+
+// addCall adds a call site with the given callee to the function statistics.
+func (s *FuncStats) addCall(callee value.Value) {
+	s.Calls++
+	switch stripPointerCasts(callee).(type) {
+	case *llir.Func, *llir.InlineAsm:
+		s.DirectCalls++
+	default:
+		s.IndirectCalls++
+	}
+}
+
+// add adds the statistics of o to s.
+func (s *FuncStats) add(o *FuncStats) {
+	s.Blocks += o.Blocks
+	s.Insts += o.Insts
+	for opcode, n := range o.Opcodes {
+		s.Opcodes[opcode] += n
+	}
+	s.Phis += o.Phis
+	s.Calls += o.Calls
+	s.DirectCalls += o.DirectCalls
+	s.IndirectCalls += o.IndirectCalls
+	s.Allocas += o.Allocas
+}
+
+// addAttachments adds the given metadata attachments to the module
+// statistics.
+func (s *ModuleStats) addAttachments(mds []*metadata.Attachment) {
+	for _, md := range mds {
+		s.MetadataAttachments[md.Name]++
+	}
+}
+
+// mdAttachments returns the metadata attachments of the given instruction or
+// terminator.
+func mdAttachments(inst interface{}) []*metadata.Attachment {
+	if v, ok := inst.(interface {
+		MDAttachments() []*metadata.Attachment
+	}); ok {
+		return v.MDAttachments()
+	}
+	return nil
+}
+
+// metadataKind returns the kind of the given metadata definition; the name of
+// its Go type without package qualifier (e.g. "DILocation").
+func metadataKind(md metadata.Definition) string {
+	kind := fmt.Sprintf("%T", md)
+	if i := strings.LastIndex(kind, "."); i != -1 {
+		kind = kind[i+1:]
+	}
+	return kind
+}
+
+// Opcode returns the opcode of the given instruction or terminator (e.g.
+// "add", "br"); or the empty string for comments.
+func Opcode(inst interface{}) string {
+	switch inst.(type) {
+	// Pseudo-instructions.
+	case *Comment:
+		return ""
+	// Unary instructions.
+	case *llir.InstFNeg:
+		return "fneg"
+	// Binary instructions.
+	case *llir.InstAdd:
+		return "add"
+	case *llir.InstFAdd:
+		return "fadd"
+	case *llir.InstSub:
+		return "sub"
+	case *llir.InstFSub:
+		return "fsub"
+	case *llir.InstMul:
+		return "mul"
+	case *llir.InstFMul:
+		return "fmul"
+	case *llir.InstUDiv:
+		return "udiv"
+	case *llir.InstSDiv:
+		return "sdiv"
+	case *llir.InstFDiv:
+		return "fdiv"
+	case *llir.InstURem:
+		return "urem"
+	case *llir.InstSRem:
+		return "srem"
+	case *llir.InstFRem:
+		return "frem"
+	// Bitwise instructions.
+	case *llir.InstShl:
+		return "shl"
+	case *llir.InstLShr:
+		return "lshr"
+	case *llir.InstAShr:
+		return "ashr"
+	case *llir.InstAnd:
+		return "and"
+	case *llir.InstOr:
+		return "or"
+	case *llir.InstXor:
+		return "xor"
+	// Vector instructions.
+	case *llir.InstExtractElement:
+		return "extractelement"
+	case *llir.InstInsertElement:
+		return "insertelement"
+	case *llir.InstShuffleVector:
+		return "shufflevector"
+	// Aggregate instructions.
+	case *llir.InstExtractValue:
+		return "extractvalue"
+	case *llir.InstInsertValue:
+		return "insertvalue"
+	// Memory instructions.
+	case *llir.InstAlloca:
+		return "alloca"
+	case *llir.InstLoad:
+		return "load"
+	case *llir.InstStore:
+		return "store"
+	case *llir.InstFence:
+		return "fence"
+	case *llir.InstCmpXchg:
+		return "cmpxchg"
+	case *llir.InstAtomicRMW:
+		return "atomicrmw"
+	case *llir.InstGetElementPtr:
+		return "getelementptr"
+	// Conversion instructions.
+	case *llir.InstTrunc:
+		return "trunc"
+	case *llir.InstZExt:
+		return "zext"
+	case *llir.InstSExt:
+		return "sext"
+	case *llir.InstFPTrunc:
+		return "fptrunc"
+	case *llir.InstFPExt:
+		return "fpext"
+	case *llir.InstFPToUI:
+		return "fptoui"
+	case *llir.InstFPToSI:
+		return "fptosi"
+	case *llir.InstUIToFP:
+		return "uitofp"
+	case *llir.InstSIToFP:
+		return "sitofp"
+	case *llir.InstPtrToInt:
+		return "ptrtoint"
+	case *llir.InstIntToPtr:
+		return "inttoptr"
+	case *llir.InstBitCast:
+		return "bitcast"
+	case *llir.InstAddrSpaceCast:
+		return "addrspacecast"
+	// Other instructions.
+	case *llir.InstICmp:
+		return "icmp"
+	case *llir.InstFCmp:
+		return "fcmp"
+	case *llir.InstPhi:
+		return "phi"
+	case *llir.InstSelect:
+		return "select"
+	case *llir.InstFreeze:
+		return "freeze"
+	case *llir.InstCall:
+		return "call"
+	case *llir.InstVAArg:
+		return "va_arg"
+	case *llir.InstLandingPad:
+		return "landingpad"
+	case *llir.InstCatchPad:
+		return "catchpad"
+	case *llir.InstCleanupPad:
+		return "cleanuppad"
+	// Terminators.
+	case *llir.TermRet:
+		return "ret"
+	case *llir.TermBr, *llir.TermCondBr:
+		return "br"
+	case *llir.TermSwitch:
+		return "switch"
+	case *llir.TermIndirectBr:
+		return "indirectbr"
+	case *llir.TermInvoke:
+		return "invoke"
+	case *llir.TermCallBr:
+		return "callbr"
+	case *llir.TermResume:
+		return "resume"
+	case *llir.TermCatchSwitch:
+		return "catchswitch"
+	case *llir.TermCatchRet:
+		return "catchret"
+	case *llir.TermCleanupRet:
+		return "cleanupret"
+	case *llir.TermUnreachable:
+		return "unreachable"
+	}
+	panic(fmt.Errorf("support for instruction %T not yet implemented", inst))
+}
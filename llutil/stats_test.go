@@ -0,0 +1,102 @@
+package llutil
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/metadata"
+	"github.com/wa-lang/llir/types"
+)
+
+func TestStats(t *testing.T) {
+	m := llir.NewModule()
+	m.NewGlobalDef("x", constant.NewInt(types.I32, 0))
+	y := m.NewGlobalDef("y", constant.NewInt(types.I32, 0))
+	y.Linkage = enum.LinkageInternal
+	z := m.NewGlobalDef("z", constant.NewInt(types.I32, 0))
+	z.Linkage = enum.LinkageInternal
+	ext := m.NewFunc("ext", types.Void)
+	fnPtr := m.NewGlobal("fn_ptr", types.NewPointer(ext.Sig))
+	tbaa := &metadata.Tuple{MetadataID: -1, Fields: []metadata.Field{&metadata.String{Value: "int"}}}
+	m.MetadataDefs = append(m.MetadataDefs, tbaa)
+
+	c := llir.NewParam("c", types.I1)
+	f := m.NewFunc("f", types.I32, c)
+	entry := f.NewBlock("entry")
+	then := f.NewBlock("then")
+	exit := f.NewBlock("exit")
+	p := entry.NewAlloca(types.I32)
+	// Comments are not counted.
+	entry.Insts = append(entry.Insts, NewComment("load"))
+	load := entry.NewLoad(types.I32, p)
+	load.Metadata = append(load.Metadata, &metadata.Attachment{Name: "tbaa", Node: tbaa})
+	entry.NewCall(ext)
+	entry.NewCall(constant.NewBitCast(ext, types.NewPointer(types.NewFunc(types.Void, types.I32))), constant.NewInt(types.I32, 0))
+	entry.NewCondBr(c, then, exit)
+	callee := then.NewLoad(fnPtr.ContentType, fnPtr)
+	then.NewCall(callee)
+	then.NewBr(exit)
+	phi := exit.NewPhi(llir.NewIncoming(load, entry), llir.NewIncoming(constant.NewInt(types.I32, 1), then))
+	exit.NewRet(phi)
+	g := m.NewFunc("g", types.Void)
+	g.NewBlock("").NewRet(nil)
+
+	s := Stats(m)
+	want := &ModuleStats{
+		Funcs:               2,
+		FuncDecls:           1,
+		Globals:             4,
+		GlobalsByLinkage:    map[string]int{"external": 2, "internal": 2},
+		MetadataNodes:       map[string]int{"Tuple": 1},
+		MetadataAttachments: map[string]int{"tbaa": 1},
+		Total: &FuncStats{
+			Blocks:        4,
+			Insts:         11,
+			Opcodes:       map[string]int{"alloca": 1, "load": 2, "call": 3, "br": 2, "phi": 1, "ret": 2},
+			Phis:          1,
+			Calls:         3,
+			DirectCalls:   2,
+			IndirectCalls: 1,
+			Allocas:       1,
+		},
+		FuncStats: []*FuncStats{
+			{
+				Name:          "f",
+				Blocks:        3,
+				Insts:         10,
+				Opcodes:       map[string]int{"alloca": 1, "load": 2, "call": 3, "br": 2, "phi": 1, "ret": 1},
+				Phis:          1,
+				Calls:         3,
+				DirectCalls:   2,
+				IndirectCalls: 1,
+				Allocas:       1,
+			},
+			{
+				Name:    "g",
+				Blocks:  1,
+				Insts:   1,
+				Opcodes: map[string]int{"ret": 1},
+			},
+		},
+	}
+	if !reflect.DeepEqual(s, want) {
+		got, _ := json.Marshal(s)
+		exp, _ := json.Marshal(want)
+		t.Errorf("module statistics mismatch; expected %s, got %s", exp, got)
+	}
+	buf, err := s.JSON()
+	if err != nil {
+		t.Fatalf("unable to encode module statistics as JSON; %v", err)
+	}
+	dec := &ModuleStats{}
+	if err := json.Unmarshal(buf, dec); err != nil {
+		t.Fatalf("unable to decode module statistics; %v", err)
+	}
+	if !reflect.DeepEqual(dec, want) {
+		t.Errorf("decoded module statistics mismatch; expected %v, got %v", want, dec)
+	}
+}
@@ -0,0 +1,38 @@
+package llutil
+
+import (
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/value"
+)
+
+// Users returns the instructions and terminators using each value of the given
+// function, in function order. An instruction using a value more than once is
+// listed once.
+func Users(f *llir.Func) map[value.Value][]interface{} {
+	users := make(map[value.Value][]interface{})
+	addUses := func(user interface {
+		Operands() []*value.Value
+	}) {
+		for _, op := range Operands(user) {
+			v := *op
+			if v == nil {
+				continue
+			}
+			us := users[v]
+			if len(us) > 0 && us[len(us)-1] == user {
+				// Skip duplicate uses by the same instruction.
+				continue
+			}
+			users[v] = append(us, user)
+		}
+	}
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			addUses(inst)
+		}
+		if block.Term != nil {
+			addUses(block.Term)
+		}
+	}
+	return users
+}
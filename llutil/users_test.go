@@ -0,0 +1,30 @@
+package llutil
+
+import (
+	"testing"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/types"
+)
+
+func TestUsers(t *testing.T) {
+	m := llir.NewModule()
+	g := m.NewFunc("g", types.Void, llir.NewParam("x", types.I32))
+	a := llir.NewParam("a", types.I32)
+	f := m.NewFunc("f", types.Void, a)
+	entry := f.NewBlock("entry")
+	add := entry.NewAdd(a, a)
+	call := entry.NewCall(g, llir.NewArg(add, enum.ParamAttrNoUndef))
+	entry.NewRet(nil)
+	users := Users(f)
+	if got := users[a]; len(got) != 1 || got[0] != add {
+		t.Errorf("users of %s mismatch; expected [%s], got %v", a.Ident(), add.Ident(), got)
+	}
+	if got := users[add]; len(got) != 1 || got[0] != call {
+		t.Errorf("users of %s mismatch; expected [%q], got %v", add.Ident(), call.LLString(), got)
+	}
+	if got := users[call]; len(got) != 0 {
+		t.Errorf("users of %q mismatch; expected none, got %v", call.LLString(), got)
+	}
+}
@@ -0,0 +1,660 @@
+package llutil
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/types"
+	"github.com/wa-lang/llir/value"
+)
+
+// VerifyError is an error reported by Verify, locating the invalid IR.
+type VerifyError struct {
+	// Function identifier (e.g. "@f"); or empty if module-level.
+	Func string
+	// Basic block identifier (e.g. "%entry"); or empty if function-level.
+	Block string
+	// LLVM syntax representation of the instruction or terminator; or empty if
+	// block-level.
+	Inst string
+	// Error message.
+	Msg string
+}
+
+// Error returns the string representation of the verification error.
+func (e *VerifyError) Error() string {
+	buf := &strings.Builder{}
+	if len(e.Func) > 0 {
+		fmt.Fprintf(buf, "function %s: ", e.Func)
+	}
+	if len(e.Block) > 0 {
+		fmt.Fprintf(buf, "block %s: ", e.Block)
+	}
+	if len(e.Inst) > 0 {
+		fmt.Fprintf(buf, "%q: ", e.Inst)
+	}
+	buf.WriteString(e.Msg)
+	return buf.String()
+}
+
+// Verify verifies the given module, and returns the errors found; or nil if
+// the module is valid.
+//
+// The following properties are verified:
+//
+//    * parent pointers of functions and basic blocks;
+//    * valid combinations of linkage, visibility and DLL storage class;
+//    * each basic block has a terminator;
+//    * phi instructions are located at the start of basic blocks, and have one
+//      incoming value per predecessor;
+//    * definitions dominate their uses;
+//    * operand types are consistent (e.g. binary operands, store, load, call
+//      arguments, branch conditions);
+//    * return values match the function signature.
+//
+// IDs are assigned to unnamed local variables while verifying, to identify
+// them in error messages; the original IDs are restored before returning.
+func Verify(m *llir.Module) []error {
+	v := &verifier{}
+	for _, g := range m.Globals {
+		if msg := checkLinkage(g.Linkage, g.Visibility, g.DLLStorageClass, g.Init == nil); len(msg) > 0 {
+			v.errorf(nil, nil, nil, "global %s: %s", g.Ident(), msg)
+		}
+		if g.Linkage == enum.LinkageAppending {
+			if _, ok := g.ContentType.(*types.ArrayType); !ok {
+				v.errorf(nil, nil, nil, "global %s: appending linkage of non-array global variable", g.Ident())
+			}
+		}
+		if g.Init != nil && !g.Init.Type().Equal(g.ContentType) {
+			v.errorf(nil, nil, nil, "global %s: initializer type %v does not match content type %v", g.Ident(), g.Init.Type(), g.ContentType)
+		}
+	}
+	for _, f := range m.Funcs {
+		if f.Parent != m {
+			v.errorf(f, nil, nil, "invalid parent module")
+		}
+		v.verifyFunc(f)
+	}
+	return v.errs
+}
+
+// verifier is a module verifier.
+type verifier struct {
+	// Errors found.
+	errs []error
+}
+
+// errorf records a verification error located at the given function, basic
+// block and instruction or terminator, each of which may be nil.
+func (v *verifier) errorf(f *llir.Func, block *llir.Block, inst interface{}, format string, args ...interface{}) {
+	e := &VerifyError{Msg: fmt.Sprintf(format, args...)}
+	if f != nil {
+		e.Func = f.Ident()
+	}
+	if block != nil {
+		e.Block = block.Ident()
+	}
+	if inst != nil {
+		e.Inst = llString(inst)
+	}
+	v.errs = append(v.errs, e)
+}
+
+// checkLinkage returns a message describing the invalid combination of the
+// given linkage, visibility and DLL storage class; or the empty string if
+// valid. The decl flag reports whether the global is a declaration.
+func checkLinkage(linkage enum.Linkage, visibility enum.Visibility, dll enum.DLLStorageClass, decl bool) string {
+	local := linkage == enum.LinkagePrivate || linkage == enum.LinkageInternal
+	switch {
+	case local && visibility != enum.VisibilityNone && visibility != enum.VisibilityDefault:
+		return fmt.Sprintf("%v linkage requires default visibility, got %v", linkage, visibility)
+	case local && dll != enum.DLLStorageClassNone:
+		return fmt.Sprintf("%v linkage incompatible with %v", linkage, dll)
+	case dll == enum.DLLStorageClassDLLImport && visibility != enum.VisibilityNone && visibility != enum.VisibilityDefault:
+		return fmt.Sprintf("dllimport requires default visibility, got %v", visibility)
+	case decl && linkage != enum.LinkageNone && linkage != enum.LinkageExternal && linkage != enum.LinkageExternWeak:
+		return fmt.Sprintf("invalid linkage %v of declaration; expected external or extern_weak", linkage)
+	case !decl && linkage == enum.LinkageExternWeak:
+		return "extern_weak linkage of definition"
+	case !decl && dll == enum.DLLStorageClassDLLImport:
+		return "dllimport of definition"
+	}
+	return ""
+}
+
+// def is the definition site of a local value.
+type def struct {
+	// Basic block of the definition.
+	block *llir.Block
+	// Index of the instruction within the basic block; or len(block.Insts) for
+	// terminators.
+	index int
+}
+
+// verifyFunc verifies the given function.
+func (v *verifier) verifyFunc(f *llir.Func) {
+	if msg := checkLinkage(f.Linkage, f.Visibility, f.DLLStorageClass, len(f.Blocks) == 0); len(msg) > 0 {
+		v.errorf(f, nil, nil, "%s", msg)
+	}
+	if len(f.Params) != len(f.Sig.Params) {
+		v.errorf(f, nil, nil, "number of parameters (%d) does not match signature (%d)", len(f.Params), len(f.Sig.Params))
+	} else {
+		for i, param := range f.Params {
+			if !param.Typ.Equal(f.Sig.Params[i]) {
+				v.errorf(f, nil, nil, "type of parameter %s (%v) does not match signature (%v)", param.Ident(), param.Typ, f.Sig.Params[i])
+			}
+		}
+	}
+	if len(f.Blocks) == 0 {
+		return
+	}
+	defer saveIDs(f)()
+	if err := f.AssignIDs(); err != nil {
+		v.errorf(f, nil, nil, "%v", err)
+	}
+	// Definition sites of local values, and parameters.
+	defs := make(map[value.Value]def)
+	params := make(map[value.Value]bool)
+	for _, param := range f.Params {
+		params[param] = true
+	}
+	blocks := make(map[*llir.Block]bool)
+	for _, block := range f.Blocks {
+		blocks[block] = true
+		if block.Parent != f {
+			v.errorf(f, block, nil, "invalid parent function")
+		}
+		for i, inst := range block.Insts {
+			if val, ok := inst.(value.Value); ok {
+				defs[val] = def{block: block, index: i}
+			}
+		}
+		if val, ok := block.Term.(value.Value); ok {
+			defs[val] = def{block: block, index: len(block.Insts)}
+		}
+	}
+	// Control flow graph.
+	preds := make(map[*llir.Block][]*llir.Block)
+	for _, block := range f.Blocks {
+		if block.Term == nil {
+			v.errorf(f, block, nil, "missing terminator")
+			continue
+		}
+		for _, succ := range block.Term.Succs() {
+			if !blocks[succ] {
+				v.errorf(f, block, block.Term, "successor %s not in function", succ.Ident())
+				continue
+			}
+			preds[succ] = append(preds[succ], block)
+		}
+	}
+	if entry := f.Blocks[0]; len(preds[entry]) > 0 {
+		v.errorf(f, entry, nil, "entry basic block has predecessors")
+	}
+	dom := newDominators(f, preds)
+	// Instructions and terminators.
+	for _, block := range f.Blocks {
+		phis := true
+		for i, inst := range block.Insts {
+			phi, isPhi := inst.(*llir.InstPhi)
+			if isPhi && !phis {
+				v.errorf(f, block, inst, "phi instruction not at start of basic block")
+			}
+			phis = phis && isPhi
+			if isPhi {
+				v.verifyPhi(f, block, phi, preds[block])
+			} else {
+				v.verifyInst(f, block, inst)
+			}
+			// Dominance.
+			for _, op := range Operands(inst) {
+				v.verifyUse(f, block, inst, *op, i, phi, defs, params, dom)
+			}
+		}
+		if block.Term == nil {
+			continue
+		}
+		v.verifyTerm(f, block, block.Term)
+		for _, op := range Operands(block.Term) {
+			v.verifyUse(f, block, block.Term, *op, len(block.Insts), nil, defs, params, dom)
+		}
+	}
+}
+
+// verifyUse verifies the use of the given operand by the instruction or
+// terminator at index i of block; the definition of a local value must
+// dominate its use. For phi instructions, the definition must dominate the end
+// of the incoming predecessor basic block.
+func (v *verifier) verifyUse(f *llir.Func, block *llir.Block, inst interface{}, op value.Value, i int, phi *llir.InstPhi, defs map[value.Value]def, params map[value.Value]bool, dom *dominators) {
+	switch op := op.(type) {
+	case nil:
+		v.errorf(f, block, inst, "nil operand")
+		return
+	case *llir.Param:
+		if !params[op] {
+			v.errorf(f, block, inst, "operand %s refers to parameter of another function", op.Ident())
+		}
+		return
+	case *llir.Block:
+		// Label operands are verified as successors.
+		return
+	case llir.Instruction, llir.Terminator:
+		// Local values.
+	default:
+		return
+	}
+	d, ok := defs[op]
+	if !ok {
+		v.errorf(f, block, inst, "operand %s refers to instruction not in function", op.Ident())
+		return
+	}
+	if !dom.reachable(block) {
+		// Uses within unreachable basic blocks are not checked.
+		return
+	}
+	if phi != nil {
+		for _, inc := range phi.Incs {
+			if inc.X != op {
+				continue
+			}
+			pred, ok := inc.Pred.(*llir.Block)
+			if !ok || !dom.reachable(pred) {
+				continue
+			}
+			if !dom.dominatesIncoming(d, pred, block) {
+				v.errorf(f, block, inst, "definition of %s does not dominate end of incoming basic block %s", op.Ident(), pred.Ident())
+			}
+		}
+		return
+	}
+	if !dom.dominatesUse(d, block, i) {
+		v.errorf(f, block, inst, "definition of %s does not dominate use", op.Ident())
+	}
+}
+
+// verifyPhi verifies the incoming values of the given phi instruction against
+// the predecessors of its basic block.
+func (v *verifier) verifyPhi(f *llir.Func, block *llir.Block, phi *llir.InstPhi, preds []*llir.Block) {
+	want := make(map[*llir.Block]bool)
+	for _, pred := range preds {
+		want[pred] = true
+	}
+	got := make(map[*llir.Block]value.Value)
+	for _, inc := range phi.Incs {
+		if !inc.X.Type().Equal(phi.Typ) {
+			v.errorf(f, block, phi, "type of incoming value %s (%v) does not match phi type (%v)", inc.X.Ident(), inc.X.Type(), phi.Typ)
+		}
+		pred, ok := inc.Pred.(*llir.Block)
+		if !ok {
+			v.errorf(f, block, phi, "invalid incoming basic block %s", inc.Pred.Ident())
+			continue
+		}
+		if !want[pred] {
+			v.errorf(f, block, phi, "incoming basic block %s is not a predecessor", pred.Ident())
+			continue
+		}
+		if prev, ok := got[pred]; ok && prev != inc.X {
+			v.errorf(f, block, phi, "different incoming values from predecessor %s", pred.Ident())
+		}
+		got[pred] = inc.X
+	}
+	for _, pred := range preds {
+		if _, ok := got[pred]; !ok {
+			v.errorf(f, block, phi, "missing incoming value from predecessor %s", pred.Ident())
+		}
+	}
+}
+
+// verifyInst verifies the operand types of the given non-phi instruction.
+func (v *verifier) verifyInst(f *llir.Func, block *llir.Block, inst llir.Instruction) {
+	sameType := func(x, y value.Value) {
+		if !x.Type().Equal(y.Type()) {
+			v.errorf(f, block, inst, "operand type mismatch; %v and %v", x.Type(), y.Type())
+		}
+	}
+	switch inst := inst.(type) {
+	case *llir.InstAdd:
+		sameType(inst.X, inst.Y)
+	case *llir.InstFAdd:
+		sameType(inst.X, inst.Y)
+	case *llir.InstSub:
+		sameType(inst.X, inst.Y)
+	case *llir.InstFSub:
+		sameType(inst.X, inst.Y)
+	case *llir.InstMul:
+		sameType(inst.X, inst.Y)
+	case *llir.InstFMul:
+		sameType(inst.X, inst.Y)
+	case *llir.InstUDiv:
+		sameType(inst.X, inst.Y)
+	case *llir.InstSDiv:
+		sameType(inst.X, inst.Y)
+	case *llir.InstFDiv:
+		sameType(inst.X, inst.Y)
+	case *llir.InstURem:
+		sameType(inst.X, inst.Y)
+	case *llir.InstSRem:
+		sameType(inst.X, inst.Y)
+	case *llir.InstFRem:
+		sameType(inst.X, inst.Y)
+	case *llir.InstShl:
+		sameType(inst.X, inst.Y)
+	case *llir.InstLShr:
+		sameType(inst.X, inst.Y)
+	case *llir.InstAShr:
+		sameType(inst.X, inst.Y)
+	case *llir.InstAnd:
+		sameType(inst.X, inst.Y)
+	case *llir.InstOr:
+		sameType(inst.X, inst.Y)
+	case *llir.InstXor:
+		sameType(inst.X, inst.Y)
+	case *llir.InstICmp:
+		sameType(inst.X, inst.Y)
+	case *llir.InstFCmp:
+		sameType(inst.X, inst.Y)
+	case *llir.InstSelect:
+		sameType(inst.ValueTrue, inst.ValueFalse)
+		if !isBool(inst.Cond.Type()) {
+			v.errorf(f, block, inst, "invalid condition type %v; expected i1", inst.Cond.Type())
+		}
+	case *llir.InstLoad:
+		if ptr, ok := inst.Src.Type().(*types.PointerType); !ok {
+			v.errorf(f, block, inst, "invalid source type %v; expected pointer type", inst.Src.Type())
+		} else if !ptr.ElemType.Equal(inst.ElemType) {
+			v.errorf(f, block, inst, "element type %v does not match source element type %v", inst.ElemType, ptr.ElemType)
+		}
+	case *llir.InstStore:
+		if ptr, ok := inst.Dst.Type().(*types.PointerType); !ok {
+			v.errorf(f, block, inst, "invalid destination type %v; expected pointer type", inst.Dst.Type())
+		} else if !ptr.ElemType.Equal(inst.Src.Type()) {
+			v.errorf(f, block, inst, "source type %v does not match destination element type %v", inst.Src.Type(), ptr.ElemType)
+		}
+	case *llir.InstCall:
+		v.verifyArgs(f, block, inst, inst.Callee, inst.Args)
+	}
+}
+
+// verifyTerm verifies the operand types of the given terminator.
+func (v *verifier) verifyTerm(f *llir.Func, block *llir.Block, term llir.Terminator) {
+	switch term := term.(type) {
+	case *llir.TermRet:
+		retType := f.Sig.RetType
+		switch {
+		case term.X == nil && !types.IsVoid(retType):
+			v.errorf(f, block, term, "missing return value of type %v", retType)
+		case term.X != nil && types.IsVoid(retType):
+			v.errorf(f, block, term, "return value in function with void return type")
+		case term.X != nil && !term.X.Type().Equal(retType):
+			v.errorf(f, block, term, "return value type %v does not match function return type %v", term.X.Type(), retType)
+		}
+	case *llir.TermCondBr:
+		if !types.Equal(term.Cond.Type(), types.I1) {
+			v.errorf(f, block, term, "invalid condition type %v; expected i1", term.Cond.Type())
+		}
+	case *llir.TermSwitch:
+		for _, c := range term.Cases {
+			if !c.X.Type().Equal(term.X.Type()) {
+				v.errorf(f, block, term, "case type %v does not match control variable type %v", c.X.Type(), term.X.Type())
+			}
+		}
+	case *llir.TermInvoke:
+		v.verifyArgs(f, block, term, term.Invokee, term.Args)
+	case *llir.TermCallBr:
+		v.verifyArgs(f, block, term, term.Callee, term.Args)
+	}
+}
+
+// verifyArgs verifies the given call arguments against the signature of the
+// callee.
+func (v *verifier) verifyArgs(f *llir.Func, block *llir.Block, inst interface{}, callee value.Value, args []value.Value) {
+	ptr, ok := callee.Type().(*types.PointerType)
+	if !ok {
+		v.errorf(f, block, inst, "invalid callee type %v; expected pointer to function type", callee.Type())
+		return
+	}
+	sig, ok := ptr.ElemType.(*types.FuncType)
+	if !ok {
+		v.errorf(f, block, inst, "invalid callee type %v; expected pointer to function type", callee.Type())
+		return
+	}
+	if len(args) < len(sig.Params) || (!sig.Variadic && len(args) != len(sig.Params)) {
+		v.errorf(f, block, inst, "number of arguments (%d) does not match callee signature %v", len(args), sig)
+		return
+	}
+	for i, param := range sig.Params {
+		if !args[i].Type().Equal(param) {
+			v.errorf(f, block, inst, "type of argument %d (%v) does not match callee parameter type %v", i, args[i].Type(), param)
+		}
+	}
+}
+
+// isBool reports whether t is a boolean or vector of boolean type.
+func isBool(t types.Type) bool {
+	if t, ok := t.(*types.VectorType); ok {
+		return types.Equal(t.ElemType, types.I1)
+	}
+	return types.Equal(t, types.I1)
+}
+
+// llString returns the LLVM syntax representation of the given instruction or
+// terminator. Panics caused by invalid IR during printing are recovered.
+func llString(inst interface{}) (s string) {
+	defer func() {
+		if e := recover(); e != nil {
+			s = fmt.Sprintf("<%T>", inst)
+		}
+	}()
+	if inst, ok := inst.(llir.LLStringer); ok {
+		return inst.LLString()
+	}
+	return fmt.Sprintf("<%T>", inst)
+}
+
+// ### [ Dominators ] ##########################################################
+
+// dominators is the dominator tree of a function.
+type dominators struct {
+	// Immediate dominator of each reachable basic block; the entry basic block
+	// is its own immediate dominator.
+	idom map[*llir.Block]*llir.Block
+	// Reverse post-order number of each reachable basic block.
+	rpo map[*llir.Block]int
+	// Predecessors of each basic block.
+	preds map[*llir.Block][]*llir.Block
+}
+
+// newDominators returns the dominator tree of f, based on the iterative
+// algorithm of Cooper, Harvey and Kennedy.
+func newDominators(f *llir.Func, preds map[*llir.Block][]*llir.Block) *dominators {
+	d := &dominators{
+		idom:  make(map[*llir.Block]*llir.Block),
+		rpo:   make(map[*llir.Block]int),
+		preds: preds,
+	}
+	// Post-order of reachable basic blocks.
+	var order []*llir.Block
+	visited := make(map[*llir.Block]bool)
+	var visit func(block *llir.Block)
+	visit = func(block *llir.Block) {
+		visited[block] = true
+		if block.Term != nil {
+			for _, succ := range block.Term.Succs() {
+				if !visited[succ] && succ.Parent == f {
+					visit(succ)
+				}
+			}
+		}
+		order = append(order, block)
+	}
+	entry := f.Blocks[0]
+	visit(entry)
+	for i, block := range order {
+		d.rpo[block] = len(order) - 1 - i
+	}
+	d.idom[entry] = entry
+	for changed := true; changed; {
+		changed = false
+		for i := len(order) - 1; i >= 0; i-- {
+			block := order[i]
+			if block == entry {
+				continue
+			}
+			var idom *llir.Block
+			for _, pred := range preds[block] {
+				if _, ok := d.idom[pred]; !ok {
+					continue
+				}
+				if idom == nil {
+					idom = pred
+				} else {
+					idom = d.intersect(pred, idom)
+				}
+			}
+			if d.idom[block] != idom {
+				d.idom[block] = idom
+				changed = true
+			}
+		}
+	}
+	return d
+}
+
+// intersect returns the nearest common dominator of the given basic blocks.
+func (d *dominators) intersect(a, b *llir.Block) *llir.Block {
+	for a != b {
+		for d.rpo[a] > d.rpo[b] {
+			a = d.idom[a]
+		}
+		for d.rpo[b] > d.rpo[a] {
+			b = d.idom[b]
+		}
+	}
+	return a
+}
+
+// reachable reports whether the given basic block is reachable from the entry
+// basic block.
+func (d *dominators) reachable(block *llir.Block) bool {
+	_, ok := d.rpo[block]
+	return ok
+}
+
+// dominates reports whether basic block a dominates basic block b.
+func (d *dominators) dominates(a, b *llir.Block) bool {
+	if !d.reachable(b) {
+		return true
+	}
+	for {
+		if a == b {
+			return true
+		}
+		idom := d.idom[b]
+		if idom == b {
+			return false
+		}
+		b = idom
+	}
+}
+
+// dominatesUse reports whether the definition dominates the use at index i of
+// the given basic block.
+func (d *dominators) dominatesUse(def def, block *llir.Block, i int) bool {
+	if def.index == len(def.block.Insts) {
+		// Results of invoke and callbr terminators are available in the normal
+		// return basic block, and results of catchswitch terminators in its
+		// handler basic blocks, provided they are only reached from the
+		// terminator.
+		return d.dominatesTermResult(def, block)
+	}
+	if def.block == block {
+		return def.index < i
+	}
+	return d.dominates(def.block, block)
+}
+
+// dominatesEnd reports whether the definition dominates the end of the given
+// basic block.
+func (d *dominators) dominatesEnd(def def, block *llir.Block) bool {
+	if def.index == len(def.block.Insts) {
+		return d.dominatesTermResult(def, block)
+	}
+	return d.dominates(def.block, block)
+}
+
+// dominatesIncoming reports whether the definition is available to the phi
+// instructions of the given basic block on the incoming edge from pred.
+func (d *dominators) dominatesIncoming(def def, pred, block *llir.Block) bool {
+	if def.index == len(def.block.Insts) && def.block == pred {
+		// Results of invoke and callbr terminators are available on the edge to
+		// their normal return basic block.
+		switch term := pred.Term.(type) {
+		case *llir.TermInvoke:
+			if term.NormalRetTarget == block {
+				return true
+			}
+		case *llir.TermCallBr:
+			if term.NormalRetTarget == block {
+				return true
+			}
+		}
+	}
+	return d.dominatesEnd(def, pred)
+}
+
+// dominatesTermResult reports whether the result of the terminator definition
+// dominates the given basic block.
+func (d *dominators) dominatesTermResult(def def, block *llir.Block) bool {
+	var targets []value.Value
+	switch term := def.block.Term.(type) {
+	case *llir.TermInvoke:
+		targets = []value.Value{term.NormalRetTarget}
+	case *llir.TermCallBr:
+		targets = []value.Value{term.NormalRetTarget}
+	case *llir.TermCatchSwitch:
+		// The result of catchswitch is used by the catchpads of its handlers.
+		targets = term.Handlers
+	default:
+		return false
+	}
+	for _, t := range targets {
+		target, ok := t.(*llir.Block)
+		if ok && len(d.preds[target]) == 1 && d.dominates(target, block) {
+			return true
+		}
+	}
+	return false
+}
+
+// saveIDs returns a function which restores the current IDs of the unnamed
+// local variables of the given function.
+func saveIDs(f *llir.Func) (restore func()) {
+	var idents []Ident
+	for _, param := range f.Params {
+		idents = append(idents, param)
+	}
+	for _, block := range f.Blocks {
+		idents = append(idents, block)
+		for _, inst := range block.Insts {
+			if inst, ok := inst.(Ident); ok {
+				idents = append(idents, inst)
+			}
+		}
+		if term, ok := block.Term.(Ident); ok {
+			idents = append(idents, term)
+		}
+	}
+	ids := make([]int64, len(idents))
+	for i, ident := range idents {
+		ids[i] = ident.ID()
+	}
+	return func() {
+		for i, ident := range idents {
+			if ident.IsUnnamed() {
+				ident.SetID(ids[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package llutil
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/metadata"
+)
+
+// VerifyDebugInfo verifies the debug information metadata of the given module
+// and returns the errors found; or nil if the debug information is valid.
+//
+// The following properties are verified:
+//
+//    * DWARF tags of specialized metadata nodes match the node kind (e.g.
+//      DW_TAG_base_type for DIBasicType);
+//    * each subprogram definition has a unit, and the unit of each subprogram
+//      is a compile unit listed in the !llvm.dbg.cu named metadata;
+//    * the !dbg attachment of a function definition is a distinct subprogram
+//      definition;
+//    * the scope of each !dbg location of an instruction or terminator chains
+//      up, through inlined-at locations, to the subprogram of the enclosing
+//      function;
+//    * calls in functions with debug information have a !dbg location, except
+//      calls to intrinsics.
+func VerifyDebugInfo(m *llir.Module) []error {
+	v := &verifier{}
+	// Assign metadata IDs to refer to metadata definitions by ID in errors.
+	if err := m.AssignMetadataIDs(); err != nil {
+		v.errorf(nil, nil, nil, "%v", err)
+	}
+	// Index compile units listed in !llvm.dbg.cu.
+	cus := make(map[*metadata.DICompileUnit]bool)
+	if def, ok := m.NamedMetadataDefs["llvm.dbg.cu"]; ok {
+		for _, node := range def.Nodes {
+			cu, ok := node.(*metadata.DICompileUnit)
+			if !ok {
+				v.errorf(nil, nil, nil, "!llvm.dbg.cu: invalid node %s; expected DICompileUnit", node.Ident())
+				continue
+			}
+			cus[cu] = true
+		}
+	}
+	for _, md := range m.MetadataDefs {
+		if msg := checkDwarfTag(md); len(msg) > 0 {
+			v.errorf(nil, nil, nil, "metadata %s: %s", md.Ident(), msg)
+		}
+		if sp, ok := md.(*metadata.DISubprogram); ok {
+			v.verifySubprogram(nil, sp, cus)
+		}
+	}
+	for _, f := range m.Funcs {
+		v.verifyFuncDebugInfo(f, cus)
+	}
+	return v.errs
+}
+
+// verifyFuncDebugInfo verifies the debug information of the given function.
+func (v *verifier) verifyFuncDebugInfo(f *llir.Func, cus map[*metadata.DICompileUnit]bool) {
+	var sp *metadata.DISubprogram
+	for _, md := range f.Metadata {
+		if md.Name != "dbg" {
+			continue
+		}
+		s, ok := md.Node.(*metadata.DISubprogram)
+		if !ok {
+			v.errorf(f, nil, nil, "invalid !dbg attachment %s; expected DISubprogram", md.Node.Ident())
+			continue
+		}
+		sp = s
+	}
+	if sp == nil {
+		// Verify that no debug locations are present in functions without debug
+		// information, as they cannot be scoped.
+		for _, block := range f.Blocks {
+			for _, inst := range block.Insts {
+				if dbgLocation(inst) != nil {
+					v.errorf(f, block, inst, "!dbg location in function without subprogram")
+				}
+			}
+			if block.Term != nil && dbgLocation(block.Term) != nil {
+				v.errorf(f, block, block.Term, "!dbg location in function without subprogram")
+			}
+		}
+		return
+	}
+	if len(f.Blocks) > 0 {
+		if !sp.Distinct {
+			v.errorf(f, nil, nil, "!dbg attachment %s of function definition is not distinct", sp.Ident())
+		}
+		if !isDefinition(sp) {
+			v.errorf(f, nil, nil, "!dbg attachment %s of function definition is not a subprogram definition", sp.Ident())
+		}
+	}
+	v.verifySubprogram(f, sp, cus)
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			v.verifyLocation(f, block, inst, sp)
+			if _, ok := inst.(*llir.InstCall); ok && dbgLocation(inst) == nil && !isIntrinsicCall(inst) {
+				v.errorf(f, block, inst, "call in function with debug information lacks !dbg location")
+			}
+		}
+		if block.Term != nil {
+			v.verifyLocation(f, block, block.Term, sp)
+			switch block.Term.(type) {
+			case *llir.TermInvoke, *llir.TermCallBr:
+				if dbgLocation(block.Term) == nil {
+					v.errorf(f, block, block.Term, "call in function with debug information lacks !dbg location")
+				}
+			}
+		}
+	}
+}
+
+// verifySubprogram verifies the unit of the given subprogram, which is
+// optionally attached to f.
+func (v *verifier) verifySubprogram(f *llir.Func, sp *metadata.DISubprogram, cus map[*metadata.DICompileUnit]bool) {
+	switch {
+	case sp.Unit == nil:
+		if isDefinition(sp) {
+			v.errorf(f, nil, nil, "subprogram definition %s lacks unit", sp.Ident())
+		}
+	case !cus[sp.Unit]:
+		v.errorf(f, nil, nil, "unit %s of subprogram %s not listed in !llvm.dbg.cu", sp.Unit.Ident(), sp.Ident())
+	}
+}
+
+// verifyLocation verifies the !dbg location of the given instruction or
+// terminator in f, with subprogram sp.
+func (v *verifier) verifyLocation(f *llir.Func, block *llir.Block, inst interface{}, sp *metadata.DISubprogram) {
+	loc := dbgLocation(inst)
+	if loc == nil {
+		return
+	}
+	// The outermost inlined-at location is scoped in the enclosing function.
+	for loc.InlinedAt != nil {
+		if scopeSubprogram(loc.Scope) == nil {
+			v.errorf(f, block, inst, "scope of location %s is not within a subprogram", loc.Ident())
+			return
+		}
+		loc = loc.InlinedAt
+	}
+	switch s := scopeSubprogram(loc.Scope); {
+	case s == nil:
+		v.errorf(f, block, inst, "scope of location %s is not within a subprogram", loc.Ident())
+	case s != sp:
+		v.errorf(f, block, inst, "scope of location %s is within subprogram %s; expected %s", loc.Ident(), s.Ident(), sp.Ident())
+	}
+}
+
+// dbgLocation returns the !dbg location attached to the given instruction or
+// terminator; or nil if not present.
+func dbgLocation(inst interface{}) *metadata.DILocation {
+	v, ok := inst.(interface {
+		MDAttachments() []*metadata.Attachment
+	})
+	if !ok {
+		return nil
+	}
+	for _, md := range v.MDAttachments() {
+		if md.Name != "dbg" {
+			continue
+		}
+		if loc, ok := md.Node.(*metadata.DILocation); ok {
+			return loc
+		}
+	}
+	return nil
+}
+
+// scopeSubprogram returns the subprogram enclosing the given local scope; or
+// nil if the scope chain does not end in a subprogram.
+func scopeSubprogram(scope metadata.Field) *metadata.DISubprogram {
+	// Guard against cyclic scope chains.
+	visited := make(map[metadata.Field]bool)
+	for scope != nil && !visited[scope] {
+		visited[scope] = true
+		switch s := scope.(type) {
+		case *metadata.DISubprogram:
+			return s
+		case *metadata.DILexicalBlock:
+			scope = s.Scope
+		case *metadata.DILexicalBlockFile:
+			scope = s.Scope
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// isDefinition reports whether the given subprogram is a definition.
+func isDefinition(sp *metadata.DISubprogram) bool {
+	return sp.IsDefinition || sp.SPFlags&enum.DISPFlagDefinition != 0
+}
+
+// isIntrinsicCall reports whether the given instruction is a call to an LLVM
+// intrinsic function.
+func isIntrinsicCall(inst llir.Instruction) bool {
+	call, ok := inst.(*llir.InstCall)
+	if !ok {
+		return false
+	}
+	callee, ok := call.Callee.(*llir.Func)
+	return ok && strings.HasPrefix(callee.Name(), "llvm.")
+}
+
+// checkDwarfTag returns a message describing the invalid DWARF tag of the given
+// metadata node; or the empty string if valid or if the node has no tag.
+func checkDwarfTag(md metadata.Definition) string {
+	var (
+		tag   enum.DwarfTag
+		valid []enum.DwarfTag
+	)
+	switch md := md.(type) {
+	case *metadata.DIBasicType:
+		if md.Tag == 0 {
+			return ""
+		}
+		tag = md.Tag
+		valid = []enum.DwarfTag{enum.DwarfTagBaseType, enum.DwarfTagUnspecifiedType}
+	case *metadata.DICompositeType:
+		tag = md.Tag
+		valid = []enum.DwarfTag{enum.DwarfTagArrayType, enum.DwarfTagClassType, enum.DwarfTagEnumerationType, enum.DwarfTagStructureType, enum.DwarfTagUnionType, enum.DwarfTagVariantPart, enum.DwarfTagNamelist}
+	case *metadata.DIDerivedType:
+		tag = md.Tag
+		valid = []enum.DwarfTag{enum.DwarfTagTypedef, enum.DwarfTagPointerType, enum.DwarfTagPtrToMemberType, enum.DwarfTagReferenceType, enum.DwarfTagRvalueReferenceType, enum.DwarfTagConstType, enum.DwarfTagVolatileType, enum.DwarfTagRestrictType, enum.DwarfTagAtomicType, enum.DwarfTagImmutableType, enum.DwarfTagMember, enum.DwarfTagInheritance, enum.DwarfTagFriend, enum.DwarfTagSetType, enum.DwarfTagVariant}
+	case *metadata.DIImportedEntity:
+		tag = md.Tag
+		valid = []enum.DwarfTag{enum.DwarfTagImportedModule, enum.DwarfTagImportedDeclaration}
+	case *metadata.DITemplateValueParameter:
+		if md.Tag == 0 {
+			return ""
+		}
+		tag = md.Tag
+		valid = []enum.DwarfTag{enum.DwarfTagTemplateValueParameter, enum.DwarfTagGNUTemplateTemplateParam, enum.DwarfTagGNUTemplateParameterPack}
+	default:
+		return ""
+	}
+	for _, t := range valid {
+		if tag == t {
+			return ""
+		}
+	}
+	return fmt.Sprintf("invalid DWARF tag %v of %s", tag, strings.TrimPrefix(fmt.Sprintf("%T", md), "*metadata."))
+}
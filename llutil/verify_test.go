@@ -0,0 +1,147 @@
+package llutil
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/metadata"
+	"github.com/wa-lang/llir/types"
+)
+
+func TestVerifyValid(t *testing.T) {
+	m := llir.NewModule()
+	x := llir.NewParam("x", types.I32)
+	f := m.NewFunc("f", types.I32, x)
+	entry := f.NewBlock("entry")
+	loop := f.NewBlock("loop")
+	exit := f.NewBlock("exit")
+	entry.NewBr(loop)
+	i := loop.NewPhi(llir.NewIncoming(constant.NewInt(types.I32, 0), entry))
+	next := loop.NewAdd(i, constant.NewInt(types.I32, 1))
+	i.Incs = append(i.Incs, llir.NewIncoming(next, loop))
+	cond := loop.NewICmp(enum.IPredSLT, next, x)
+	loop.NewCondBr(cond, loop, exit)
+	exit.NewRet(next)
+	if errs := Verify(m); len(errs) > 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
+
+// TestVerifyInvokePhi tests phi instructions using the result of an invoke
+// terminator on the edge to its normal return basic block.
+func TestVerifyInvokePhi(t *testing.T) {
+	m := llir.NewModule()
+	foo := m.NewFunc("foo", types.I32)
+	x := llir.NewParam("x", types.I1)
+	f := m.NewFunc("f", types.I32, x)
+	entry := f.NewBlock("entry")
+	call := f.NewBlock("call")
+	cont := f.NewBlock("cont")
+	unwind := f.NewBlock("unwind")
+	entry.NewCondBr(x, call, cont)
+	result := call.NewInvoke(foo, nil, cont, unwind)
+	phi := cont.NewPhi(llir.NewIncoming(result, call), llir.NewIncoming(constant.NewInt(types.I32, 0), entry))
+	cont.NewRet(phi)
+	lp := unwind.NewLandingPad(types.NewStruct(types.I8Ptr, types.I32))
+	lp.Cleanup = true
+	unwind.NewResume(lp)
+	if errs := Verify(m); len(errs) > 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
+
+func TestVerifyInvalid(t *testing.T) {
+	m := llir.NewModule()
+	g := m.NewGlobal("g", types.I32)
+	g.Linkage = enum.LinkageInternal
+	use := m.NewFunc("use", types.Void, llir.NewParam("", types.I32))
+	x := llir.NewParam("x", types.I32)
+	f := m.NewFunc("f", types.I32, x)
+	entry := f.NewBlock("entry")
+	then := f.NewBlock("then")
+	end := f.NewBlock("end")
+	cond := entry.NewICmp(enum.IPredEQ, x, constant.NewInt(types.I32, 0))
+	entry.NewCondBr(cond, then, end)
+	y := then.NewAdd(x, constant.NewInt(types.I64, 1))
+	then.NewBr(end)
+	// Missing incoming value from entry.
+	end.NewPhi(llir.NewIncoming(x, then))
+	// Use not dominated by definition.
+	end.NewAdd(y, x)
+	end.NewCall(use, llir.NewArg(y, enum.ParamAttrNoUndef))
+	end.NewRet(nil)
+	f.NewBlock("dangling")
+
+	var got []string
+	for _, err := range Verify(m) {
+		got = append(got, err.Error())
+	}
+	s := strings.Join(got, "\n")
+	for _, want := range []string{
+		"global @g: invalid linkage internal of declaration; expected external or extern_weak",
+		`function @f: block %then: "%1 = add i32 %x, 1": operand type mismatch; i32 and i64`,
+		`function @f: block %end: "%2 = phi i32 [ %x, %then ]": missing incoming value from predecessor %entry`,
+		`function @f: block %end: "%3 = add i32 %1, %x": definition of %1 does not dominate use`,
+		`function @f: block %end: "call void @use(i32 noundef %1)": definition of %1 does not dominate use`,
+		`function @f: block %end: "ret void": missing return value of type i32`,
+		"function @f: block %dangling: missing terminator",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("unable to locate %q in errors:\n%s", want, s)
+		}
+	}
+	// IDs of unnamed local variables are restored.
+	if y.ID() != 0 {
+		t.Errorf("ID of %s not restored", y.Ident())
+	}
+}
+
+func TestVerifyDebugInfo(t *testing.T) {
+	m := llir.NewModule()
+	file := &metadata.DIFile{MetadataID: -1, Filename: "a.c"}
+	cu := &metadata.DICompileUnit{MetadataID: -1, Distinct: true, Language: enum.DwarfLangC99, File: file}
+	other := &metadata.DICompileUnit{MetadataID: -1, Distinct: true, Language: enum.DwarfLangC99, File: file}
+	spF := &metadata.DISubprogram{MetadataID: -1, Distinct: true, Name: "f", File: file, IsDefinition: true, Unit: cu}
+	spG := &metadata.DISubprogram{MetadataID: -1, Distinct: true, Name: "g", File: file, IsDefinition: true, Unit: other}
+	block := &metadata.DILexicalBlock{MetadataID: -1, Scope: spF, File: file}
+	typ := &metadata.DIBasicType{MetadataID: -1, Tag: enum.DwarfTagPointerType, Name: "int"}
+	m.MetadataDefs = append(m.MetadataDefs, file, cu, other, spF, spG, block, typ)
+	m.NamedMetadataDefs["llvm.dbg.cu"] = &metadata.NamedDef{Name: "llvm.dbg.cu", Nodes: []metadata.Node{cu}}
+	dbg := func(node metadata.MDNode) *metadata.Attachment {
+		return &metadata.Attachment{Name: "dbg", Node: node}
+	}
+
+	callee := m.NewFunc("callee", types.Void)
+	f := m.NewFunc("f", types.Void)
+	f.Metadata = append(f.Metadata, dbg(spF))
+	entry := f.NewBlock("entry")
+	call := entry.NewCall(callee)
+	call.Metadata = append(call.Metadata, dbg(&metadata.DILocation{MetadataID: -1, Line: 1, Scope: block}))
+	// Missing !dbg location.
+	entry.NewCall(callee)
+	ret := entry.NewRet(nil)
+	// Location scoped in another function.
+	ret.Metadata = append(ret.Metadata, dbg(&metadata.DILocation{MetadataID: -1, Line: 2, Scope: spG}))
+
+	var got []string
+	for _, err := range VerifyDebugInfo(m) {
+		got = append(got, err.Error())
+	}
+	s := strings.Join(got, "\n")
+	for _, want := range []string{
+		"metadata !6: invalid DWARF tag DW_TAG_pointer_type of DIBasicType",
+		"unit !2 of subprogram !4 not listed in !llvm.dbg.cu",
+		`function @f: block %entry: "call void @callee()": call in function with debug information lacks !dbg location`,
+		`function @f: block %entry: "ret void, !dbg !DILocation(line: 2, scope: !4)": scope of location !DILocation(line: 2, scope: !4) is within subprogram !4; expected !3`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("missing error %q; got:\n%s", want, s)
+		}
+	}
+	if len(got) != 4 {
+		t.Errorf("expected 4 errors, got %d:\n%s", len(got), s)
+	}
+}
@@ -77,15 +77,45 @@ func (m *Module) String() string {
 
 // WriteTo write the string representation of the module in LLVM IR assembly
 // syntax to w.
+//
+// An error is returned if IDs cannot be assigned (e.g. ID conflicts), if a
+// basic block lacks a terminator, or if an instruction or terminator has a nil
+// operand or operands of invalid type. Nothing is written to w in these cases.
 func (m *Module) WriteTo(w io.Writer) (n int64, err error) {
 	fw := &fmtWriter{w: w}
 	// Assign global IDs.
 	if err := m.AssignGlobalIDs(); err != nil {
-		panic(fmt.Errorf("unable to assign globals IDs of module; %v", err))
+		return 0, errors.Errorf("unable to assign globals IDs of module; %v", err)
 	}
 	// Assign metadata IDs.
 	if err := m.AssignMetadataIDs(); err != nil {
-		panic(fmt.Errorf("unable to assign metadata IDs of module; %v", err))
+		return 0, errors.Errorf("unable to assign metadata IDs of module; %v", err)
+	}
+	// Check for invalid operands, assign local IDs and check for missing
+	// terminators. Operands are checked first, as assigning IDs relies on the
+	// types of instructions.
+	for _, f := range m.Funcs {
+		c := newOperandChecker()
+		for _, block := range f.Blocks {
+			for _, inst := range block.Insts {
+				if err := c.check(inst); err != nil {
+					return 0, errors.Errorf("invalid instruction in basic block %q of function %q; %v", block.Ident(), f.Ident(), err)
+				}
+			}
+			if block.Term != nil {
+				if err := c.check(block.Term); err != nil {
+					return 0, errors.Errorf("invalid terminator in basic block %q of function %q; %v", block.Ident(), f.Ident(), err)
+				}
+			}
+		}
+		if err := f.AssignIDs(); err != nil {
+			return 0, errors.Errorf("unable to assign IDs of function %q; %v", f.Ident(), err)
+		}
+		for _, block := range f.Blocks {
+			if block.Term == nil {
+				return 0, errors.Errorf("missing terminator in basic block %q of function %q", block.Ident(), f.Ident())
+			}
+		}
 	}
 	// Source filename.
 	if len(m.SourceFilename) > 0 {
@@ -407,3 +437,87 @@ func (m *Module) AssignMetadataIDs() error {
 	}
 	return nil
 }
+
+// operandChecker checks the operands of instructions and terminators on which
+// printing relies, so that printing does not panic.
+type operandChecker struct {
+	// Checked instructions and terminators.
+	checked map[interface{}]bool
+}
+
+// newOperandChecker returns a new operand checker.
+func newOperandChecker() *operandChecker {
+	return &operandChecker{checked: make(map[interface{}]bool)}
+}
+
+// check returns an error if the given instruction or terminator has a nil
+// operand, or operands of invalid type. Instructions and terminators used as
+// operands are checked first, as the type of v may be computed from theirs.
+func (c *operandChecker) check(v interface{ Operands() []*value.Value }) error {
+	if c.checked[v] {
+		return nil
+	}
+	c.checked[v] = true
+	for i, op := range v.Operands() {
+		switch op := (*op).(type) {
+		case nil:
+			return errors.Errorf("nil operand at index %d", i)
+		case Instruction:
+			if err := c.check(op); err != nil {
+				return err
+			}
+		case Terminator:
+			if err := c.check(op); err != nil {
+				return err
+			}
+		}
+	}
+	var err error
+	switch v := v.(type) {
+	case *InstExtractValue:
+		if v.Typ == nil {
+			_, err = tryAggregateElemType(v.X.Type(), v.Indices)
+		}
+	case *InstGetElementPtr:
+		if v.Typ == nil {
+			_, err = tryGEPInstType(v.ElemType, v.Src.Type(), v.Indices)
+		}
+	case *InstAtomicRMW:
+		if v.Typ == nil {
+			if _, ok := v.Dst.Type().(*types.PointerType); !ok {
+				err = errors.Errorf("invalid destination type; expected *types.PointerType, got %T", v.Dst.Type())
+			}
+		}
+	case *InstICmp:
+		if v.Typ == nil {
+			_, err = icmpType(v.X.Type())
+		}
+	case *InstFCmp:
+		if v.Typ == nil {
+			_, err = fcmpType(v.X.Type())
+		}
+	case *InstPhi:
+		if v.Typ == nil && len(v.Incs) == 0 {
+			err = errors.New("phi instruction without incoming values")
+		}
+	case *InstCall:
+		_, err = calleeSig(v.Callee)
+	case *InstExtractElement:
+		if v.Typ == nil {
+			_, err = vectorType(v.X)
+		}
+	case *InstInsertElement:
+		if v.Typ == nil {
+			_, err = vectorType(v.X)
+		}
+	case *InstShuffleVector:
+		if v.Typ == nil {
+			_, err = shuffleVectorType(v.X, v.Mask)
+		}
+	case *TermInvoke:
+		_, err = calleeSig(v.Invokee)
+	case *TermCallBr:
+		_, err = calleeSig(v.Callee)
+	}
+	return err
+}
@@ -36,6 +36,12 @@ type Terminator interface {
 	LLStringer
 	// Succs returns the successor basic blocks of the terminator.
 	Succs() []*Block
+	// Operands returns a mutable list of operands of the terminator. Each
+	// element points to an operand slot, and may thus be used to rewrite the
+	// operand in place. Target basic blocks are included as operands.
+	Operands() []*value.Value
+	// SetOperand sets the i-th operand of the terminator to v.
+	SetOperand(i int, v value.Value)
 }
 
 // --- [ ret ] -----------------------------------------------------------------
@@ -85,6 +91,19 @@ func (term *TermRet) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given terminator.
+func (term *TermRet) Operands() []*value.Value {
+	if term.X != nil {
+		return []*value.Value{&term.X}
+	}
+	return nil
+}
+
+// SetOperand sets the i-th operand of the given terminator to v.
+func (term *TermRet) SetOperand(i int, v value.Value) {
+	setOperand(term.Operands(), i, v)
+}
+
 // --- [ br ] ------------------------------------------------------------------
 
 // TermBr is an unconditional LLVM IR br terminator.
@@ -94,7 +113,7 @@ type TermBr struct {
 
 	// extra.
 
-	// Successor basic blocks of the terminator.
+	// Successor basic blocks of the terminator; recomputed by Succs.
 	Successors []*Block
 	// (optional) Metadata.
 	Metadata
@@ -108,10 +127,8 @@ func NewBr(target *Block) *TermBr {
 
 // Succs returns the successor basic blocks of the terminator.
 func (term *TermBr) Succs() []*Block {
-	// Cache successors if not present.
-	if term.Successors == nil {
-		term.Successors = []*Block{term.Target.(*Block)}
-	}
+	// Recompute successors, as targets may have been updated through Operands.
+	term.Successors = []*Block{term.Target.(*Block)}
 	return term.Successors
 }
 
@@ -127,6 +144,16 @@ func (term *TermBr) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given terminator.
+func (term *TermBr) Operands() []*value.Value {
+	return []*value.Value{&term.Target}
+}
+
+// SetOperand sets the i-th operand of the given terminator to v.
+func (term *TermBr) SetOperand(i int, v value.Value) {
+	setOperand(term.Operands(), i, v)
+}
+
 // --- [ conditional br ] ------------------------------------------------------
 
 // TermCondBr is a conditional LLVM IR br terminator.
@@ -140,7 +167,7 @@ type TermCondBr struct {
 
 	// extra.
 
-	// Successor basic blocks of the terminator.
+	// Successor basic blocks of the terminator; recomputed by Succs.
 	Successors []*Block
 	// (optional) Metadata.
 	Metadata
@@ -154,10 +181,8 @@ func NewCondBr(cond value.Value, targetTrue, targetFalse *Block) *TermCondBr {
 
 // Succs returns the successor basic blocks of the terminator.
 func (term *TermCondBr) Succs() []*Block {
-	// Cache successors if not present.
-	if term.Successors == nil {
-		term.Successors = []*Block{term.TargetTrue.(*Block), term.TargetFalse.(*Block)}
-	}
+	// Recompute successors, as targets may have been updated through Operands.
+	term.Successors = []*Block{term.TargetTrue.(*Block), term.TargetFalse.(*Block)}
 	return term.Successors
 }
 
@@ -173,6 +198,16 @@ func (term *TermCondBr) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given terminator.
+func (term *TermCondBr) Operands() []*value.Value {
+	return []*value.Value{&term.Cond, &term.TargetTrue, &term.TargetFalse}
+}
+
+// SetOperand sets the i-th operand of the given terminator to v.
+func (term *TermCondBr) SetOperand(i int, v value.Value) {
+	setOperand(term.Operands(), i, v)
+}
+
 // --- [ switch ] --------------------------------------------------------------
 
 // TermSwitch is an LLVM IR switch terminator.
@@ -186,7 +221,7 @@ type TermSwitch struct {
 
 	// extra.
 
-	// Successor basic blocks of the terminator.
+	// Successor basic blocks of the terminator; recomputed by Succs.
 	Successors []*Block
 	// (optional) Metadata.
 	Metadata
@@ -200,15 +235,13 @@ func NewSwitch(x value.Value, targetDefault *Block, cases ...*Case) *TermSwitch
 
 // Succs returns the successor basic blocks of the terminator.
 func (term *TermSwitch) Succs() []*Block {
-	// Cache successors if not present.
-	if term.Successors == nil {
-		succs := make([]*Block, 0, 1+len(term.Cases))
-		succs = append(succs, term.TargetDefault.(*Block))
-		for _, c := range term.Cases {
-			succs = append(succs, c.Target.(*Block))
-		}
-		term.Successors = succs
+	// Recompute successors, as targets may have been updated through Operands.
+	succs := make([]*Block, 0, 1+len(term.Cases))
+	succs = append(succs, term.TargetDefault.(*Block))
+	for _, c := range term.Cases {
+		succs = append(succs, c.Target.(*Block))
 	}
+	term.Successors = succs
 	return term.Successors
 }
 
@@ -228,6 +261,21 @@ func (term *TermSwitch) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given terminator.
+func (term *TermSwitch) Operands() []*value.Value {
+	ops := make([]*value.Value, 0, 2+2*len(term.Cases))
+	ops = append(ops, &term.X, &term.TargetDefault)
+	for _, c := range term.Cases {
+		ops = append(ops, &c.X, &c.Target)
+	}
+	return ops
+}
+
+// SetOperand sets the i-th operand of the given terminator to v.
+func (term *TermSwitch) SetOperand(i int, v value.Value) {
+	setOperand(term.Operands(), i, v)
+}
+
 // ~~~ [ Switch case ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // Case is a switch case.
@@ -261,7 +309,7 @@ type TermIndirectBr struct {
 
 	// extra.
 
-	// Successor basic blocks of the terminator.
+	// Successor basic blocks of the terminator; recomputed by Succs.
 	Successors []*Block
 	// (optional) Metadata.
 	Metadata
@@ -281,12 +329,11 @@ func NewIndirectBr(addr constant.Constant, validTargets ...*Block) *TermIndirect
 
 // Succs returns the successor basic blocks of the terminator.
 func (term *TermIndirectBr) Succs() []*Block {
-	// Cache successors if not present.
-	if term.Successors == nil {
-		// convert ValidTargets slice to []*ir.Block.
-		for _, target := range term.ValidTargets {
-			term.Successors = append(term.Successors, target.(*Block))
-		}
+	// Recompute successors, as targets may have been updated through Operands.
+	term.Successors = nil
+	// convert ValidTargets slice to []*ir.Block.
+	for _, target := range term.ValidTargets {
+		term.Successors = append(term.Successors, target.(*Block))
 	}
 	return term.Successors
 }
@@ -310,6 +357,20 @@ func (term *TermIndirectBr) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given terminator.
+func (term *TermIndirectBr) Operands() []*value.Value {
+	ops := []*value.Value{&term.Addr}
+	for i := range term.ValidTargets {
+		ops = append(ops, &term.ValidTargets[i])
+	}
+	return ops
+}
+
+// SetOperand sets the i-th operand of the given terminator to v.
+func (term *TermIndirectBr) SetOperand(i int, v value.Value) {
+	setOperand(term.Operands(), i, v)
+}
+
 // --- [ invoke ] --------------------------------------------------------------
 
 // TermInvoke is an LLVM IR invoke terminator.
@@ -334,7 +395,7 @@ type TermInvoke struct {
 
 	// Type of result produced by the terminator.
 	Typ types.Type
-	// Successor basic blocks of the terminator.
+	// Successor basic blocks of the terminator; recomputed by Succs.
 	Successors []*Block
 	// (optional) Calling convention; zero if not present.
 	CallingConv enum.CallingConv
@@ -380,10 +441,8 @@ func (term *TermInvoke) Type() types.Type {
 
 // Succs returns the successor basic blocks of the terminator.
 func (term *TermInvoke) Succs() []*Block {
-	// Cache successors if not present.
-	if term.Successors == nil {
-		term.Successors = []*Block{term.NormalRetTarget.(*Block), term.ExceptionRetTarget.(*Block)}
-	}
+	// Recompute successors, as targets may have been updated through Operands.
+	term.Successors = []*Block{term.NormalRetTarget.(*Block), term.ExceptionRetTarget.(*Block)}
 	return term.Successors
 }
 
@@ -439,6 +498,21 @@ func (term *TermInvoke) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given terminator.
+func (term *TermInvoke) Operands() []*value.Value {
+	ops := []*value.Value{&term.Invokee}
+	for i := range term.Args {
+		ops = append(ops, &term.Args[i])
+	}
+	ops = append(ops, operandBundleOperands(term.OperandBundles)...)
+	return append(ops, &term.NormalRetTarget, &term.ExceptionRetTarget)
+}
+
+// SetOperand sets the i-th operand of the given terminator to v.
+func (term *TermInvoke) SetOperand(i int, v value.Value) {
+	setOperand(term.Operands(), i, v)
+}
+
 // Sig returns the function signature of the invokee.
 func (term *TermInvoke) Sig() *types.FuncType {
 	t, ok := term.Invokee.Type().(*types.PointerType)
@@ -476,7 +550,7 @@ type TermCallBr struct {
 
 	// Type of result produced by the terminator.
 	Typ types.Type
-	// Successor basic blocks of the terminator.
+	// Successor basic blocks of the terminator; recomputed by Succs.
 	Successors []*Block
 	// (optional) Calling convention; zero if not present.
 	CallingConv enum.CallingConv
@@ -527,13 +601,11 @@ func (term *TermCallBr) Type() types.Type {
 
 // Succs returns the successor basic blocks of the terminator.
 func (term *TermCallBr) Succs() []*Block {
-	// Cache successors if not present.
-	if term.Successors == nil {
-		term.Successors = []*Block{term.NormalRetTarget.(*Block)}
-		// Convert OtherRetTargets slice to []*ir.Block.
-		for _, otherRetTarget := range term.OtherRetTargets {
-			term.Successors = append(term.Successors, otherRetTarget.(*Block))
-		}
+	// Recompute successors, as targets may have been updated through Operands.
+	term.Successors = []*Block{term.NormalRetTarget.(*Block)}
+	// Convert OtherRetTargets slice to []*ir.Block.
+	for _, otherRetTarget := range term.OtherRetTargets {
+		term.Successors = append(term.Successors, otherRetTarget.(*Block))
 	}
 	return term.Successors
 }
@@ -597,6 +669,25 @@ func (term *TermCallBr) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given terminator.
+func (term *TermCallBr) Operands() []*value.Value {
+	ops := []*value.Value{&term.Callee}
+	for i := range term.Args {
+		ops = append(ops, &term.Args[i])
+	}
+	ops = append(ops, operandBundleOperands(term.OperandBundles)...)
+	ops = append(ops, &term.NormalRetTarget)
+	for i := range term.OtherRetTargets {
+		ops = append(ops, &term.OtherRetTargets[i])
+	}
+	return ops
+}
+
+// SetOperand sets the i-th operand of the given terminator to v.
+func (term *TermCallBr) SetOperand(i int, v value.Value) {
+	setOperand(term.Operands(), i, v)
+}
+
 // Sig returns the function signature of the callee.
 func (term *TermCallBr) Sig() *types.FuncType {
 	t, ok := term.Callee.Type().(*types.PointerType)
@@ -647,6 +738,16 @@ func (term *TermResume) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given terminator.
+func (term *TermResume) Operands() []*value.Value {
+	return []*value.Value{&term.X}
+}
+
+// SetOperand sets the i-th operand of the given terminator to v.
+func (term *TermResume) SetOperand(i int, v value.Value) {
+	setOperand(term.Operands(), i, v)
+}
+
 // --- [ catchswitch ] ---------------------------------------------------------
 
 // TermCatchSwitch is an LLVM IR catchswitch terminator.
@@ -663,7 +764,7 @@ type TermCatchSwitch struct {
 
 	// extra.
 
-	// Successor basic blocks of the terminator.
+	// Successor basic blocks of the terminator; recomputed by Succs.
 	Successors []*Block
 	// (optional) Metadata.
 	Metadata
@@ -703,15 +804,14 @@ func (term *TermCatchSwitch) Type() types.Type {
 
 // Succs returns the successor basic blocks of the terminator.
 func (term *TermCatchSwitch) Succs() []*Block {
-	// Cache successors if not present.
-	if term.Successors == nil {
-		// convert Handlers slice to []*ir.Block.
-		for _, handler := range term.Handlers {
-			term.Successors = append(term.Successors, handler.(*Block))
-		}
-		if defaultUnwindTarget, ok := term.DefaultUnwindTarget.(*Block); ok {
-			term.Successors = append(term.Successors, defaultUnwindTarget)
-		}
+	// Recompute successors, as targets may have been updated through Operands.
+	term.Successors = nil
+	// convert Handlers slice to []*ir.Block.
+	for _, handler := range term.Handlers {
+		term.Successors = append(term.Successors, handler.(*Block))
+	}
+	if defaultUnwindTarget, ok := term.DefaultUnwindTarget.(*Block); ok {
+		term.Successors = append(term.Successors, defaultUnwindTarget)
 	}
 	return term.Successors
 }
@@ -741,6 +841,23 @@ func (term *TermCatchSwitch) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given terminator.
+func (term *TermCatchSwitch) Operands() []*value.Value {
+	ops := []*value.Value{&term.ParentPad}
+	for i := range term.Handlers {
+		ops = append(ops, &term.Handlers[i])
+	}
+	if term.DefaultUnwindTarget != nil {
+		ops = append(ops, &term.DefaultUnwindTarget)
+	}
+	return ops
+}
+
+// SetOperand sets the i-th operand of the given terminator to v.
+func (term *TermCatchSwitch) SetOperand(i int, v value.Value) {
+	setOperand(term.Operands(), i, v)
+}
+
 // --- [ catchret ] ------------------------------------------------------------
 
 // TermCatchRet is an LLVM IR catchret terminator, which catches an in-flight
@@ -753,7 +870,7 @@ type TermCatchRet struct {
 
 	// extra.
 
-	// Successor basic blocks of the terminator.
+	// Successor basic blocks of the terminator; recomputed by Succs.
 	Successors []*Block
 	// (optional) Metadata.
 	Metadata
@@ -767,10 +884,8 @@ func NewCatchRet(catchPad *InstCatchPad, target *Block) *TermCatchRet {
 
 // Succs returns the successor basic blocks of the terminator.
 func (term *TermCatchRet) Succs() []*Block {
-	// Cache successors if not present.
-	if term.Successors == nil {
-		term.Successors = []*Block{term.Target.(*Block)}
-	}
+	// Recompute successors, as targets may have been updated through Operands.
+	term.Successors = []*Block{term.Target.(*Block)}
 	return term.Successors
 }
 
@@ -786,6 +901,16 @@ func (term *TermCatchRet) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given terminator.
+func (term *TermCatchRet) Operands() []*value.Value {
+	return []*value.Value{&term.CatchPad, &term.Target}
+}
+
+// SetOperand sets the i-th operand of the given terminator to v.
+func (term *TermCatchRet) SetOperand(i int, v value.Value) {
+	setOperand(term.Operands(), i, v)
+}
+
 // --- [ cleanupret ] ----------------------------------------------------------
 
 // TermCleanupRet is an LLVM IR cleanupret terminator, which indicates that the
@@ -800,7 +925,7 @@ type TermCleanupRet struct {
 
 	// extra.
 
-	// Successor basic blocks of the terminator.
+	// Successor basic blocks of the terminator; recomputed by Succs.
 	Successors []*Block
 	// (optional) Metadata.
 	Metadata
@@ -824,13 +949,11 @@ func NewCleanupRet(cleanupPad *InstCleanupPad, unwindTarget *Block) *TermCleanup
 
 // Succs returns the successor basic blocks of the terminator.
 func (term *TermCleanupRet) Succs() []*Block {
-	// Cache successors if not present.
-	if term.Successors == nil {
-		if unwindTarget, ok := term.UnwindTarget.(*Block); ok {
-			term.Successors = []*Block{unwindTarget}
-		} else {
-			term.Successors = []*Block{}
-		}
+	// Recompute successors, as targets may have been updated through Operands.
+	if unwindTarget, ok := term.UnwindTarget.(*Block); ok {
+		term.Successors = []*Block{unwindTarget}
+	} else {
+		term.Successors = []*Block{}
 	}
 	return term.Successors
 }
@@ -852,6 +975,20 @@ func (term *TermCleanupRet) LLString() string {
 	return buf.String()
 }
 
+// Operands returns a mutable list of operands of the given terminator.
+func (term *TermCleanupRet) Operands() []*value.Value {
+	ops := []*value.Value{&term.CleanupPad}
+	if term.UnwindTarget != nil {
+		ops = append(ops, &term.UnwindTarget)
+	}
+	return ops
+}
+
+// SetOperand sets the i-th operand of the given terminator to v.
+func (term *TermCleanupRet) SetOperand(i int, v value.Value) {
+	setOperand(term.Operands(), i, v)
+}
+
 // --- [ unreachable ] ---------------------------------------------------------
 
 // TermUnreachable is an LLVM IR unreachable terminator.
@@ -884,3 +1021,14 @@ func (term *TermUnreachable) LLString() string {
 	}
 	return buf.String()
 }
+
+// Operands returns a mutable list of operands of the given terminator.
+func (term *TermUnreachable) Operands() []*value.Value {
+	// no operands.
+	return nil
+}
+
+// SetOperand sets the i-th operand of the given terminator to v.
+func (term *TermUnreachable) SetOperand(i int, v value.Value) {
+	setOperand(term.Operands(), i, v)
+}
@@ -0,0 +1,38 @@
+package transform
+
+import (
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/llutil"
+	"github.com/wa-lang/llir/value"
+)
+
+// replaceUses replaces each use of a value in the instructions and terminators
+// of the given function by its replacement value, following chains of
+// replacements.
+func replaceUses(f *llir.Func, repl map[value.Value]value.Value) {
+	replaceOperands := func(ops []*value.Value) {
+		for _, op := range ops {
+			*op = resolve(repl, *op)
+		}
+	}
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			replaceOperands(llutil.Operands(inst))
+		}
+		if block.Term != nil {
+			replaceOperands(llutil.Operands(block.Term))
+		}
+	}
+}
+
+// resolve returns the replacement value of v, following chains of
+// replacements.
+func resolve(repl map[value.Value]value.Value, v value.Value) value.Value {
+	for {
+		r, ok := repl[v]
+		if !ok {
+			return v
+		}
+		v = r
+	}
+}
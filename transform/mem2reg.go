@@ -0,0 +1,297 @@
+// Package transform provides transformations of LLVM IR functions.
+package transform
+
+import (
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/cfg"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/llutil"
+	"github.com/wa-lang/llir/types"
+	"github.com/wa-lang/llir/value"
+)
+
+// IsPromotable reports whether the given alloca instruction of f may be
+// promoted to SSA values by PromoteAllocas.
+//
+// An alloca instruction is promotable if it allocates a single element, and is
+// only used as the source address of non-volatile, non-atomic load
+// instructions and as the destination address of non-volatile, non-atomic
+// store instructions, which load and store values of its element type.
+func IsPromotable(f *llir.Func, alloca *llir.InstAlloca) bool {
+	return isPromotable(alloca, llutil.Users(f)[alloca])
+}
+
+// PromoteAllocas promotes the promotable alloca instructions of the given
+// function to SSA values, and returns the promoted alloca instructions in
+// function order.
+//
+// Loads of promoted allocas are replaced by the value last stored on each
+// path, using phi instructions inserted at the iterated dominance frontiers of
+// the stores; loads not preceded by a store on some path yield undef. The
+// promoted allocas and their loads and stores are removed, as are inserted phi
+// instructions which turn out to be unused.
+//
+// Phi instructions are named after the alloca they promote (e.g. "x1" for
+// "x"). IDs of unnamed local variables are reset.
+func PromoteAllocas(f *llir.Func) []*llir.InstAlloca {
+	users := llutil.Users(f)
+	var allocas []*llir.InstAlloca
+	promoted := make(map[*llir.InstAlloca]bool)
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			if alloca, ok := inst.(*llir.InstAlloca); ok && isPromotable(alloca, users[alloca]) {
+				allocas = append(allocas, alloca)
+				promoted[alloca] = true
+			}
+		}
+	}
+	if len(allocas) == 0 {
+		return nil
+	}
+	p := &promoter{
+		promoted: promoted,
+		dom:      cfg.NewDomTree(cfg.New(f)),
+		phis:     make(map[*llir.Block][]*phiSlot),
+		repl:     make(map[value.Value]value.Value),
+		names:    llutil.LocalNames(f),
+	}
+	// Insert phi instructions at the iterated dominance frontiers of the basic
+	// blocks storing to each alloca.
+	parent := make(map[interface{}]*llir.Block)
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			parent[inst] = block
+		}
+	}
+	for _, alloca := range allocas {
+		var defs []*llir.Block
+		for _, user := range users[alloca] {
+			if _, ok := user.(*llir.InstStore); ok {
+				defs = append(defs, parent[user])
+			}
+		}
+		for _, block := range p.dom.IteratedFrontier(defs) {
+			phi := &llir.InstPhi{Typ: alloca.ElemType}
+			if !alloca.IsUnnamed() {
+				phi.SetName(llutil.UniqueName(p.names, alloca.LocalName))
+			}
+			p.phis[block] = append(p.phis[block], &phiSlot{alloca: alloca, phi: phi})
+		}
+	}
+	// Rename loads and stores in a pre-order walk of the dominator tree.
+	for _, root := range p.dom.Roots() {
+		cur := make(map[*llir.InstAlloca]value.Value)
+		for _, alloca := range allocas {
+			cur[alloca] = constant.NewUndef(alloca.ElemType)
+		}
+		p.rename(root, cur)
+	}
+	// Loads in unreachable basic blocks yield undef, and incoming values of
+	// edges from unreachable basic blocks are undef.
+	for _, block := range p.dom.Graph.Unreachable() {
+		for _, inst := range block.Insts {
+			if load, ok := inst.(*llir.InstLoad); ok && p.isPromoted(load.Src) {
+				p.repl[load] = constant.NewUndef(load.ElemType)
+			}
+		}
+		p.addIncomings(block, nil)
+	}
+	p.removeDeadPhis(f)
+	// Remove promoted memory operations and insert phi instructions.
+	for _, block := range f.Blocks {
+		var insts []llir.Instruction
+		for _, slot := range p.phis[block] {
+			insts = append(insts, slot.phi)
+		}
+		for _, inst := range block.Insts {
+			switch inst := inst.(type) {
+			case *llir.InstAlloca:
+				if promoted[inst] {
+					continue
+				}
+			case *llir.InstLoad:
+				if p.isPromoted(inst.Src) {
+					continue
+				}
+			case *llir.InstStore:
+				if p.isPromoted(inst.Dst) {
+					continue
+				}
+			}
+			insts = append(insts, inst)
+		}
+		block.Insts = insts
+	}
+	// Replace uses of removed loads.
+	replaceUses(f, p.repl)
+	llutil.ResetNames(f)
+	return allocas
+}
+
+// phiSlot is a phi instruction inserted for a promoted alloca.
+type phiSlot struct {
+	// Promoted alloca.
+	alloca *llir.InstAlloca
+	// Inserted phi instruction.
+	phi *llir.InstPhi
+}
+
+// promoter tracks the state of alloca promotion.
+type promoter struct {
+	// Promoted allocas.
+	promoted map[*llir.InstAlloca]bool
+	// Dominator tree of the function.
+	dom *cfg.DomTree
+	// Phi instructions inserted at the start of each basic block.
+	phis map[*llir.Block][]*phiSlot
+	// Replacement value of each removed load instruction; possibly another
+	// removed load.
+	repl map[value.Value]value.Value
+	// Local names in use.
+	names map[string]bool
+}
+
+// isPromoted reports whether the given address is a promoted alloca.
+func (p *promoter) isPromoted(addr value.Value) bool {
+	alloca, ok := addr.(*llir.InstAlloca)
+	return ok && p.promoted[alloca]
+}
+
+// rename replaces the loads of promoted allocas in the given basic block and
+// the basic blocks it dominates, given the current value of each promoted
+// alloca on entry to block.
+func (p *promoter) rename(block *llir.Block, cur map[*llir.InstAlloca]value.Value) {
+	for _, slot := range p.phis[block] {
+		cur[slot.alloca] = slot.phi
+	}
+	for _, inst := range block.Insts {
+		switch inst := inst.(type) {
+		case *llir.InstLoad:
+			if p.isPromoted(inst.Src) {
+				p.repl[inst] = cur[inst.Src.(*llir.InstAlloca)]
+			}
+		case *llir.InstStore:
+			if p.isPromoted(inst.Dst) {
+				cur[inst.Dst.(*llir.InstAlloca)] = inst.Src
+			}
+		}
+	}
+	p.addIncomings(block, cur)
+	for _, child := range p.dom.Children(block) {
+		c := make(map[*llir.InstAlloca]value.Value, len(cur))
+		for alloca, v := range cur {
+			c[alloca] = v
+		}
+		p.rename(child, c)
+	}
+}
+
+// addIncomings adds incoming values from the given basic block to the
+// inserted phi instructions of its successors, given the current value of
+// each promoted alloca on exit from block; undef if cur is nil. An incoming
+// value is added for each edge, including duplicate edges (e.g. switch cases
+// with the same target).
+func (p *promoter) addIncomings(block *llir.Block, cur map[*llir.InstAlloca]value.Value) {
+	if block.Term == nil {
+		return
+	}
+	for _, succ := range block.Term.Succs() {
+		for _, slot := range p.phis[succ] {
+			var x value.Value
+			if cur != nil {
+				x = cur[slot.alloca]
+			} else {
+				x = constant.NewUndef(slot.alloca.ElemType)
+			}
+			slot.phi.Incs = append(slot.phi.Incs, llir.NewIncoming(x, block))
+		}
+	}
+}
+
+// removeDeadPhis removes inserted phi instructions which are not used, other
+// than by inserted phi instructions which are themselves not used.
+func (p *promoter) removeDeadPhis(f *llir.Func) {
+	inserted := make(map[value.Value]bool)
+	for _, slots := range p.phis {
+		for _, slot := range slots {
+			inserted[slot.phi] = true
+		}
+	}
+	live := make(map[value.Value]bool)
+	var work []value.Value
+	markLive := func(v value.Value) {
+		v = resolve(p.repl, v)
+		if inserted[v] && !live[v] {
+			live[v] = true
+			work = append(work, v)
+		}
+	}
+	markOperands := func(ops []*value.Value) {
+		for _, op := range ops {
+			markLive(*op)
+		}
+	}
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			switch inst := inst.(type) {
+			case *llir.InstLoad:
+				if p.isPromoted(inst.Src) {
+					continue
+				}
+			case *llir.InstStore:
+				if p.isPromoted(inst.Dst) {
+					continue
+				}
+			}
+			markOperands(llutil.Operands(inst))
+		}
+		if block.Term != nil {
+			markOperands(llutil.Operands(block.Term))
+		}
+	}
+	for len(work) > 0 {
+		phi := work[len(work)-1].(*llir.InstPhi)
+		work = work[:len(work)-1]
+		for _, inc := range phi.Incs {
+			markLive(inc.X)
+		}
+	}
+	for block, slots := range p.phis {
+		var keep []*phiSlot
+		for _, slot := range slots {
+			if live[slot.phi] {
+				keep = append(keep, slot)
+			}
+		}
+		p.phis[block] = keep
+	}
+}
+
+// isPromotable reports whether the given alloca instruction with the given
+// users is promotable.
+func isPromotable(alloca *llir.InstAlloca, users []interface{}) bool {
+	if alloca.InAlloca || alloca.SwiftError {
+		return false
+	}
+	if alloca.NElems != nil {
+		n, ok := alloca.NElems.(*constant.Int)
+		if !ok || !n.X.IsInt64() || n.X.Int64() != 1 {
+			return false
+		}
+	}
+	for _, user := range users {
+		switch user := user.(type) {
+		case *llir.InstLoad:
+			if user.Atomic || user.Volatile || !types.Equal(user.ElemType, alloca.ElemType) {
+				return false
+			}
+		case *llir.InstStore:
+			if user.Atomic || user.Volatile || user.Src == alloca || !types.Equal(user.Src.Type(), alloca.ElemType) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,142 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/types"
+)
+
+func TestPromoteAllocasDiamond(t *testing.T) {
+	i32 := func(x int64) *constant.Int { return constant.NewInt(types.I32, x) }
+	a := llir.NewParam("a", types.I32)
+	c := llir.NewParam("c", types.I1)
+	f := llir.NewFunc("f", types.I32, a, c)
+	entry := f.NewBlock("entry")
+	then := f.NewBlock("then")
+	els := f.NewBlock("else")
+	join := f.NewBlock("join")
+	x := entry.NewAlloca(types.I32)
+	x.SetName("x")
+	// Unnamed alloca loaded before any store.
+	y := entry.NewAlloca(types.I32)
+	entry.NewStore(a, x)
+	entry.NewCondBr(c, then, els)
+	then.NewStore(i32(1), x)
+	// Store of a loaded value.
+	then.NewStore(then.NewLoad(types.I32, x), y)
+	then.NewBr(join)
+	els.NewBr(join)
+	sum := join.NewAdd(join.NewLoad(types.I32, x), join.NewLoad(types.I32, y))
+	join.NewRet(sum)
+
+	if !IsPromotable(f, x) || !IsPromotable(f, y) {
+		t.Fatalf("expected %s and %s to be promotable", x.Ident(), y.Ident())
+	}
+	promoted := PromoteAllocas(f)
+	if len(promoted) != 2 || promoted[0] != x || promoted[1] != y {
+		t.Errorf("promoted allocas mismatch; expected [x, y], got %v", promoted)
+	}
+	const want = `define i32 @f(i32 %a, i1 %c) {
+entry:
+	br i1 %c, label %then, label %else
+
+then:
+	br label %join
+
+else:
+	br label %join
+
+join:
+	%x1 = phi i32 [ 1, %then ], [ %a, %else ]
+	%0 = phi i32 [ 1, %then ], [ undef, %else ]
+	%1 = add i32 %x1, %0
+	ret i32 %1
+}`
+	if got := f.LLString(); got != want {
+		t.Errorf("function mismatch; expected\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestPromoteAllocasLoop(t *testing.T) {
+	i32 := func(x int64) *constant.Int { return constant.NewInt(types.I32, x) }
+	m := llir.NewModule()
+	use := m.NewFunc("use", types.Void, llir.NewParam("p", types.I32Ptr))
+	n := llir.NewParam("n", types.I32)
+	f := m.NewFunc("sum", types.I32, n)
+	entry := f.NewBlock("entry")
+	loop := f.NewBlock("loop")
+	body := f.NewBlock("body")
+	exit := f.NewBlock("exit")
+	dead := f.NewBlock("dead")
+	i := entry.NewAlloca(types.I32)
+	i.SetName("i")
+	s := entry.NewAlloca(types.I32)
+	s.SetName("s")
+	// Not promotable; the address escapes.
+	escaped := entry.NewAlloca(types.I32)
+	escaped.SetName("escaped")
+	// Not promotable; volatile load.
+	volatile := entry.NewAlloca(types.I32)
+	volatile.SetName("volatile")
+	entry.NewStore(i32(0), i)
+	entry.NewStore(i32(0), s)
+	entry.NewCall(use, escaped)
+	entry.NewBr(loop)
+	iv := loop.NewLoad(types.I32, i)
+	cond := loop.NewICmp(enum.IPredSLT, iv, n)
+	loop.NewCondBr(cond, body, exit)
+	sv := body.NewLoad(types.I32, s)
+	iv2 := body.NewLoad(types.I32, i)
+	body.NewStore(body.NewAdd(sv, iv2), s)
+	body.NewStore(body.NewAdd(iv2, i32(1)), i)
+	// Dead phi for volatile is not inserted.
+	body.NewStore(iv2, volatile)
+	body.NewBr(loop)
+	res := exit.NewLoad(types.I32, s)
+	vol := exit.NewLoad(types.I32, volatile)
+	vol.Volatile = true
+	exit.NewRet(exit.NewAdd(res, vol))
+	dead.NewStore(i32(7), i)
+	dead.NewBr(loop)
+
+	if IsPromotable(f, escaped) || IsPromotable(f, volatile) {
+		t.Fatalf("expected %s and %s not to be promotable", escaped.Ident(), volatile.Ident())
+	}
+	promoted := PromoteAllocas(f)
+	if len(promoted) != 2 || promoted[0] != i || promoted[1] != s {
+		t.Errorf("promoted allocas mismatch; expected [i, s], got %v", promoted)
+	}
+	const want = `define i32 @sum(i32 %n) {
+entry:
+	%escaped = alloca i32
+	%volatile = alloca i32
+	call void @use(i32* %escaped)
+	br label %loop
+
+loop:
+	%i1 = phi i32 [ 0, %entry ], [ %2, %body ], [ undef, %dead ]
+	%s1 = phi i32 [ 0, %entry ], [ %1, %body ], [ undef, %dead ]
+	%0 = icmp slt i32 %i1, %n
+	br i1 %0, label %body, label %exit
+
+body:
+	%1 = add i32 %s1, %i1
+	%2 = add i32 %i1, 1
+	store i32 %i1, i32* %volatile
+	br label %loop
+
+exit:
+	%3 = load volatile i32, i32* %volatile
+	%4 = add i32 %s1, %3
+	ret i32 %4
+
+dead:
+	br label %loop
+}`
+	if got := f.LLString(); got != want {
+		t.Errorf("function mismatch; expected\n%s\ngot\n%s", want, got)
+	}
+}
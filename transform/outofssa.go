@@ -0,0 +1,399 @@
+package transform
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/cfg"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/internal/enc"
+	"github.com/wa-lang/llir/llutil"
+	"github.com/wa-lang/llir/types"
+	"github.com/wa-lang/llir/value"
+)
+
+// Move is a move pseudo-instruction, which copies a value into a variable.
+type Move struct {
+	// Destination variable; a removed phi instruction or a temporary variable.
+	Dst value.Value
+	// Source value; a value of the function, a variable or a temporary
+	// variable.
+	Src value.Value
+}
+
+// String returns the string representation of the move.
+func (m *Move) String() string {
+	return fmt.Sprintf("%s = move %s", m.Dst.Ident(), m.Src)
+}
+
+// Temp is a temporary variable introduced to break cycles of moves.
+type Temp struct {
+	// Name of the temporary variable.
+	llir.LocalIdent
+	// Type of the temporary variable.
+	Typ types.Type
+}
+
+// String returns the LLVM syntax representation of the temporary variable as
+// a type-value pair.
+func (t *Temp) String() string {
+	return fmt.Sprintf("%s %s", t.Typ, t.Ident())
+}
+
+// Type returns the type of the temporary variable.
+func (t *Temp) Type() types.Type {
+	return t.Typ
+}
+
+// PhiMoves is the result of eliminating the phi instructions of a function.
+//
+// Each removed phi instruction is a variable, which is assigned by the moves
+// on exit from the predecessors of its basic block, and read by its uses. The
+// moves of a basic block are executed in order after its instructions and
+// before its terminator.
+type PhiMoves struct {
+	// Function without phi instructions.
+	Func *llir.Func
+	// Variables; the removed phi instructions, in function order.
+	Vars []*llir.InstPhi
+	// Temporary variables, in order of introduction.
+	Temps []*Temp
+
+	// Moves on exit from each basic block.
+	moves map[*llir.Block][]*Move
+	// Basic block of each variable.
+	varBlocks map[*llir.InstPhi]*llir.Block
+}
+
+// Moves returns the moves on exit from the given basic block, in execution
+// order.
+func (pm *PhiMoves) Moves(block *llir.Block) []*Move {
+	return pm.moves[block]
+}
+
+// String returns the string representation of the basic blocks of the
+// function, with moves inserted before the terminators.
+func (pm *PhiMoves) String() string {
+	f := pm.Func
+	if err := f.AssignIDs(); err != nil {
+		panic(fmt.Errorf("unable to assign IDs to local variables of function %q; %v", f.Ident(), err))
+	}
+	buf := &strings.Builder{}
+	for i, block := range f.Blocks {
+		if i != 0 {
+			buf.WriteString("\n")
+		}
+		if block.IsUnnamed() {
+			fmt.Fprintf(buf, "%s\n", enc.LabelID(block.LocalID))
+		} else {
+			fmt.Fprintf(buf, "%s\n", enc.LabelName(block.LocalName))
+		}
+		for _, inst := range block.Insts {
+			fmt.Fprintf(buf, "\t%s\n", inst.LLString())
+		}
+		for _, m := range pm.moves[block] {
+			fmt.Fprintf(buf, "\t%s\n", m)
+		}
+		fmt.Fprintf(buf, "\t%s\n", block.Term.LLString())
+	}
+	return buf.String()
+}
+
+// EliminatePhis removes the phi instructions of the given function by
+// inserting parallel copies on the edges to their basic blocks, and returns
+// the sequences of moves implementing the parallel copies.
+//
+// Critical edges to basic blocks with phi instructions are split, to prevent
+// moves from clobbering variables which are live along other edges (the lost
+// copy problem); as are edges from invoke terminators whose results are used
+// by the parallel copy. Parallel copies are sequentialized such that each
+// variable is read before it is overwritten, using temporary variables to
+// break cycles (the swap problem). Copies of undef values are omitted.
+//
+// Unnamed phi instructions are named "phi", to identify the variables after
+// their removal. An error is returned, without modifying the function, if an
+// edge which must be split cannot be split; i.e. an edge from an indirectbr
+// or callbr terminator, or an edge to an exception handling pad.
+func EliminatePhis(f *llir.Func) (*PhiMoves, error) {
+	return eliminatePhis(f, true)
+}
+
+// eliminatePhis removes the phi instructions of the given function, and
+// returns the moves implementing them. If nameVars is set, unnamed phi
+// instructions are named.
+func eliminatePhis(f *llir.Func, nameVars bool) (*PhiMoves, error) {
+	pm := &PhiMoves{
+		Func:      f,
+		moves:     make(map[*llir.Block][]*Move),
+		varBlocks: make(map[*llir.InstPhi]*llir.Block),
+	}
+	// Split edges, after checking that all edges which must be split can be
+	// split.
+	g := cfg.New(f)
+	var split []cfg.Edge
+	for _, block := range f.Blocks {
+		if len(phisOf(block)) == 0 {
+			continue
+		}
+		for _, pred := range g.Preds(block) {
+			if !mustSplit(g, pred, block) {
+				continue
+			}
+			if !canSplit(pred, block) {
+				e := cfg.Edge{From: pred, To: block}
+				return nil, errors.Errorf("unable to split edge %v to phi instructions in function %q", e, f.Ident())
+			}
+			split = append(split, cfg.Edge{From: pred, To: block})
+		}
+	}
+	for _, e := range split {
+		cfg.SplitEdge(e.From, e.To)
+	}
+	// Sequentialize the parallel copy on each edge to a basic block with phi
+	// instructions.
+	g = cfg.New(f)
+	names := llutil.LocalNames(f)
+	newTemp := func(dst value.Value) value.Value {
+		t := &Temp{Typ: dst.Type()}
+		if n, ok := dst.(llutil.Ident); ok && !n.IsUnnamed() {
+			t.SetName(llutil.UniqueName(names, n.Name()+".tmp"))
+		}
+		pm.Temps = append(pm.Temps, t)
+		return t
+	}
+	for _, block := range f.Blocks {
+		phis := phisOf(block)
+		if len(phis) == 0 {
+			continue
+		}
+		for _, phi := range phis {
+			pm.Vars = append(pm.Vars, phi)
+			pm.varBlocks[phi] = block
+			if nameVars && phi.IsUnnamed() {
+				phi.SetName(llutil.UniqueName(names, "phi"))
+			}
+		}
+		for _, pred := range g.Preds(block) {
+			var copies []*Move
+			for _, phi := range phis {
+				for _, inc := range phi.Incs {
+					if inc.Pred != pred {
+						continue
+					}
+					if _, ok := inc.X.(*constant.Undef); !ok {
+						copies = append(copies, &Move{Dst: phi, Src: inc.X})
+					}
+					break
+				}
+			}
+			pm.moves[pred] = append(pm.moves[pred], sequentialize(copies, newTemp)...)
+		}
+		block.Insts = block.Insts[len(phis):]
+	}
+	llutil.ResetNames(f)
+	return pm, nil
+}
+
+// DemotePhis removes the phi instructions of the given function by demoting
+// them to stack slots, and returns the alloca instructions inserted in the
+// entry basic block.
+//
+// The moves of EliminatePhis are implemented by stores to (and loads from) the
+// alloca instructions of variables and temporary variables, and each phi
+// instruction is replaced by a load from its stack slot at the start of its
+// basic block. The same edges as for EliminatePhis are split, and an error is
+// returned under the same conditions; as well as for phi instructions in
+// catchswitch basic blocks, which cannot hold loads.
+func DemotePhis(f *llir.Func) ([]*llir.InstAlloca, error) {
+	for _, block := range f.Blocks {
+		if _, ok := block.Term.(*llir.TermCatchSwitch); ok && len(phisOf(block)) > 0 {
+			return nil, errors.Errorf("unable to demote phi instructions of catchswitch basic block %s in function %q", block.Ident(), f.Ident())
+		}
+	}
+	pm, err := eliminatePhis(f, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(pm.Vars) == 0 {
+		return nil, nil
+	}
+	names := llutil.LocalNames(f)
+	var allocas []*llir.InstAlloca
+	slots := make(map[value.Value]*llir.InstAlloca)
+	newSlot := func(v value.Value) {
+		alloca := llir.NewAlloca(v.Type())
+		if n := v.(llutil.Ident); !n.IsUnnamed() {
+			alloca.SetName(llutil.UniqueName(names, n.Name()+".reg2mem"))
+		}
+		allocas = append(allocas, alloca)
+		slots[v] = alloca
+	}
+	for _, phi := range pm.Vars {
+		newSlot(phi)
+	}
+	for _, t := range pm.Temps {
+		newSlot(t)
+	}
+	// Replace phi instructions by loads from their stack slots.
+	loads := make(map[*llir.Block][]llir.Instruction)
+	repl := make(map[value.Value]value.Value)
+	for _, phi := range pm.Vars {
+		block := pm.varBlocks[phi]
+		load := llir.NewLoad(phi.Type(), slots[phi])
+		if !phi.IsUnnamed() {
+			load.SetName(phi.Name())
+		}
+		loads[block] = append(loads[block], load)
+		repl[phi] = load
+	}
+	for block, ls := range loads {
+		// Loads are inserted after exception handling pads, which must be the
+		// first non-phi instruction of their basic block.
+		i := 0
+		if len(block.Insts) > 0 && isPadInst(block.Insts[0]) {
+			i = 1
+		}
+		insts := append([]llir.Instruction{}, block.Insts[:i]...)
+		insts = append(insts, ls...)
+		block.Insts = append(insts, block.Insts[i:]...)
+	}
+	// Implement moves by loads and stores before terminators.
+	for _, block := range f.Blocks {
+		for _, m := range pm.moves[block] {
+			src := m.Src
+			if slot, ok := slots[src]; ok {
+				load := llir.NewLoad(src.Type(), slot)
+				block.Insts = append(block.Insts, load)
+				src = load
+			}
+			block.Insts = append(block.Insts, llir.NewStore(src, slots[m.Dst]))
+		}
+	}
+	replaceUses(f, repl)
+	entry := f.Blocks[0]
+	insts := make([]llir.Instruction, 0, len(allocas)+len(entry.Insts))
+	for _, alloca := range allocas {
+		insts = append(insts, alloca)
+	}
+	entry.Insts = append(insts, entry.Insts...)
+	llutil.ResetNames(f)
+	return allocas, nil
+}
+
+// sequentialize returns a sequence of moves equivalent to the given parallel
+// copy, in which each destination is distinct. Temporary variables are
+// created by newTemp, given the destination whose value is saved, to break
+// cycles of copies.
+//
+// The algorithm is based on Algorithm 1 of Boissinot et al., "Revisiting
+// Out-of-SSA Translation for Correctness, Code Quality, and Efficiency".
+func sequentialize(copies []*Move, newTemp func(dst value.Value) value.Value) []*Move {
+	// Source of each pending copy by destination.
+	pred := make(map[value.Value]value.Value)
+	// Current location of the original value of each source.
+	loc := make(map[value.Value]value.Value)
+	// Destinations of pending copies, and destinations which may be
+	// overwritten; processed in order of the parallel copy.
+	var todo, ready []value.Value
+	for _, c := range copies {
+		if c.Src == c.Dst {
+			continue
+		}
+		pred[c.Dst] = c.Src
+		loc[c.Src] = c.Src
+		todo = append(todo, c.Dst)
+	}
+	for _, dst := range todo {
+		if _, ok := loc[dst]; !ok {
+			// Not the source of any copy.
+			ready = append(ready, dst)
+		}
+	}
+	var moves []*Move
+	done := make(map[value.Value]bool)
+	for len(todo) > 0 {
+		for len(ready) > 0 {
+			b := ready[0]
+			ready = ready[1:]
+			a := pred[b]
+			c := loc[a]
+			moves = append(moves, &Move{Dst: b, Src: c})
+			done[b] = true
+			loc[a] = b
+			if _, ok := pred[a]; ok && a == c && !done[a] {
+				// The original value of a is saved in b; a may be overwritten.
+				ready = append(ready, a)
+			}
+		}
+		b := todo[0]
+		todo = todo[1:]
+		if !done[b] {
+			// b is part of a cycle of copies; save its value in a temporary
+			// variable.
+			t := newTemp(b)
+			moves = append(moves, &Move{Dst: t, Src: b})
+			loc[b] = t
+			ready = append(ready, b)
+		}
+	}
+	return moves
+}
+
+// mustSplit reports whether the edge from -> to, where to has phi
+// instructions, must be split before inserting moves on exit from from.
+func mustSplit(g *cfg.Graph, from, to *llir.Block) bool {
+	if g.IsCriticalEdge(from, to) {
+		return true
+	}
+	// Moves using the result of the terminator (e.g. invoke) must be inserted
+	// after the terminator.
+	if v, ok := from.Term.(value.Value); ok {
+		for _, phi := range phisOf(to) {
+			for _, inc := range phi.Incs {
+				if inc.Pred == from && inc.X == v {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// canSplit reports whether the edge from -> to may be split by cfg.SplitEdge.
+func canSplit(from, to *llir.Block) bool {
+	switch from.Term.(type) {
+	case *llir.TermIndirectBr, *llir.TermCallBr:
+		return false
+	}
+	insts := to.Insts[len(phisOf(to)):]
+	if len(insts) > 0 {
+		return !isPadInst(insts[0])
+	}
+	_, ok := to.Term.(*llir.TermCatchSwitch)
+	return !ok
+}
+
+// isPadInst reports whether the given instruction is an exception handling
+// pad.
+func isPadInst(inst llir.Instruction) bool {
+	switch inst.(type) {
+	case *llir.InstLandingPad, *llir.InstCatchPad, *llir.InstCleanupPad:
+		return true
+	}
+	return false
+}
+
+// phisOf returns the phi instructions at the start of the given basic block.
+func phisOf(block *llir.Block) []*llir.InstPhi {
+	var phis []*llir.InstPhi
+	for _, inst := range block.Insts {
+		phi, ok := inst.(*llir.InstPhi)
+		if !ok {
+			break
+		}
+		phis = append(phis, phi)
+	}
+	return phis
+}
@@ -0,0 +1,193 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/wa-lang/llir"
+	"github.com/wa-lang/llir/constant"
+	"github.com/wa-lang/llir/enum"
+	"github.com/wa-lang/llir/llutil"
+	"github.com/wa-lang/llir/types"
+	"github.com/wa-lang/llir/value"
+)
+
+func TestSequentialize(t *testing.T) {
+	a := llir.NewParam("a", types.I32)
+	b := llir.NewParam("b", types.I32)
+	c := llir.NewParam("c", types.I32)
+	d := llir.NewParam("d", types.I32)
+	golden := []struct {
+		name   string
+		copies []*Move
+		temps  int
+	}{
+		{name: "chain", copies: []*Move{{Dst: a, Src: b}, {Dst: b, Src: c}, {Dst: c, Src: d}}},
+		{name: "fan-out", copies: []*Move{{Dst: a, Src: b}, {Dst: b, Src: c}, {Dst: d, Src: b}}},
+		{name: "self", copies: []*Move{{Dst: a, Src: a}, {Dst: b, Src: a}}},
+		{name: "swap", copies: []*Move{{Dst: a, Src: b}, {Dst: b, Src: a}}, temps: 1},
+		{name: "rotate", copies: []*Move{{Dst: a, Src: b}, {Dst: b, Src: c}, {Dst: c, Src: a}}, temps: 1},
+		{name: "swap with fan-out", copies: []*Move{{Dst: a, Src: b}, {Dst: b, Src: a}, {Dst: c, Src: a}, {Dst: d, Src: b}}},
+		{name: "two swaps", copies: []*Move{{Dst: a, Src: b}, {Dst: b, Src: a}, {Dst: c, Src: d}, {Dst: d, Src: c}}, temps: 2},
+	}
+	for _, g := range golden {
+		// Initial values of variables.
+		env := map[value.Value]int{a: 1, b: 2, c: 3, d: 4}
+		want := make(map[value.Value]int)
+		for v, x := range env {
+			want[v] = x
+		}
+		for _, c := range g.copies {
+			want[c.Dst] = env[c.Src]
+		}
+		temps := 0
+		newTemp := func(dst value.Value) value.Value {
+			temps++
+			return &Temp{Typ: dst.Type()}
+		}
+		for _, m := range sequentialize(g.copies, newTemp) {
+			env[m.Dst] = env[m.Src]
+		}
+		for _, v := range []value.Value{a, b, c, d} {
+			if env[v] != want[v] {
+				t.Errorf("%s: value of %s mismatch; expected %d, got %d", g.name, v.Ident(), want[v], env[v])
+			}
+		}
+		if temps != g.temps {
+			t.Errorf("%s: number of temporary variables mismatch; expected %d, got %d", g.name, g.temps, temps)
+		}
+	}
+}
+
+// newSwapFunc returns a function with a loop swapping a and b in each
+// iteration, and with i used after the loop.
+//
+//    entry -> loop
+//    loop  -> loop, exit
+func newSwapFunc() *llir.Func {
+	n := llir.NewParam("n", types.I32)
+	f := llir.NewFunc("f", types.I32, n)
+	entry := f.NewBlock("entry")
+	loop := f.NewBlock("loop")
+	exit := f.NewBlock("exit")
+	entry.NewBr(loop)
+	a := loop.NewPhi(llir.NewIncoming(constant.NewInt(types.I32, 0), entry))
+	a.SetName("a")
+	b := loop.NewPhi(llir.NewIncoming(constant.NewInt(types.I32, 1), entry))
+	b.SetName("b")
+	i := loop.NewPhi(llir.NewIncoming(constant.NewUndef(types.I32), entry))
+	a.Incs = append(a.Incs, llir.NewIncoming(b, loop))
+	b.Incs = append(b.Incs, llir.NewIncoming(a, loop))
+	inc := loop.NewAdd(i, constant.NewInt(types.I32, 1))
+	i.Incs = append(i.Incs, llir.NewIncoming(inc, loop))
+	cond := loop.NewICmp(enum.IPredSLT, inc, n)
+	loop.NewCondBr(cond, loop, exit)
+	exit.NewRet(exit.NewSub(a, i))
+	return f
+}
+
+func TestEliminatePhis(t *testing.T) {
+	f := newSwapFunc()
+	pm, err := EliminatePhis(f)
+	if err != nil {
+		t.Fatalf("unable to eliminate phi instructions; %v", err)
+	}
+	if len(pm.Vars) != 3 || len(pm.Temps) != 1 {
+		t.Errorf("number of variables mismatch; expected 3 variables and 1 temporary variable, got %d and %d", len(pm.Vars), len(pm.Temps))
+	}
+	// The lost copy problem is avoided by splitting the critical edge of the
+	// loop, and the swap problem by saving a in a temporary variable.
+	const want = `entry:
+	%a = move i32 0
+	%b = move i32 1
+	br label %loop
+
+loop:
+	%0 = add i32 %phi, 1
+	%1 = icmp slt i32 %0, %n
+	br i1 %1, label %loop.loop_crit_edge, label %exit
+
+loop.loop_crit_edge:
+	%phi = move i32 %0
+	%a.tmp = move i32 %a
+	%a = move i32 %b
+	%b = move i32 %a.tmp
+	br label %loop
+
+exit:
+	%2 = sub i32 %a, %phi
+	ret i32 %2
+`
+	if got := pm.String(); got != want {
+		t.Errorf("moves mismatch; expected\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestDemotePhis(t *testing.T) {
+	m := llir.NewModule()
+	f := newSwapFunc()
+	m.Funcs = append(m.Funcs, f)
+	f.Parent = m
+	allocas, err := DemotePhis(f)
+	if err != nil {
+		t.Fatalf("unable to demote phi instructions; %v", err)
+	}
+	if len(allocas) != 4 {
+		t.Errorf("number of allocas mismatch; expected 4, got %d", len(allocas))
+	}
+	const want = `define i32 @f(i32 %n) {
+entry:
+	%a.reg2mem = alloca i32
+	%b.reg2mem = alloca i32
+	%0 = alloca i32
+	%a.tmp.reg2mem = alloca i32
+	store i32 0, i32* %a.reg2mem
+	store i32 1, i32* %b.reg2mem
+	br label %loop
+
+loop:
+	%a = load i32, i32* %a.reg2mem
+	%b = load i32, i32* %b.reg2mem
+	%1 = load i32, i32* %0
+	%2 = add i32 %1, 1
+	%3 = icmp slt i32 %2, %n
+	br i1 %3, label %loop.loop_crit_edge, label %exit
+
+loop.loop_crit_edge:
+	store i32 %2, i32* %0
+	%4 = load i32, i32* %a.reg2mem
+	store i32 %4, i32* %a.tmp.reg2mem
+	%5 = load i32, i32* %b.reg2mem
+	store i32 %5, i32* %a.reg2mem
+	%6 = load i32, i32* %a.tmp.reg2mem
+	store i32 %6, i32* %b.reg2mem
+	br label %loop
+
+exit:
+	%7 = sub i32 %a, %1
+	ret i32 %7
+}`
+	if got := f.LLString(); got != want {
+		t.Errorf("function mismatch; expected\n%s\ngot\n%s", want, got)
+	}
+	if errs := llutil.Verify(m); len(errs) > 0 {
+		t.Errorf("unable to verify module; %v", errs)
+	}
+}
+
+func TestEliminatePhisIndirectBr(t *testing.T) {
+	f := llir.NewFunc("f", types.I32)
+	entry := f.NewBlock("entry")
+	a := f.NewBlock("a")
+	b := f.NewBlock("b")
+	addr := constant.NewBlockAddress(f, a)
+	entry.NewIndirectBr(addr, a, b)
+	b.NewBr(a)
+	a.NewRet(a.NewPhi(llir.NewIncoming(constant.NewInt(types.I32, 0), entry), llir.NewIncoming(constant.NewInt(types.I32, 1), b)))
+	before := f.LLString()
+	if _, err := EliminatePhis(f); err == nil {
+		t.Errorf("expected error for critical edge from indirectbr terminator")
+	}
+	if after := f.LLString(); after != before {
+		t.Errorf("function modified; expected\n%s\ngot\n%s", before, after)
+	}
+}